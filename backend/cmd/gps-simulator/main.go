@@ -0,0 +1,63 @@
+// Command gps-simulator impersonates a GT06 or Teltonika tracker and drives
+// a vehicle along a straight line so the TCP gateway can be tried locally:
+//
+//	go run ./cmd/gps-simulator -protocol gt06 -addr localhost:5023 -imei 356307042441013
+package main
+
+import (
+	"flag"
+	"log"
+	"math"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/gateway"
+)
+
+func main() {
+	protocol := flag.String("protocol", gateway.ProtocolGT06, "tracker protocol: gt06 or teltonika")
+	addr := flag.String("addr", "localhost:5023", "gateway address")
+	imei := flag.String("imei", "356307042441013", "15-digit device IMEI")
+	lat := flag.Float64("lat", -6.175392, "start latitude")
+	lng := flag.Float64("lng", 106.827153, "start longitude")
+	speed := flag.Float64("speed", 40, "speed in km/h")
+	heading := flag.Float64("heading", 90, "heading in degrees")
+	interval := flag.Duration("interval", 10*time.Second, "time between fixes")
+	count := flag.Int("count", 30, "number of fixes to send")
+	flag.Parse()
+
+	sim, err := gateway.DialSimulator(*protocol, *addr, *imei)
+	if err != nil {
+		log.Fatalf("Connect failed: %v", err)
+	}
+	defer sim.Close()
+	log.Printf("Logged in to %s as %s (%s)", *addr, *imei, *protocol)
+
+	// Degrees moved per fix along the heading
+	meters := *speed / 3.6 * interval.Seconds()
+	rad := *heading * math.Pi / 180
+	dLat := meters * math.Cos(rad) / 111320
+	dLng := meters * math.Sin(rad) / (111320 * math.Cos(*lat*math.Pi/180))
+
+	for i := 0; i < *count; i++ {
+		p := gateway.Position{
+			Time:       time.Now().UTC(),
+			Latitude:   *lat + dLat*float64(i),
+			Longitude:  *lng + dLng*float64(i),
+			Speed:      *speed,
+			Course:     int(*heading),
+			Satellites: 9,
+			Valid:      true,
+		}
+		if err := sim.SendPositions([]gateway.Position{p}); err != nil {
+			log.Fatalf("Send failed: %v", err)
+		}
+		log.Printf("Sent fix %d: %.6f, %.6f", i+1, p.Latitude, p.Longitude)
+
+		if i+1 < *count {
+			time.Sleep(*interval)
+			if err := sim.SendHeartbeat(); err != nil {
+				log.Fatalf("Heartbeat failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/db"
+	"github.com/youruser/aplikasi-tms/backend/internal/mail"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+// newMailer sends through SMTP when SMTP_HOST is set; otherwise emails are
+// only logged
+func newMailer(cfg *config.MailConfig) mail.Mailer {
+	if cfg.SMTPHost == "" {
+		log.Println("SMTP_HOST not set; emails will be logged, not sent")
+		return mail.LogMailer{}
+	}
+	return mail.NewSMTPMailer(cfg)
+}
+
+// requireVerifiedEmail stops fleet owners who have not verified their email
+// from registering vehicles
+func requireVerifiedEmail(c *gin.Context) {
+	role, _ := c.Get("user_role")
+	if role != auth.RoleFleetOwner {
+		c.Next()
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userIDInt, _ := userID.(int)
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	verified, err := services.NewAccountService(conn, mailer, mailConfig, sessionConfig).IsEmailVerified(userIDInt)
+	if err != nil {
+		log.Printf("Email verification check error: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check email verification"})
+		return
+	}
+	if !verified {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "Email verification required before registering vehicles",
+			"code":  "email_not_verified",
+		})
+		return
+	}
+	c.Next()
+}
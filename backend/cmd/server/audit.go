@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/youruser/aplikasi-tms/backend/internal/audit"
+	"github.com/youruser/aplikasi-tms/backend/internal/db"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+// auditEntities maps state-changing routes to the row they change, so the
+// audit entry carries a before/after diff. Routes missing here are still
+// recorded, without an entity.
+var auditEntities = map[string]audit.Entity{
+	"POST /api/v1/register":                                 {Type: "user", Table: "users", Result: "user.id"},
+	"POST /api/v1/auth/2fa/setup":                           {Type: "user", Table: "users", Actor: true},
+	"POST /api/v1/auth/2fa/enable":                          {Type: "user", Table: "users", Actor: true},
+	"POST /api/v1/auth/2fa/disable":                         {Type: "user", Table: "users", Actor: true},
+	"PUT /api/v1/admin/users/:id/role":                      {Type: "user", Table: "users", Param: "id"},
+	"DELETE /api/v1/admin/users/:id/2fa":                    {Type: "user", Table: "users", Param: "id"},
+	"POST /api/v1/fleet/register":                           {Type: "fleet_owner", Table: "fleet_owners", Result: "fleet_owner.id"},
+	"POST /api/v1/ocr/face-match":                           {Type: "fleet_owner", Table: "fleet_owners", Column: "user_id", Actor: true},
+	"POST /api/v1/vehicles":                                 {Type: "vehicle", Table: "vehicles", Result: "vehicle.id"},
+	"POST /api/v1/fleet/vehicles":                           {Type: "vehicle", Table: "vehicles", Result: "vehicle.id"},
+	"PUT /api/v1/admin/vehicles/:id/verify":                 {Type: "vehicle", Table: "vehicles", Param: "id"},
+	"PUT /api/v1/admin/vehicles/:id/qa-review":              {Type: "vehicle", Table: "vehicles", Param: "id"},
+	"POST /api/v1/admin/vehicles/:id/claim":                 {Type: "vehicle", Table: "vehicles", Param: "id"},
+	"DELETE /api/v1/admin/vehicles/:id/claim":               {Type: "vehicle", Table: "vehicles", Param: "id"},
+	"POST /api/v1/admin/verification-queue/next":            {Type: "vehicle", Table: "vehicles", Result: "item.vehicle_id"},
+	"PUT /api/v1/admin/vehicles/:id/correction":             {Type: "vehicle", Table: "vehicles", Param: "id"},
+	"POST /api/v1/admin/vehicles/:id/cross-check":           {Type: "vehicle", Table: "vehicles", Param: "id"},
+	"POST /api/v1/admin/vehicles/:id/schedule-inspection":   {Type: "vehicle", Table: "vehicles", Param: "id"},
+	"PUT /api/v1/admin/validation-rules":                    {Type: "validation_rules", Table: "validation_rule_sets", Column: "version", Result: "version"},
+	"POST /api/v1/vehicles/:id/attachments":                 {Type: "vehicle_attachment", Table: "vehicle_attachments", Result: "attachment.id"},
+	"DELETE /api/v1/vehicles/:id/attachments/:attachmentId": {Type: "vehicle_attachment", Table: "vehicle_attachments", Param: "attachmentId"},
+	"POST /api/v1/documents/upload":                         {Type: "document", Table: "user_documents", Result: "id"},
+	"PUT /api/v1/admin/documents/:id/verify":                {Type: "document", Table: "user_documents", Param: "id"},
+	"POST /api/v1/admin/customer-contracts":                 {Type: "customer_contract", Table: "customer_contracts", Result: "contract.id"},
+	"POST /api/v1/trips":                                    {Type: "trip", Table: "trips", Result: "trip.id"},
+	"PUT /api/v1/trips/:id/transition":                      {Type: "trip", Table: "trips", Param: "id"},
+	"PUT /api/v1/driver/trips/:id/status":                   {Type: "trip", Table: "trips", Param: "id"},
+	"POST /api/v1/driver/trips/:id/stops/:stopId/check-in":  {Type: "trip_stop", Table: "trip_stops", Param: "stopId"},
+	"POST /api/v1/driver/trips/:id/stops/:stopId/check-out": {Type: "trip_stop", Table: "trip_stops", Param: "stopId"},
+	"POST /api/v1/driver/trips/:id/pod":                     {Type: "trip_pod", Table: "trip_pods", Result: "pod.id"},
+	"PUT /api/v1/notifications/:id/read":                    {Type: "notification", Table: "notifications", Param: "id"},
+	"POST /api/v1/gps-registration":                         {Type: "gps_registration", Table: "gps_registrations", Result: "id"},
+	"PUT /api/v1/gps-registration/:id/approve":              {Type: "gps_registration", Table: "gps_registrations", Param: "id"},
+	"POST /api/v1/gps-devices/assign":                       {Type: "gps_device", Table: "gps_devices", Column: "device_id", Body: "device_id"},
+	"PUT /api/v1/gps-devices/:deviceId/status":              {Type: "gps_device", Table: "gps_devices", Column: "device_id", Param: "deviceId"},
+	"POST /api/v1/geofences":                                {Type: "geofence", Table: "geofences", Result: "id"},
+	"PUT /api/v1/geofences/:id":                             {Type: "geofence", Table: "geofences", Param: "id"},
+	"DELETE /api/v1/geofences/:id":                          {Type: "geofence", Table: "geofences", Param: "id"},
+	"PUT /api/v1/driving-alerts/:id/acknowledge":            {Type: "driving_alert", Table: "driving_alerts", Param: "id"},
+	"PUT /api/v1/speed-limits":                              {Type: "speed_limit", Table: "vehicle_speed_limits", Column: "vehicle_type", Body: "vehicle_type"},
+}
+
+// auditSkipped are POST routes that change nothing or that devices call
+// at a rate the audit log is not meant for
+var auditSkipped = map[string]bool{
+	"POST /api/v1/gps-tracking/ingest":            true,
+	"POST /api/v1/gps-tracking/batch-ingest":      true,
+	"POST /api/v1/driver/trips/:id/tracking":      true,
+	"POST /api/v1/ocr/stnk":                       true,
+	"POST /api/v1/ocr/ktp":                        true,
+	"POST /api/v1/ocr/validate-quality":           true,
+	"POST /api/v1/admin/validation-rules/dry-run": true,
+}
+
+// auditRecorder writes the audit entries of API calls
+type auditRecorder struct{}
+
+func (auditRecorder) Snapshot(entity audit.Entity, key string) (map[string]interface{}, error) {
+	conn, err := db.Connect()
+	if err != nil {
+		return nil, err
+	}
+	return services.NewAuditService(conn).Snapshot(entity, key)
+}
+
+func (auditRecorder) Record(event *models.AuditEvent) error {
+	conn, err := db.Connect()
+	if err != nil {
+		return err
+	}
+	return services.NewAuditService(conn).Record(event)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/db"
+	"github.com/youruser/aplikasi-tms/backend/internal/gateway"
+	"github.com/youruser/aplikasi-tms/backend/internal/handlers"
+	"github.com/youruser/aplikasi-tms/backend/internal/ingest"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+// startGPSGateways starts a TCP listener for each tracker protocol that has
+// an address configured, e.g. GT06_LISTEN_ADDR=:5023 and
+// TELTONIKA_LISTEN_ADDR=:5027. Points go through the same path as
+// /gps-tracking/ingest.
+func startGPSGateways() {
+	listeners := map[string]string{
+		gateway.ProtocolGT06:      os.Getenv("GT06_LISTEN_ADDR"),
+		gateway.ProtocolTeltonika: os.Getenv("TELTONIKA_LISTEN_ADDR"),
+	}
+
+	for protocol, addr := range listeners {
+		if addr == "" {
+			continue
+		}
+
+		server, err := gateway.NewServer(protocol, resolveGatewayIMEI, storeGatewayPoint)
+		if err != nil {
+			log.Printf("GPS gateway error: %v", err)
+			continue
+		}
+		go func(protocol, addr string) {
+			if err := server.ListenAndServe(addr); err != nil {
+				log.Printf("GPS gateway (%s) stopped: %v", protocol, err)
+			}
+		}(protocol, addr)
+	}
+}
+
+func resolveGatewayIMEI(imei string) (string, error) {
+	conn, err := db.Connect()
+	if err != nil {
+		return "", err
+	}
+
+	deviceID, err := repository.NewGPSDeviceRepository(conn).GetDeviceIDByIMEI(imei)
+	if err == sql.ErrNoRows {
+		return "", gateway.ErrUnknownDevice
+	}
+	return deviceID, err
+}
+
+// storeGatewayPoint queues a gateway point on the ingest writer. A refused
+// point is returned as an error so the device keeps it and resends; one
+// older than the raw history kept is dropped, as resending cannot help.
+func storeGatewayPoint(data *models.GPSTrackingData) error {
+	if maxAge := gpsWriter.MaxPointAge(); maxAge > 0 && data.Timestamp.Before(time.Now().Add(-maxAge)) {
+		log.Printf("GPS gateway dropped point of device %s at %v: older than raw history", data.DeviceID, data.Timestamp)
+		return nil
+	}
+	return gpsWriter.Submit(*data)
+}
+
+// startGPSWriter starts the buffered writer used by every ingest path. Each
+// batch is written with COPY and then run through the geofence and driving
+// rule engines in order.
+func startGPSWriter() *ingest.Writer {
+	cfg := config.LoadIngestConfig()
+	cfg.MaxPointAge = time.Duration(gpsRetention.RawRetentionDays) * 24 * time.Hour
+
+	flush := func(points []models.GPSTrackingData) error {
+		conn, err := db.Connect()
+		if err != nil {
+			return err
+		}
+		err = repository.NewGPSTrackingRepository(conn).BulkInsertTrackingData(points)
+		// Data exceptions and constraint violations come from the points
+		// themselves; anything else is storage and is retried
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && (pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23") {
+			return fmt.Errorf("%w: %v", ingest.ErrInvalidBatch, err)
+		}
+		return err
+	}
+
+	stored := func(points []models.GPSTrackingData) {
+		conn, err := db.Connect()
+		if err != nil {
+			log.Printf("Database connection error: %v", err)
+			return
+		}
+
+		processor := handlers.NewTrackingProcessor(services.NewGeofenceService(conn), services.NewDrivingAlertService(conn, drivingRules), repository.NewGPSDeviceRepository(conn))
+		for i := range points {
+			processor.Process(&points[i])
+		}
+	}
+
+	return ingest.NewWriter(cfg, flush, stored)
+}
+
+// startGPSHistoryMaintenance keeps gps_tracking partitions ahead of time
+// and applies the retention policy in the background. Close the returned
+// channel to stop it.
+func startGPSHistoryMaintenance() chan struct{} {
+	stop := make(chan struct{})
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		return stop
+	}
+
+	go services.NewGPSHistoryService(conn, gpsRetention).StartMaintenance(stop)
+	return stop
+}
@@ -1,32 +1,109 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/youruser/aplikasi-tms/backend/internal/db"
 	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/ingest"
 	"github.com/youruser/aplikasi-tms/backend/internal/middleware"
 	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/registry"
 	"github.com/youruser/aplikasi-tms/backend/internal/services"
+	"github.com/youruser/aplikasi-tms/backend/internal/mail"
+	"github.com/youruser/aplikasi-tms/backend/internal/ratelimit"
 	"github.com/youruser/aplikasi-tms/backend/internal/repository"
 	"github.com/youruser/aplikasi-tms/backend/internal/handlers"
 )
 
+// drivingRules holds the driving alert thresholds, loaded once at startup
+var drivingRules *config.DrivingRulesConfig
+
+// gpsWriter buffers ingested GPS points and writes them in batches
+var gpsWriter *ingest.Writer
+
+// gpsRetention holds the GPS history retention policy, loaded once at startup
+var gpsRetention *config.GPSRetentionConfig
+
+// sessionConfig holds the access and refresh token lifetimes, loaded once at startup
+var sessionConfig *config.SessionConfig
+
+// loginLockout locks accounts after repeated failed logins
+var loginLockout *ratelimit.Lockout
+
+// mailConfig and mailer send verification and password reset emails
+var mailConfig *config.MailConfig
+var mailer mail.Mailer
+
+// ocrEngine reads STNK and KTP photos
+var ocrEngine services.OCREngine
+
+// faceEngine and faceConfig match a fleet owner's selfie with their KTP
+var faceEngine services.FaceEngine
+var faceConfig *config.FaceConfig
+
+// registries are the Samsat, KIR and insurance registries vehicles are
+// cross-checked against
+var registries map[string]registry.Provider
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found")
 	}
+	drivingRules = config.LoadDrivingRulesConfig()
+	gpsRetention = config.LoadGPSRetentionConfig()
+	sessionConfig = config.LoadSessionConfig()
+	mailConfig = config.LoadMailConfig()
+	mailer = newMailer(mailConfig)
+	ocrEngine = newOCREngine(config.LoadOCRConfig())
+	faceConfig = config.LoadFaceConfig()
+	faceEngine = newFaceEngine(faceConfig)
+	registries = newRegistries(config.LoadRegistryConfig())
+	verificationQueue = config.LoadVerificationQueueConfig()
+
+	// Schema migrations: `server migrate up|down|status`
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:]))
+	}
+	// Role bootstrap: `server grant-role <username|email> <role>`
+	if len(os.Args) > 1 && os.Args[1] == "grant-role" {
+		os.Exit(runGrantRoleCommand(os.Args[2:]))
+	}
+	security, err := config.LoadSecurityConfig()
+	if err != nil {
+		log.Fatalf("Security configuration error: %v", err)
+	}
+	autoMigrate()
+	gpsWriter = startGPSWriter()
+	startGPSGateways()
+	stopGPSMaintenance := startGPSHistoryMaintenance()
+	stopSessionCleanup := startSessionCleanup()
+	stopDocumentValidation := startDocumentValidation()
+	stopVerificationQueue := startVerificationQueue()
+	middleware.SetRevocationChecker(isTokenRevoked)
+
+	// Rate limits: per client IP on public endpoints, per user once authenticated
+	limits := ratelimit.NewMemoryStore()
+	ipLimit := middleware.RateLimitByIP(ratelimit.NewLimiter(limits, "ip:", ratelimit.PerMinute(security.RateLimitRPM)))
+	middleware.SetUserRateLimiter(ratelimit.NewLimiter(limits, "user:", ratelimit.PerMinute(security.RateLimitRPM)))
+	loginLockout = ratelimit.NewLockout(security.MaxLoginAttempts, security.LoginLockoutBase, security.LoginLockoutMax)
 
 	// Initialize Gin router
 	r := gin.Default()
@@ -73,6 +150,7 @@ func main() {
 
 	// API routes
 	api := r.Group("/api/v1")
+	api.Use(middleware.Audit(auditRecorder{}, auditEntities, auditSkipped))
 	{
 		api.GET("/ping", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{
@@ -111,33 +189,50 @@ func main() {
 			})
 		})
 		
-		api.POST("/register", registerHandler)
-		api.POST("/login", loginHandler)
+		api.POST("/register", ipLimit, registerHandler)
+		api.POST("/login", ipLimit, loginHandler)
+		api.POST("/auth/refresh", ipLimit, refreshTokenHandler)
+		api.POST("/auth/logout", middleware.AuthRequired(), logoutHandler)
+		api.POST("/auth/logout-all", middleware.AuthRequired(), logoutAllHandler)
+		api.POST("/auth/verify-email", ipLimit, verifyEmailHandler)
+		api.POST("/auth/resend-verification", middleware.AuthRequired(), resendVerificationHandler)
+		api.POST("/auth/forgot-password", ipLimit, forgotPasswordHandler)
+		api.POST("/auth/reset-password", ipLimit, resetPasswordHandler)
+		api.GET("/auth/2fa", middleware.AuthRequired(), getTwoFactorStatusHandler)
+		api.POST("/auth/2fa/setup", middleware.TwoFactorEnrollment(), setupTwoFactorHandler)
+		api.POST("/auth/2fa/enable", middleware.TwoFactorEnrollment(), enableTwoFactorHandler)
+		api.POST("/auth/2fa/verify", middleware.TwoFactorPending(), verifyTwoFactorHandler)
+		api.POST("/auth/2fa/disable", middleware.AuthRequired(), disableTwoFactorHandler)
+		api.POST("/auth/2fa/recovery-codes", middleware.AuthRequired(), regenerateRecoveryCodesHandler)
 		
 		// Vehicle endpoints
-		api.POST("/vehicles", middleware.AuthRequired(), createVehicleHandler)
+		api.POST("/vehicles", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesCreate), requireVerifiedEmail, createVehicleHandler)
 		api.GET("/approved-vehicles", getApprovedVehiclesPublicHandler)
-		api.GET("/vehicles", getVehiclesHandler)
-		api.GET("/vehicles/:id", getVehicleHandler)
+		api.GET("/vehicles", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesRead), getVehiclesHandler)
+		api.GET("/vehicles/:id", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesRead), getVehicleHandler)
 		
 		// Driver endpoints
 		api.POST("/drivers", middleware.AuthRequired(), createDriverHandler)
-		api.GET("/drivers", getDriversHandler)
-		api.GET("/drivers/:id", getDriverHandler)
+		api.GET("/drivers", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDriversRead), getDriversHandler)
+		api.GET("/drivers/:id", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDriversRead), getDriverHandler)
 		
 		// Trip endpoints
-		api.POST("/trips", middleware.AuthRequired(), createTripHandler)
-		api.GET("/trips", getTripsHandler)
-		api.GET("/trips/:id", getTripHandler)
+		api.POST("/trips", middleware.AuthRequired(), middleware.RequirePermission(auth.PermTripsCreate), createTripHandler)
+		api.GET("/trips", middleware.AuthRequired(), middleware.RequirePermission(auth.PermTripsRead), getTripsHandler)
+		api.GET("/trips/:id", middleware.AuthRequired(), middleware.RequirePermission(auth.PermTripsRead), getTripHandler)
+		api.PUT("/trips/:id/transition", middleware.AuthRequired(), middleware.RequirePermission(auth.PermTripsDispatch), transitionTripHandler)
+		api.GET("/trips/:id/history", middleware.AuthRequired(), middleware.RequirePermission(auth.PermTripsRead), getTripStatusHistoryHandler)
+		api.GET("/trips/:id/pod", middleware.AuthRequired(), getTripPODHandler)
+		api.GET("/trips/:id/pod/pdf", middleware.AuthRequired(), getTripPODPDFHandler)
 		
 		// Analytics endpoints
-		api.GET("/dashboard/stats", getDashboardStatsHandler)
-		api.GET("/dashboard/vehicle-utilization", getVehicleUtilizationHandler)
+		api.GET("/dashboard/stats", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDashboardRead), getDashboardStatsHandler)
+		api.GET("/dashboard/vehicle-utilization", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDashboardRead), getVehicleUtilizationHandler)
 		
 		// Fleet management endpoints
 		api.POST("/fleet/register", middleware.AuthRequired(), registerFleetOwnerHandler)
 		api.GET("/fleet/profile", middleware.AuthRequired(), getFleetProfileHandler)
-		api.POST("/fleet/vehicles", middleware.AuthRequired(), registerFleetVehicleHandler)
+		api.POST("/fleet/vehicles", middleware.AuthRequired(), requireVerifiedEmail, registerFleetVehicleHandler)
 		api.GET("/fleet/vehicles", middleware.AuthRequired(), getFleetVehiclesHandler)
 		
 		// File upload endpoints
@@ -148,19 +243,41 @@ func main() {
 		api.GET("/files/:filename", serveFileHandler)
 		
 		// Admin endpoints
-		api.GET("/admin/dashboard", middleware.AuthRequired(), middleware.AdminRequired(), getAdminDashboardHandler)
-		api.GET("/admin/verification-dashboard", middleware.AuthRequired(), middleware.AdminRequired(), getAdminVerificationDashboardHandler)
-		api.GET("/admin/vehicles/pending", middleware.AuthRequired(), middleware.AdminRequired(), getPendingVehiclesHandler)
-		api.GET("/admin/vehicles/status/:status", middleware.AuthRequired(), middleware.AdminRequired(), getVehiclesByStatusHandler)
-		api.GET("/admin/vehicles", middleware.AuthRequired(), middleware.AdminRequired(), getAllVehiclesAdminHandler)
-		api.GET("/admin/vehicles/:id", middleware.AuthRequired(), middleware.AdminRequired(), getVehicleDetailsAdminHandler)
-		api.PUT("/admin/vehicles/:id/verify", middleware.AuthRequired(), middleware.AdminRequired(), verifyVehicleHandler)
-		api.PUT("/admin/vehicles/:id/correction", middleware.AuthRequired(), middleware.AdminRequired(), requestCorrectionHandler)
-		api.POST("/admin/vehicles/:id/cross-check", middleware.AuthRequired(), middleware.AdminRequired(), performCrossCheckHandler)
-		api.POST("/admin/vehicles/:id/schedule-inspection", middleware.AuthRequired(), middleware.AdminRequired(), scheduleInspectionHandler)
-		api.GET("/admin/vehicles/:id/history", middleware.AuthRequired(), middleware.AdminRequired(), getVehicleVerificationHistoryHandler)
-		api.GET("/admin/documents", middleware.AuthRequired(), middleware.AdminRequired(), getUploadedDocumentsHandler)
-		api.PUT("/admin/documents/:id/verify", middleware.AuthRequired(), middleware.AdminRequired(), verifyDocumentHandler)
+		api.GET("/admin/dashboard", middleware.AuthRequired(), middleware.RequirePermission(auth.PermAdminDashboard), getAdminDashboardHandler)
+		api.GET("/admin/verification-dashboard", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), getAdminVerificationDashboardHandler)
+		api.GET("/admin/vehicles/pending", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), getPendingVehiclesHandler)
+		api.GET("/admin/vehicles/status/:status", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), getVehiclesByStatusHandler)
+		api.GET("/admin/vehicles", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), getAllVehiclesAdminHandler)
+		api.GET("/admin/vehicles/:id", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), getVehicleDetailsAdminHandler)
+		api.PUT("/admin/vehicles/:id/verify", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), verifyVehicleHandler)
+		api.PUT("/admin/vehicles/:id/correction", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), requestCorrectionHandler)
+		api.POST("/admin/vehicles/:id/cross-check", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), performCrossCheckHandler)
+		api.POST("/admin/vehicles/:id/schedule-inspection", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), scheduleInspectionHandler)
+		api.GET("/admin/vehicles/:id/history", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), getVehicleVerificationHistoryHandler)
+		api.GET("/admin/qa-reviews", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), getQAReviewsHandler)
+		api.PUT("/admin/vehicles/:id/qa-review", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), completeQAReviewHandler)
+		api.POST("/admin/vehicles/:id/claim", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), claimVerificationHandler)
+		api.DELETE("/admin/vehicles/:id/claim", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), releaseVerificationHandler)
+		api.GET("/admin/verification-queue", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), getVerificationQueueHandler)
+		api.POST("/admin/verification-queue/next", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), claimNextVerificationHandler)
+		api.POST("/admin/verification-queue/assign", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVerificationQueueManage), assignVerificationQueueHandler)
+		api.GET("/admin/verification-queue/workload", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVerificationQueueManage), getVerifierWorkloadHandler)
+		api.GET("/admin/validation-rules", middleware.AuthRequired(), middleware.RequirePermission(auth.PermVehiclesVerify), getValidationRulesHandler)
+		api.PUT("/admin/validation-rules", middleware.AuthRequired(), middleware.RequirePermission(auth.PermValidationRulesManage), updateValidationRulesHandler)
+		api.POST("/admin/validation-rules/dry-run", middleware.AuthRequired(), middleware.RequirePermission(auth.PermValidationRulesManage), dryRunValidationRulesHandler)
+		api.GET("/admin/fleet-owners/:id/face-verifications", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDocumentsVerify), getFaceVerificationsHandler)
+		api.GET("/admin/documents", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDocumentsVerify), getUploadedDocumentsHandler)
+		api.PUT("/admin/documents/:id/verify", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDocumentsVerify), verifyDocumentHandler)
+		api.GET("/admin/customer-contracts", middleware.AuthRequired(), middleware.RequirePermission(auth.PermContractsManage), getCustomerContractsHandler)
+		api.GET("/admin/roles", middleware.AuthRequired(), middleware.RequirePermission(auth.PermUsersManage), getRolesHandler)
+		api.GET("/admin/users", middleware.AuthRequired(), middleware.RequirePermission(auth.PermUsersManage), getUsersHandler)
+		api.PUT("/admin/users/:id/role", middleware.AuthRequired(), middleware.RequirePermission(auth.PermUsersManage), assignUserRoleHandler)
+		api.GET("/admin/users/:id/sessions", middleware.AuthRequired(), middleware.RequirePermission(auth.PermUsersManage), getUserSessionsHandler)
+		api.POST("/admin/users/:id/sessions/revoke", middleware.AuthRequired(), middleware.RequirePermission(auth.PermUsersManage), revokeUserSessionsHandler)
+		api.DELETE("/admin/users/:id/2fa", middleware.AuthRequired(), middleware.RequirePermission(auth.PermUsersManage), resetUserTwoFactorHandler)
+		api.POST("/admin/customer-contracts", middleware.AuthRequired(), middleware.RequirePermission(auth.PermContractsManage), createCustomerContractHandler)
+		api.GET("/admin/audit", middleware.AuthRequired(), middleware.RequirePermission(auth.PermAuditRead), getAuditLogHandler)
+		api.GET("/admin/audit/verify", middleware.AuthRequired(), middleware.RequirePermission(auth.PermAuditRead), verifyAuditChainHandler)
 		
 		// Enhanced dashboard endpoints
 		api.GET("/notifications", middleware.AuthRequired(), getNotificationsHandler)
@@ -169,25 +286,41 @@ func main() {
 		api.GET("/fleet/analytics", middleware.AuthRequired(), getRevenueAnalyticsHandler)
 		
 		// GPS Registration endpoints
-		api.POST("/gps-registration", createGPSRegistrationHandler)
-		api.GET("/gps-registration", middleware.AuthRequired(), middleware.AdminRequired(), getAllGPSRegistrationsHandler)
-		api.GET("/gps-registration/pending", middleware.AuthRequired(), middleware.AdminRequired(), getPendingGPSRegistrationsHandler)
-		api.PUT("/gps-registration/:id/approve", middleware.AuthRequired(), middleware.AdminRequired(), approveGPSRegistrationHandler)
+		api.POST("/gps-registration", ipLimit, createGPSRegistrationHandler)
+		api.GET("/gps-registration", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDevicesManage), getAllGPSRegistrationsHandler)
+		api.GET("/gps-registration/pending", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDevicesManage), getPendingGPSRegistrationsHandler)
+		api.PUT("/gps-registration/:id/approve", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDevicesManage), approveGPSRegistrationHandler)
 		api.GET("/gps-registration/:id", middleware.AuthRequired(), getGPSRegistrationByIDHandler)
 		
 		// GPS Device endpoints
-		api.GET("/gps-devices", middleware.AuthRequired(), middleware.AdminRequired(), getAllGPSDevicesHandler)
-		api.POST("/gps-devices/assign", middleware.AuthRequired(), middleware.AdminRequired(), assignGPSDeviceHandler)
-		api.PUT("/gps-devices/:deviceId/status", middleware.AuthRequired(), middleware.AdminRequired(), updateGPSDeviceStatusHandler)
+		api.GET("/gps-devices", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDevicesManage), getAllGPSDevicesHandler)
+		api.POST("/gps-devices/assign", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDevicesManage), assignGPSDeviceHandler)
+		api.PUT("/gps-devices/:deviceId/status", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDevicesManage), updateGPSDeviceStatusHandler)
 		
 		// GPS Tracking endpoints
 		api.POST("/gps-tracking/ingest", ingestGPSDataHandler)
 		api.POST("/gps-tracking/batch-ingest", batchIngestGPSDataHandler)
-		api.GET("/gps-tracking/positions", middleware.AuthRequired(), getLatestPositionsHandler)
-		api.GET("/gps-tracking/history/:deviceId", middleware.AuthRequired(), getTrackingHistoryHandler)
+		api.GET("/gps-tracking/positions", middleware.AuthRequired(), middleware.RequirePermission(auth.PermTrackingRead), getLatestPositionsHandler)
+		api.GET("/gps-tracking/ingest/stats", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDevicesManage), getIngestStatsHandler)
+		api.GET("/gps-tracking/history/:deviceId", middleware.AuthRequired(), middleware.RequirePermission(auth.PermTrackingRead), getTrackingHistoryHandler)
+		api.GET("/gps-tracking/playback/:deviceId", middleware.AuthRequired(), middleware.RequirePermission(auth.PermTrackingPlayback), getRoutePlaybackHandler)
+
+		// Geofence endpoints
+		api.GET("/geofences", middleware.AuthRequired(), middleware.RequirePermission(auth.PermGeofencesManage), getGeofencesHandler)
+		api.POST("/geofences", middleware.AuthRequired(), middleware.RequirePermission(auth.PermGeofencesManage), createGeofenceHandler)
+		api.GET("/geofences/events", middleware.AuthRequired(), middleware.RequirePermission(auth.PermGeofencesManage), getGeofenceEventsHandler)
+		api.GET("/geofences/:id", middleware.AuthRequired(), middleware.RequirePermission(auth.PermGeofencesManage), getGeofenceHandler)
+		api.PUT("/geofences/:id", middleware.AuthRequired(), middleware.RequirePermission(auth.PermGeofencesManage), updateGeofenceHandler)
+		api.DELETE("/geofences/:id", middleware.AuthRequired(), middleware.RequirePermission(auth.PermGeofencesManage), deleteGeofenceHandler)
+
+		// Driving alert endpoints
+		api.GET("/driving-alerts", middleware.AuthRequired(), middleware.RequirePermission(auth.PermAlertsRead), getDrivingAlertsHandler)
+		api.PUT("/driving-alerts/:id/acknowledge", middleware.AuthRequired(), middleware.RequirePermission(auth.PermAlertsRead), acknowledgeDrivingAlertHandler)
+		api.GET("/speed-limits", middleware.AuthRequired(), middleware.RequirePermission(auth.PermGeofencesManage), getSpeedLimitsHandler)
+		api.PUT("/speed-limits", middleware.AuthRequired(), middleware.RequirePermission(auth.PermGeofencesManage), setSpeedLimitHandler)
 		
 		// WebSocket endpoint
-		api.GET("/ws/tracking", handleWebSocketConnection)
+		api.GET("/ws/tracking", middleware.QueryTokenAuth(), handleWebSocketConnection)
 		
 		// OCR endpoints
 		api.POST("/ocr/stnk", middleware.AuthRequired(), extractSTNKHandler)
@@ -199,10 +332,14 @@ func main() {
 		api.POST("/documents/upload", middleware.AuthRequired(), uploadDocumentDirectHandler)
 		
 		// Driver mobile app endpoints
-		api.GET("/driver/profile", middleware.AuthRequired(), getDriverProfileHandler)
-		api.GET("/driver/trips", middleware.AuthRequired(), getDriverTripsHandler)
-		api.PUT("/driver/trips/:id/status", middleware.AuthRequired(), updateTripStatusHandler)
-		api.POST("/driver/trips/:id/tracking", middleware.AuthRequired(), recordTripTrackingHandler)
+		api.GET("/driver/profile", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDriverApp), getDriverProfileHandler)
+		api.GET("/driver/trips", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDriverApp), getDriverTripsHandler)
+		api.GET("/driver/trips/:id", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDriverApp), getDriverTripHandler)
+		api.PUT("/driver/trips/:id/status", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDriverApp), updateTripStatusHandler)
+		api.POST("/driver/trips/:id/stops/:stopId/check-in", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDriverApp), checkInTripStopHandler)
+		api.POST("/driver/trips/:id/stops/:stopId/check-out", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDriverApp), checkOutTripStopHandler)
+		api.POST("/driver/trips/:id/pod", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDriverApp), submitTripPODHandler)
+		api.POST("/driver/trips/:id/tracking", middleware.AuthRequired(), middleware.RequirePermission(auth.PermDriverApp), recordTripTrackingHandler)
 
 	}
 
@@ -212,10 +349,32 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	// Stop taking requests, then write whatever GPS points are still buffered
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Server shutdown error: %v", err)
 	}
+	if err := gpsWriter.Close(ctx); err != nil {
+		log.Printf("GPS ingest shutdown error: %v", err)
+	}
+	close(stopGPSMaintenance)
+	close(stopSessionCleanup)
+	close(stopDocumentValidation)
+	close(stopVerificationQueue)
 }
 
 func registerHandler(c *gin.Context) {
@@ -237,7 +396,9 @@ func registerHandler(c *gin.Context) {
 	user, err := auth.CreateUser(conn, req)
 	if err != nil {
 		log.Printf("Create user error: %v", middleware.SanitizeForLog(err.Error()))
-		if strings.Contains(err.Error(), "already exists") {
+		if errors.Is(err, auth.ErrRoleNotAllowed) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Role tidak dapat dipilih saat pendaftaran"})
+		} else if strings.Contains(err.Error(), "already exists") {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Username atau email sudah terdaftar"})
 		} else {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Gagal membuat akun"})
@@ -245,25 +406,19 @@ func registerHandler(c *gin.Context) {
 		return
 	}
 	
-	token, err := auth.GenerateToken(user.ID, user.Username, user.Role)
+	tokens, err := services.NewSessionService(conn, sessionConfig).StartSession(user, c.GetHeader("User-Agent"), c.ClientIP())
 	if err != nil {
 		log.Printf("Token generation error: %v", middleware.SanitizeForLog(err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 	
-	c.JSON(http.StatusCreated, models.LoginResponse{
-		Token: token,
-		User: models.UserResponse{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			FullName:  user.FullName,
-			Role:      user.Role,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		},
-	})
+	// The account works without verification; the link can be resent
+	if err := services.NewAccountService(conn, mailer, mailConfig, sessionConfig).SendVerification(user); err != nil {
+		log.Printf("Verification email error - UserID: %d: %v", user.ID, err)
+	}
+	
+	c.JSON(http.StatusCreated, handlers.NewLoginResponse(tokens, user))
 }
 
 func loginHandler(c *gin.Context) {
@@ -285,14 +440,64 @@ func loginHandler(c *gin.Context) {
 	}
 	// Don't close connection - let pool manage it
 	
+	// The lockout follows the account whether its username or email is
+	// typed; names of no account are left to the IP limiter
+	userID, err := auth.LoginUserID(conn, req.Email)
+	if err != nil {
+		log.Printf("Login lookup error: %v", middleware.SanitizeForLog(err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	account := ratelimit.UserKey(userID)
+	if locked, retryAfter := loginLockout.Locked(account, time.Now()); locked {
+		log.Printf("Login refused for locked account: %s", middleware.SanitizeForLog(req.Email))
+		middleware.TooManyRequests(c, retryAfter, "Terlalu banyak percobaan login, coba lagi nanti")
+		return
+	}
+
 	user, err := auth.LoginUser(conn, req)
 	if err != nil {
 		log.Printf("Login error for user: %s - %v", middleware.SanitizeForLog(req.Email), middleware.SanitizeForLog(err.Error()))
+		if !errors.Is(err, auth.ErrInvalidCredentials) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if userID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Email/username atau kata sandi salah"})
+			return
+		}
+		if lock, lockouts := loginLockout.Fail(account, time.Now()); lock > 0 {
+			err := services.RecordAuditEvent(conn, models.AuditEvent{
+				Action:    models.AuditAccountLocked,
+				ActorID:   &userID,
+				IPAddress: c.ClientIP(),
+				Details:   map[string]interface{}{"login": strings.TrimSpace(req.Email), "lockouts": lockouts, "locked_seconds": int(lock.Seconds())},
+			})
+			if err != nil {
+				log.Printf("Audit error: %v", err)
+			}
+			middleware.TooManyRequests(c, lock, "Terlalu banyak percobaan login, coba lagi nanti")
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email/username atau kata sandi salah"})
 		return
 	}
+	loginLockout.Succeed(account)
+	
+	// Users with 2FA, and roles that must enroll, get a limited token for the second step
+	challenge, err := services.NewTwoFactorService(conn, sessionConfig).Challenge(user)
+	if err != nil {
+		log.Printf("Two-factor check error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	if challenge != nil {
+		log.Printf("Login password step passed for user ID: %d, two-factor %s pending", user.ID, challenge.Step)
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
 	
-	token, err := auth.GenerateToken(user.ID, user.Username, user.Role)
+	tokens, err := services.NewSessionService(conn, sessionConfig).StartSession(user, c.GetHeader("User-Agent"), c.ClientIP())
 	if err != nil {
 		log.Printf("Token generation error: %v", middleware.SanitizeForLog(err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
@@ -301,18 +506,7 @@ func loginHandler(c *gin.Context) {
 	
 	log.Printf("Login successful for user ID: %d", user.ID)
 	
-	c.JSON(http.StatusOK, models.LoginResponse{
-		Token: token,
-		User: models.UserResponse{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			FullName:  user.FullName,
-			Role:      user.Role,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-		},
-	})
+	c.JSON(http.StatusOK, handlers.NewLoginResponse(tokens, user))
 }
 
 func createVehicleHandler(c *gin.Context) {
@@ -346,7 +540,7 @@ func createVehicleHandler(c *gin.Context) {
 		return
 	}
 	
-	vehicle, err := services.CreateVehicle(conn, req, userIDInt)
+	vehicle, err := services.CreateVehicle(conn, registries, req, userIDInt)
 	if err != nil {
 		log.Printf("Create vehicle error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create vehicle"})
@@ -365,9 +559,14 @@ func getVehiclesHandler(c *gin.Context) {
 	}
 	// Let connection pool manage connections
 	
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+	
 	// Check if requesting approved vehicles only
 	if c.Query("status") == "approved" {
-		vehicles, err := services.GetApprovedVehicles(conn)
+		vehicles, err := services.GetApprovedVehicles(conn, scope)
 		if err != nil {
 			log.Printf("Get approved vehicles error: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get approved vehicles"})
@@ -377,7 +576,7 @@ func getVehiclesHandler(c *gin.Context) {
 		return
 	}
 	
-	vehicles, err := services.GetVehicles(conn)
+	vehicles, err := services.GetVehicles(conn, scope)
 	if err != nil {
 		log.Printf("Get vehicles error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get vehicles"})
@@ -403,7 +602,12 @@ func getVehicleHandler(c *gin.Context) {
 	}
 	// Let connection pool manage connections
 	
-	vehicle, err := services.GetVehicleByID(conn, id)
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+	
+	vehicle, err := services.GetVehicleByID(conn, scope, id)
 	if err != nil {
 		log.Printf("Get vehicle error: %v", err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
@@ -421,7 +625,12 @@ func getApprovedVehiclesHandler(c *gin.Context) {
 		return
 	}
 	
-	vehicles, err := services.GetApprovedVehicles(conn)
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+	
+	vehicles, err := services.GetApprovedVehicles(conn, scope)
 	if err != nil {
 		log.Printf("Get approved vehicles error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get approved vehicles"})
@@ -444,13 +653,55 @@ func createDriverHandler(c *gin.Context) {
 }
 
 func getDriversHandler(c *gin.Context) {
-	// TODO: Implement GetDrivers service
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "Get drivers not implemented yet"})
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+
+	drivers, err := services.GetDrivers(conn, scope)
+	if err != nil {
+		log.Printf("Get drivers error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get drivers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"drivers": drivers})
 }
 
 func getDriverHandler(c *gin.Context) {
-	// TODO: Implement GetDriverByID service
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "Get driver by ID not implemented yet"})
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid driver ID"})
+		return
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+
+	driver, err := services.GetDriverByID(conn, scope, id)
+	if err != nil {
+		log.Printf("Get driver error: %v", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"driver": driver})
 }
 
 // Trip handlers
@@ -469,7 +720,16 @@ func createTripHandler(c *gin.Context) {
 	}
 	// Let connection pool manage connections
 	
-	trip, err := services.CreateTrip(conn, req)
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+	
+	trip, err := services.CreateTrip(conn, scope, req, c.GetInt("user_id"), c.GetString("user_role"))
+	if errors.Is(err, services.ErrTenantAccessDenied) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("Create trip error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create trip"})
@@ -488,7 +748,12 @@ func getTripsHandler(c *gin.Context) {
 	}
 	// Let connection pool manage connections
 	
-	trips, err := services.GetTrips(conn)
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+	
+	trips, err := services.GetTrips(conn, scope)
 	if err != nil {
 		log.Printf("Get trips error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get trips"})
@@ -514,6 +779,10 @@ func getTripHandler(c *gin.Context) {
 	}
 	// Let connection pool manage connections
 	
+	if !authorizeTrip(c, conn, id) {
+		return
+	}
+	
 	trip, err := services.GetTripByID(conn, id)
 	if err != nil {
 		log.Printf("Get trip error: %v", err)
@@ -524,6 +793,158 @@ func getTripHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"trip": trip})
 }
 
+func transitionTripHandler(c *gin.Context) {
+	tripID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return
+	}
+
+	var req models.TripTransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userIDInt, ok := userID.(int)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	role, _ := c.Get("user_role")
+	roleStr, _ := role.(string)
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if !authorizeTrip(c, conn, tripID) {
+		return
+	}
+
+	trip, err := services.TransitionTrip(conn, tripID, req, userIDInt, roleStr)
+	if err != nil {
+		log.Printf("Trip transition error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		c.JSON(tripErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trip": trip})
+}
+
+func getTripStatusHistoryHandler(c *gin.Context) {
+	tripID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if !authorizeTrip(c, conn, tripID) {
+		return
+	}
+
+	history, err := services.GetTripStatusHistory(conn, tripID)
+	if err != nil {
+		log.Printf("Get trip history error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get trip history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
+
+func getCustomerContractsHandler(c *gin.Context) {
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	contracts, err := services.GetCustomerContracts(conn)
+	if err != nil {
+		log.Printf("Get customer contracts error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get customer contracts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contracts": contracts})
+}
+
+func createCustomerContractHandler(c *gin.Context) {
+	var req models.CustomerContractRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	contract, err := services.CreateCustomerContract(conn, req)
+	if err != nil {
+		log.Printf("Create customer contract error: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create customer contract"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"contract": contract})
+}
+
+// authorizeTrip checks that the trip is in the caller's tenant scope,
+// writing the error response itself when not. Other tenants' trips are
+// reported as not found.
+func authorizeTrip(c *gin.Context, conn *sql.DB, tripID int) bool {
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return false
+	}
+
+	err := services.CheckTripInScope(conn, scope, tripID)
+	if errors.Is(err, services.ErrTripNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Trip not found"})
+		return false
+	}
+	if err != nil {
+		log.Printf("Trip access check error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check access"})
+		return false
+	}
+	return true
+}
+
+// tripErrorStatus maps trip lifecycle errors to HTTP status codes
+func tripErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrTripNotFound), errors.Is(err, services.ErrTripStopNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidTripTransition), errors.Is(err, services.ErrTripStopOutOfOrder),
+		errors.Is(err, services.ErrPODRequired):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidTripStatus):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrTenantAccessDenied):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // Analytics handlers
 func getDashboardStatsHandler(c *gin.Context) {
 	conn, err := db.Connect()
@@ -534,7 +955,12 @@ func getDashboardStatsHandler(c *gin.Context) {
 	}
 	// Let connection pool manage connections
 	
-	stats, err := services.GetDashboardStats(conn)
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+	
+	stats, err := services.GetDashboardStats(conn, scope)
 	if err != nil {
 		log.Printf("Get dashboard stats error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get dashboard stats"})
@@ -553,7 +979,12 @@ func getVehicleUtilizationHandler(c *gin.Context) {
 	}
 	// Let connection pool manage connections
 	
-	utilization, err := services.GetVehicleUtilization(conn)
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+	
+	utilization, err := services.GetVehicleUtilization(conn, scope)
 	if err != nil {
 		log.Printf("Get vehicle utilization error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get vehicle utilization"})
@@ -611,12 +1042,13 @@ func uploadVehicleAttachmentHandler(c *gin.Context) {
 		}
 
 		// Create attachment record
-		query := `INSERT INTO vehicle_attachments (vehicle_id, attachment_type, file_name, file_path, file_size, mime_type)
-				  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, uploaded_at`
+		query := `INSERT INTO vehicle_attachments (vehicle_id, attachment_type, file_name, file_path, file_size, mime_type, validation_status)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, uploaded_at`
 
 		var attachmentID int
 		var uploadedAt time.Time
-		err = conn.QueryRow(query, vehicleID, req.AttachmentType, req.FileName, filePath, req.FileSize, req.MimeType).
+		validationStatus := services.InitialValidationStatus(req.AttachmentType)
+		err = conn.QueryRow(query, vehicleID, req.AttachmentType, req.FileName, filePath, req.FileSize, req.MimeType, validationStatus).
 			Scan(&attachmentID, &uploadedAt)
 
 		if err != nil {
@@ -624,17 +1056,19 @@ func uploadVehicleAttachmentHandler(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save attachment"})
 			return
 		}
+		wakeDocumentValidation()
 
 		c.JSON(http.StatusCreated, gin.H{
 			"attachment": gin.H{
-				"id":              attachmentID,
-				"vehicle_id":      vehicleID,
-				"attachment_type": req.AttachmentType,
-				"file_name":       req.FileName,
-				"file_path":       filePath,
-				"file_size":       req.FileSize,
-				"mime_type":       req.MimeType,
-				"uploaded_at":     uploadedAt,
+				"id":                attachmentID,
+				"vehicle_id":        vehicleID,
+				"attachment_type":   req.AttachmentType,
+				"file_name":         req.FileName,
+				"file_path":         filePath,
+				"file_size":         req.FileSize,
+				"mime_type":         req.MimeType,
+				"validation_status": validationStatus,
+				"uploaded_at":       uploadedAt,
 			},
 		})
 		return
@@ -694,6 +1128,7 @@ func uploadVehicleAttachmentHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to upload attachment"})
 		return
 	}
+	wakeDocumentValidation()
 
 	c.JSON(http.StatusCreated, gin.H{"attachment": attachment})
 }
@@ -994,7 +1429,13 @@ func verifyVehicleHandler(c *gin.Context) {
 		return
 	}
 
-	err = services.UpdateVehicleVerificationStatus(conn, vehicleID, req.Status, req.Notes, adminIDInt)
+	// Supervisors may decide submissions other verifiers hold
+	overrideClaim := auth.HasPermission(c.GetString("user_role"), auth.PermVerificationQueueManage)
+	err = services.UpdateVehicleVerificationStatus(conn, vehicleID, req.Status, req.Notes, adminIDInt, nil, overrideClaim)
+	if errors.Is(err, services.ErrClaimedByOther) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("Update verification status error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -1046,6 +1487,65 @@ func getVehicleVerificationHistoryHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"history": history})
 }
 
+func getQAReviewsHandler(c *gin.Context) {
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	reviews, err := services.GetQAReviews(conn)
+	if err != nil {
+		log.Printf("Get QA reviews error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get QA reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews})
+}
+
+func completeQAReviewHandler(c *gin.Context) {
+	vehicleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+		return
+	}
+
+	var req struct {
+		Outcome string `json:"outcome" binding:"required,oneof=confirmed rejected needs_correction"`
+		Notes   string `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "outcome must be confirmed, rejected or needs_correction"})
+		return
+	}
+	if req.Outcome != "confirmed" && strings.TrimSpace(req.Notes) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Notes are required to overturn an approval"})
+		return
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	err = services.CompleteQAReview(conn, vehicleID, req.Outcome, req.Notes, c.GetInt("user_id"))
+	if errors.Is(err, services.ErrNoQAReview) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle has no pending QA review"})
+		return
+	}
+	if err != nil {
+		log.Printf("Complete QA review error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete QA review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "QA review completed"})
+}
+
 // Enhanced dashboard handlers
 func getNotificationsHandler(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -1290,6 +1790,7 @@ func updateTripStatusHandler(c *gin.Context) {
 
 	var req struct {
 		Status string `json:"status" binding:"required"`
+		Notes  string `json:"notes"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -1328,18 +1829,263 @@ func updateTripStatusHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid driver ID type"})
 		return
 	}
-	err = services.UpdateTripStatus(conn, tripID, driverID, req.Status)
+	err = services.UpdateTripStatus(conn, tripID, driverID, userIDInt, req.Status, req.Notes)
 	if err != nil {
 		log.Printf("Update trip status error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(tripErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Trip status updated successfully"})
 }
 
-func recordTripTrackingHandler(c *gin.Context) {
-	tripIDStr := c.Param("id")
+// currentDriverID resolves the driver profile of the authenticated user,
+// writing the error response itself when it cannot
+func currentDriverID(c *gin.Context, conn *sql.DB) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return 0, false
+	}
+
+	userIDInt, ok := userID.(int)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+
+	driver, err := services.GetDriverByUserID(conn, userIDInt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Driver profile not found"})
+		return 0, false
+	}
+
+	driverID, ok := driver["id"].(int)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid driver ID type"})
+		return 0, false
+	}
+	return driverID, true
+}
+
+func getDriverTripHandler(c *gin.Context) {
+	tripID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	driverID, ok := currentDriverID(c, conn)
+	if !ok {
+		return
+	}
+
+	trip, err := services.GetDriverTrip(conn, tripID, driverID)
+	if err != nil {
+		log.Printf("Get driver trip error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		c.JSON(tripErrorStatus(err), gin.H{"error": "Trip not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trip": trip})
+}
+
+func checkInTripStopHandler(c *gin.Context) {
+	updateTripStopHandler(c, services.CheckInTripStop)
+}
+
+func checkOutTripStopHandler(c *gin.Context) {
+	updateTripStopHandler(c, services.CheckOutTripStop)
+}
+
+func updateTripStopHandler(c *gin.Context, update func(*sql.DB, int, int, int, string) (*models.TripStop, error)) {
+	tripID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return
+	}
+	stopID, err := strconv.Atoi(c.Param("stopId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stop ID"})
+		return
+	}
+
+	var req struct {
+		Notes string `json:"notes"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	driverID, ok := currentDriverID(c, conn)
+	if !ok {
+		return
+	}
+
+	stop, err := update(conn, tripID, stopID, driverID, req.Notes)
+	if err != nil {
+		log.Printf("Update trip stop error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		c.JSON(tripErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stop": stop})
+}
+
+func submitTripPODHandler(c *gin.Context) {
+	tripID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return
+	}
+
+	var req models.TripPODRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	signature, err := c.FormFile("signature")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Signature image is required"})
+		return
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart form"})
+		return
+	}
+	photos := form.File["photos"]
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	driverID, ok := currentDriverID(c, conn)
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("user_id")
+	userIDInt, _ := userID.(int)
+
+	pod, err := services.SubmitTripPOD(conn, tripID, driverID, userIDInt, req, signature, photos)
+	if err != nil {
+		log.Printf("Submit POD error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
+		status := tripErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"pod": pod})
+}
+
+// authorizeTripPOD checks that the caller may read the trip's proof of
+// delivery, writing the error response itself when not
+func authorizeTripPOD(c *gin.Context, conn *sql.DB, tripID int) bool {
+	userID, _ := c.Get("user_id")
+	userIDInt, ok := userID.(int)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return false
+	}
+	role, _ := c.Get("user_role")
+	roleStr, _ := role.(string)
+
+	allowed, err := services.CanAccessTripPOD(conn, tripID, userIDInt, roleStr)
+	if err != nil {
+		log.Printf("POD access check error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check access"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return false
+	}
+	return true
+}
+
+func getTripPODHandler(c *gin.Context) {
+	tripID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if !authorizeTripPOD(c, conn, tripID) {
+		return
+	}
+
+	pods, err := services.GetTripPODs(conn, tripID)
+	if err != nil {
+		log.Printf("Get POD error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get proof of delivery"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pods": pods})
+}
+
+func getTripPODPDFHandler(c *gin.Context) {
+	tripID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
+		return
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if !authorizeTripPOD(c, conn, tripID) {
+		return
+	}
+
+	pdf, err := services.GenerateTripPODPDF(conn, tripID)
+	if err != nil {
+		log.Printf("Generate POD PDF error: %v", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=pod_trip_%d.pdf", tripID))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+func recordTripTrackingHandler(c *gin.Context) {
+	tripIDStr := c.Param("id")
 	tripID, err := strconv.Atoi(tripIDStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trip ID"})
@@ -1671,6 +2417,8 @@ func uploadDocumentHandler(c *gin.Context) {
 	})
 }
 
+// getApprovedVehiclesPublicHandler lists approved vehicles of every fleet
+// owner; it is the public catalogue and needs no login
 func getApprovedVehiclesPublicHandler(c *gin.Context) {
 	conn, err := db.Connect()
 	if err != nil {
@@ -1679,7 +2427,7 @@ func getApprovedVehiclesPublicHandler(c *gin.Context) {
 		return
 	}
 
-	vehicles, err := services.GetApprovedVehicles(conn)
+	vehicles, err := services.GetApprovedVehicles(conn, models.AllTenants())
 	if err != nil {
 		log.Printf("Get approved vehicles error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get approved vehicles"})
@@ -1857,7 +2605,13 @@ func requestCorrectionHandler(c *gin.Context) {
 		return
 	}
 
-	err = services.UpdateVehicleWithCorrection(conn, vehicleID, req.CorrectionItems, req.Notes, adminIDInt)
+	// Supervisors may decide submissions other verifiers hold
+	overrideClaim := auth.HasPermission(c.GetString("user_role"), auth.PermVerificationQueueManage)
+	err = services.UpdateVehicleWithCorrection(conn, vehicleID, req.CorrectionItems, req.Notes, adminIDInt, overrideClaim)
+	if errors.Is(err, services.ErrClaimedByOther) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("Update vehicle correction error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -1917,7 +2671,7 @@ func performCrossCheckHandler(c *gin.Context) {
 		return
 	}
 
-	result, err := services.PerformCrossCheck(conn, vehicleID, req.CheckType)
+	result, err := services.PerformCrossCheck(c.Request.Context(), conn, registries, vehicleID, req.CheckType)
 	if err != nil {
 		log.Printf("Cross-check error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -1977,7 +2731,13 @@ func scheduleInspectionHandler(c *gin.Context) {
 		return
 	}
 
-	err = services.ScheduleInspection(conn, vehicleID, inspectionDate, req.Location, adminIDInt)
+	// Supervisors may decide submissions other verifiers hold
+	overrideClaim := auth.HasPermission(c.GetString("user_role"), auth.PermVerificationQueueManage)
+	err = services.ScheduleInspection(conn, vehicleID, inspectionDate, req.Location, adminIDInt, overrideClaim)
+	if errors.Is(err, services.ErrClaimedByOther) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("Schedule inspection error: %s", strings.ReplaceAll(err.Error(), "\n", " "))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -2016,11 +2776,11 @@ func extractSTNKHandler(c *gin.Context) {
 		return
 	}
 
-	ocrService := services.NewOCRService()
+	ocrService := services.NewOCRService(ocrEngine)
 	data, err := ocrService.ExtractSTNKData(req.ImageData)
 	if err != nil {
 		log.Printf("STNK OCR error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondOCRError(c, err)
 		return
 	}
 
@@ -2045,11 +2805,11 @@ func extractKTPHandler(c *gin.Context) {
 		return
 	}
 
-	ocrService := services.NewOCRService()
+	ocrService := services.NewOCRService(ocrEngine)
 	data, err := ocrService.ExtractKTPData(req.ImageData)
 	if err != nil {
 		log.Printf("KTP OCR error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondOCRError(c, err)
 		return
 	}
 
@@ -2066,7 +2826,6 @@ func extractKTPHandler(c *gin.Context) {
 func faceMatchHandler(c *gin.Context) {
 	var req struct {
 		SelfieImage string `json:"selfie_image" binding:"required"`
-		KTPImage    string `json:"ktp_image" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -2074,11 +2833,26 @@ func faceMatchHandler(c *gin.Context) {
 		return
 	}
 
-	ocrService := services.NewOCRService()
-	result, err := ocrService.PerformFaceMatch(req.SelfieImage, req.KTPImage)
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// The selfie is compared with the user's own verified KTP on file. A
+	// fleet owner's result is kept and verifies them; anyone else only gets
+	// the comparison.
+	faceService := services.NewFaceMatchService(conn, faceEngine, faceConfig.Threshold)
+	var result *services.FaceMatchResult
+	if fleetOwner, ownerErr := services.GetFleetOwnerByUserID(conn, c.GetInt("user_id")); ownerErr == nil {
+		result, err = faceService.VerifyFleetOwner(fleetOwner.ID, fleetOwner.UserID, req.SelfieImage)
+	} else {
+		result, err = faceService.MatchStoredKTP(c.GetInt("user_id"), req.SelfieImage)
+	}
 	if err != nil {
 		log.Printf("Face match error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondOCRError(c, err)
 		return
 	}
 
@@ -2096,11 +2870,11 @@ func validateQualityHandler(c *gin.Context) {
 		return
 	}
 
-	ocrService := services.NewOCRService()
+	ocrService := services.NewOCRService(ocrEngine)
 	result, err := ocrService.ValidateDocumentQuality(req.ImageData, req.DocumentType)
 	if err != nil {
 		log.Printf("Quality validation error: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondOCRError(c, err)
 		return
 	}
 
@@ -2431,6 +3205,18 @@ func updateGPSDeviceStatusHandler(c *gin.Context) {
 
 // GPS Tracking handlers
 func ingestGPSDataHandler(c *gin.Context) {
+	handlers.NewGPSTrackingHandler(nil, gpsWriter).IngestGPSData(c)
+}
+
+func batchIngestGPSDataHandler(c *gin.Context) {
+	handlers.NewGPSTrackingHandler(nil, gpsWriter).BatchIngestGPSData(c)
+}
+
+func getIngestStatsHandler(c *gin.Context) {
+	handlers.NewGPSTrackingHandler(nil, gpsWriter).IngestStats(c)
+}
+
+func getLatestPositionsHandler(c *gin.Context) {
 	conn, err := db.Connect()
 	if err != nil {
 		log.Printf("Database connection error: %v", err)
@@ -2438,12 +3224,17 @@ func ingestGPSDataHandler(c *gin.Context) {
 		return
 	}
 
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+
 	repo := repository.NewGPSTrackingRepository(conn)
-	handler := handlers.NewGPSTrackingHandler(repo)
-	handler.IngestGPSData(c)
+	handler := handlers.NewGPSTrackingHandler(repo, nil)
+	handler.GetLatestPositions(c, scope)
 }
 
-func batchIngestGPSDataHandler(c *gin.Context) {
+func getTrackingHistoryHandler(c *gin.Context) {
 	conn, err := db.Connect()
 	if err != nil {
 		log.Printf("Database connection error: %v", err)
@@ -2451,12 +3242,16 @@ func batchIngestGPSDataHandler(c *gin.Context) {
 		return
 	}
 
-	repo := repository.NewGPSTrackingRepository(conn)
-	handler := handlers.NewGPSTrackingHandler(repo)
-	handler.BatchIngestGPSData(c)
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+
+	handler := handlers.NewGPSHistoryHandler(services.NewGPSHistoryService(conn, gpsRetention))
+	handler.GetTrackingHistory(c, scope)
 }
 
-func getLatestPositionsHandler(c *gin.Context) {
+func getRoutePlaybackHandler(c *gin.Context) {
 	conn, err := db.Connect()
 	if err != nil {
 		log.Printf("Database connection error: %v", err)
@@ -2464,12 +3259,16 @@ func getLatestPositionsHandler(c *gin.Context) {
 		return
 	}
 
-	repo := repository.NewGPSTrackingRepository(conn)
-	handler := handlers.NewGPSTrackingHandler(repo)
-	handler.GetLatestPositions(c)
+	scope, ok := handlers.TenantScope(c, conn)
+	if !ok {
+		return
+	}
+
+	handler := handlers.NewGPSHistoryHandler(services.NewGPSHistoryService(conn, gpsRetention))
+	handler.GetRoutePlayback(c, scope)
 }
 
-func getTrackingHistoryHandler(c *gin.Context) {
+func handleWebSocketConnection(c *gin.Context) {
 	conn, err := db.Connect()
 	if err != nil {
 		log.Printf("Database connection error: %v", err)
@@ -2477,11 +3276,266 @@ func getTrackingHistoryHandler(c *gin.Context) {
 		return
 	}
 
-	repo := repository.NewGPSTrackingRepository(conn)
-	handler := handlers.NewGPSTrackingHandler(repo)
-	handler.GetTrackingHistory(c)
+	handlers.HandleWebSocket(c, conn)
 }
 
-func handleWebSocketConnection(c *gin.Context) {
-	handlers.HandleWebSocket(c)
-}
\ No newline at end of file
+func getGeofencesHandler(c *gin.Context) {
+	withGeofenceHandler(c, (*handlers.GeofenceHandler).GetGeofences)
+}
+
+func createGeofenceHandler(c *gin.Context) {
+	withGeofenceHandler(c, (*handlers.GeofenceHandler).CreateGeofence)
+}
+
+func getGeofenceHandler(c *gin.Context) {
+	withGeofenceHandler(c, (*handlers.GeofenceHandler).GetGeofence)
+}
+
+func updateGeofenceHandler(c *gin.Context) {
+	withGeofenceHandler(c, (*handlers.GeofenceHandler).UpdateGeofence)
+}
+
+func deleteGeofenceHandler(c *gin.Context) {
+	withGeofenceHandler(c, (*handlers.GeofenceHandler).DeleteGeofence)
+}
+
+func getGeofenceEventsHandler(c *gin.Context) {
+	withGeofenceHandler(c, (*handlers.GeofenceHandler).GetGeofenceEvents)
+}
+
+func withGeofenceHandler(c *gin.Context, handle func(*handlers.GeofenceHandler, *gin.Context)) {
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	repo := repository.NewGeofenceRepository(conn)
+	handle(handlers.NewGeofenceHandler(repo), c)
+}
+
+func getDrivingAlertsHandler(c *gin.Context) {
+	withDrivingAlertHandler(c, (*handlers.DrivingAlertHandler).GetDrivingAlerts)
+}
+
+func acknowledgeDrivingAlertHandler(c *gin.Context) {
+	withDrivingAlertHandler(c, (*handlers.DrivingAlertHandler).AcknowledgeDrivingAlert)
+}
+
+func getSpeedLimitsHandler(c *gin.Context) {
+	withDrivingAlertHandler(c, (*handlers.DrivingAlertHandler).GetSpeedLimits)
+}
+
+func setSpeedLimitHandler(c *gin.Context) {
+	withDrivingAlertHandler(c, (*handlers.DrivingAlertHandler).SetSpeedLimit)
+}
+
+func withDrivingAlertHandler(c *gin.Context, handle func(*handlers.DrivingAlertHandler, *gin.Context)) {
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	repo := repository.NewDrivingAlertRepository(conn)
+	handle(handlers.NewDrivingAlertHandler(conn, repo), c)
+}
+
+func getRolesHandler(c *gin.Context) {
+	withUserRoleHandler(c, (*handlers.UserRoleHandler).GetRoles)
+}
+
+func getUsersHandler(c *gin.Context) {
+	withUserRoleHandler(c, (*handlers.UserRoleHandler).GetUsers)
+}
+
+func assignUserRoleHandler(c *gin.Context) {
+	withUserRoleHandler(c, (*handlers.UserRoleHandler).AssignRole)
+}
+
+func withUserRoleHandler(c *gin.Context, handle func(*handlers.UserRoleHandler, *gin.Context)) {
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	handle(handlers.NewUserRoleHandler(conn, services.NewSessionService(conn, sessionConfig)), c)
+}
+
+func refreshTokenHandler(c *gin.Context) {
+	withSessionHandler(c, (*handlers.SessionHandler).Refresh)
+}
+
+func logoutHandler(c *gin.Context) {
+	withSessionHandler(c, (*handlers.SessionHandler).Logout)
+}
+
+func logoutAllHandler(c *gin.Context) {
+	withSessionHandler(c, (*handlers.SessionHandler).LogoutAll)
+}
+
+func getUserSessionsHandler(c *gin.Context) {
+	withSessionHandler(c, (*handlers.SessionHandler).GetUserSessions)
+}
+
+func revokeUserSessionsHandler(c *gin.Context) {
+	withSessionHandler(c, (*handlers.SessionHandler).RevokeUserSessions)
+}
+
+func withSessionHandler(c *gin.Context, handle func(*handlers.SessionHandler, *gin.Context)) {
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	handle(handlers.NewSessionHandler(services.NewSessionService(conn, sessionConfig)), c)
+}
+
+func verifyEmailHandler(c *gin.Context) {
+	withAccountHandler(c, (*handlers.AccountHandler).VerifyEmail)
+}
+
+func resendVerificationHandler(c *gin.Context) {
+	withAccountHandler(c, (*handlers.AccountHandler).ResendVerification)
+}
+
+func forgotPasswordHandler(c *gin.Context) {
+	withAccountHandler(c, (*handlers.AccountHandler).ForgotPassword)
+}
+
+func resetPasswordHandler(c *gin.Context) {
+	withAccountHandler(c, (*handlers.AccountHandler).ResetPassword)
+}
+
+func withAccountHandler(c *gin.Context, handle func(*handlers.AccountHandler, *gin.Context)) {
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	handle(handlers.NewAccountHandler(services.NewAccountService(conn, mailer, mailConfig, sessionConfig)), c)
+}
+
+func getTwoFactorStatusHandler(c *gin.Context) {
+	withTwoFactorHandler(c, (*handlers.TwoFactorHandler).GetStatus)
+}
+
+func setupTwoFactorHandler(c *gin.Context) {
+	withTwoFactorHandler(c, (*handlers.TwoFactorHandler).Setup)
+}
+
+func enableTwoFactorHandler(c *gin.Context) {
+	withTwoFactorHandler(c, (*handlers.TwoFactorHandler).Enable)
+}
+
+func verifyTwoFactorHandler(c *gin.Context) {
+	withTwoFactorHandler(c, (*handlers.TwoFactorHandler).Verify)
+}
+
+func disableTwoFactorHandler(c *gin.Context) {
+	withTwoFactorHandler(c, (*handlers.TwoFactorHandler).Disable)
+}
+
+func regenerateRecoveryCodesHandler(c *gin.Context) {
+	withTwoFactorHandler(c, (*handlers.TwoFactorHandler).RegenerateRecoveryCodes)
+}
+
+func resetUserTwoFactorHandler(c *gin.Context) {
+	withTwoFactorHandler(c, (*handlers.TwoFactorHandler).ResetUser)
+}
+
+func withTwoFactorHandler(c *gin.Context, handle func(*handlers.TwoFactorHandler, *gin.Context)) {
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	handle(handlers.NewTwoFactorHandler(conn, services.NewTwoFactorService(conn, sessionConfig), loginLockout), c)
+}
+
+func getAuditLogHandler(c *gin.Context) {
+	withAuditHandler(c, (*handlers.AuditHandler).GetAuditLog)
+}
+
+func verifyAuditChainHandler(c *gin.Context) {
+	withAuditHandler(c, (*handlers.AuditHandler).VerifyAuditChain)
+}
+
+func withAuditHandler(c *gin.Context, handle func(*handlers.AuditHandler, *gin.Context)) {
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	handle(handlers.NewAuditHandler(services.NewAuditService(conn)), c)
+}
+
+func getValidationRulesHandler(c *gin.Context) {
+	withValidationRulesHandler(c, (*handlers.ValidationRulesHandler).GetRules)
+}
+
+func updateValidationRulesHandler(c *gin.Context) {
+	withValidationRulesHandler(c, (*handlers.ValidationRulesHandler).UpdateRules)
+}
+
+func dryRunValidationRulesHandler(c *gin.Context) {
+	withValidationRulesHandler(c, (*handlers.ValidationRulesHandler).DryRun)
+}
+
+func withValidationRulesHandler(c *gin.Context, handle func(*handlers.ValidationRulesHandler, *gin.Context)) {
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	handle(handlers.NewValidationRulesHandler(services.NewValidationRulesService(conn)), c)
+}
+
+func getVerificationQueueHandler(c *gin.Context) {
+	withVerificationQueueHandler(c, (*handlers.VerificationQueueHandler).List)
+}
+
+func claimNextVerificationHandler(c *gin.Context) {
+	withVerificationQueueHandler(c, (*handlers.VerificationQueueHandler).ClaimNext)
+}
+
+func claimVerificationHandler(c *gin.Context) {
+	withVerificationQueueHandler(c, (*handlers.VerificationQueueHandler).Claim)
+}
+
+func releaseVerificationHandler(c *gin.Context) {
+	withVerificationQueueHandler(c, (*handlers.VerificationQueueHandler).Release)
+}
+
+func assignVerificationQueueHandler(c *gin.Context) {
+	withVerificationQueueHandler(c, (*handlers.VerificationQueueHandler).Assign)
+}
+
+func getVerifierWorkloadHandler(c *gin.Context) {
+	withVerificationQueueHandler(c, (*handlers.VerificationQueueHandler).Workload)
+}
+
+func withVerificationQueueHandler(c *gin.Context, handle func(*handlers.VerificationQueueHandler, *gin.Context)) {
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	handle(handlers.NewVerificationQueueHandler(services.NewVerificationQueueService(conn, verificationQueue)), c)
+}
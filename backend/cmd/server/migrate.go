@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/db"
+)
+
+// runMigrateCommand handles `server migrate up|down [steps]|status`
+func runMigrateCommand(args []string) int {
+	if len(args) == 0 {
+		printMigrateUsage()
+		return 2
+	}
+
+	conn, err := db.GetDB()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(conn)
+		for _, version := range applied {
+			fmt.Printf("applied %04d\n", version)
+		}
+		if err != nil {
+			log.Printf("Migration failed: %v", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				log.Printf("Invalid steps %q", args[1])
+				return 2
+			}
+		}
+		reverted, err := db.MigrateDown(conn, steps)
+		for _, version := range reverted {
+			fmt.Printf("reverted %04d\n", version)
+		}
+		if err != nil {
+			log.Printf("Rollback failed: %v", err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("nothing to roll back")
+		}
+
+	case "status":
+		statuses, err := db.GetMigrationStatus(conn)
+		if err != nil {
+			log.Printf("Failed to read migration status: %v", err)
+			return 1
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		printMigrateUsage()
+		return 2
+	}
+
+	return 0
+}
+
+// autoMigrate applies pending migrations on startup when DB_AUTO_MIGRATE=true
+func autoMigrate() {
+	if os.Getenv("DB_AUTO_MIGRATE") != "true" {
+		return
+	}
+
+	conn, err := db.GetDB()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		return
+	}
+
+	applied, err := db.MigrateUp(conn)
+	if err != nil {
+		log.Fatalf("Auto migration failed: %v", err)
+	}
+	if len(applied) > 0 {
+		log.Printf("Applied %d migration(s)", len(applied))
+	}
+}
+
+func printMigrateUsage() {
+	fmt.Fprintln(os.Stderr, "usage: server migrate <command>")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  up            apply all pending migrations")
+	fmt.Fprintln(os.Stderr, "  down [steps]  roll back the last migration (or the last N)")
+	fmt.Fprintln(os.Stderr, "  status        list migrations and whether they are applied")
+}
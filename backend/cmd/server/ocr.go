@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/db"
+	"github.com/youruser/aplikasi-tms/backend/internal/face"
+	"github.com/youruser/aplikasi-tms/backend/internal/ocr"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+// newOCREngine returns the local Tesseract engine. A missing binary is
+// only logged: the OCR endpoints answer 503 until it is installed.
+func newOCREngine(cfg *config.OCRConfig) services.OCREngine {
+	engine := ocr.NewTesseract(cfg.TesseractPath, cfg.Languages, cfg.Timeout)
+	if !engine.Available() {
+		log.Printf("OCR disabled - tesseract not found at %q", cfg.TesseractPath)
+	}
+	return engine
+}
+
+// newFaceEngine returns the built-in face engine, or the local model runner
+// at FACE_ENGINE_PATH when one is set. Like OCR, a missing runner or model
+// is only logged and face matching answers 503.
+func newFaceEngine(cfg *config.FaceConfig) services.FaceEngine {
+	if cfg.EnginePath == "" {
+		engine, err := face.NewLocal()
+		if err != nil {
+			log.Printf("Face matching disabled - %v", err)
+			return nil
+		}
+		return engine
+	}
+
+	engine := face.NewCommand(cfg.EnginePath, cfg.ModelPath, cfg.Timeout)
+	if !engine.Available() {
+		log.Printf("Face matching disabled - %q or model %q not found", cfg.EnginePath, cfg.ModelPath)
+	}
+	return engine
+}
+
+// respondOCRError maps an OCR or face match failure to a response
+func respondOCRError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOCRUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "OCR is not available"})
+	case errors.Is(err, services.ErrFaceMatchUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Face matching is not available"})
+	case errors.Is(err, services.ErrNoVerifiedKTP):
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload a KTP and wait for it to be verified before face matching"})
+	case errors.Is(err, services.ErrInvalidImage), errors.Is(err, services.ErrImageTooLarge),
+		errors.Is(err, services.ErrImageTooManyPixels):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read document"})
+	}
+}
+
+// documentWake nudges the document validation worker after an upload
+var documentWake = make(chan struct{}, 1)
+
+// startDocumentValidation reads queued STNK, KTP, KIR and insurance
+// attachments in the background. Close the returned channel to stop it.
+func startDocumentValidation() chan struct{} {
+	stop := make(chan struct{})
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		return stop
+	}
+
+	go services.NewDocumentValidationService(conn, services.NewOCRService(ocrEngine), registries).StartWorker(stop, documentWake)
+	return stop
+}
+
+// wakeDocumentValidation tells the worker an attachment was queued
+func wakeDocumentValidation() {
+	select {
+	case documentWake <- struct{}{}:
+	default:
+	}
+}
+
+func getFaceVerificationsHandler(c *gin.Context) {
+	fleetOwnerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fleet owner ID"})
+		return
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	verifications, err := services.NewFaceMatchService(conn, faceEngine, faceConfig.Threshold).ListVerifications(fleetOwnerID)
+	if err != nil {
+		log.Printf("Face verification list error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get face verifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"verifications": verifications})
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/registry"
+)
+
+// newRegistries sets up a provider for every registry with a URL: the
+// HTTP adapter behind a circuit breaker, with its answers cached. The
+// cache sits outside the breaker, so cached vehicles are still answered
+// while a registry is down. Registries without a URL are left out and
+// their checks come back "unavailable".
+func newRegistries(cfg *config.RegistryConfig) map[string]registry.Provider {
+	providers := make(map[string]registry.Provider)
+	for _, kind := range registry.Kinds {
+		endpoint := cfg.Endpoints[kind]
+		if endpoint.URL == "" {
+			log.Printf("Registry %s not configured - its cross-checks need a manual check", kind)
+			continue
+		}
+		provider := registry.NewHTTP(endpoint.URL, endpoint.APIKey, cfg.Timeout)
+		providers[kind] = registry.NewCached(registry.NewBreaker(provider, cfg.BreakerFailures, cfg.BreakerCooldown), cfg.CacheTTL)
+	}
+	return providers
+}
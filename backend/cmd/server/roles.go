@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/db"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+// runGrantRoleCommand handles `server grant-role <username|email> <role>`.
+// It is how the first super admin is created, since self-registration
+// cannot grant privileged roles.
+func runGrantRoleCommand(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: server grant-role <username|email> <role>")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintf(os.Stderr, "roles: %s\n", strings.Join(auth.Roles(), ", "))
+		return 2
+	}
+
+	conn, err := db.GetDB()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		return 1
+	}
+	defer conn.Close()
+
+	userID, err := services.GetUserIDByLogin(conn, args[0])
+	if err != nil {
+		log.Printf("Grant role failed: %v", err)
+		return 1
+	}
+
+	// Actor 0 marks a change made from the command line
+	user, err := services.AssignUserRole(conn, 0, userID, args[1])
+	if err != nil {
+		log.Printf("Grant role failed: %v", err)
+		return 1
+	}
+
+	fmt.Printf("%s is now %s\n", user.Username, user.Role)
+	return 0
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/db"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+// isTokenRevoked is the revocation check AuthRequired runs on every request
+func isTokenRevoked(jti string) (bool, error) {
+	conn, err := db.Connect()
+	if err != nil {
+		return false, err
+	}
+	return services.NewSessionService(conn, sessionConfig).IsTokenRevoked(jti)
+}
+
+// startSessionCleanup purges expired refresh tokens and revocations in the
+// background. Closing the returned channel stops it.
+func startSessionCleanup() chan struct{} {
+	stop := make(chan struct{})
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		return stop
+	}
+
+	services.NewSessionService(conn, sessionConfig).StartCleanup(stop)
+	return stop
+}
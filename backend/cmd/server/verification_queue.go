@@ -0,0 +1,28 @@
+package main
+
+import (
+	"log"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/db"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+// verificationQueue configures how submissions reach verifiers
+var verificationQueue *config.VerificationQueueConfig
+
+// startVerificationQueue assigns submissions in turn, when configured, and
+// escalates SLA breaches in the background. Close the returned channel to
+// stop it.
+func startVerificationQueue() chan struct{} {
+	stop := make(chan struct{})
+
+	conn, err := db.Connect()
+	if err != nil {
+		log.Printf("Database connection error: %v", err)
+		return stop
+	}
+
+	services.NewVerificationQueueService(conn, verificationQueue).StartWorker(stop)
+	return stop
+}
@@ -0,0 +1,195 @@
+// Package audit builds the tamper-evident audit trail: the before/after
+// diff of the entity a request changed and the hash chain over entries.
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// Redacted replaces the value of a sensitive field in a diff
+const Redacted = "[redacted]"
+
+// Entity maps a route to the table row it changes. The key comes from a
+// route parameter, a request body field, the response (for creates) or
+// the acting user, in that order.
+type Entity struct {
+	Type   string // recorded entity_type
+	Table  string
+	Column string // key column; "id" when empty
+	Param  string
+	Body   string // top-level field of the JSON request
+	Result string // dotted path in the JSON response, e.g. "vehicle.id"
+	Actor  bool
+}
+
+// KeyColumn is the column the entity key is matched against
+func (e Entity) KeyColumn() string {
+	if e.Column == "" {
+		return "id"
+	}
+	return e.Column
+}
+
+// sensitiveFields are recorded as changed but never with their values
+var sensitiveFields = map[string]bool{
+	"password":       true,
+	"password_hash":  true,
+	"totp_secret":    true,
+	"totp_last_step": true,
+	"token_hash":     true,
+	"code_hash":      true,
+	"access_token":   true,
+	"refresh_token":  true,
+}
+
+// Diff returns the fields whose values differ between two snapshots of a
+// row. A nil snapshot means the row did not exist, so a create lists every
+// field with a nil before and a delete every field with a nil after.
+func Diff(before, after map[string]interface{}) map[string]models.AuditChange {
+	changes := map[string]models.AuditChange{}
+	for field, old := range before {
+		if now, ok := after[field]; !ok || !reflect.DeepEqual(old, now) {
+			changes[field] = change(field, old, after[field])
+		}
+	}
+	for field, now := range after {
+		if _, ok := before[field]; !ok {
+			changes[field] = change(field, nil, now)
+		}
+	}
+	return changes
+}
+
+func change(field string, before, after interface{}) models.AuditChange {
+	if sensitiveFields[field] {
+		if before != nil {
+			before = Redacted
+		}
+		if after != nil {
+			after = Redacted
+		}
+	}
+	return models.AuditChange{Before: before, After: after}
+}
+
+// Lookup reads the value at a dotted path from a JSON document and returns
+// it as a string, or "" when it is missing or not a scalar
+func Lookup(document []byte, path string) string {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return ""
+	}
+	for _, field := range strings.Split(path, ".") {
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = object[field]
+	}
+	switch value := v.(type) {
+	case json.Number:
+		return value.String()
+	case string:
+		return value
+	}
+	return ""
+}
+
+// DecodeRow parses a row_to_json document, keeping numbers as written
+func DecodeRow(document []byte) (map[string]interface{}, error) {
+	var row map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	if err := decoder.Decode(&row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// chained is what an entry's hash covers. The ID is left out: the chain
+// itself fixes the order, and the ID is only known after the insert.
+type chained struct {
+	PrevHash   string          `json:"prev_hash"`
+	Action     string          `json:"action"`
+	ActorID    *int            `json:"actor_id"`
+	ActorRole  string          `json:"actor_role"`
+	IPAddress  string          `json:"ip_address"`
+	Method     string          `json:"method"`
+	Route      string          `json:"route"`
+	StatusCode int             `json:"status_code"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Changes    json.RawMessage `json:"changes"`
+	Details    json.RawMessage `json:"details"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// Hash returns the chain hash of event following prevHash ("" for the
+// first entry). JSON fields are canonicalised so the hash of an entry read
+// back from the database matches the one computed when it was written.
+func Hash(prevHash string, event *models.AuditEvent) (string, error) {
+	changes, err := Canonical(event.Changes)
+	if err != nil {
+		return "", err
+	}
+	details, err := Canonical(event.Details)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(chained{
+		PrevHash:   prevHash,
+		Action:     event.Action,
+		ActorID:    event.ActorID,
+		ActorRole:  event.ActorRole,
+		IPAddress:  event.IPAddress,
+		Method:     event.Method,
+		Route:      event.Route,
+		StatusCode: event.StatusCode,
+		EntityType: event.EntityType,
+		EntityID:   event.EntityID,
+		Changes:    changes,
+		Details:    details,
+		CreatedAt:  Timestamp(event.CreatedAt).Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Canonical encodes v as JSON with sorted keys and numbers as written.
+// Empty maps and nil encode as null, matching a NULL column.
+func Canonical(v interface{}) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	if object, ok := generic.(map[string]interface{}); ok && len(object) == 0 {
+		generic = nil
+	}
+	return json.Marshal(generic)
+}
+
+// Timestamp is t as the database stores it: UTC with microsecond precision
+func Timestamp(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+func TestDiff(t *testing.T) {
+	before := map[string]interface{}{"status": "pending", "year": json.Number("2022"), "password_hash": "a", "notes": "x"}
+	after := map[string]interface{}{"status": "approved", "year": json.Number("2022"), "password_hash": "b", "color": "white"}
+
+	changes := Diff(before, after)
+	if len(changes) != 4 {
+		t.Fatalf("Expected 4 changed fields, got %v", changes)
+	}
+	if c := changes["status"]; c.Before != "pending" || c.After != "approved" {
+		t.Errorf("Expected status pending -> approved, got %+v", c)
+	}
+	if _, ok := changes["year"]; ok {
+		t.Error("Expected unchanged year to be left out")
+	}
+	if c := changes["password_hash"]; c.Before != Redacted || c.After != Redacted {
+		t.Errorf("Expected password hash to be redacted, got %+v", c)
+	}
+	if c := changes["notes"]; c.Before != "x" || c.After != nil {
+		t.Errorf("Expected removed field to have nil after, got %+v", c)
+	}
+	if c := changes["color"]; c.Before != nil || c.After != "white" {
+		t.Errorf("Expected added field to have nil before, got %+v", c)
+	}
+
+	created := Diff(nil, map[string]interface{}{"id": json.Number("1"), "totp_secret": nil})
+	if c := created["id"]; c.Before != nil || c.After != json.Number("1") {
+		t.Errorf("Expected create to list every field, got %+v", created)
+	}
+	if c := created["totp_secret"]; c.After != nil {
+		t.Errorf("Expected empty secret to stay nil, got %+v", c)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	body := []byte(`{"vehicle": {"id": 42, "plate": "B 1234 CD"}, "id": "dev-1"}`)
+
+	cases := map[string]string{
+		"vehicle.id":    "42",
+		"vehicle.plate": "B 1234 CD",
+		"id":            "dev-1",
+		"vehicle":       "",
+		"missing.id":    "",
+	}
+	for path, want := range cases {
+		if got := Lookup(body, path); got != want {
+			t.Errorf("Lookup(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if got := Lookup([]byte("not json"), "id"); got != "" {
+		t.Errorf("Expected invalid JSON to give nothing, got %q", got)
+	}
+}
+
+func TestHashChain(t *testing.T) {
+	actor := 7
+	event := &models.AuditEvent{
+		Action:     "trip.update",
+		ActorID:    &actor,
+		ActorRole:  "dispatcher",
+		IPAddress:  "10.0.0.1",
+		Method:     "PUT",
+		Route:      "/api/v1/trips/:id/transition",
+		StatusCode: 200,
+		EntityType: "trip",
+		EntityID:   "3",
+		Changes:    map[string]models.AuditChange{"status": {Before: "planned", After: "dispatched"}},
+		CreatedAt:  time.Date(2026, 3, 1, 8, 30, 0, 123456789, time.FixedZone("WIB", 7*3600)),
+	}
+
+	first, err := Hash("", event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 64 {
+		t.Fatalf("Expected a hex SHA-256, got %q", first)
+	}
+
+	// The entry as read back: UTC, microseconds, numbers as json.Number
+	stored := *event
+	stored.CreatedAt = Timestamp(event.CreatedAt)
+	stored.Changes = map[string]models.AuditChange{"status": {Before: "planned", After: "dispatched"}}
+	if again, _ := Hash("", &stored); again != first {
+		t.Error("Expected the stored entry to hash the same")
+	}
+
+	if linked, _ := Hash(first, event); linked == first {
+		t.Error("Expected the previous hash to change the result")
+	}
+
+	tampered := stored
+	tampered.Changes = map[string]models.AuditChange{"status": {Before: "planned", After: "completed"}}
+	if h, _ := Hash("", &tampered); h == first {
+		t.Error("Expected a changed diff to change the hash")
+	}
+	tampered = stored
+	other := 8
+	tampered.ActorID = &other
+	if h, _ := Hash("", &tampered); h == first {
+		t.Error("Expected a changed actor to change the hash")
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	a, _ := Canonical(map[string]interface{}{"b": 1, "a": 2.5})
+	b, _ := Canonical(map[string]interface{}{"a": json.Number("2.5"), "b": json.Number("1")})
+	if string(a) != string(b) {
+		t.Errorf("Expected equal documents to encode the same, got %s and %s", a, b)
+	}
+
+	for _, empty := range []interface{}{nil, map[string]interface{}{}, map[string]models.AuditChange(nil)} {
+		if data, _ := Canonical(empty); string(data) != "null" {
+			t.Errorf("Expected %#v to encode as null, got %s", empty, data)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Purposes of action tokens; a token only works for the purpose it was
+// signed for
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+// ErrInvalidActionToken covers bad signatures, wrong purposes and expired
+// tokens alike
+var ErrInvalidActionToken = errors.New("invalid or expired token")
+
+// ActionToken is a signed, expiring token sent by email. Nonce is what the
+// database stores (hashed) so each token can be used once.
+type ActionToken struct {
+	UserID    int
+	Purpose   string
+	Nonce     string
+	ExpiresAt time.Time
+}
+
+// actionTokenKey separates action token signatures from JWT signatures
+func actionTokenKey() []byte {
+	key := sha256.Sum256([]byte("action-token:" + jwtSecret))
+	return key[:]
+}
+
+func signActionPayload(payload string) string {
+	mac := hmac.New(sha256.New, actionTokenKey())
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignActionToken issues a token for userID valid for ttl
+func SignActionToken(userID int, purpose string, ttl time.Duration) (string, *ActionToken, error) {
+	nonce, err := RandomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+
+	t := &ActionToken{UserID: userID, Purpose: purpose, Nonce: nonce, ExpiresAt: time.Now().Add(ttl).UTC()}
+	payload := fmt.Sprintf("%d.%s.%d.%s", t.UserID, t.Purpose, t.ExpiresAt.Unix(), t.Nonce)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + signActionPayload(payload), t, nil
+}
+
+// ParseActionToken checks the signature, purpose and expiry of token. It
+// does not check whether the token was already used.
+func ParseActionToken(token, purpose string, now time.Time) (*ActionToken, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidActionToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	payload := string(data)
+	if !hmac.Equal([]byte(signature), []byte(signActionPayload(payload))) {
+		return nil, ErrInvalidActionToken
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 4 || parts[1] != purpose {
+		return nil, ErrInvalidActionToken
+	}
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidActionToken
+	}
+
+	t := &ActionToken{UserID: userID, Purpose: parts[1], Nonce: parts[3], ExpiresAt: time.Unix(expires, 0).UTC()}
+	if !now.Before(t.ExpiresAt) {
+		return nil, ErrInvalidActionToken
+	}
+	return t, nil
+}
@@ -1,8 +1,12 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
 	"time"
 
@@ -11,13 +15,26 @@ import (
 	"github.com/youruser/aplikasi-tms/backend/internal/models"
 )
 
+// Claims of an access token. RegisteredClaims.ID is the token's jti, used
+// to revoke it; SessionID ties it to the refresh token chain it came from.
+// Scope is empty for full access, see ScopeTwoFactor.
 type Claims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
+	Scope     string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ErrRoleNotAllowed is returned when a user tries to register with a role
+// only an administrator may grant
+var ErrRoleNotAllowed = errors.New("role cannot be self-assigned")
+
+// ErrInvalidCredentials is returned by LoginUser for an unknown login or a
+// wrong password; these are the failures that count towards a lockout
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
 // Cache JWT secret untuk performance
 var jwtSecret string
 
@@ -39,24 +56,63 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
-func GenerateToken(userID int, username, role string) (string, error) {
+// GenerateAccessToken issues a short-lived access token with a fresh jti
+func GenerateAccessToken(userID int, username, role, sessionID string, ttl time.Duration) (string, *Claims, error) {
+	return signAccessToken(userID, username, role, sessionID, "", ttl)
+}
+
+// GenerateScopedToken issues a token limited to scope, outside any session
+func GenerateScopedToken(userID int, username, role, scope string, ttl time.Duration) (string, *Claims, error) {
+	return signAccessToken(userID, username, role, "", scope, ttl)
+}
+
+func signAccessToken(userID int, username, role, sessionID, scope string, ttl time.Duration) (string, *Claims, error) {
 	if jwtSecret == "" {
-		return "", errors.New("JWT_SECRET not configured")
+		return "", nil, errors.New("JWT_SECRET not configured")
 	}
 
-	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+	jti, err := RandomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	claims := &Claims{
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
+		Scope:     scope,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "tms-backend",
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(jwtSecret))
+	signed, err := token.SignedString([]byte(jwtSecret))
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// RandomToken returns n random bytes, hex encoded
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken is how refresh tokens are stored, so a database leak does not
+// leak usable tokens
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 func ValidateToken(tokenString string) (*Claims, error) {
@@ -84,6 +140,15 @@ func ValidateToken(tokenString string) (*Claims, error) {
 }
 
 func CreateUser(db *sql.DB, req models.RegisterRequest) (*models.User, error) {
+	// Self-registration may only pick an unprivileged role
+	role := DefaultRole
+	if req.Role != "" {
+		role = req.Role
+	}
+	if !IsSelfRegistrableRole(role) {
+		return nil, ErrRoleNotAllowed
+	}
+
 	// Check if user already exists
 	var exists bool
 	checkQuery := "SELECT EXISTS(SELECT 1 FROM users WHERE username = $1 OR email = $2)"
@@ -100,12 +165,6 @@ func CreateUser(db *sql.DB, req models.RegisterRequest) (*models.User, error) {
 		return nil, err
 	}
 
-	// Default role is user, can be overridden
-	role := "user"
-	if req.Role != "" {
-		role = req.Role
-	}
-
 	query := `INSERT INTO users (username, email, password_hash, full_name, role) 
 			  VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`
 	
@@ -125,29 +184,44 @@ func CreateUser(db *sql.DB, req models.RegisterRequest) (*models.User, error) {
 	return &user, nil
 }
 
+// LoginUserID returns the ID of the user a login names by username or
+// email, 0 when it names nobody
+func LoginUserID(db *sql.DB, login string) (int, error) {
+	var id int
+	err := db.QueryRow(`SELECT id FROM users WHERE username = $1 OR email = $1`, login).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
 func LoginUser(db *sql.DB, req models.LoginRequest) (*models.User, error) {
 	// Database connection errors will be caught by the actual query
 	
 	// Allow login with either username or email
-	query := `SELECT id, username, email, password_hash, full_name, role, created_at, updated_at 
+	query := `SELECT id, username, email, password_hash, full_name, role, email_verified_at, created_at, updated_at 
 			  FROM users WHERE username = $1 OR email = $1`
 	
 	var user models.User
 	var passwordHash string
+	var verifiedAt sql.NullTime
 	
 	err := db.QueryRow(query, req.Email).Scan(
 		&user.ID, &user.Username, &user.Email, &passwordHash, 
-		&user.FullName, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+		&user.FullName, &user.Role, &verifiedAt, &user.CreatedAt, &user.UpdatedAt)
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+			return nil, fmt.Errorf("%w: user not found", ErrInvalidCredentials)
 		}
 		return nil, errors.New("database error: " + err.Error())
 	}
 
 	if !CheckPassword(req.Password, passwordHash) {
-		return nil, errors.New("invalid password")
+		return nil, fmt.Errorf("%w: invalid password", ErrInvalidCredentials)
+	}
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
 	}
 
 	return &user, nil
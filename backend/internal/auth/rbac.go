@@ -0,0 +1,156 @@
+package auth
+
+import "sort"
+
+// Roles
+const (
+	RoleSuperAdmin = "super_admin"
+	RoleVerifier   = "verifier"
+	RoleDispatcher = "dispatcher"
+	RoleFleetOwner = "fleet_owner"
+	RoleDriver     = "driver"
+	RoleFinance    = "finance"
+	RoleViewer     = "viewer"
+)
+
+// Permissions
+const (
+	PermVehiclesRead     = "vehicles.read"
+	PermVehiclesCreate   = "vehicles.create"
+	PermVehiclesVerify   = "vehicles.verify"
+	PermDocumentsVerify  = "documents.verify"
+	PermDriversRead      = "drivers.read"
+	PermDriverApp        = "driver.app"
+	PermTripsRead        = "trips.read"
+	PermTripsCreate      = "trips.create"
+	PermTripsDispatch    = "trips.dispatch"
+	PermTrackingRead     = "tracking.read"
+	PermTrackingPlayback = "tracking.playback"
+	PermGeofencesManage  = "geofences.manage"
+	PermAlertsRead       = "alerts.read"
+	PermDevicesManage    = "devices.manage"
+	PermContractsManage  = "contracts.manage"
+	PermFinanceRead      = "finance.read"
+	PermDashboardRead    = "dashboard.read"
+	PermAdminDashboard   = "admin.dashboard"
+	PermUsersManage      = "users.manage"
+	PermAuditRead        = "audit.read"
+	// PermValidationRulesManage lets a role change how submissions are
+	// validated automatically
+	PermValidationRulesManage = "validation_rules.manage"
+	// PermVerificationQueueManage lets a role hand out verification work,
+	// see every verifier's workload and receive SLA escalations
+	PermVerificationQueueManage = "verification_queue.manage"
+	// PermTenantsAll lets a role read every fleet owner's data
+	PermTenantsAll = "tenants.all"
+)
+
+// DefaultRole is given to self-registered users who do not pick a role
+const DefaultRole = RoleFleetOwner
+
+var allPermissions = []string{
+	PermVehiclesRead, PermVehiclesCreate, PermVehiclesVerify, PermDocumentsVerify,
+	PermDriversRead, PermDriverApp, PermTripsRead, PermTripsCreate, PermTripsDispatch,
+	PermTrackingRead, PermTrackingPlayback, PermGeofencesManage, PermAlertsRead,
+	PermDevicesManage, PermContractsManage, PermFinanceRead, PermDashboardRead,
+	PermAdminDashboard, PermUsersManage, PermAuditRead, PermValidationRulesManage, PermVerificationQueueManage, PermTenantsAll,
+}
+
+var rolePermissions = map[string][]string{
+	RoleSuperAdmin: allPermissions,
+	RoleVerifier: {
+		PermVehiclesRead, PermVehiclesVerify, PermDocumentsVerify, PermDriversRead,
+		PermAdminDashboard, PermTenantsAll,
+	},
+	RoleDispatcher: {
+		PermVehiclesRead, PermDriversRead, PermTripsRead, PermTripsCreate, PermTripsDispatch,
+		PermTrackingRead, PermTrackingPlayback, PermGeofencesManage, PermAlertsRead,
+		PermDashboardRead, PermTenantsAll,
+	},
+	RoleFleetOwner: {
+		PermVehiclesRead, PermVehiclesCreate, PermDriversRead, PermTripsRead, PermTripsCreate,
+		PermTrackingRead, PermAlertsRead, PermDashboardRead,
+	},
+	RoleDriver: {
+		PermDriverApp,
+	},
+	RoleFinance: {
+		PermVehiclesRead, PermTripsRead, PermContractsManage, PermFinanceRead,
+		PermDashboardRead, PermTenantsAll,
+	},
+	RoleViewer: {
+		PermVehiclesRead, PermDriversRead, PermTripsRead, PermTrackingRead, PermAlertsRead,
+		PermDashboardRead, PermTenantsAll,
+	},
+}
+
+// selfRegistrableRoles are the roles a user may pick when signing up;
+// every other role is granted by an administrator
+var selfRegistrableRoles = map[string]bool{
+	RoleFleetOwner: true,
+	RoleDriver:     true,
+}
+
+// twoFactorPermissions are powerful enough that roles holding any of them
+// must use two-factor authentication
+var twoFactorPermissions = []string{PermUsersManage, PermVehiclesVerify, PermDevicesManage}
+
+// RequiresTwoFactor reports whether role may only log in with a second factor
+func RequiresTwoFactor(role string) bool {
+	for _, p := range twoFactorPermissions {
+		if HasPermission(role, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRole reports whether role is a known role
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// IsSelfRegistrableRole reports whether a user may give themselves role
+func IsSelfRegistrableRole(role string) bool {
+	return selfRegistrableRoles[role]
+}
+
+// HasPermission reports whether role grants permission. Unknown roles
+// grant nothing.
+func HasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolesWithPermission returns the roles that grant permission, sorted
+func RolesWithPermission(permission string) []string {
+	var roles []string
+	for _, role := range Roles() {
+		if HasPermission(role, permission) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// RolePermissions returns the permissions of role, sorted
+func RolePermissions(role string) []string {
+	permissions := append([]string(nil), rolePermissions[role]...)
+	sort.Strings(permissions)
+	return permissions
+}
+
+// Roles returns every role name, sorted
+func Roles() []string {
+	roles := make([]string, 0, len(rolePermissions))
+	for role := range rolePermissions {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
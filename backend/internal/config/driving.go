@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// DrivingRulesConfig holds the thresholds used to raise driving alerts from
+// GPS data. Speeds are in km/h.
+type DrivingRulesConfig struct {
+	HarshAccelKmhPerSec float64 // speed gain per second that counts as harsh acceleration
+	HarshBrakeKmhPerSec float64 // speed loss per second that counts as harsh braking
+	IdleAlertMinutes    int     // minutes stopped while reporting before an idling alert
+	WorkingHoursStart   int     // hour of day, inclusive
+	WorkingHoursEnd     int     // hour of day, exclusive
+	Location            *time.Location
+}
+
+func LoadDrivingRulesConfig() *DrivingRulesConfig {
+	cfg := &DrivingRulesConfig{
+		HarshAccelKmhPerSec: 12,
+		HarshBrakeKmhPerSec: 14,
+		IdleAlertMinutes:    15,
+		WorkingHoursStart:   5,
+		WorkingHoursEnd:     22,
+	}
+
+	if v, err := strconv.ParseFloat(os.Getenv("HARSH_ACCEL_KMH_PER_SEC"), 64); err == nil && v > 0 {
+		cfg.HarshAccelKmhPerSec = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("HARSH_BRAKE_KMH_PER_SEC"), 64); err == nil && v > 0 {
+		cfg.HarshBrakeKmhPerSec = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("IDLE_ALERT_MINUTES")); err == nil && v > 0 {
+		cfg.IdleAlertMinutes = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("WORKING_HOURS_START")); err == nil && v >= 0 && v <= 23 {
+		cfg.WorkingHoursStart = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("WORKING_HOURS_END")); err == nil && v >= 1 && v <= 24 {
+		cfg.WorkingHoursEnd = v
+	}
+
+	// Working hours are local time; default to WIB
+	tz := os.Getenv("ALERT_TIMEZONE")
+	if tz == "" {
+		tz = "Asia/Jakarta"
+	}
+	if loc, err := time.LoadLocation(tz); err == nil {
+		cfg.Location = loc
+	} else {
+		cfg.Location = time.FixedZone("WIB", 7*60*60)
+	}
+
+	return cfg
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// FaceConfig configures the local face model that matches a fleet owner's
+// selfie with their KTP photo
+type FaceConfig struct {
+	// EnginePath is a model runner used instead of the built-in engine,
+	// with its model files at ModelPath
+	EnginePath string
+	ModelPath  string
+	// Threshold is the embedding similarity from which two faces are the
+	// same person. It depends on the model.
+	Threshold float64
+	Timeout   time.Duration
+}
+
+func LoadFaceConfig() *FaceConfig {
+	cfg := &FaceConfig{
+		ModelPath: "/opt/face/models",
+		// Suits the built-in engine; a runner's model needs its own
+		Threshold: 0.58,
+		Timeout:   30 * time.Second,
+	}
+
+	if path := os.Getenv("FACE_ENGINE_PATH"); path != "" {
+		cfg.EnginePath = path
+	}
+	if path := os.Getenv("FACE_MODEL_PATH"); path != "" {
+		cfg.ModelPath = path
+	}
+	if thresholdStr := os.Getenv("FACE_MATCH_THRESHOLD"); thresholdStr != "" {
+		if threshold, err := strconv.ParseFloat(thresholdStr, 64); err == nil && threshold > 0 && threshold < 1 {
+			cfg.Threshold = threshold
+		}
+	}
+	if secondsStr := os.Getenv("FACE_TIMEOUT_SECONDS"); secondsStr != "" {
+		if seconds, err := strconv.Atoi(secondsStr); err == nil && seconds > 0 {
+			cfg.Timeout = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return cfg
+}
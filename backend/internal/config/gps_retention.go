@@ -0,0 +1,45 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// GPSRetentionConfig controls how long GPS history is kept and at what
+// resolution
+type GPSRetentionConfig struct {
+	RawRetentionDays     int           // raw points older than this are folded into per-minute summaries
+	SummaryRetentionDays int           // summaries older than this are deleted; 0 keeps them forever
+	PremakeMonths        int           // monthly partitions created ahead of the current month
+	MaintenanceInterval  time.Duration // how often partitions, downsampling and retention run
+}
+
+func LoadGPSRetentionConfig() *GPSRetentionConfig {
+	cfg := &GPSRetentionConfig{
+		RawRetentionDays:     30,
+		SummaryRetentionDays: 365,
+		PremakeMonths:        2,
+		MaintenanceInterval:  time.Hour,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("GPS_RAW_RETENTION_DAYS")); err == nil && v > 0 {
+		cfg.RawRetentionDays = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GPS_SUMMARY_RETENTION_DAYS")); err == nil && v >= 0 {
+		cfg.SummaryRetentionDays = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GPS_PARTITION_PREMAKE_MONTHS")); err == nil && v >= 0 {
+		cfg.PremakeMonths = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GPS_MAINTENANCE_INTERVAL_MINUTES")); err == nil && v > 0 {
+		cfg.MaintenanceInterval = time.Duration(v) * time.Minute
+	}
+
+	// Summaries must outlive the raw points they replace
+	if cfg.SummaryRetentionDays != 0 && cfg.SummaryRetentionDays < cfg.RawRetentionDays {
+		cfg.SummaryRetentionDays = cfg.RawRetentionDays
+	}
+
+	return cfg
+}
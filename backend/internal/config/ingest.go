@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// IngestConfig sizes the buffered GPS writer
+type IngestConfig struct {
+	BufferSize    int           // points held in memory before ingest is refused
+	BatchSize     int           // points written per flush
+	FlushInterval time.Duration // longest a point waits before being written
+	MaxPointAge   time.Duration // older points are refused; 0 accepts any age
+}
+
+func LoadIngestConfig() IngestConfig {
+	cfg := IngestConfig{
+		BufferSize:    10000,
+		BatchSize:     500,
+		FlushInterval: time.Second,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("GPS_INGEST_BUFFER_SIZE")); err == nil && v > 0 {
+		cfg.BufferSize = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GPS_INGEST_BATCH_SIZE")); err == nil && v > 0 {
+		cfg.BatchSize = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("GPS_INGEST_FLUSH_INTERVAL_MS")); err == nil && v > 0 {
+		cfg.FlushInterval = time.Duration(v) * time.Millisecond
+	}
+
+	return cfg
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MailConfig configures outgoing email. An empty SMTPHost disables sending.
+type MailConfig struct {
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+	// AppBaseURL is where links in emails point, e.g. https://tms.example.com
+	AppBaseURL string
+}
+
+func LoadMailConfig() *MailConfig {
+	cfg := &MailConfig{
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     587,
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		From:         "TMS <no-reply@tms.local>",
+		AppBaseURL:   "http://localhost:3000",
+	}
+
+	if portStr := os.Getenv("SMTP_PORT"); portStr != "" {
+		if port, err := strconv.Atoi(portStr); err == nil && port > 0 && port < 65536 {
+			cfg.SMTPPort = port
+		}
+	}
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		cfg.From = from
+	}
+	if baseURL := os.Getenv("APP_BASE_URL"); baseURL != "" {
+		cfg.AppBaseURL = strings.TrimRight(baseURL, "/")
+	}
+
+	return cfg
+}
+
+// FromAddress is the bare address of From, used as the SMTP envelope sender
+func (c *MailConfig) FromAddress() string {
+	if addr, err := mail.ParseAddress(c.From); err == nil {
+		return addr.Address
+	}
+	return c.From
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// OCRConfig configures the local Tesseract engine that reads STNK and KTP
+// photos
+type OCRConfig struct {
+	TesseractPath string
+	// Languages are tesseract language packs joined with +
+	Languages string
+	Timeout   time.Duration
+}
+
+func LoadOCRConfig() *OCRConfig {
+	cfg := &OCRConfig{
+		TesseractPath: "tesseract",
+		Languages:     "ind+eng",
+		Timeout:       30 * time.Second,
+	}
+
+	if path := os.Getenv("TESSERACT_PATH"); path != "" {
+		cfg.TesseractPath = path
+	}
+	if languages := os.Getenv("OCR_LANGUAGES"); languages != "" {
+		cfg.Languages = languages
+	}
+	if secondsStr := os.Getenv("OCR_TIMEOUT_SECONDS"); secondsStr != "" {
+		if seconds, err := strconv.Atoi(secondsStr); err == nil && seconds > 0 {
+			cfg.Timeout = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return cfg
+}
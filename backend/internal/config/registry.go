@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RegistryEndpoint is where one registry's API is and how to sign in. An
+// empty URL leaves the registry unconfigured.
+type RegistryEndpoint struct {
+	URL    string
+	APIKey string
+}
+
+// RegistryConfig configures the Samsat, KIR and insurance registries the
+// vehicle cross-checks ask
+type RegistryConfig struct {
+	// Endpoints by registry kind: samsat, kir, insurance
+	Endpoints map[string]RegistryEndpoint
+	Timeout   time.Duration
+	CacheTTL  time.Duration
+	// After BreakerFailures errors in a row a registry is not asked for
+	// BreakerCooldown
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+func LoadRegistryConfig() *RegistryConfig {
+	cfg := &RegistryConfig{
+		Endpoints:       make(map[string]RegistryEndpoint),
+		Timeout:         10 * time.Second,
+		CacheTTL:        6 * time.Hour,
+		BreakerFailures: 5,
+		BreakerCooldown: time.Minute,
+	}
+
+	for _, kind := range []string{"samsat", "kir", "insurance"} {
+		prefix := strings.ToUpper(kind)
+		cfg.Endpoints[kind] = RegistryEndpoint{
+			URL:    os.Getenv(prefix + "_API_URL"),
+			APIKey: os.Getenv(prefix + "_API_KEY"),
+		}
+	}
+
+	if secondsStr := os.Getenv("REGISTRY_TIMEOUT_SECONDS"); secondsStr != "" {
+		if seconds, err := strconv.Atoi(secondsStr); err == nil && seconds > 0 {
+			cfg.Timeout = time.Duration(seconds) * time.Second
+		}
+	}
+	if minutesStr := os.Getenv("REGISTRY_CACHE_TTL_MINUTES"); minutesStr != "" {
+		if minutes, err := strconv.Atoi(minutesStr); err == nil && minutes > 0 {
+			cfg.CacheTTL = time.Duration(minutes) * time.Minute
+		}
+	}
+	if failuresStr := os.Getenv("REGISTRY_BREAKER_FAILURES"); failuresStr != "" {
+		if failures, err := strconv.Atoi(failuresStr); err == nil && failures > 0 {
+			cfg.BreakerFailures = failures
+		}
+	}
+	if secondsStr := os.Getenv("REGISTRY_BREAKER_COOLDOWN_SECONDS"); secondsStr != "" {
+		if seconds, err := strconv.Atoi(secondsStr); err == nil && seconds > 0 {
+			cfg.BreakerCooldown = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return cfg
+}
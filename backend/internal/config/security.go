@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type SecurityConfig struct {
@@ -15,6 +16,9 @@ type SecurityConfig struct {
 	TokenExpiration  int // hours
 	MaxLoginAttempts int
 	RateLimitRPM     int // requests per minute
+	// First lockout after MaxLoginAttempts failures; each further one doubles
+	LoginLockoutBase time.Duration
+	LoginLockoutMax  time.Duration
 }
 
 func LoadSecurityConfig() (*SecurityConfig, error) {
@@ -71,6 +75,23 @@ func LoadSecurityConfig() (*SecurityConfig, error) {
 		}
 	}
 
+	// Login lockout (base in seconds, cap in minutes)
+	lockoutBase := time.Minute
+	if baseStr := os.Getenv("LOGIN_LOCKOUT_BASE_SECONDS"); baseStr != "" {
+		if base, err := strconv.Atoi(baseStr); err == nil && base > 0 {
+			lockoutBase = time.Duration(base) * time.Second
+		}
+	}
+	lockoutMax := time.Hour
+	if maxStr := os.Getenv("LOGIN_LOCKOUT_MAX_MINUTES"); maxStr != "" {
+		if max, err := strconv.Atoi(maxStr); err == nil && max > 0 {
+			lockoutMax = time.Duration(max) * time.Minute
+		}
+	}
+	if lockoutMax < lockoutBase {
+		lockoutMax = lockoutBase
+	}
+
 	return &SecurityConfig{
 		JWTSecret:        jwtSecret,
 		CSRFSecret:       csrfSecret,
@@ -79,5 +100,7 @@ func LoadSecurityConfig() (*SecurityConfig, error) {
 		TokenExpiration:  tokenExpiration,
 		MaxLoginAttempts: maxLoginAttempts,
 		RateLimitRPM:     rateLimitRPM,
+		LoginLockoutBase: lockoutBase,
+		LoginLockoutMax:  lockoutMax,
 	}, nil
 }
\ No newline at end of file
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// SessionConfig controls token lifetimes
+type SessionConfig struct {
+	AccessTokenTTL  time.Duration // lifetime of the JWT sent with each request
+	RefreshTokenTTL time.Duration // lifetime of a refresh token; each refresh issues a new one
+	CleanupInterval time.Duration // how often expired tokens are purged
+}
+
+func LoadSessionConfig() *SessionConfig {
+	cfg := &SessionConfig{
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 30 * 24 * time.Hour,
+		CleanupInterval: time.Hour,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("ACCESS_TOKEN_TTL_MINUTES")); err == nil && v > 0 && v <= 60 {
+		cfg.AccessTokenTTL = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.Atoi(os.Getenv("REFRESH_TOKEN_TTL_DAYS")); err == nil && v > 0 {
+		cfg.RefreshTokenTTL = time.Duration(v) * 24 * time.Hour
+	}
+
+	return cfg
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ways submissions reach a verifier
+const (
+	AssignmentClaim      = "claim"
+	AssignmentRoundRobin = "round_robin"
+)
+
+// VerificationQueueConfig controls how vehicle submissions are handed to
+// verifiers and how long each stage may take
+type VerificationQueueConfig struct {
+	// Assignment is "claim", verifiers take work themselves, or
+	// "round_robin", the queue hands unassigned work out in turn
+	Assignment string
+	// A claim is released when the verifier does not renew it within
+	// ClaimTTL; a round-robin assignment lasts AssignTTL
+	ClaimTTL  time.Duration
+	AssignTTL time.Duration
+	// SLA is how long a submission may wait in each stage: pending,
+	// under_review, pending_inspection
+	SLA map[string]time.Duration
+	// CheckInterval is how often assignment and SLA breaches are checked
+	CheckInterval time.Duration
+}
+
+func LoadVerificationQueueConfig() *VerificationQueueConfig {
+	cfg := &VerificationQueueConfig{
+		Assignment: AssignmentClaim,
+		ClaimTTL:   30 * time.Minute,
+		AssignTTL:  8 * time.Hour,
+		SLA: map[string]time.Duration{
+			"pending":            24 * time.Hour,
+			"under_review":       48 * time.Hour,
+			"pending_inspection": 5 * 24 * time.Hour,
+		},
+		CheckInterval: 5 * time.Minute,
+	}
+
+	if os.Getenv("VERIFICATION_ASSIGNMENT") == AssignmentRoundRobin {
+		cfg.Assignment = AssignmentRoundRobin
+	}
+	if minutesStr := os.Getenv("VERIFICATION_CLAIM_MINUTES"); minutesStr != "" {
+		if minutes, err := strconv.Atoi(minutesStr); err == nil && minutes > 0 {
+			cfg.ClaimTTL = time.Duration(minutes) * time.Minute
+		}
+	}
+	if hoursStr := os.Getenv("VERIFICATION_ASSIGN_HOURS"); hoursStr != "" {
+		if hours, err := strconv.Atoi(hoursStr); err == nil && hours > 0 {
+			cfg.AssignTTL = time.Duration(hours) * time.Hour
+		}
+	}
+	for stage := range cfg.SLA {
+		if hoursStr := os.Getenv("VERIFICATION_SLA_" + strings.ToUpper(stage) + "_HOURS"); hoursStr != "" {
+			if hours, err := strconv.Atoi(hoursStr); err == nil && hours > 0 {
+				cfg.SLA[stage] = time.Duration(hours) * time.Hour
+			}
+		}
+	}
+	if minutesStr := os.Getenv("VERIFICATION_SLA_CHECK_MINUTES"); minutesStr != "" {
+		if minutes, err := strconv.Atoi(minutesStr); err == nil && minutes > 0 {
+			cfg.CheckInterval = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	return cfg
+}
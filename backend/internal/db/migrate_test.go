@@ -0,0 +1,55 @@
+package db
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("Expected embedded migrations to load, got %v", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("Expected at least one migration")
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("Expected migration %d to have version %d, got %d", i, i+1, m.Version)
+		}
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("Migration %d is missing up or down SQL", m.Version)
+		}
+	}
+}
+
+func TestParseMigrationFilename(t *testing.T) {
+	version, name, direction, err := parseMigrationFilename("0007_trip_stops.down.sql")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if version != 7 || name != "trip_stops" || direction != "down" {
+		t.Errorf("Unexpected parse result: %d %q %q", version, name, direction)
+	}
+
+	invalid := []string{"trip_stops.up.sql", "0001.up.sql", "0001_trip.sql", "abc_trip.up.sql"}
+	for _, filename := range invalid {
+		if _, _, _, err := parseMigrationFilename(filename); err == nil {
+			t.Errorf("Expected error for %s", filename)
+		}
+	}
+}
+
+func TestLoadMigrationsRequiresDownFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_init.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+		"migrations/0001_init.down.sql": {Data: []byte("DROP TABLE a;")},
+		"migrations/0002_more.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+	}
+
+	if _, err := loadMigrations(fsys, "migrations"); err == nil {
+		t.Fatal("Expected error for migration without down file")
+	}
+}
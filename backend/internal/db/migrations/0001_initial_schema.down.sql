@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS gps_tracking;
+DROP TABLE IF EXISTS gps_devices;
+DROP TABLE IF EXISTS gps_registrations;
+DROP TABLE IF EXISTS notification_templates;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS revenue_records;
+DROP TABLE IF EXISTS vehicle_tracking;
+DROP TABLE IF EXISTS trip_tracking;
+DROP TABLE IF EXISTS trips;
+DROP TABLE IF EXISTS user_documents;
+DROP TABLE IF EXISTS fraud_checks;
+DROP TABLE IF EXISTS verification_history;
+DROP TABLE IF EXISTS vehicle_inspections;
+DROP TABLE IF EXISTS vehicle_attachments;
+DROP TABLE IF EXISTS vehicles;
+DROP TABLE IF EXISTS drivers;
+DROP TABLE IF EXISTS fleet_owners;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,319 @@
+-- Baseline schema for the TMS backend.
+-- Every statement is guarded so the migration can be applied on top of a
+-- database that was created by hand before migrations existed.
+
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(50) NOT NULL UNIQUE,
+    email VARCHAR(100) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    full_name VARCHAR(100) NOT NULL,
+    role VARCHAR(20) DEFAULT 'user',
+    user_type VARCHAR(20) DEFAULT 'customer',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS user_type VARCHAR(20) DEFAULT 'customer';
+CREATE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS fleet_owners (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    company_name VARCHAR(255),
+    business_license VARCHAR(100),
+    address TEXT,
+    phone VARCHAR(20),
+    email VARCHAR(100),
+    owner_name VARCHAR(100),
+    ktp_number VARCHAR(20),
+    npwp VARCHAR(30),
+    verified BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE fleet_owners ADD COLUMN IF NOT EXISTS email VARCHAR(100);
+ALTER TABLE fleet_owners ADD COLUMN IF NOT EXISTS owner_name VARCHAR(100);
+ALTER TABLE fleet_owners ADD COLUMN IF NOT EXISTS ktp_number VARCHAR(20);
+ALTER TABLE fleet_owners ADD COLUMN IF NOT EXISTS npwp VARCHAR(30);
+
+CREATE TABLE IF NOT EXISTS drivers (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    license_number VARCHAR(50) NOT NULL UNIQUE,
+    license_expiry DATE NOT NULL,
+    license_type VARCHAR(20) NOT NULL DEFAULT 'B2',
+    status VARCHAR(20) DEFAULT 'available',
+    phone VARCHAR(20),
+    address TEXT,
+    emergency_contact VARCHAR(100),
+    emergency_phone VARCHAR(20),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_drivers_status ON drivers (status);
+
+CREATE TABLE IF NOT EXISTS vehicles (
+    id SERIAL PRIMARY KEY,
+    registration_number VARCHAR(50) NOT NULL UNIQUE,
+    vehicle_type VARCHAR(50) NOT NULL,
+    brand VARCHAR(100) NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    year INTEGER NOT NULL,
+    chassis_number VARCHAR(100) NOT NULL UNIQUE,
+    engine_number VARCHAR(100) NOT NULL,
+    color VARCHAR(50) NOT NULL,
+    capacity_weight NUMERIC(10,2),
+    capacity_volume NUMERIC(10,2),
+    ownership_status VARCHAR(50) NOT NULL,
+    operational_status VARCHAR(50) NOT NULL DEFAULT 'active',
+    verification_status VARCHAR(20) DEFAULT 'pending',
+    verification_substatus VARCHAR(50) DEFAULT 'initial',
+    auto_validation_result TEXT,
+    verification_notes TEXT,
+    admin_notes TEXT,
+    requires_inspection BOOLEAN DEFAULT false,
+    inspection_scheduled_at TIMESTAMP,
+    verified_by INTEGER REFERENCES users(id),
+    verified_at TIMESTAMP,
+    insurance_company VARCHAR(100),
+    insurance_policy_number VARCHAR(100),
+    insurance_expiry_date DATE,
+    last_maintenance_date DATE,
+    next_maintenance_date DATE,
+    maintenance_notes TEXT,
+    created_by INTEGER REFERENCES users(id),
+    fleet_owner_id INTEGER REFERENCES fleet_owners(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS verification_substatus VARCHAR(50) DEFAULT 'initial';
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS auto_validation_result TEXT;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS verification_notes TEXT;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS admin_notes TEXT;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS requires_inspection BOOLEAN DEFAULT false;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS inspection_scheduled_at TIMESTAMP;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS verified_by INTEGER REFERENCES users(id);
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_vehicles_registration ON vehicles (registration_number);
+CREATE INDEX IF NOT EXISTS idx_vehicles_status ON vehicles (operational_status);
+CREATE INDEX IF NOT EXISTS idx_vehicles_verification ON vehicles (verification_status, verification_substatus);
+CREATE INDEX IF NOT EXISTS idx_vehicles_fleet_owner ON vehicles (fleet_owner_id);
+
+CREATE TABLE IF NOT EXISTS vehicle_attachments (
+    id SERIAL PRIMARY KEY,
+    vehicle_id INTEGER REFERENCES vehicles(id) ON DELETE CASCADE,
+    attachment_type VARCHAR(50) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    file_path VARCHAR(500) NOT NULL,
+    file_size INTEGER,
+    mime_type VARCHAR(100),
+    ocr_data TEXT,
+    validation_status VARCHAR(20) DEFAULT 'pending',
+    validation_errors TEXT,
+    uploaded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE vehicle_attachments ADD COLUMN IF NOT EXISTS ocr_data TEXT;
+ALTER TABLE vehicle_attachments ADD COLUMN IF NOT EXISTS validation_status VARCHAR(20) DEFAULT 'pending';
+ALTER TABLE vehicle_attachments ADD COLUMN IF NOT EXISTS validation_errors TEXT;
+CREATE INDEX IF NOT EXISTS idx_vehicle_attachments_type ON vehicle_attachments (attachment_type);
+CREATE INDEX IF NOT EXISTS idx_vehicle_attachments_vehicle ON vehicle_attachments (vehicle_id);
+
+CREATE TABLE IF NOT EXISTS vehicle_inspections (
+    id SERIAL PRIMARY KEY,
+    vehicle_id INTEGER NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    inspector_id INTEGER REFERENCES users(id),
+    inspection_type VARCHAR(50) NOT NULL DEFAULT 'physical',
+    checklist_data TEXT,
+    photos TEXT,
+    location TEXT,
+    result VARCHAR(20) NOT NULL DEFAULT 'pending',
+    notes TEXT,
+    scheduled_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_vehicle_inspections_vehicle ON vehicle_inspections (vehicle_id);
+
+CREATE TABLE IF NOT EXISTS verification_history (
+    id SERIAL PRIMARY KEY,
+    vehicle_id INTEGER NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    admin_id INTEGER REFERENCES users(id),
+    previous_status VARCHAR(50),
+    new_status VARCHAR(50) NOT NULL,
+    verification_substatus VARCHAR(50),
+    admin_notes TEXT,
+    correction_items TEXT,
+    verified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_verification_history_vehicle ON verification_history (vehicle_id, verified_at DESC);
+
+CREATE TABLE IF NOT EXISTS fraud_checks (
+    id SERIAL PRIMARY KEY,
+    vehicle_id INTEGER NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    check_type VARCHAR(50) NOT NULL,
+    result VARCHAR(20) NOT NULL,
+    confidence_score NUMERIC(5,4),
+    details TEXT,
+    checked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_fraud_checks_vehicle ON fraud_checks (vehicle_id, check_type);
+
+CREATE TABLE IF NOT EXISTS user_documents (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    document_type VARCHAR(50) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    file_path VARCHAR(500) NOT NULL,
+    file_size BIGINT DEFAULT 0,
+    mime_type VARCHAR(100) DEFAULT '',
+    upload_status VARCHAR(20) DEFAULT 'uploaded',
+    verification_status VARCHAR(20) DEFAULT 'pending',
+    verification_notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_documents_user ON user_documents (user_id);
+
+CREATE TABLE IF NOT EXISTS trips (
+    id SERIAL PRIMARY KEY,
+    driver_id INTEGER REFERENCES drivers(id),
+    vehicle_id INTEGER REFERENCES vehicles(id),
+    trip_number VARCHAR(50) UNIQUE,
+    origin VARCHAR(255) NOT NULL,
+    destination VARCHAR(255) NOT NULL,
+    origin_address TEXT,
+    destination_address TEXT,
+    origin_lat NUMERIC(10,8),
+    origin_lng NUMERIC(11,8),
+    destination_lat NUMERIC(10,8),
+    destination_lng NUMERIC(11,8),
+    departure_time TIMESTAMP,
+    arrival_time TIMESTAMP,
+    scheduled_start TIMESTAMP,
+    actual_start TIMESTAMP,
+    actual_end TIMESTAMP,
+    status VARCHAR(20) DEFAULT 'planned',
+    distance NUMERIC(10,2),
+    distance_km NUMERIC(10,2),
+    fuel_used NUMERIC(8,2),
+    cargo_weight NUMERIC(10,2),
+    cargo_description TEXT,
+    trip_fee NUMERIC(12,2),
+    driver_fee NUMERIC(12,2),
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_trips_departure ON trips (departure_time);
+CREATE INDEX IF NOT EXISTS idx_trips_status ON trips (status);
+CREATE INDEX IF NOT EXISTS idx_trips_driver ON trips (driver_id);
+
+CREATE TABLE IF NOT EXISTS trip_tracking (
+    id SERIAL PRIMARY KEY,
+    trip_id INTEGER REFERENCES trips(id) ON DELETE CASCADE,
+    latitude NUMERIC(10,8) NOT NULL,
+    longitude NUMERIC(11,8) NOT NULL,
+    speed NUMERIC(5,2),
+    heading NUMERIC(5,2),
+    accuracy NUMERIC(8,2),
+    recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_trip_tracking_trip ON trip_tracking (trip_id, recorded_at);
+
+CREATE TABLE IF NOT EXISTS vehicle_tracking (
+    id SERIAL PRIMARY KEY,
+    vehicle_id INTEGER REFERENCES vehicles(id) ON DELETE CASCADE,
+    latitude NUMERIC(10,8),
+    longitude NUMERIC(11,8),
+    speed NUMERIC(5,2),
+    status VARCHAR(50) DEFAULT 'idle',
+    fuel_level NUMERIC(5,2),
+    mileage NUMERIC(10,2),
+    last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS revenue_records (
+    id SERIAL PRIMARY KEY,
+    fleet_owner_id INTEGER REFERENCES fleet_owners(id) ON DELETE CASCADE,
+    vehicle_id INTEGER REFERENCES vehicles(id) ON DELETE CASCADE,
+    trip_date DATE NOT NULL,
+    distance NUMERIC(10,2),
+    revenue NUMERIC(12,2) NOT NULL,
+    expenses NUMERIC(12,2) DEFAULT 0,
+    profit NUMERIC(12,2) GENERATED ALWAYS AS (revenue - expenses) STORED,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_revenue_records_owner_date ON revenue_records (fleet_owner_id, trip_date);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    type VARCHAR(50) DEFAULT 'info',
+    priority VARCHAR(20) DEFAULT 'normal',
+    channels TEXT,
+    is_read BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS priority VARCHAR(20) DEFAULT 'normal';
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS channels TEXT;
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications (user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS notification_templates (
+    id SERIAL PRIMARY KEY,
+    template_key VARCHAR(50) NOT NULL UNIQUE,
+    title VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    channels TEXT NOT NULL DEFAULT '["in_app"]',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO notification_templates (template_key, title, message, channels) VALUES
+    ('vehicle_submitted', 'Pendaftaran Kendaraan Diterima', 'Pendaftaran kendaraan {plate} ({application_id}) telah diterima dan sedang diproses.', '["in_app","email"]'),
+    ('under_review', 'Kendaraan Sedang Ditinjau', 'Kendaraan {plate} sedang ditinjau oleh tim verifikasi.', '["in_app"]'),
+    ('needs_correction', 'Perbaikan Data Diperlukan', 'Kendaraan {plate} memerlukan perbaikan: {correction_items}.', '["in_app","email"]'),
+    ('inspection_scheduled', 'Inspeksi Dijadwalkan', 'Inspeksi kendaraan {plate} dijadwalkan pada {date} di {location}.', '["in_app","email"]'),
+    ('approved', 'Kendaraan Disetujui', 'Selamat {owner_name}, kendaraan {plate} telah disetujui dan siap beroperasi.', '["in_app","email"]'),
+    ('rejected', 'Kendaraan Ditolak', 'Kendaraan {plate} ditolak. Alasan: {reason}.', '["in_app","email"]')
+ON CONFLICT (template_key) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS gps_registrations (
+    id SERIAL PRIMARY KEY,
+    registration_number VARCHAR(50) NOT NULL,
+    vehicle_type VARCHAR(50) NOT NULL,
+    capacity_tons INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    operator_notes TEXT,
+    admin_notes TEXT,
+    approved_at TIMESTAMP,
+    approved_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_gps_registrations_status ON gps_registrations (status);
+
+CREATE TABLE IF NOT EXISTS gps_devices (
+    id SERIAL PRIMARY KEY,
+    device_id VARCHAR(50) NOT NULL UNIQUE,
+    vehicle_id INTEGER REFERENCES vehicles(id) ON DELETE SET NULL,
+    registration_id INTEGER REFERENCES gps_registrations(id),
+    status VARCHAR(30) NOT NULL DEFAULT 'pending_installation',
+    installed_date TIMESTAMP,
+    last_signal TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_gps_devices_vehicle ON gps_devices (vehicle_id);
+
+CREATE TABLE IF NOT EXISTS gps_tracking (
+    id BIGSERIAL PRIMARY KEY,
+    device_id VARCHAR(50) NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    speed DOUBLE PRECISION DEFAULT 0,
+    timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_gps_tracking_device_time ON gps_tracking (device_id, timestamp DESC);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS trip_status_history;
+
+ALTER TABLE trips DROP CONSTRAINT IF EXISTS trips_status_check;
+ALTER TABLE trips ALTER COLUMN status DROP NOT NULL;
+
+UPDATE trips SET status = 'started' WHERE status IN ('en_route_pickup', 'loading', 'in_transit', 'unloading');
+UPDATE trips SET status = 'completed' WHERE status = 'delivered';
@@ -0,0 +1,24 @@
+-- Normalise legacy trip statuses onto the lifecycle state machine
+UPDATE trips SET status = 'in_transit' WHERE status IN ('started', 'ongoing', 'in_progress');
+UPDATE trips SET status = 'delivered' WHERE status = 'completed';
+UPDATE trips SET status = 'planned' WHERE status IS NULL;
+
+ALTER TABLE trips ALTER COLUMN status SET DEFAULT 'planned';
+ALTER TABLE trips ALTER COLUMN status SET NOT NULL;
+ALTER TABLE trips DROP CONSTRAINT IF EXISTS trips_status_check;
+ALTER TABLE trips ADD CONSTRAINT trips_status_check CHECK (status IN (
+    'planned', 'assigned', 'en_route_pickup', 'loading', 'in_transit',
+    'unloading', 'delivered', 'cancelled', 'failed'
+));
+
+CREATE TABLE IF NOT EXISTS trip_status_history (
+    id SERIAL PRIMARY KEY,
+    trip_id INTEGER NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    changed_by INTEGER REFERENCES users(id),
+    changed_by_role VARCHAR(20),
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_trip_status_history_trip ON trip_status_history (trip_id, created_at);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS trip_stops (
+    id SERIAL PRIMARY KEY,
+    trip_id INTEGER NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    sequence INTEGER NOT NULL,
+    stop_type VARCHAR(20) NOT NULL DEFAULT 'dropoff' CHECK (stop_type IN ('pickup', 'dropoff')),
+    address TEXT NOT NULL,
+    latitude NUMERIC(10,8),
+    longitude NUMERIC(11,8),
+    window_start TIMESTAMP,
+    window_end TIMESTAMP,
+    contact_name VARCHAR(100),
+    contact_phone VARCHAR(20),
+    expected_cargo TEXT,
+    expected_weight NUMERIC(10,2),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'arrived', 'departed')),
+    arrived_at TIMESTAMP,
+    departed_at TIMESTAMP,
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (trip_id, sequence)
+);
+CREATE INDEX IF NOT EXISTS idx_trip_stops_trip ON trip_stops (trip_id, sequence);
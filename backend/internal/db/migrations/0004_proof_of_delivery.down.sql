@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS trip_pod_photos;
+DROP TABLE IF EXISTS trip_pods;
+ALTER TABLE trips DROP COLUMN IF EXISTS customer_contract_id;
+DROP TABLE IF EXISTS customer_contracts;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS customer_contracts (
+    id SERIAL PRIMARY KEY,
+    customer_name VARCHAR(255) NOT NULL,
+    contract_number VARCHAR(100) UNIQUE NOT NULL,
+    requires_pod BOOLEAN NOT NULL DEFAULT false,
+    valid_from DATE,
+    valid_until DATE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS customer_contract_id INTEGER REFERENCES customer_contracts(id);
+
+CREATE TABLE IF NOT EXISTS trip_pods (
+    id SERIAL PRIMARY KEY,
+    trip_id INTEGER NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    stop_id INTEGER REFERENCES trip_stops(id) ON DELETE SET NULL,
+    recipient_name VARCHAR(255) NOT NULL,
+    signature_path TEXT NOT NULL,
+    latitude NUMERIC(10,8) NOT NULL,
+    longitude NUMERIC(11,8) NOT NULL,
+    accuracy NUMERIC(8,2),
+    notes TEXT,
+    captured_by INTEGER REFERENCES users(id),
+    captured_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_trip_pods_trip ON trip_pods (trip_id);
+
+CREATE TABLE IF NOT EXISTS trip_pod_photos (
+    id SERIAL PRIMARY KEY,
+    pod_id INTEGER NOT NULL REFERENCES trip_pods(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    file_path TEXT NOT NULL,
+    file_size INTEGER,
+    mime_type VARCHAR(100),
+    uploaded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_trip_pod_photos_pod ON trip_pod_photos (pod_id);
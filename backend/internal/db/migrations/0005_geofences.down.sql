@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS geofence_events;
+DROP TABLE IF EXISTS geofence_device_state;
+DROP TABLE IF EXISTS geofences;
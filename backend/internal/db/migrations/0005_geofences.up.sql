@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS geofences (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    category VARCHAR(30) NOT NULL DEFAULT 'customer_site' CHECK (category IN ('depot', 'customer_site', 'restricted')),
+    shape_type VARCHAR(10) NOT NULL CHECK (shape_type IN ('circle', 'polygon')),
+    center_lat DOUBLE PRECISION,
+    center_lng DOUBLE PRECISION,
+    radius_meters DOUBLE PRECISION,
+    polygon JSONB,
+    dwell_seconds INTEGER NOT NULL DEFAULT 600,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (
+        (shape_type = 'circle' AND center_lat IS NOT NULL AND center_lng IS NOT NULL AND radius_meters > 0)
+        OR (shape_type = 'polygon' AND polygon IS NOT NULL)
+    )
+);
+
+-- Which devices are currently inside which geofence
+CREATE TABLE IF NOT EXISTS geofence_device_state (
+    device_id VARCHAR(50) NOT NULL,
+    geofence_id INTEGER NOT NULL REFERENCES geofences(id) ON DELETE CASCADE,
+    entered_at TIMESTAMP NOT NULL,
+    dwell_reported BOOLEAN NOT NULL DEFAULT false,
+    PRIMARY KEY (device_id, geofence_id)
+);
+
+CREATE TABLE IF NOT EXISTS geofence_events (
+    id BIGSERIAL PRIMARY KEY,
+    geofence_id INTEGER NOT NULL REFERENCES geofences(id) ON DELETE CASCADE,
+    device_id VARCHAR(50) NOT NULL,
+    vehicle_id INTEGER REFERENCES vehicles(id),
+    event_type VARCHAR(10) NOT NULL CHECK (event_type IN ('enter', 'exit', 'dwell')),
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    trip_id INTEGER REFERENCES trips(id) ON DELETE SET NULL,
+    stop_id INTEGER REFERENCES trip_stops(id) ON DELETE SET NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_geofence_events_geofence ON geofence_events (geofence_id, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_geofence_events_device ON geofence_events (device_id, occurred_at DESC);
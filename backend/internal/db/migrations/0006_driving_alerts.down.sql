@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS driving_alerts;
+DROP TABLE IF EXISTS device_driving_state;
+ALTER TABLE geofences DROP COLUMN IF EXISTS speed_limit_kmh;
+DROP TABLE IF EXISTS vehicle_speed_limits;
@@ -0,0 +1,58 @@
+-- Speed limits per vehicle type; 'default' applies to unlisted types.
+-- vehicle_type is matched case-insensitively against vehicles.vehicle_type.
+CREATE TABLE IF NOT EXISTS vehicle_speed_limits (
+    vehicle_type VARCHAR(50) PRIMARY KEY,
+    max_speed_kmh DOUBLE PRECISION NOT NULL CHECK (max_speed_kmh > 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO vehicle_speed_limits (vehicle_type, max_speed_kmh) VALUES
+    ('default', 80),
+    ('truck', 80),
+    ('truk', 80),
+    ('box truck', 80),
+    ('truk_kecil', 80),
+    ('truk_besar', 60),
+    ('trailer', 60),
+    ('pickup', 90),
+    ('van', 90),
+    ('bus', 80),
+    ('motorcycle', 80)
+ON CONFLICT (vehicle_type) DO NOTHING;
+
+-- Geofences may impose a lower limit, e.g. inside a depot
+ALTER TABLE geofences ADD COLUMN IF NOT EXISTS speed_limit_kmh DOUBLE PRECISION CHECK (speed_limit_kmh > 0);
+
+-- Last point and open episodes per device, so consecutive points can be compared
+CREATE TABLE IF NOT EXISTS device_driving_state (
+    device_id VARCHAR(50) PRIMARY KEY,
+    last_latitude DOUBLE PRECISION NOT NULL,
+    last_longitude DOUBLE PRECISION NOT NULL,
+    last_speed DOUBLE PRECISION NOT NULL,
+    last_timestamp TIMESTAMP NOT NULL,
+    stopped_since TIMESTAMP,
+    idle_alerted BOOLEAN NOT NULL DEFAULT false,
+    speeding BOOLEAN NOT NULL DEFAULT false,
+    after_hours_alerted_on DATE
+);
+
+CREATE TABLE IF NOT EXISTS driving_alerts (
+    id BIGSERIAL PRIMARY KEY,
+    device_id VARCHAR(50) NOT NULL,
+    vehicle_id INTEGER REFERENCES vehicles(id) ON DELETE CASCADE,
+    fleet_owner_id INTEGER REFERENCES fleet_owners(id) ON DELETE SET NULL,
+    alert_type VARCHAR(30) NOT NULL CHECK (alert_type IN ('speeding', 'harsh_acceleration', 'harsh_braking', 'idling', 'after_hours')),
+    severity VARCHAR(10) NOT NULL DEFAULT 'warning' CHECK (severity IN ('warning', 'critical')),
+    value DOUBLE PRECISION,
+    threshold DOUBLE PRECISION,
+    geofence_id INTEGER REFERENCES geofences(id) ON DELETE SET NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    message TEXT NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    acknowledged_at TIMESTAMP,
+    acknowledged_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_driving_alerts_vehicle ON driving_alerts (vehicle_id, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_driving_alerts_fleet_owner ON driving_alerts (fleet_owner_id, occurred_at DESC);
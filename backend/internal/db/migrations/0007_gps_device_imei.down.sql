@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_gps_devices_imei;
+ALTER TABLE gps_devices DROP COLUMN IF EXISTS imei;
@@ -0,0 +1,3 @@
+-- Binary trackers identify themselves by IMEI; map it to the device record
+ALTER TABLE gps_devices ADD COLUMN IF NOT EXISTS imei VARCHAR(20);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_gps_devices_imei ON gps_devices (imei) WHERE imei IS NOT NULL;
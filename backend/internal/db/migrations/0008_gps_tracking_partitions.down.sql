@@ -0,0 +1,20 @@
+DROP TABLE IF EXISTS gps_tracking_minutes;
+
+CREATE TABLE gps_tracking_unpartitioned (
+    id BIGINT PRIMARY KEY DEFAULT nextval('gps_tracking_id_seq'),
+    device_id VARCHAR(50) NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    speed DOUBLE PRECISION DEFAULT 0,
+    timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO gps_tracking_unpartitioned (id, device_id, latitude, longitude, speed, timestamp)
+SELECT id, device_id, latitude, longitude, speed, timestamp FROM gps_tracking;
+
+ALTER SEQUENCE gps_tracking_id_seq OWNED BY gps_tracking_unpartitioned.id;
+DROP TABLE gps_tracking;
+
+ALTER TABLE gps_tracking_unpartitioned RENAME TO gps_tracking;
+ALTER TABLE gps_tracking RENAME CONSTRAINT gps_tracking_unpartitioned_pkey TO gps_tracking_pkey;
+CREATE INDEX IF NOT EXISTS idx_gps_tracking_device_time ON gps_tracking (device_id, timestamp DESC);
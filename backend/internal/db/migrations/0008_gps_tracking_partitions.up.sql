@@ -0,0 +1,56 @@
+-- Partition gps_tracking by month. The server creates upcoming partitions
+-- itself; rows outside every partition land in gps_tracking_default.
+ALTER TABLE gps_tracking RENAME TO gps_tracking_unpartitioned;
+ALTER TABLE gps_tracking_unpartitioned RENAME CONSTRAINT gps_tracking_pkey TO gps_tracking_unpartitioned_pkey;
+ALTER INDEX IF EXISTS idx_gps_tracking_device_time RENAME TO idx_gps_tracking_unpartitioned_device_time;
+
+CREATE TABLE gps_tracking (
+    id BIGINT NOT NULL DEFAULT nextval('gps_tracking_id_seq'),
+    device_id VARCHAR(50) NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    speed DOUBLE PRECISION DEFAULT 0,
+    timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id, timestamp)
+) PARTITION BY RANGE (timestamp);
+CREATE INDEX idx_gps_tracking_device_time ON gps_tracking (device_id, timestamp DESC);
+CREATE TABLE gps_tracking_default PARTITION OF gps_tracking DEFAULT;
+
+-- One partition per month of existing data (up to two years back) through
+-- next month
+DO $$
+DECLARE
+    part_start TIMESTAMP;
+BEGIN
+    FOR part_start IN
+        SELECT generate_series(
+            date_trunc('month', GREATEST(
+                LEAST(COALESCE((SELECT MIN(timestamp) FROM gps_tracking_unpartitioned), LOCALTIMESTAMP), LOCALTIMESTAMP),
+                LOCALTIMESTAMP - INTERVAL '2 years')),
+            date_trunc('month', LOCALTIMESTAMP) + INTERVAL '1 month',
+            INTERVAL '1 month')
+    LOOP
+        EXECUTE format('CREATE TABLE %I PARTITION OF gps_tracking FOR VALUES FROM (%L) TO (%L)',
+            'gps_tracking_y' || to_char(part_start, 'YYYY') || 'm' || to_char(part_start, 'MM'),
+            part_start, part_start + INTERVAL '1 month');
+    END LOOP;
+END $$;
+
+INSERT INTO gps_tracking (id, device_id, latitude, longitude, speed, timestamp)
+SELECT id, device_id, latitude, longitude, speed, timestamp FROM gps_tracking_unpartitioned;
+
+ALTER SEQUENCE gps_tracking_id_seq OWNED BY gps_tracking.id;
+DROP TABLE gps_tracking_unpartitioned;
+
+-- Raw points past retention are folded into one row per device per minute
+CREATE TABLE IF NOT EXISTS gps_tracking_minutes (
+    device_id VARCHAR(50) NOT NULL,
+    minute TIMESTAMP NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    avg_speed DOUBLE PRECISION NOT NULL DEFAULT 0,
+    max_speed DOUBLE PRECISION NOT NULL DEFAULT 0,
+    point_count INTEGER NOT NULL,
+    PRIMARY KEY (device_id, minute)
+);
+CREATE INDEX IF NOT EXISTS idx_gps_tracking_minutes_minute ON gps_tracking_minutes (minute);
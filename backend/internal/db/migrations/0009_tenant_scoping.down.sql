@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_drivers_fleet_owner;
+ALTER TABLE drivers DROP COLUMN IF EXISTS fleet_owner_id;
+DROP INDEX IF EXISTS idx_trips_fleet_owner;
+ALTER TABLE trips DROP COLUMN IF EXISTS fleet_owner_id;
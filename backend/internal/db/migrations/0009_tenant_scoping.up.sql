@@ -0,0 +1,19 @@
+-- Trips and drivers belong to a fleet owner so reads can be scoped by tenant
+ALTER TABLE trips ADD COLUMN IF NOT EXISTS fleet_owner_id INTEGER REFERENCES fleet_owners(id);
+UPDATE trips t SET fleet_owner_id = v.fleet_owner_id
+FROM vehicles v
+WHERE t.vehicle_id = v.id AND t.fleet_owner_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_trips_fleet_owner ON trips (fleet_owner_id, created_at DESC);
+
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS fleet_owner_id INTEGER REFERENCES fleet_owners(id);
+-- A driver belongs to the fleet whose vehicle they drove most recently
+UPDATE drivers d SET fleet_owner_id = latest.fleet_owner_id
+FROM (
+    SELECT DISTINCT ON (t.driver_id) t.driver_id, v.fleet_owner_id
+    FROM trips t
+    JOIN vehicles v ON t.vehicle_id = v.id
+    WHERE t.driver_id IS NOT NULL AND v.fleet_owner_id IS NOT NULL
+    ORDER BY t.driver_id, t.created_at DESC
+) latest
+WHERE d.id = latest.driver_id AND d.fleet_owner_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_drivers_fleet_owner ON drivers (fleet_owner_id);
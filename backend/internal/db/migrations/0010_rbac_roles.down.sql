@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_role;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ALTER COLUMN role DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'user';
+UPDATE users SET role = 'admin' WHERE role = 'super_admin';
+UPDATE users SET role = 'user' WHERE role NOT IN ('admin', 'dispatcher');
@@ -0,0 +1,16 @@
+-- Replace the free-form role column with a fixed set of roles. Legacy
+-- admins become super admins; everyone else gets the least privileged
+-- role that matches what they already do.
+UPDATE users SET role = 'super_admin' WHERE role = 'admin';
+UPDATE users u SET role = 'driver'
+WHERE u.role NOT IN ('super_admin', 'dispatcher')
+  AND EXISTS (SELECT 1 FROM drivers d WHERE d.user_id = u.id);
+UPDATE users SET role = 'fleet_owner'
+WHERE role IS NULL
+   OR role NOT IN ('super_admin', 'verifier', 'dispatcher', 'fleet_owner', 'driver', 'finance', 'viewer');
+
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'fleet_owner';
+ALTER TABLE users ALTER COLUMN role SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_role_check
+    CHECK (role IN ('super_admin', 'verifier', 'dispatcher', 'fleet_owner', 'driver', 'finance', 'viewer'));
+CREATE INDEX IF NOT EXISTS idx_users_role ON users (role);
@@ -0,0 +1,12 @@
+-- Security-relevant events (lockouts and the like). actor_id is the user
+-- the event is about when known; details holds event-specific fields.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    action VARCHAR(64) NOT NULL,
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    ip_address VARCHAR(45),
+    details JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log (action, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log (created_at);
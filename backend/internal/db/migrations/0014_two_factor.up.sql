@@ -0,0 +1,21 @@
+-- totp_secret is encrypted. It is set when enrollment starts and only
+-- enforced once totp_enabled_at is set; totp_last_step stops a code from
+-- being used twice.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_user ON recovery_codes (user_id) WHERE used_at IS NULL;
+
+-- Roles that must use 2FA (users.manage, vehicles.verify or devices.manage
+-- in auth.RequiresTwoFactor) log in again, this time with the second step
+UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+WHERE revoked_at IS NULL
+  AND user_id IN (SELECT id FROM users WHERE role IN ('super_admin', 'verifier'));
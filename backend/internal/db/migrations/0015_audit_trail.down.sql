@@ -0,0 +1,17 @@
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
+
+DROP INDEX IF EXISTS idx_audit_log_hash;
+DROP INDEX IF EXISTS idx_audit_log_entity;
+DROP INDEX IF EXISTS idx_audit_log_actor;
+
+ALTER TABLE audit_log DROP COLUMN IF EXISTS hash;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS prev_hash;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS changes;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS entity_id;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS entity_type;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS status_code;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS route;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS method;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS actor_role;
@@ -0,0 +1,35 @@
+-- Every state-changing API call is recorded with who made it, what it
+-- touched and a before/after diff. Entries are append-only and chained:
+-- hash covers the entry and prev_hash, so editing or removing an entry
+-- breaks every hash after it. Rows written before this migration have no
+-- hash and are reported as legacy by the chain check.
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS actor_role VARCHAR(50);
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS method VARCHAR(10);
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS route VARCHAR(255);
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS status_code INTEGER;
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS entity_type VARCHAR(50);
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS entity_id VARCHAR(64);
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS changes JSONB;
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS prev_hash CHAR(64);
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS hash CHAR(64);
+
+-- Deleting a user must not rewrite their history
+ALTER TABLE audit_log DROP CONSTRAINT IF EXISTS audit_log_actor_id_fkey;
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log (entity_type, entity_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_log_hash ON audit_log (hash);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log;
+CREATE TRIGGER audit_log_no_change BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
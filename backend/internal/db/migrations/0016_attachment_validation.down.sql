@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_vehicle_attachments_queued;
+ALTER TABLE vehicle_attachments DROP COLUMN IF EXISTS validated_at;
+ALTER TABLE vehicle_attachments DROP COLUMN IF EXISTS validation_started_at;
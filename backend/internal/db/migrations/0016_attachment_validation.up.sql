@@ -0,0 +1,7 @@
+-- Uploaded STNK, KTP, KIR and insurance documents are queued
+-- (validation_status 'queued') and read in the background. ocr_data holds
+-- the analysis as JSON and validation_errors a JSON array of messages.
+ALTER TABLE vehicle_attachments ADD COLUMN IF NOT EXISTS validation_started_at TIMESTAMP;
+ALTER TABLE vehicle_attachments ADD COLUMN IF NOT EXISTS validated_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_vehicle_attachments_queued ON vehicle_attachments (uploaded_at)
+    WHERE validation_status IN ('queued', 'processing');
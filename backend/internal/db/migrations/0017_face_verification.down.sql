@@ -0,0 +1,3 @@
+ALTER TABLE fleet_owners DROP COLUMN IF EXISTS face_verification_id;
+ALTER TABLE fleet_owners DROP COLUMN IF EXISTS verified_at;
+DROP TABLE IF EXISTS face_verifications;
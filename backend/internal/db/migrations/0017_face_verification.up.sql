@@ -0,0 +1,19 @@
+-- Every selfie-to-KTP comparison is kept as evidence. A verified one marks
+-- the fleet owner verified and is linked from fleet_owners.
+CREATE TABLE IF NOT EXISTS face_verifications (
+    id BIGSERIAL PRIMARY KEY,
+    fleet_owner_id INTEGER NOT NULL REFERENCES fleet_owners(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    match_score DOUBLE PRECISION NOT NULL,
+    threshold DOUBLE PRECISION NOT NULL,
+    is_match BOOLEAN NOT NULL,
+    liveness_passed BOOLEAN NOT NULL,
+    liveness_flags JSONB NOT NULL DEFAULT '[]',
+    verified BOOLEAN NOT NULL,
+    result JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_face_verifications_owner ON face_verifications (fleet_owner_id, created_at DESC);
+
+ALTER TABLE fleet_owners ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP;
+ALTER TABLE fleet_owners ADD COLUMN IF NOT EXISTS face_verification_id BIGINT REFERENCES face_verifications(id) ON DELETE SET NULL;
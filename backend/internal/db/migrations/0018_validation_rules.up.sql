@@ -0,0 +1,9 @@
+-- Auto-validation rules are saved whole, one version per change; the
+-- highest version is in force. Without any row the built-in defaults apply.
+CREATE TABLE IF NOT EXISTS validation_rule_sets (
+    version SERIAL PRIMARY KEY,
+    rules JSONB NOT NULL,
+    note TEXT,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_vehicles_qa_pending;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS qa_reviewed_at;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS qa_reviewed_by;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS qa_review_status;
+ALTER TABLE verification_history DROP COLUMN IF EXISTS decision;
+ALTER TABLE verification_history DROP COLUMN IF EXISTS rule_version;
+ALTER TABLE verification_history DROP COLUMN IF EXISTS decided_by;
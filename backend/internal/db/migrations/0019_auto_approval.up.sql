@@ -0,0 +1,11 @@
+-- Decisions the system makes by itself are recorded with the rules that
+-- made them
+ALTER TABLE verification_history ADD COLUMN IF NOT EXISTS decided_by VARCHAR(20) NOT NULL DEFAULT 'admin';
+ALTER TABLE verification_history ADD COLUMN IF NOT EXISTS rule_version INTEGER;
+ALTER TABLE verification_history ADD COLUMN IF NOT EXISTS decision JSONB;
+
+-- Auto-approvals picked at random for a verifier to check afterwards
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS qa_review_status VARCHAR(20);
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS qa_reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS qa_reviewed_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_vehicles_qa_pending ON vehicles (verified_at) WHERE qa_review_status = 'pending';
@@ -0,0 +1,10 @@
+DELETE FROM notification_templates WHERE template_key = 'verification_sla_breached';
+DROP TABLE IF EXISTS verification_assignments;
+DROP TRIGGER IF EXISTS vehicles_verification_stage ON vehicles;
+DROP FUNCTION IF EXISTS vehicles_verification_stage();
+DROP INDEX IF EXISTS idx_vehicles_assigned;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS sla_escalated_at;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS stage_entered_at;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS assignment_expires_at;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS assigned_at;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS assigned_to;
@@ -0,0 +1,52 @@
+-- Who is working on a submission and until when. An assignment whose
+-- expiry has passed is free to be claimed again.
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS assigned_to INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS assignment_expires_at TIMESTAMP;
+
+-- The SLA of a stage runs from stage_entered_at; a breach is escalated
+-- once per stage
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS stage_entered_at TIMESTAMP;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS sla_escalated_at TIMESTAMP;
+UPDATE vehicles SET stage_entered_at = COALESCE(verified_at, updated_at, created_at, CURRENT_TIMESTAMP)
+WHERE stage_entered_at IS NULL;
+ALTER TABLE vehicles ALTER COLUMN stage_entered_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE vehicles ALTER COLUMN stage_entered_at SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_vehicles_assigned ON vehicles (assigned_to) WHERE assigned_to IS NOT NULL;
+
+-- Every status change starts a new stage, and a decided submission, or one
+-- sent back to its owner, leaves the verifier's hands
+CREATE OR REPLACE FUNCTION vehicles_verification_stage() RETURNS trigger AS $$
+BEGIN
+    IF NEW.verification_status IS DISTINCT FROM OLD.verification_status
+       OR NEW.verification_substatus IS DISTINCT FROM OLD.verification_substatus THEN
+        NEW.stage_entered_at := CURRENT_TIMESTAMP;
+        NEW.sla_escalated_at := NULL;
+        IF NEW.verification_status IN ('approved', 'rejected', 'needs_correction')
+           OR NEW.verification_substatus = 'needs_correction' THEN
+            NEW.assigned_to := NULL;
+            NEW.assigned_at := NULL;
+            NEW.assignment_expires_at := NULL;
+        END IF;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS vehicles_verification_stage ON vehicles;
+CREATE TRIGGER vehicles_verification_stage BEFORE UPDATE OF verification_status, verification_substatus ON vehicles
+    FOR EACH ROW EXECUTE FUNCTION vehicles_verification_stage();
+
+-- Every claim and assignment, for round-robin turns and throughput
+CREATE TABLE IF NOT EXISTS verification_assignments (
+    id SERIAL PRIMARY KEY,
+    vehicle_id INTEGER NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    verifier_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    method VARCHAR(20) NOT NULL,
+    assigned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_verification_assignments_verifier ON verification_assignments (verifier_id, assigned_at);
+
+INSERT INTO notification_templates (template_key, title, message, channels) VALUES
+    ('verification_sla_breached', 'SLA Verifikasi Terlampaui', 'Kendaraan {plate} ({application_id}) sudah {hours_waiting} jam di tahap {stage}, melewati batas {sla_hours} jam. Verifikator: {verifier}.', '["in_app","email"]')
+ON CONFLICT (template_key) DO NOTHING;
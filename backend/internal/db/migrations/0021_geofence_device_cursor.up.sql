@@ -0,0 +1,6 @@
+-- The timestamp of the last point evaluated per device, so points arriving
+-- out of order are not evaluated against a newer state
+CREATE TABLE IF NOT EXISTS geofence_device_cursor (
+    device_id VARCHAR(50) PRIMARY KEY,
+    last_point_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_gps_tracking_device_point;
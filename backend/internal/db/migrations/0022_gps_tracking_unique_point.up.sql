@@ -0,0 +1,7 @@
+-- A device reports one fix per instant; trackers resend packets that were
+-- not acknowledged, so a repeated fix must not be stored twice
+DELETE FROM gps_tracking a
+USING gps_tracking b
+WHERE a.device_id = b.device_id AND a.timestamp = b.timestamp AND a.id > b.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_gps_tracking_device_point ON gps_tracking (device_id, timestamp);
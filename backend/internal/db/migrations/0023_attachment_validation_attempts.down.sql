@@ -0,0 +1 @@
+ALTER TABLE vehicle_attachments DROP COLUMN IF EXISTS validation_attempts;
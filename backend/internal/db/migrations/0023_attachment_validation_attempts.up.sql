@@ -0,0 +1,4 @@
+-- How many times the background reader has claimed an attachment, so one
+-- that keeps stopping the worker is handed to a verifier instead of being
+-- retried forever
+ALTER TABLE vehicle_attachments ADD COLUMN IF NOT EXISTS validation_attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,98 @@
+package face
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Command runs a face model runner, such as a small ONNX Runtime wrapper
+// around a detector and an embedding model. It is called as
+//
+//	<path> --model <model>
+//
+// with the image on stdin, and writes JSON to stdout:
+//
+//	{"faces": [{"box": [x, y, width, height], "score": 0.99, "embedding": [...]}]}
+type Command struct {
+	Path    string
+	Model   string
+	Timeout time.Duration
+}
+
+// NewCommand uses the runner at path (looked up in PATH when it has no
+// slash) with the model files at model
+func NewCommand(path, model string, timeout time.Duration) *Command {
+	return &Command{Path: path, Model: model, Timeout: timeout}
+}
+
+// Available reports whether the runner and the model can be found
+func (c *Command) Available() bool {
+	if _, err := exec.LookPath(c.Path); err != nil {
+		return false
+	}
+	_, err := os.Stat(c.Model)
+	return err == nil
+}
+
+type commandOutput struct {
+	Faces []struct {
+		Box       [4]int    `json:"box"`
+		Score     float64   `json:"score"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"faces"`
+}
+
+// Detect runs the model on a PNG or JPEG image
+func (c *Command) Detect(ctx context.Context, img []byte) ([]Face, error) {
+	if !c.Available() {
+		return nil, ErrUnavailable
+	}
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, c.Path, "--model", c.Model)
+	cmd.Stdin = bytes.NewReader(img)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("face model timed out after %s", c.Timeout)
+		}
+		return nil, fmt.Errorf("face model failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return ParseOutput(stdout.Bytes())
+}
+
+// ParseOutput reads the runner's JSON output
+func ParseOutput(data []byte) ([]Face, error) {
+	var out commandOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("invalid face model output: %v", err)
+	}
+
+	faces := make([]Face, 0, len(out.Faces))
+	for _, f := range out.Faces {
+		if f.Box[2] <= 0 || f.Box[3] <= 0 || len(f.Embedding) == 0 {
+			continue
+		}
+		faces = append(faces, Face{
+			Box:       image.Rect(f.Box[0], f.Box[1], f.Box[0]+f.Box[2], f.Box[1]+f.Box[3]),
+			Score:     f.Score,
+			Embedding: f.Embedding,
+		})
+	}
+	return faces, nil
+}
@@ -0,0 +1,61 @@
+// Package face finds faces in a photo and compares them by embedding. The
+// built-in models run in process (see Local); a separate model runner can
+// be used instead (see Command).
+package face
+
+import (
+	"context"
+	"errors"
+	"image"
+	"math"
+)
+
+// ErrUnavailable means no face model is installed
+var ErrUnavailable = errors.New("face engine not available")
+
+// Face is one detected face: where it is, how sure the detector is, and
+// an embedding that is close to the embedding of the same person's face
+type Face struct {
+	Box       image.Rectangle
+	Score     float64
+	Embedding []float64
+}
+
+// Engine detects the faces in an encoded PNG or JPEG image
+type Engine interface {
+	Detect(ctx context.Context, image []byte) ([]Face, error)
+}
+
+// Largest returns the biggest face, which in a selfie or on an ID card is
+// the subject, or nil
+func Largest(faces []Face) *Face {
+	var best *Face
+	for i := range faces {
+		if best == nil || area(faces[i].Box) > area(best.Box) {
+			best = &faces[i]
+		}
+	}
+	return best
+}
+
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
+
+// Similarity is the cosine similarity of two embeddings, from -1 to 1. It
+// is 0 when they cannot be compared.
+func Similarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
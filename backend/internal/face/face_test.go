@@ -0,0 +1,67 @@
+package face
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestParseOutput(t *testing.T) {
+	out := `{"faces": [
+		{"box": [10, 20, 100, 120], "score": 0.98, "embedding": [0.1, 0.2, 0.3]},
+		{"box": [0, 0, 0, 50], "score": 0.5, "embedding": [1]},
+		{"box": [5, 5, 40, 40], "score": 0.7, "embedding": []}
+	]}`
+
+	faces, err := ParseOutput([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(faces) != 1 {
+		t.Fatalf("Expected faces without a box or embedding to be dropped, got %+v", faces)
+	}
+	if faces[0].Box != image.Rect(10, 20, 110, 140) || faces[0].Score != 0.98 || len(faces[0].Embedding) != 3 {
+		t.Errorf("Unexpected face %+v", faces[0])
+	}
+
+	if faces, err := ParseOutput([]byte(`{"faces": []}`)); err != nil || len(faces) != 0 {
+		t.Errorf("Expected no faces, got %v %v", faces, err)
+	}
+	if _, err := ParseOutput([]byte("model not found")); err == nil {
+		t.Error("Expected an error for output that is not JSON")
+	}
+}
+
+func TestLargest(t *testing.T) {
+	if Largest(nil) != nil {
+		t.Error("Expected nil without faces")
+	}
+	faces := []Face{
+		{Box: image.Rect(0, 0, 20, 20)},
+		{Box: image.Rect(50, 50, 150, 160)},
+		{Box: image.Rect(0, 0, 40, 40)},
+	}
+	if got := Largest(faces); got != &faces[1] {
+		t.Errorf("Expected the second face, got %+v", got)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b []float64
+		want float64
+	}{
+		{[]float64{1, 2, 3}, []float64{2, 4, 6}, 1},
+		{[]float64{1, 0}, []float64{0, 1}, 0},
+		{[]float64{1, 0}, []float64{-1, 0}, -1},
+		{[]float64{1, 1}, []float64{1, 0}, math.Sqrt2 / 2},
+		{[]float64{1, 2}, []float64{1, 2, 3}, 0},
+		{[]float64{0, 0}, []float64{1, 2}, 0},
+		{nil, nil, 0},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Similarity(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package face
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"sort"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// The face detector and the pupil localizer are pixel-comparison tree
+// cascades (pico and puploc, from github.com/esimov/pigo, MIT licensed)
+var (
+	//go:embed models/facefinder
+	facefinderCascade []byte
+	//go:embed models/puploc
+	puplocCascade []byte
+)
+
+// Faces are compared as an alignedSize square crop with the pupils at
+// fixed points, described by uniform LBP histograms over a grid of cells
+const (
+	alignedSize = 98
+	eyeRow      = 0.36
+	eyeInset    = 0.28
+	gridCells   = 7
+	lbpBins     = 59
+	// minFaceShare is the smallest face looked for, as a share of the
+	// photo's shorter side
+	minFaceShare = 0.08
+	// minDetection is the detector score below which a window is not a face
+	minDetection = 5.0
+	// maxDetectSide is the longest side faces are looked for at; bigger
+	// photos are scaled down first
+	maxDetectSide = 800
+)
+
+// LocalThreshold is the similarity from which two faces described by Local
+// are taken to be the same person
+const LocalThreshold = 0.58
+
+// Local finds and describes faces in process, with the models built in.
+// It needs no runner or model files.
+type Local struct {
+	finder *pigo.Pigo
+	pupils *pigo.PuplocCascade
+}
+
+// NewLocal loads the built-in models
+func NewLocal() (*Local, error) {
+	finder, err := pigo.NewPigo().Unpack(facefinderCascade)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load face detector: %v", err)
+	}
+	pupils, err := pigo.NewPuplocCascade().UnpackCascade(puplocCascade)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pupil localizer: %v", err)
+	}
+	return &Local{finder: finder, pupils: pupils}, nil
+}
+
+// Detect finds the faces in a PNG or JPEG image
+func (l *Local) Detect(ctx context.Context, img []byte) ([]Face, error) {
+	decoded, _, err := image.Decode(bytes.NewReader(img))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+	gray, w, h, step := toGray(decoded, maxDetectSide)
+	params := pigo.ImageParams{Pixels: gray, Rows: h, Cols: w, Dim: w}
+
+	detections := l.finder.RunCascade(pigo.CascadeParams{
+		MinSize:     max(24, int(float64(min(w, h))*minFaceShare)),
+		MaxSize:     min(w, h),
+		ShiftFactor: 0.1,
+		ScaleFactor: 1.1,
+		ImageParams: params,
+	}, 0)
+	detections = l.finder.ClusterDetections(detections, 0.2)
+
+	// The strongest window wins where several windows overlap one face
+	sort.Slice(detections, func(i, j int) bool { return detections[i].Q > detections[j].Q })
+	var kept []pigo.Detection
+	var faces []Face
+	for _, d := range detections {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if d.Q < minDetection || overlapsAny(d, kept) {
+			continue
+		}
+		kept = append(kept, d)
+		left, right := l.locatePupils(d, params)
+		box := image.Rect(d.Col-d.Scale/2, d.Row-d.Scale/2, d.Col+d.Scale/2, d.Row+d.Scale/2).Intersect(image.Rect(0, 0, w, h))
+		faces = append(faces, Face{
+			Box:       image.Rect(box.Min.X*step, box.Min.Y*step, box.Max.X*step, box.Max.Y*step).Add(decoded.Bounds().Min),
+			Score:     math.Min(1, float64(d.Q)/(4*minDetection)),
+			Embedding: describe(align(gray, w, h, left, right)),
+		})
+	}
+	return faces, nil
+}
+
+// overlapsAny reports whether the centre of d lies in one of kept
+func overlapsAny(d pigo.Detection, kept []pigo.Detection) bool {
+	for _, k := range kept {
+		if abs(d.Row-k.Row) < k.Scale/2 && abs(d.Col-k.Col) < k.Scale/2 {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+type point struct{ x, y float64 }
+
+// locatePupils returns the centre of the left and right eye as seen in the
+// image. Where a pupil is not found its usual place in the face box is used.
+func (l *Local) locatePupils(d pigo.Detection, params pigo.ImageParams) (point, point) {
+	scale := float64(d.Scale)
+	guess := func(side float64) point {
+		return point{x: float64(d.Col) + side*0.175*scale, y: float64(d.Row) - 0.075*scale}
+	}
+	find := func(side float64) point {
+		g := guess(side)
+		p := l.pupils.RunDetector(pigo.Puploc{
+			Row:      int(g.y),
+			Col:      int(g.x),
+			Scale:    float32(scale) * 0.25,
+			Perturbs: 63,
+		}, params, 0, false)
+		found := point{x: float64(p.Col), y: float64(p.Row)}
+		if p.Row <= 0 || p.Col <= 0 || math.Hypot(found.x-g.x, found.y-g.y) > 0.15*scale {
+			return g
+		}
+		return found
+	}
+	return find(-1), find(1)
+}
+
+// align samples the face into an alignedSize square so that the pupils
+// land on fixed points, undoing the tilt and the distance to the camera
+func align(gray []uint8, w, h int, left, right point) []float64 {
+	dx, dy := right.x-left.x, right.y-left.y
+	dist := math.Hypot(dx, dy)
+	target := alignedSize * (1 - 2*eyeInset)
+	scale := dist / target
+	cos, sin := dx/dist, dy/dist
+
+	out := make([]float64, alignedSize*alignedSize)
+	for y := 0; y < alignedSize; y++ {
+		for x := 0; x < alignedSize; x++ {
+			// Offset from the left pupil in the aligned crop, mapped back
+			// into the image
+			u := (float64(x) - alignedSize*eyeInset) * scale
+			v := (float64(y) - alignedSize*eyeRow) * scale
+			out[y*alignedSize+x] = sample(gray, w, h, left.x+u*cos-v*sin, left.y+u*sin+v*cos)
+		}
+	}
+	equalize(out)
+	return out
+}
+
+// sample interpolates the pixel at x, y, clamping to the image edge
+func sample(gray []uint8, w, h int, x, y float64) float64 {
+	x = math.Max(0, math.Min(float64(w-1), x))
+	y = math.Max(0, math.Min(float64(h-1), y))
+	x0, y0 := int(x), int(y)
+	x1, y1 := min(x0+1, w-1), min(y0+1, h-1)
+	fx, fy := x-float64(x0), y-float64(y0)
+	top := float64(gray[y0*w+x0])*(1-fx) + float64(gray[y0*w+x1])*fx
+	bottom := float64(gray[y1*w+x0])*(1-fx) + float64(gray[y1*w+x1])*fx
+	return top*(1-fy) + bottom*fy
+}
+
+// equalize spreads the levels of pix evenly, so lighting and exposure
+// matter less
+func equalize(pix []float64) {
+	var hist [256]int
+	for _, v := range pix {
+		hist[int(v)]++
+	}
+	var cdf [256]float64
+	sum := 0
+	for i, n := range hist {
+		sum += n
+		cdf[i] = 255 * float64(sum) / float64(len(pix))
+	}
+	for i, v := range pix {
+		pix[i] = cdf[int(v)]
+	}
+}
+
+// uniformBin maps each 8-bit LBP code to its bin: one for each of the 58
+// codes with at most two 0/1 transitions, and a shared one for the rest
+var uniformBin = func() [256]int {
+	var bins [256]int
+	next := 0
+	for code := 0; code < 256; code++ {
+		transitions := 0
+		for i := 0; i < 8; i++ {
+			if (code>>i)&1 != (code>>((i+1)%8))&1 {
+				transitions++
+			}
+		}
+		if transitions <= 2 {
+			bins[code] = next
+			next++
+		} else {
+			bins[code] = lbpBins - 1
+		}
+	}
+	return bins
+}()
+
+// describe turns an aligned face into an embedding: the uniform LBP
+// histogram of each grid cell, square-rooted and centred on its mean so
+// the cosine similarity is not dominated by the texture every face shares
+func describe(face []float64) []float64 {
+	const cell = alignedSize / gridCells
+	hist := make([]float64, gridCells*gridCells*lbpBins)
+	offsets := [8][2]int{{-1, -1}, {0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}}
+
+	for y := 1; y < alignedSize-1; y++ {
+		for x := 1; x < alignedSize-1; x++ {
+			center := face[y*alignedSize+x]
+			code := 0
+			for i, o := range offsets {
+				if face[(y+o[1])*alignedSize+x+o[0]] >= center {
+					code |= 1 << i
+				}
+			}
+			c := min(y/cell, gridCells-1)*gridCells + min(x/cell, gridCells-1)
+			hist[c*lbpBins+uniformBin[code]]++
+		}
+	}
+
+	for c := 0; c < gridCells*gridCells; c++ {
+		cellHist := hist[c*lbpBins : (c+1)*lbpBins]
+		var total, mean float64
+		for _, n := range cellHist {
+			total += n
+		}
+		for i, n := range cellHist {
+			cellHist[i] = math.Sqrt(n / total)
+			mean += cellHist[i]
+		}
+		mean /= lbpBins
+		for i := range cellHist {
+			cellHist[i] -= mean
+		}
+	}
+	return hist
+}
+
+// toGray converts img to 8-bit luminance, averaging blocks of pixels so
+// the longest side is at most maxSide. It returns the pixels row by row,
+// their width and height, and the block size.
+func toGray(img image.Image, maxSide int) ([]uint8, int, int, int) {
+	b := img.Bounds()
+	step := 1
+	if longest := max(b.Dx(), b.Dy()); longest > maxSide {
+		step = int(math.Ceil(float64(longest) / float64(maxSide)))
+	}
+
+	w, h := b.Dx()/step, b.Dy()/step
+	out := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sum float64
+			for dy := 0; dy < step; dy++ {
+				for dx := 0; dx < step; dx++ {
+					r, g, bl, _ := img.At(b.Min.X+x*step+dx, b.Min.Y+y*step+dy).RGBA()
+					sum += (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)) / 257
+				}
+			}
+			out[y*w+x] = uint8(sum / float64(step*step))
+		}
+	}
+	return out, w, h, step
+}
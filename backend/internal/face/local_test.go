@@ -0,0 +1,127 @@
+package face
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"testing"
+)
+
+// The photos in testdata: face.jpg and face-detect.jpg are two webcam shots
+// of the same man (from gocv.io/x/gocv, Apache 2.0), sample.jpg is another
+// person (from github.com/esimov/pigo, MIT)
+
+func readPhoto(t *testing.T, name string) []byte {
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// reprint resizes a photo by factor and flattens its contrast, roughly what
+// printing it on an ID card and photographing that does
+func reprint(t *testing.T, data []byte, factor, contrast float64) []byte {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, h := int(float64(src.Bounds().Dx())*factor), int(float64(src.Bounds().Dy())*factor)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	level := func(v uint32) uint8 { return uint8(128 + (float64(v>>8)-128)*contrast) }
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, _ := src.At(int(float64(x)/factor), int(float64(y)/factor)).RGBA()
+			dst.Set(x, y, color.RGBA{level(r), level(g), level(b), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 60}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func detectOne(t *testing.T, engine *Local, name string, data []byte) Face {
+	faces, err := engine.Detect(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(faces) != 1 {
+		t.Fatalf("Expected one face in %s, got %d", name, len(faces))
+	}
+	return faces[0]
+}
+
+func TestLocalDetect(t *testing.T) {
+	engine, err := NewLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		center image.Point
+	}{
+		{"face.jpg", readPhoto(t, "face.jpg"), image.Pt(350, 260)},
+		{"face-detect.jpg", readPhoto(t, "face-detect.jpg"), image.Pt(207, 150)},
+		{"sample.jpg", readPhoto(t, "sample.jpg"), image.Pt(160, 200)},
+		// Large photos are scaled down to search but boxes stay in the
+		// photo's own pixels
+		{"face.jpg x3", reprint(t, readPhoto(t, "face.jpg"), 3, 1), image.Pt(1050, 780)},
+	}
+	for _, tt := range tests {
+		f := detectOne(t, engine, tt.name, tt.data)
+		if !tt.center.In(f.Box) || f.Box.Dx() < 50 {
+			t.Errorf("Expected the face box in %s around %v, got %v", tt.name, tt.center, f.Box)
+		}
+		if f.Score <= 0 || len(f.Embedding) == 0 {
+			t.Errorf("Expected a score and an embedding for %s, got %+v", tt.name, f)
+		}
+	}
+
+	blank := image.NewGray(image.Rect(0, 0, 320, 240))
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, blank, nil); err != nil {
+		t.Fatal(err)
+	}
+	if faces, err := engine.Detect(context.Background(), buf.Bytes()); err != nil || len(faces) != 0 {
+		t.Errorf("Expected no face in a blank image, got %d, %v", len(faces), err)
+	}
+	if _, err := engine.Detect(context.Background(), []byte("not an image")); err == nil {
+		t.Error("Expected an error for data that is not an image")
+	}
+}
+
+func TestLocalMatch(t *testing.T) {
+	engine, err := NewLocal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	selfie := detectOne(t, engine, "face.jpg", readPhoto(t, "face.jpg"))
+	samePerson := map[string][]byte{
+		"face-detect.jpg":         readPhoto(t, "face-detect.jpg"),
+		"face-detect.jpg printed": reprint(t, readPhoto(t, "face-detect.jpg"), 0.5, 0.7),
+		"face.jpg printed":        reprint(t, readPhoto(t, "face.jpg"), 0.5, 0.7),
+	}
+	for name, data := range samePerson {
+		if got := Similarity(selfie.Embedding, detectOne(t, engine, name, data).Embedding); got < LocalThreshold {
+			t.Errorf("Expected face.jpg and %s to match, similarity %.3f", name, got)
+		}
+	}
+
+	otherPerson := map[string][]byte{
+		"sample.jpg":         readPhoto(t, "sample.jpg"),
+		"sample.jpg printed": reprint(t, readPhoto(t, "sample.jpg"), 0.5, 0.7),
+	}
+	for name, data := range otherPerson {
+		if got := Similarity(selfie.Embedding, detectOne(t, engine, name, data).Embedding); got >= LocalThreshold {
+			t.Errorf("Expected face.jpg and %s not to match, similarity %.3f", name, got)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package gateway accepts TCP connections from GPS trackers that speak
+// binary protocols (GT06 and Teltonika Codec 8), acknowledges their
+// packets and hands decoded fixes to the regular tracking ingest path.
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// Supported tracker protocols
+const (
+	ProtocolGT06      = "gt06"
+	ProtocolTeltonika = "teltonika"
+)
+
+// ErrUnknownDevice is returned by a Resolver for an IMEI that is not
+// registered; the gateway then refuses the connection
+var ErrUnknownDevice = errors.New("unknown device")
+
+// Resolver maps a tracker IMEI to the device_id used in gps_devices
+type Resolver func(imei string) (string, error)
+
+// Sink stores one decoded point
+type Sink func(data *models.GPSTrackingData) error
+
+// Position is a single fix decoded from a tracker packet
+type Position struct {
+	Time       time.Time
+	Latitude   float64
+	Longitude  float64
+	Speed      float64 // km/h
+	Course     int
+	Satellites int
+	Valid      bool
+}
+
+// idleTimeout closes connections that stay silent longer than a tracker's
+// usual heartbeat interval
+const idleTimeout = 10 * time.Minute
+
+// Server listens for one protocol on one TCP address
+type Server struct {
+	protocol string
+	resolve  Resolver
+	store    Sink
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	closed   bool
+}
+
+func NewServer(protocol string, resolve Resolver, store Sink) (*Server, error) {
+	if protocol != ProtocolGT06 && protocol != ProtocolTeltonika {
+		return nil, fmt.Errorf("unsupported protocol: %s", protocol)
+	}
+	return &Server{
+		protocol: protocol,
+		resolve:  resolve,
+		store:    store,
+		conns:    make(map[net.Conn]struct{}),
+	}, nil
+}
+
+// ListenAndServe listens on addr and serves connections until Close
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until Close is called
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return net.ErrClosed
+	}
+	s.listener = l
+	s.mu.Unlock()
+
+	log.Printf("GPS gateway (%s) listening on %s", s.protocol, l.Addr())
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.handleConn(conn)
+	}
+}
+
+// Addr returns the listening address once Serve has started
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Close stops accepting connections, closes open ones and waits for their
+// handlers to finish
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	var err error
+	switch s.protocol {
+	case ProtocolGT06:
+		err = s.serveGT06(conn)
+	case ProtocolTeltonika:
+		err = s.serveTeltonika(conn)
+	}
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("GPS gateway (%s) %s: %v", s.protocol, conn.RemoteAddr(), err)
+	}
+}
+
+// storePositions saves the valid fixes of a packet for deviceID
+func (s *Server) storePositions(deviceID string, positions []Position) error {
+	for _, p := range positions {
+		if !p.Valid {
+			continue
+		}
+		data := &models.GPSTrackingData{
+			DeviceID:  deviceID,
+			Latitude:  p.Latitude,
+			Longitude: p.Longitude,
+			Speed:     p.Speed,
+			Timestamp: p.Time,
+		}
+		if err := s.store(data); err != nil {
+			return fmt.Errorf("failed to store point: %v", err)
+		}
+	}
+	return nil
+}
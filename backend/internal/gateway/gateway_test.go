@@ -0,0 +1,226 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestGT06LoginPacket(t *testing.T) {
+	// Login example from the GT06 protocol document
+	frame := mustHex(t, "78780D01012345678901234500018CDD0D0A")
+
+	if got := EncodeGT06Login("123456789012345", 1); !bytes.Equal(got, frame) {
+		t.Fatalf("EncodeGT06Login = %X, want %X", got, frame)
+	}
+
+	packet, err := readGT06Packet(bufio.NewReader(bytes.NewReader(frame)))
+	if err != nil {
+		t.Fatalf("Expected valid frame, got %v", err)
+	}
+	imei, err := decodeGT06IMEI(packet.Info)
+	if err != nil || imei != "123456789012345" {
+		t.Fatalf("Expected IMEI 123456789012345, got %q (%v)", imei, err)
+	}
+
+	frame[len(frame)-4] ^= 0xFF
+	if _, err := readGT06Packet(bufio.NewReader(bytes.NewReader(frame))); !errors.Is(err, errGT06Frame) {
+		t.Errorf("Expected checksum error, got %v", err)
+	}
+}
+
+func TestDecodeGT06Location(t *testing.T) {
+	// Location example from the GT06 protocol document
+	frame := mustHex(t, "78781F120B081D112E10CF027AC7EB0C46584900148F01CC00287D001FB8000380810D0A")
+
+	packet, err := readGT06Packet(bufio.NewReader(bytes.NewReader(frame)))
+	if err != nil {
+		t.Fatalf("Expected valid frame, got %v", err)
+	}
+	if packet.Protocol != gt06Location || packet.Serial != 3 {
+		t.Fatalf("Unexpected packet header: %+v", packet)
+	}
+
+	p, err := decodeGT06Position(packet.Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTime := time.Date(2011, 8, 29, 17, 46, 16, 0, time.UTC)
+	if !p.Time.Equal(wantTime) || !p.Valid || p.Satellites != 15 || p.Course != 143 {
+		t.Errorf("Unexpected position: %+v", p)
+	}
+	if math.Abs(p.Latitude-23.111668) > 1e-5 || math.Abs(p.Longitude-114.409285) > 1e-5 {
+		t.Errorf("Unexpected coordinates: %f, %f", p.Latitude, p.Longitude)
+	}
+}
+
+func TestGT06LocationRoundTrip(t *testing.T) {
+	want := Position{
+		Time:     time.Date(2025, 1, 10, 3, 0, 0, 0, time.UTC),
+		Latitude: -6.2, Longitude: 106.816666, Speed: 62, Course: 270, Satellites: 9, Valid: true,
+	}
+
+	packet, err := readGT06Packet(bufio.NewReader(bytes.NewReader(EncodeGT06Location(want, 7))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeGT06Position(packet.Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Time.Equal(want.Time) || got.Speed != want.Speed || got.Course != want.Course ||
+		math.Abs(got.Latitude-want.Latitude) > 1e-6 || math.Abs(got.Longitude-want.Longitude) > 1e-6 {
+		t.Errorf("Round trip mismatch: got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeTeltonikaCodec8(t *testing.T) {
+	// Codec 8 example from the Teltonika documentation
+	packet := mustHex(t, "000000000000003608010000016B40D8EA30010000000000000000000000000000000105021503010101425E0F01F10000601A014E0000000000000000010000C7CF")
+
+	data, err := readTeltonikaPacket(bytes.NewReader(packet))
+	if err != nil {
+		t.Fatalf("Expected valid packet, got %v", err)
+	}
+	positions, err := decodeTeltonikaCodec8(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(positions) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(positions))
+	}
+	if positions[0].Time.UnixMilli() != 1560161086000 || positions[0].Valid {
+		t.Errorf("Unexpected record: %+v", positions[0])
+	}
+
+	packet[len(packet)-1] ^= 0xFF
+	if _, err := readTeltonikaPacket(bytes.NewReader(packet)); !errors.Is(err, errTeltonikaPacket) {
+		t.Errorf("Expected checksum error, got %v", err)
+	}
+}
+
+// startTestGateway runs a gateway on a random port that knows one IMEI and
+// delivers stored points on the returned channel
+func startTestGateway(t *testing.T, protocol string, store Sink) (string, chan *models.GPSTrackingData) {
+	t.Helper()
+	points := make(chan *models.GPSTrackingData, 16)
+	if store == nil {
+		store = func(data *models.GPSTrackingData) error {
+			points <- data
+			return nil
+		}
+	}
+
+	resolve := func(imei string) (string, error) {
+		if imei == "356307042441013" {
+			return "GPS-TRUCK-01", nil
+		}
+		return "", ErrUnknownDevice
+	}
+
+	server, err := NewServer(protocol, resolve, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+
+	return l.Addr().String(), points
+}
+
+func testPositions() []Position {
+	start := time.Date(2025, 1, 10, 3, 0, 0, 0, time.UTC)
+	return []Position{
+		{Time: start, Latitude: -6.2, Longitude: 106.8, Speed: 40, Satellites: 8, Valid: true},
+		{Time: start.Add(10 * time.Second), Latitude: -6.2010, Longitude: 106.8010, Speed: 45, Satellites: 8, Valid: true},
+	}
+}
+
+func receivePoints(t *testing.T, points chan *models.GPSTrackingData, n int) []*models.GPSTrackingData {
+	t.Helper()
+	var got []*models.GPSTrackingData
+	for len(got) < n {
+		select {
+		case p := <-points:
+			got = append(got, p)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out after %d of %d points", len(got), n)
+		}
+	}
+	return got
+}
+
+func TestGatewaySessions(t *testing.T) {
+	for _, protocol := range []string{ProtocolGT06, ProtocolTeltonika} {
+		t.Run(protocol, func(t *testing.T) {
+			addr, points := startTestGateway(t, protocol, nil)
+
+			sim, err := DialSimulator(protocol, addr, "356307042441013")
+			if err != nil {
+				t.Fatalf("Expected login to succeed, got %v", err)
+			}
+			defer sim.Close()
+
+			if err := sim.SendHeartbeat(); err != nil {
+				t.Fatalf("Expected heartbeat ack, got %v", err)
+			}
+			if err := sim.SendPositions(testPositions()); err != nil {
+				t.Fatalf("Expected positions to be accepted, got %v", err)
+			}
+
+			got := receivePoints(t, points, 2)
+			for i, want := range testPositions() {
+				if got[i].DeviceID != "GPS-TRUCK-01" || !got[i].Timestamp.Equal(want.Time) ||
+					math.Abs(got[i].Latitude-want.Latitude) > 1e-6 || got[i].Speed != want.Speed {
+					t.Errorf("Point %d: got %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestGatewayRejectsUnknownIMEI(t *testing.T) {
+	for _, protocol := range []string{ProtocolGT06, ProtocolTeltonika} {
+		addr, _ := startTestGateway(t, protocol, nil)
+		if _, err := DialSimulator(protocol, addr, "111111111111111"); err == nil {
+			t.Errorf("%s: expected unknown IMEI to be refused", protocol)
+		}
+	}
+}
+
+func TestTeltonikaStoreFailureIsNotAcknowledged(t *testing.T) {
+	addr, _ := startTestGateway(t, ProtocolTeltonika, func(*models.GPSTrackingData) error {
+		return errors.New("database unavailable")
+	})
+
+	sim, err := DialSimulator(ProtocolTeltonika, addr, "356307042441013")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Close()
+
+	if err := sim.SendPositions(testPositions()); err == nil {
+		t.Error("Expected the packet to be refused so the device resends it")
+	}
+}
@@ -0,0 +1,208 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+// GT06 packet types
+const (
+	gt06Login      = 0x01
+	gt06Location   = 0x12
+	gt06Heartbeat  = 0x13
+	gt06Alarm      = 0x16
+	gt06Location2  = 0x22 // GT06N and later firmware
+	gt06MaxPayload = 1024
+)
+
+var errGT06Frame = errors.New("malformed GT06 frame")
+
+type gt06Packet struct {
+	Protocol byte
+	Info     []byte
+	Serial   uint16
+}
+
+// crcITU is the CRC-16/X-25 checksum GT06 uses
+func crcITU(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0x8408
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return ^crc
+}
+
+// readGT06Packet reads one frame: start bits 0x7878 (1-byte length) or
+// 0x7979 (2-byte length), length, protocol, info, serial, CRC, 0x0D0A
+func readGT06Packet(r *bufio.Reader) (gt06Packet, error) {
+	var start [2]byte
+	if _, err := io.ReadFull(r, start[:]); err != nil {
+		return gt06Packet{}, err
+	}
+
+	var lengthField []byte
+	switch {
+	case start[0] == 0x78 && start[1] == 0x78:
+		b, err := r.ReadByte()
+		if err != nil {
+			return gt06Packet{}, err
+		}
+		lengthField = []byte{b}
+	case start[0] == 0x79 && start[1] == 0x79:
+		lengthField = make([]byte, 2)
+		if _, err := io.ReadFull(r, lengthField); err != nil {
+			return gt06Packet{}, err
+		}
+	default:
+		return gt06Packet{}, errGT06Frame
+	}
+
+	length := 0
+	for _, b := range lengthField {
+		length = length<<8 | int(b)
+	}
+	// protocol + serial + crc at minimum
+	if length < 5 || length > gt06MaxPayload {
+		return gt06Packet{}, errGT06Frame
+	}
+
+	body := make([]byte, length+2)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return gt06Packet{}, err
+	}
+	if body[length] != 0x0D || body[length+1] != 0x0A {
+		return gt06Packet{}, errGT06Frame
+	}
+	body = body[:length]
+
+	checked := append(append([]byte{}, lengthField...), body[:length-2]...)
+	if crcITU(checked) != binary.BigEndian.Uint16(body[length-2:]) {
+		return gt06Packet{}, fmt.Errorf("%w: bad checksum", errGT06Frame)
+	}
+
+	return gt06Packet{
+		Protocol: body[0],
+		Info:     body[1 : length-4],
+		Serial:   binary.BigEndian.Uint16(body[length-4 : length-2]),
+	}, nil
+}
+
+// encodeGT06Packet builds a short (0x7878) frame
+func encodeGT06Packet(protocol byte, info []byte, serial uint16) []byte {
+	body := []byte{byte(len(info) + 5), protocol}
+	body = append(body, info...)
+	body = binary.BigEndian.AppendUint16(body, serial)
+	body = binary.BigEndian.AppendUint16(body, crcITU(body))
+
+	frame := append([]byte{0x78, 0x78}, body...)
+	return append(frame, 0x0D, 0x0A)
+}
+
+// decodeGT06IMEI reads the 8-byte BCD terminal ID of a login packet
+func decodeGT06IMEI(info []byte) (string, error) {
+	if len(info) < 8 {
+		return "", fmt.Errorf("%w: short login packet", errGT06Frame)
+	}
+	digits := hex.EncodeToString(info[:8])
+	// 16 BCD digits carry a 15-digit IMEI behind a leading zero
+	return digits[1:], nil
+}
+
+// decodeGT06Position reads the GPS block shared by location and alarm packets
+func decodeGT06Position(info []byte) (Position, error) {
+	if len(info) < 18 {
+		return Position{}, fmt.Errorf("%w: short location packet", errGT06Frame)
+	}
+
+	p := Position{
+		Time: time.Date(2000+int(info[0]), time.Month(info[1]), int(info[2]),
+			int(info[3]), int(info[4]), int(info[5]), 0, time.UTC),
+		Satellites: int(info[6] & 0x0F),
+		Latitude:   float64(binary.BigEndian.Uint32(info[7:11])) / 1800000,
+		Longitude:  float64(binary.BigEndian.Uint32(info[11:15])) / 1800000,
+		Speed:      float64(info[15]),
+	}
+
+	flags := binary.BigEndian.Uint16(info[16:18])
+	p.Course = int(flags & 0x03FF)
+	p.Valid = flags&(1<<12) != 0
+	if flags&(1<<10) == 0 {
+		p.Latitude = -p.Latitude
+	}
+	if flags&(1<<11) != 0 {
+		p.Longitude = -p.Longitude
+	}
+	return p, nil
+}
+
+func (s *Server) serveGT06(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	deviceID := ""
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		packet, err := readGT06Packet(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch packet.Protocol {
+		case gt06Login:
+			imei, err := decodeGT06IMEI(packet.Info)
+			if err != nil {
+				return err
+			}
+			deviceID, err = s.resolve(imei)
+			if err != nil {
+				return fmt.Errorf("login refused for IMEI %s: %v", imei, err)
+			}
+
+		case gt06Heartbeat:
+			if deviceID == "" {
+				return errors.New("heartbeat before login")
+			}
+
+		case gt06Location, gt06Location2, gt06Alarm:
+			if deviceID == "" {
+				return errors.New("location before login")
+			}
+			p, err := decodeGT06Position(packet.Info)
+			if err != nil {
+				return err
+			}
+			// GT06 never resends a location, so a failed store is only logged
+			if err := s.storePositions(deviceID, []Position{p}); err != nil {
+				log.Printf("GPS gateway (gt06) device %s: %v", deviceID, err)
+			}
+			if packet.Protocol != gt06Alarm {
+				// Plain location packets are not acknowledged
+				continue
+			}
+
+		default:
+			// Unsupported packet types are skipped without a reply
+			continue
+		}
+
+		if _, err := conn.Write(encodeGT06Packet(packet.Protocol, nil, packet.Serial)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+)
+
+// Simulator impersonates a tracker so the gateway can be exercised locally
+// and in tests without hardware
+type Simulator struct {
+	protocol string
+	imei     string
+	conn     net.Conn
+	serial   uint16
+}
+
+// DialSimulator connects to a gateway and performs the protocol's login
+func DialSimulator(protocol, addr, imei string) (*Simulator, error) {
+	if len(imei) != 15 {
+		return nil, fmt.Errorf("IMEI must have 15 digits")
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := &Simulator{protocol: protocol, imei: imei, conn: conn}
+	if err := sim.login(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return sim, nil
+}
+
+func (s *Simulator) Close() error {
+	return s.conn.Close()
+}
+
+func (s *Simulator) nextSerial() uint16 {
+	s.serial++
+	return s.serial
+}
+
+func (s *Simulator) login() error {
+	s.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	defer s.conn.SetDeadline(time.Time{})
+
+	switch s.protocol {
+	case ProtocolGT06:
+		return s.sendGT06(EncodeGT06Login(s.imei, s.nextSerial()), gt06Login)
+
+	case ProtocolTeltonika:
+		if _, err := s.conn.Write(EncodeTeltonikaIMEI(s.imei)); err != nil {
+			return err
+		}
+		var reply [1]byte
+		if _, err := io.ReadFull(s.conn, reply[:]); err != nil {
+			return err
+		}
+		if reply[0] != 0x01 {
+			return errors.New("login rejected")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported protocol: %s", s.protocol)
+}
+
+// sendGT06 writes a frame and waits for the acknowledgement of protocol
+func (s *Simulator) sendGT06(frame []byte, protocol byte) error {
+	if _, err := s.conn.Write(frame); err != nil {
+		return err
+	}
+	var reply [10]byte
+	if _, err := io.ReadFull(s.conn, reply[:]); err != nil {
+		return fmt.Errorf("no acknowledgement: %v", err)
+	}
+	if reply[3] != protocol {
+		return fmt.Errorf("unexpected acknowledgement 0x%02X", reply[3])
+	}
+	return nil
+}
+
+// SendHeartbeat sends a GT06 status packet and waits for its acknowledgement
+func (s *Simulator) SendHeartbeat() error {
+	if s.protocol != ProtocolGT06 {
+		return nil
+	}
+	s.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	defer s.conn.SetDeadline(time.Time{})
+
+	// terminal info, voltage level, GSM signal, alarm/language
+	info := []byte{0x44, 0x06, 0x04, 0x00, 0x01}
+	return s.sendGT06(encodeGT06Packet(gt06Heartbeat, info, s.nextSerial()), gt06Heartbeat)
+}
+
+// SendPositions reports fixes. GT06 sends one location packet per fix;
+// Teltonika sends them as one AVL packet and returns once it is acknowledged.
+func (s *Simulator) SendPositions(positions []Position) error {
+	s.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	defer s.conn.SetDeadline(time.Time{})
+
+	switch s.protocol {
+	case ProtocolGT06:
+		for _, p := range positions {
+			if _, err := s.conn.Write(EncodeGT06Location(p, s.nextSerial())); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case ProtocolTeltonika:
+		packet, err := EncodeTeltonikaCodec8(positions)
+		if err != nil {
+			return err
+		}
+		if _, err := s.conn.Write(packet); err != nil {
+			return err
+		}
+		var ack [4]byte
+		if _, err := io.ReadFull(s.conn, ack[:]); err != nil {
+			return fmt.Errorf("no acknowledgement: %v", err)
+		}
+		if n := binary.BigEndian.Uint32(ack[:]); n != uint32(len(positions)) {
+			return fmt.Errorf("gateway accepted %d of %d records", n, len(positions))
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported protocol: %s", s.protocol)
+}
+
+// EncodeGT06Login builds a GT06 login packet for a 15-digit IMEI
+func EncodeGT06Login(imei string, serial uint16) []byte {
+	id, _ := hex.DecodeString("0" + imei)
+	return encodeGT06Packet(gt06Login, id, serial)
+}
+
+// EncodeGT06Location builds a GT06 GPS/LBS location packet
+func EncodeGT06Location(p Position, serial uint16) []byte {
+	t := p.Time.UTC()
+	info := []byte{
+		byte(t.Year() - 2000), byte(t.Month()), byte(t.Day()),
+		byte(t.Hour()), byte(t.Minute()), byte(t.Second()),
+		0xC0 | byte(p.Satellites&0x0F),
+	}
+	info = binary.BigEndian.AppendUint32(info, uint32(math.Round(math.Abs(p.Latitude)*1800000)))
+	info = binary.BigEndian.AppendUint32(info, uint32(math.Round(math.Abs(p.Longitude)*1800000)))
+	info = append(info, byte(math.Min(p.Speed, 255)))
+
+	flags := uint16(p.Course) & 0x03FF
+	if p.Valid {
+		flags |= 1 << 12
+	}
+	if p.Latitude >= 0 {
+		flags |= 1 << 10
+	}
+	if p.Longitude < 0 {
+		flags |= 1 << 11
+	}
+	info = binary.BigEndian.AppendUint16(info, flags)
+
+	// LBS block: MCC 510 (Indonesia), MNC, LAC, cell ID
+	info = append(info, 0x01, 0xFE, 0x0A, 0x00, 0x01, 0x00, 0x00, 0x01)
+	return encodeGT06Packet(gt06Location, info, serial)
+}
+
+// EncodeTeltonikaIMEI builds the Teltonika handshake
+func EncodeTeltonikaIMEI(imei string) []byte {
+	packet := binary.BigEndian.AppendUint16(nil, uint16(len(imei)))
+	return append(packet, imei...)
+}
+
+// EncodeTeltonikaCodec8 builds an AVL data packet with one record per fix
+func EncodeTeltonikaCodec8(positions []Position) ([]byte, error) {
+	if len(positions) == 0 || len(positions) > 255 {
+		return nil, fmt.Errorf("a packet carries 1 to 255 records")
+	}
+
+	data := []byte{teltonikaCodec8, byte(len(positions))}
+	for _, p := range positions {
+		data = binary.BigEndian.AppendUint64(data, uint64(p.Time.UnixMilli()))
+		data = append(data, 0) // priority
+		data = binary.BigEndian.AppendUint32(data, uint32(int32(math.Round(p.Longitude*1e7))))
+		data = binary.BigEndian.AppendUint32(data, uint32(int32(math.Round(p.Latitude*1e7))))
+		data = binary.BigEndian.AppendUint16(data, 0) // altitude
+		data = binary.BigEndian.AppendUint16(data, uint16(p.Course))
+		data = append(data, byte(p.Satellites))
+		data = binary.BigEndian.AppendUint16(data, uint16(p.Speed))
+		// IO element: event ID 0, one 1-byte value (ignition 239)
+		ignition := byte(0)
+		if p.Speed > 0 {
+			ignition = 1
+		}
+		data = append(data, 0, 1, 1, 239, ignition, 0, 0, 0)
+	}
+	data = append(data, byte(len(positions)))
+
+	packet := make([]byte, 4, 12+len(data))
+	packet = binary.BigEndian.AppendUint32(packet, uint32(len(data)))
+	packet = append(packet, data...)
+	return binary.BigEndian.AppendUint32(packet, uint32(crc16IBM(data))), nil
+}
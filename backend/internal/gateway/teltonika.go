@@ -0,0 +1,200 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+)
+
+const (
+	teltonikaCodec8     = 0x08
+	teltonikaMaxPacket  = 64 * 1024
+	teltonikaIMEILength = 15
+)
+
+var errTeltonikaPacket = errors.New("malformed Teltonika packet")
+
+// crc16IBM is the CRC-16/ARC checksum Teltonika uses over the AVL data
+func crc16IBM(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// readTeltonikaIMEI reads the handshake: a 2-byte length and the ASCII IMEI
+func readTeltonikaIMEI(r io.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+	if length != teltonikaIMEILength {
+		return "", fmt.Errorf("%w: IMEI length %d", errTeltonikaPacket, length)
+	}
+
+	imei := make([]byte, length)
+	if _, err := io.ReadFull(r, imei); err != nil {
+		return "", err
+	}
+	for _, c := range imei {
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("%w: IMEI is not numeric", errTeltonikaPacket)
+		}
+	}
+	return string(imei), nil
+}
+
+// readTeltonikaPacket reads one AVL data packet: zero preamble, data
+// length, data and CRC. It returns the data field starting at the codec ID.
+func readTeltonikaPacket(r io.Reader) ([]byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(header[:4]) != 0 {
+		return nil, fmt.Errorf("%w: bad preamble", errTeltonikaPacket)
+	}
+
+	length := binary.BigEndian.Uint32(header[4:])
+	if length < 3 || length > teltonikaMaxPacket {
+		return nil, fmt.Errorf("%w: data length %d", errTeltonikaPacket, length)
+	}
+
+	data := make([]byte, length+4)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	crc := binary.BigEndian.Uint32(data[length:])
+	data = data[:length]
+	if uint32(crc16IBM(data)) != crc {
+		return nil, fmt.Errorf("%w: bad checksum", errTeltonikaPacket)
+	}
+	return data, nil
+}
+
+// decodeTeltonikaCodec8 decodes the AVL records of a Codec 8 data field
+func decodeTeltonikaCodec8(data []byte) ([]Position, error) {
+	if data[0] != teltonikaCodec8 {
+		return nil, fmt.Errorf("%w: unsupported codec 0x%02X", errTeltonikaPacket, data[0])
+	}
+
+	count := int(data[1])
+	if int(data[len(data)-1]) != count {
+		return nil, fmt.Errorf("%w: record count mismatch", errTeltonikaPacket)
+	}
+
+	buf := data[2 : len(data)-1]
+	positions := make([]Position, 0, count)
+	for i := 0; i < count; i++ {
+		// timestamp(8) priority(1) lng(4) lat(4) altitude(2) angle(2) satellites(1) speed(2)
+		if len(buf) < 24 {
+			return nil, fmt.Errorf("%w: short record", errTeltonikaPacket)
+		}
+
+		ms := int64(binary.BigEndian.Uint64(buf[0:8]))
+		p := Position{
+			Time:       time.UnixMilli(ms).UTC(),
+			Longitude:  float64(int32(binary.BigEndian.Uint32(buf[9:13]))) / 1e7,
+			Latitude:   float64(int32(binary.BigEndian.Uint32(buf[13:17]))) / 1e7,
+			Course:     int(binary.BigEndian.Uint16(buf[19:21])),
+			Satellites: int(buf[21]),
+			Speed:      float64(binary.BigEndian.Uint16(buf[22:24])),
+		}
+		// Records without a fix carry zero satellites and coordinates
+		p.Valid = p.Satellites > 0 || p.Latitude != 0 || p.Longitude != 0
+		positions = append(positions, p)
+
+		n, err := skipTeltonikaIO(buf[24:])
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[24+n:]
+	}
+
+	if len(buf) != 0 {
+		return nil, fmt.Errorf("%w: trailing bytes", errTeltonikaPacket)
+	}
+	return positions, nil
+}
+
+// skipTeltonikaIO returns the size of a Codec 8 IO element: event ID, total
+// count, then groups of 1, 2, 4 and 8-byte values each prefixed by a count
+func skipTeltonikaIO(buf []byte) (int, error) {
+	if len(buf) < 2 {
+		return 0, fmt.Errorf("%w: short IO element", errTeltonikaPacket)
+	}
+
+	offset := 2
+	for _, size := range []int{1, 2, 4, 8} {
+		if offset >= len(buf) {
+			return 0, fmt.Errorf("%w: short IO element", errTeltonikaPacket)
+		}
+		n := int(buf[offset])
+		offset += 1 + n*(1+size)
+		if offset > len(buf) {
+			return 0, fmt.Errorf("%w: short IO element", errTeltonikaPacket)
+		}
+	}
+	return offset, nil
+}
+
+func (s *Server) serveTeltonika(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+
+	conn.SetReadDeadline(time.Now().Add(idleTimeout))
+	imei, err := readTeltonikaIMEI(r)
+	if err != nil {
+		return err
+	}
+
+	deviceID, err := s.resolve(imei)
+	if err != nil {
+		conn.Write([]byte{0x00})
+		return fmt.Errorf("login refused for IMEI %s: %v", imei, err)
+	}
+	if _, err := conn.Write([]byte{0x01}); err != nil {
+		return err
+	}
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		data, err := readTeltonikaPacket(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		positions, err := decodeTeltonikaCodec8(data)
+		if err != nil {
+			return err
+		}
+
+		// Acknowledging zero records makes the device resend the packet;
+		// fixes of it already stored are skipped when stored again
+		accepted := uint32(len(positions))
+		if err := s.storePositions(deviceID, positions); err != nil {
+			log.Printf("GPS gateway (teltonika) device %s: %v", deviceID, err)
+			accepted = 0
+		}
+
+		ack := binary.BigEndian.AppendUint32(nil, accepted)
+		if _, err := conn.Write(ack); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package geo holds the small amount of spherical geometry the tracking
+// features need: distances, point-in-shape tests and bounding boxes.
+package geo
+
+import "math"
+
+// EarthRadiusMeters is the mean Earth radius used for haversine distances
+const EarthRadiusMeters = 6371008.8
+
+// Point is a WGS84 coordinate
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Valid reports whether the coordinate is within WGS84 bounds
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lng >= -180 && p.Lng <= 180
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// Distance returns the great-circle distance between two points in meters
+func Distance(a, b Point) float64 {
+	lat1, lat2 := toRadians(a.Lat), toRadians(b.Lat)
+	dLat := lat2 - lat1
+	dLng := toRadians(b.Lng - a.Lng)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// InCircle reports whether p lies within radius meters of center
+func InCircle(p, center Point, radius float64) bool {
+	return Distance(p, center) <= radius
+}
+
+// InPolygon reports whether p lies inside the polygon using ray casting.
+// The polygon may be open or closed; points on an edge count as inside.
+// Geofences are small enough that treating coordinates as planar is fine.
+func InPolygon(p Point, polygon []Point) bool {
+	n := len(polygon)
+	if n < 3 {
+		return false
+	}
+
+	inside := false
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if onSegment(p, a, b) {
+			return true
+		}
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) {
+			lng := (b.Lng-a.Lng)*(p.Lat-a.Lat)/(b.Lat-a.Lat) + a.Lng
+			if p.Lng < lng {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+func onSegment(p, a, b Point) bool {
+	const epsilon = 1e-12
+	cross := (p.Lng-a.Lng)*(b.Lat-a.Lat) - (p.Lat-a.Lat)*(b.Lng-a.Lng)
+	if math.Abs(cross) > epsilon {
+		return false
+	}
+	return p.Lng >= math.Min(a.Lng, b.Lng)-epsilon && p.Lng <= math.Max(a.Lng, b.Lng)+epsilon &&
+		p.Lat >= math.Min(a.Lat, b.Lat)-epsilon && p.Lat <= math.Max(a.Lat, b.Lat)+epsilon
+}
+
+// Bounds is an axis-aligned bounding box
+type Bounds struct {
+	MinLat, MinLng, MaxLat, MaxLng float64
+}
+
+// Contains reports whether p lies within the box
+func (b Bounds) Contains(p Point) bool {
+	return p.Lat >= b.MinLat && p.Lat <= b.MaxLat && p.Lng >= b.MinLng && p.Lng <= b.MaxLng
+}
+
+// PolygonBounds returns the bounding box of a polygon
+func PolygonBounds(polygon []Point) Bounds {
+	b := Bounds{MinLat: 90, MinLng: 180, MaxLat: -90, MaxLng: -180}
+	for _, p := range polygon {
+		b.MinLat = math.Min(b.MinLat, p.Lat)
+		b.MaxLat = math.Max(b.MaxLat, p.Lat)
+		b.MinLng = math.Min(b.MinLng, p.Lng)
+		b.MaxLng = math.Max(b.MaxLng, p.Lng)
+	}
+	return b
+}
+
+// CircleBounds returns a bounding box enclosing a circle of radius meters
+func CircleBounds(center Point, radius float64) Bounds {
+	dLat := radius / EarthRadiusMeters * 180 / math.Pi
+	cosLat := math.Cos(toRadians(center.Lat))
+	dLng := 180.0
+	if cosLat > 1e-9 {
+		dLng = math.Min(180, dLat/cosLat)
+	}
+	return Bounds{
+		MinLat: center.Lat - dLat,
+		MaxLat: center.Lat + dLat,
+		MinLng: center.Lng - dLng,
+		MaxLng: center.Lng + dLng,
+	}
+}
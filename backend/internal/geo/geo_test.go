@@ -0,0 +1,119 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	// Monas, Jakarta to Gedung Sate, Bandung is about 118 km
+	monas := Point{Lat: -6.175392, Lng: 106.827153}
+	gedungSate := Point{Lat: -6.902477, Lng: 107.618782}
+
+	d := Distance(monas, gedungSate)
+	if math.Abs(d-118000) > 3000 {
+		t.Fatalf("Expected about 118 km, got %.0f m", d)
+	}
+
+	if Distance(monas, monas) != 0 {
+		t.Fatal("Expected zero distance for identical points")
+	}
+}
+
+func TestInCircle(t *testing.T) {
+	center := Point{Lat: -6.2, Lng: 106.8}
+	near := Point{Lat: -6.2005, Lng: 106.8} // ~55 m
+	far := Point{Lat: -6.21, Lng: 106.8}    // ~1.1 km
+
+	if !InCircle(near, center, 100) {
+		t.Error("Expected nearby point inside 100 m circle")
+	}
+	if InCircle(far, center, 100) {
+		t.Error("Expected far point outside 100 m circle")
+	}
+}
+
+func TestInPolygon(t *testing.T) {
+	square := []Point{
+		{Lat: 0, Lng: 0},
+		{Lat: 0, Lng: 1},
+		{Lat: 1, Lng: 1},
+		{Lat: 1, Lng: 0},
+	}
+
+	cases := []struct {
+		p      Point
+		inside bool
+	}{
+		{Point{Lat: 0.5, Lng: 0.5}, true},
+		{Point{Lat: 1.5, Lng: 0.5}, false},
+		{Point{Lat: 0.5, Lng: -0.1}, false},
+		{Point{Lat: 0, Lng: 0.5}, true}, // on an edge
+		{Point{Lat: 1, Lng: 1}, true},   // on a vertex
+	}
+
+	for _, tc := range cases {
+		if got := InPolygon(tc.p, square); got != tc.inside {
+			t.Errorf("InPolygon(%v) = %v, want %v", tc.p, got, tc.inside)
+		}
+	}
+
+	if InPolygon(Point{Lat: 0.5, Lng: 0.5}, square[:2]) {
+		t.Error("Expected degenerate polygon to contain nothing")
+	}
+}
+
+func TestInConcavePolygon(t *testing.T) {
+	// U shape opening to the north
+	u := []Point{
+		{Lat: 0, Lng: 0}, {Lat: 0, Lng: 3}, {Lat: 3, Lng: 3}, {Lat: 3, Lng: 2},
+		{Lat: 1, Lng: 2}, {Lat: 1, Lng: 1}, {Lat: 3, Lng: 1}, {Lat: 3, Lng: 0},
+	}
+
+	if InPolygon(Point{Lat: 2, Lng: 1.5}, u) {
+		t.Error("Expected point in the notch to be outside")
+	}
+	if !InPolygon(Point{Lat: 2, Lng: 0.5}, u) {
+		t.Error("Expected point in the left arm to be inside")
+	}
+}
+
+func TestCircleBounds(t *testing.T) {
+	center := Point{Lat: -6.2, Lng: 106.8}
+	b := CircleBounds(center, 500)
+
+	edge := Point{Lat: center.Lat, Lng: center.Lng + 0.0044} // ~485 m east
+	if !b.Contains(edge) {
+		t.Error("Expected bounds to contain a point inside the circle")
+	}
+	if b.Contains(Point{Lat: center.Lat + 0.01, Lng: center.Lng}) {
+		t.Error("Expected bounds to exclude a point 1.1 km north")
+	}
+}
+
+func TestEncodePolyline(t *testing.T) {
+	// Example from Google's polyline algorithm documentation
+	points := []Point{{Lat: 38.5, Lng: -120.2}, {Lat: 40.7, Lng: -120.95}, {Lat: 43.252, Lng: -126.453}}
+	const want = "_p~iF~ps|U_ulLnnqC_mqNvxq`@"
+
+	if got := EncodePolyline(points); got != want {
+		t.Fatalf("Expected %s, got %s", want, got)
+	}
+
+	decoded, err := DecodePolyline(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(points) {
+		t.Fatalf("Expected %d points, got %d", len(points), len(decoded))
+	}
+	for i := range points {
+		if math.Abs(decoded[i].Lat-points[i].Lat) > 1e-5 || math.Abs(decoded[i].Lng-points[i].Lng) > 1e-5 {
+			t.Errorf("Point %d: expected %v, got %v", i, points[i], decoded[i])
+		}
+	}
+
+	if _, err := DecodePolyline("_p~iF~ps|U_"); err == nil {
+		t.Error("Expected an error for a truncated polyline")
+	}
+}
@@ -0,0 +1,79 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// polylinePrecision is the 1e5 scale of Google's encoded polyline format
+const polylinePrecision = 1e5
+
+// EncodePolyline encodes points in Google's encoded polyline format, which
+// map SDKs decode directly
+func EncodePolyline(points []Point) string {
+	var b strings.Builder
+	var prevLat, prevLng int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Lat * polylinePrecision))
+		lng := int64(math.Round(p.Lng * polylinePrecision))
+		encodePolylineValue(&b, lat-prevLat)
+		encodePolylineValue(&b, lng-prevLng)
+		prevLat, prevLng = lat, lng
+	}
+	return b.String()
+}
+
+func encodePolylineValue(b *strings.Builder, v int64) {
+	u := uint64(v << 1)
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		b.WriteByte(byte(0x20|u&0x1F) + 63)
+		u >>= 5
+	}
+	b.WriteByte(byte(u) + 63)
+}
+
+// DecodePolyline is the inverse of EncodePolyline
+func DecodePolyline(s string) ([]Point, error) {
+	var points []Point
+	var lat, lng int64
+	for i := 0; i < len(s); {
+		dLat, n, err := decodePolylineValue(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += n
+		dLng, n, err := decodePolylineValue(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		i += n
+
+		lat += dLat
+		lng += dLng
+		points = append(points, Point{Lat: float64(lat) / polylinePrecision, Lng: float64(lng) / polylinePrecision})
+	}
+	return points, nil
+}
+
+func decodePolylineValue(s string) (int64, int, error) {
+	var u uint64
+	for i := 0; i < len(s) && i < 13; i++ {
+		c := s[i]
+		if c < 63 || c > 126 {
+			return 0, 0, errors.New("invalid polyline character")
+		}
+		u |= uint64(c-63) & 0x1F << (5 * i)
+		if c-63 < 0x20 {
+			v := int64(u >> 1)
+			if u&1 != 0 {
+				v = ^v
+			}
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, errors.New("truncated polyline")
+}
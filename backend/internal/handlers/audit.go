@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+type AuditHandler struct {
+	audit *services.AuditService
+}
+
+func NewAuditHandler(audit *services.AuditService) *AuditHandler {
+	return &AuditHandler{audit: audit}
+}
+
+var auditCSVHeader = []string{
+	"id", "created_at", "action", "actor_id", "actor_role", "ip_address", "method", "route",
+	"status_code", "entity_type", "entity_id", "changes", "details", "prev_hash", "hash",
+}
+
+// parseAuditFilter reads actor_id, action, entity_type, entity_id, from/to
+// (RFC3339), limit and offset
+func parseAuditFilter(c *gin.Context) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+	}
+
+	if v := c.Query("actor_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, fmt.Errorf("actor_id must be a number")
+		}
+		filter.ActorID = &id
+	}
+	for name, dest := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := c.Query(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be RFC3339", name)
+			}
+			*dest = t
+		}
+	}
+	for name, dest := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return filter, fmt.Errorf("%s must be a non-negative number", name)
+			}
+			*dest = n
+		}
+	}
+	return filter, nil
+}
+
+// Search the audit log, newest first. ?format=csv downloads the matching
+// entries, up to services.MaxAuditExport, as CSV.
+func (h *AuditHandler) GetAuditLog(c *gin.Context) {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		events, err := h.audit.ExportEvents(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export audit log"})
+			return
+		}
+		writeAuditCSV(c, events)
+		return
+	}
+
+	events, err := h.audit.ListEvents(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events, "count": len(events)})
+}
+
+func writeAuditCSV(c *gin.Context, events []models.AuditEvent) {
+	filename := fmt.Sprintf("audit-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(auditCSVHeader)
+	for _, e := range events {
+		actorID := ""
+		if e.ActorID != nil {
+			actorID = strconv.Itoa(*e.ActorID)
+		}
+		statusCode := ""
+		if e.StatusCode != 0 {
+			statusCode = strconv.Itoa(e.StatusCode)
+		}
+		w.Write([]string{
+			strconv.FormatInt(e.ID, 10), e.CreatedAt.UTC().Format(time.RFC3339Nano), e.Action, actorID,
+			e.ActorRole, csvText(e.IPAddress), e.Method, e.Route, statusCode, e.EntityType, csvText(e.EntityID),
+			csvJSON(e.Changes), csvJSON(e.Details), e.PrevHash, e.Hash,
+		})
+	}
+	w.Flush()
+}
+
+// csvText stops spreadsheets from reading a client-supplied cell as a
+// formula
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvJSON writes a JSON column into one CSV cell, empty when absent
+func csvJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return ""
+	}
+	return string(data)
+}
+
+// Re-compute the audit hash chain and report the first broken entry
+func (h *AuditHandler) VerifyAuditChain(c *gin.Context) {
+	report, err := h.audit.VerifyChain()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+type DrivingAlertHandler struct {
+	db   *sql.DB
+	repo *repository.DrivingAlertRepository
+}
+
+func NewDrivingAlertHandler(db *sql.DB, repo *repository.DrivingAlertRepository) *DrivingAlertHandler {
+	return &DrivingAlertHandler{db: db, repo: repo}
+}
+
+// alertScope returns the fleet owner filter for the caller: 0 for staff
+// who see every alert, otherwise their own fleet owner
+func (h *DrivingAlertHandler) alertScope(c *gin.Context) (int, bool) {
+	scope, ok := TenantScope(c, h.db)
+	if !ok {
+		return 0, false
+	}
+	return scope.FilterID(), true
+}
+
+// List driving alerts for the caller's vehicles
+func (h *DrivingAlertHandler) GetDrivingAlerts(c *gin.Context) {
+	fleetOwnerID, ok := h.alertScope(c)
+	if !ok {
+		return
+	}
+
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > 720 { // Max 30 days
+		hours = 24
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	vehicleID, _ := strconv.Atoi(c.Query("vehicle_id"))
+
+	alerts, err := h.repo.GetAlerts(repository.DrivingAlertFilter{
+		FleetOwnerID:       fleetOwnerID,
+		VehicleID:          vehicleID,
+		AlertType:          c.Query("type"),
+		UnacknowledgedOnly: c.Query("unacknowledged") == "true",
+		Since:              time.Now().UTC().Add(-time.Duration(hours) * time.Hour),
+		Limit:              limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get driving alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+func (h *DrivingAlertHandler) AcknowledgeDrivingAlert(c *gin.Context) {
+	fleetOwnerID, ok := h.alertScope(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userIDInt, _ := userID.(int)
+
+	err = h.repo.AcknowledgeAlert(id, userIDInt, fleetOwnerID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge alert"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert acknowledged"})
+}
+
+func (h *DrivingAlertHandler) GetSpeedLimits(c *gin.Context) {
+	limits, err := h.repo.GetSpeedLimits()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get speed limits"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"speed_limits": limits})
+}
+
+// Set the speed limit for a vehicle type; "default" covers unlisted types
+func (h *DrivingAlertHandler) SetSpeedLimit(c *gin.Context) {
+	var req models.VehicleSpeedLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := &models.VehicleSpeedLimit{VehicleType: req.VehicleType, MaxSpeedKmh: req.MaxSpeedKmh}
+	if err := h.repo.UpsertSpeedLimit(limit); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save speed limit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, limit)
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+type GeofenceHandler struct {
+	repo *repository.GeofenceRepository
+}
+
+func NewGeofenceHandler(repo *repository.GeofenceRepository) *GeofenceHandler {
+	return &GeofenceHandler{repo: repo}
+}
+
+// Create a named circle or polygon geofence
+func (h *GeofenceHandler) CreateGeofence(c *gin.Context) {
+	var req models.GeofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	geofence, err := services.BuildGeofence(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(int); ok {
+			geofence.CreatedBy = &id
+		}
+	}
+
+	if err := h.repo.CreateGeofence(geofence); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create geofence"})
+		return
+	}
+	services.InvalidateGeofenceCache()
+
+	c.JSON(http.StatusCreated, geofence)
+}
+
+// List all geofences, optionally only active ones
+func (h *GeofenceHandler) GetGeofences(c *gin.Context) {
+	geofences, err := h.repo.GetGeofences(c.Query("active") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get geofences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"geofences": geofences})
+}
+
+func (h *GeofenceHandler) GetGeofence(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid geofence ID"})
+		return
+	}
+
+	geofence, err := h.repo.GetGeofenceByID(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Geofence not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get geofence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, geofence)
+}
+
+func (h *GeofenceHandler) UpdateGeofence(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid geofence ID"})
+		return
+	}
+
+	var req models.GeofenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	geofence, err := services.BuildGeofence(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	geofence.ID = id
+
+	err = h.repo.UpdateGeofence(geofence)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Geofence not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update geofence"})
+		return
+	}
+	services.InvalidateGeofenceCache()
+
+	c.JSON(http.StatusOK, geofence)
+}
+
+func (h *GeofenceHandler) DeleteGeofence(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid geofence ID"})
+		return
+	}
+
+	err = h.repo.DeleteGeofence(id)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Geofence not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete geofence"})
+		return
+	}
+	services.InvalidateGeofenceCache()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Geofence deleted successfully"})
+}
+
+// Get recent geofence events, filtered by geofence and/or device
+func (h *GeofenceHandler) GetGeofenceEvents(c *gin.Context) {
+	geofenceID, _ := strconv.Atoi(c.Query("geofence_id"))
+
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 || hours > 168 { // Max 1 week
+		hours = 24
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	events, err := h.repo.GetEvents(geofenceID, c.Query("device_id"), since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get geofence events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
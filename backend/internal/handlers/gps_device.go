@@ -28,6 +28,7 @@ func (h *GPSDeviceHandler) AssignDevice(c *gin.Context) {
 	var req struct {
 		DeviceID  string `json:"device_id" binding:"required"`
 		VehicleID int    `json:"vehicle_id" binding:"required"`
+		IMEI      string `json:"imei" binding:"omitempty,numeric,len=15"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -35,7 +36,7 @@ func (h *GPSDeviceHandler) AssignDevice(c *gin.Context) {
 		return
 	}
 
-	err := h.repo.AssignToVehicle(req.DeviceID, req.VehicleID)
+	err := h.repo.AssignToVehicle(req.DeviceID, req.VehicleID, req.IMEI)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign device"})
 		return
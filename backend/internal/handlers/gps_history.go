@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+const (
+	defaultHistoryLimit = 1000
+	maxHistoryLimit     = 10000
+)
+
+type GPSHistoryHandler struct {
+	history *services.GPSHistoryService
+}
+
+func NewGPSHistoryHandler(history *services.GPSHistoryService) *GPSHistoryHandler {
+	return &GPSHistoryHandler{history: history}
+}
+
+// parseHistoryRange reads from/to (RFC3339) or the older hours parameter,
+// which counts back from now
+func parseHistoryRange(c *gin.Context, now time.Time) (time.Time, time.Time, error) {
+	to := now
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be RFC3339")
+		}
+		to = t
+	}
+
+	if v := c.Query("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must be RFC3339")
+		}
+		return from, to, nil
+	}
+
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours < 1 {
+		hours = 24
+	}
+	return to.Add(-time.Duration(hours) * time.Hour), to, nil
+}
+
+// Get tracking history for specific device. Short recent ranges return raw
+// points; longer or older ranges return one point per minute.
+func (h *GPSHistoryHandler) GetTrackingHistory(c *gin.Context, scope models.TenantScope) {
+	deviceID := c.Param("deviceId")
+
+	from, to, err := parseHistoryRange(c, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultHistoryLimit)))
+	if err != nil || limit < 1 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	history, resolution, err := h.history.GetHistory(scope, deviceID, from, to, c.DefaultQuery("resolution", services.HistoryResolutionAuto), limit)
+	if errors.Is(err, services.ErrInvalidHistoryRange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrDeviceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tracking history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history":    history,
+		"resolution": resolution,
+		"from":       from.UTC(),
+		"to":         to.UTC(),
+		"truncated":  len(history) == limit,
+	})
+}
+
+// Get route playback for a device: the route as an encoded polyline with
+// distance, driving and stopped time, stops and speeds
+func (h *GPSHistoryHandler) GetRoutePlayback(c *gin.Context, scope models.TenantScope) {
+	deviceID := c.Param("deviceId")
+
+	from, to, err := parseHistoryRange(c, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	minStop := services.DefaultMinStopDuration
+	if v := c.Query("min_stop_minutes"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil || minutes < 1 || minutes > 240 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_stop_minutes must be between 1 and 240"})
+			return
+		}
+		minStop = time.Duration(minutes) * time.Minute
+	}
+
+	playback, err := h.history.GetRoutePlayback(scope, deviceID, from, to, c.DefaultQuery("resolution", services.HistoryResolutionAuto), minStop)
+	if errors.Is(err, services.ErrInvalidHistoryRange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrDeviceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get route playback"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"playback": playback})
+}
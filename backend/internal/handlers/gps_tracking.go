@@ -1,107 +1,146 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/ingest"
+	"github.com/youruser/aplikasi-tms/backend/internal/middleware"
 	"github.com/youruser/aplikasi-tms/backend/internal/models"
 	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
 )
 
 type GPSTrackingHandler struct {
-	repo *repository.GPSTrackingRepository
+	repo   *repository.GPSTrackingRepository
+	writer *ingest.Writer
 }
 
-func NewGPSTrackingHandler(repo *repository.GPSTrackingRepository) *GPSTrackingHandler {
-	return &GPSTrackingHandler{repo: repo}
+// NewGPSTrackingHandler creates the handler. writer may be nil for
+// read-only use.
+func NewGPSTrackingHandler(repo *repository.GPSTrackingRepository, writer *ingest.Writer) *GPSTrackingHandler {
+	return &GPSTrackingHandler{repo: repo, writer: writer}
 }
 
-// Receive GPS data from devices
-func (h *GPSTrackingHandler) IngestGPSData(c *gin.Context) {
-	var req struct {
-		DeviceID  string  `json:"device_id" binding:"required"`
-		Latitude  float64 `json:"latitude" binding:"required"`
-		Longitude float64 `json:"longitude" binding:"required"`
-		Speed     float64 `json:"speed"`
-		Timestamp string  `json:"timestamp"`
+// TrackingProcessor runs stored points through the geofence and driving
+// rule engines and pushes the results to WebSocket clients
+type TrackingProcessor struct {
+	geofences *services.GeofenceService
+	alerts    *services.DrivingAlertService
+	devices   *repository.GPSDeviceRepository
+}
+
+func NewTrackingProcessor(geofences *services.GeofenceService, alerts *services.DrivingAlertService, devices *repository.GPSDeviceRepository) *TrackingProcessor {
+	return &TrackingProcessor{geofences: geofences, alerts: alerts, devices: devices}
+}
+
+// Process evaluates one stored point. Errors are logged so they never fail
+// ingest.
+func (p *TrackingProcessor) Process(data *models.GPSTrackingData) {
+	events, err := p.geofences.EvaluatePoint(data)
+	if err != nil {
+		log.Printf("Geofence evaluation error for device %s: %v", middleware.SanitizeForLog(data.DeviceID), err)
+	}
+	if len(events) > 0 {
+		// Events only reach the device's fleet owner and staff
+		fleetOwnerID, err := p.devices.GetDeviceFleetOwnerID(data.DeviceID)
+		if err != nil {
+			log.Printf("Fleet owner lookup error for device %s: %v", middleware.SanitizeForLog(data.DeviceID), err)
+		}
+		for _, event := range events {
+			BroadcastGeofenceEvent(event, fleetOwnerID)
+		}
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	alerts, err := p.alerts.EvaluatePoint(data)
+	if err != nil {
+		log.Printf("Driving rule evaluation error for device %s: %v", middleware.SanitizeForLog(data.DeviceID), err)
 	}
+	for _, alert := range alerts {
+		BroadcastDrivingAlert(alert)
+	}
+}
 
-	// Parse timestamp or use current time
-	var timestamp time.Time
-	if req.Timestamp != "" {
-		if t, err := time.Parse(time.RFC3339, req.Timestamp); err == nil {
-			timestamp = t
-		} else {
-			timestamp = time.Now()
-		}
-	} else {
-		timestamp = time.Now()
+// submit queues a point on the ingest writer
+func (h *GPSTrackingHandler) submit(point models.GPSTrackingData) error {
+	if h.writer == nil {
+		return ingest.ErrUnavailable
 	}
+	return h.writer.Submit(point)
+}
 
-	trackingData := &models.GPSTrackingData{
-		DeviceID:  req.DeviceID,
-		Latitude:  req.Latitude,
-		Longitude: req.Longitude,
-		Speed:     req.Speed,
-		Timestamp: timestamp,
+// parse validates a posted point against the writer's age limit
+func (h *GPSTrackingHandler) parse(req ingest.PointRequest, now time.Time) (models.GPSTrackingData, error) {
+	var maxAge time.Duration
+	if h.writer != nil {
+		maxAge = h.writer.MaxPointAge()
 	}
+	return ingest.ParsePoint(req, now, maxAge)
+}
 
-	err := h.repo.InsertTrackingData(trackingData)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tracking data"})
+// respondIngestRefused maps a refused submit to 429 when the buffer is just
+// full, or 503 when storage is failing or the server is shutting down
+func respondIngestRefused(c *gin.Context, err error, body gin.H) {
+	if errors.Is(err, ingest.ErrBufferFull) {
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusTooManyRequests, body)
 		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "GPS data received successfully"})
+	c.Header("Retry-After", "30")
+	c.JSON(http.StatusServiceUnavailable, body)
 }
 
-// Get latest positions of all active vehicles
-func (h *GPSTrackingHandler) GetLatestPositions(c *gin.Context) {
-	positions, err := h.repo.GetLatestPositions()
+// Receive GPS data from devices. The point is validated, queued and written
+// in the next batch.
+func (h *GPSTrackingHandler) IngestGPSData(c *gin.Context) {
+	var req ingest.PointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	point, err := h.parse(req, time.Now())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get positions"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"positions": positions})
+	if err := h.submit(point); err != nil {
+		respondIngestRefused(c, err, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "GPS data received successfully"})
 }
 
-// Get tracking history for specific device
-func (h *GPSTrackingHandler) GetTrackingHistory(c *gin.Context) {
-	deviceID := c.Param("deviceId")
-	hoursStr := c.DefaultQuery("hours", "24")
-	
-	hours, err := strconv.Atoi(hoursStr)
-	if err != nil || hours < 1 || hours > 168 { // Max 1 week
-		hours = 24
+// IngestStats reports the ingest writer's buffer and flush counters
+func (h *GPSTrackingHandler) IngestStats(c *gin.Context) {
+	if h.writer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": ingest.ErrUnavailable.Error()})
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{"stats": h.writer.Stats()})
+}
 
-	history, err := h.repo.GetTrackingHistory(deviceID, hours)
+// Get latest positions of the active vehicles visible in scope
+func (h *GPSTrackingHandler) GetLatestPositions(c *gin.Context, scope models.TenantScope) {
+	positions, err := h.repo.GetLatestPositions(scope.FilterID())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tracking history"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get positions"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"history": history})
+	c.JSON(http.StatusOK, gin.H{"positions": positions})
 }
 
-// Batch GPS data ingestion for multiple devices
+// Batch GPS data ingestion for multiple devices. Invalid points are
+// reported by index with a reason; the rest are queued.
 func (h *GPSTrackingHandler) BatchIngestGPSData(c *gin.Context) {
 	var req struct {
-		Data []struct {
-			DeviceID  string  `json:"device_id" binding:"required"`
-			Latitude  float64 `json:"latitude" binding:"required"`
-			Longitude float64 `json:"longitude" binding:"required"`
-			Speed     float64 `json:"speed"`
-			Timestamp string  `json:"timestamp"`
-		} `json:"data" binding:"required"`
+		Data []ingest.PointRequest `json:"data" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -109,35 +148,46 @@ func (h *GPSTrackingHandler) BatchIngestGPSData(c *gin.Context) {
 		return
 	}
 
-	successCount := 0
-	for _, item := range req.Data {
-		var timestamp time.Time
-		if item.Timestamp != "" {
-			if t, err := time.Parse(time.RFC3339, item.Timestamp); err == nil {
-				timestamp = t
-			} else {
-				timestamp = time.Now()
-			}
-		} else {
-			timestamp = time.Now()
+	type rejection struct {
+		Index    int    `json:"index"`
+		DeviceID string `json:"device_id"`
+		Reason   string `json:"reason"`
+	}
+
+	now := time.Now()
+	accepted := 0
+	rejected := []rejection{}
+	var refused error
+	for i, item := range req.Data {
+		if refused != nil {
+			rejected = append(rejected, rejection{Index: i, DeviceID: item.DeviceID, Reason: refused.Error()})
+			continue
 		}
 
-		trackingData := &models.GPSTrackingData{
-			DeviceID:  item.DeviceID,
-			Latitude:  item.Latitude,
-			Longitude: item.Longitude,
-			Speed:     item.Speed,
-			Timestamp: timestamp,
+		point, err := h.parse(item, now)
+		if err != nil {
+			rejected = append(rejected, rejection{Index: i, DeviceID: item.DeviceID, Reason: err.Error()})
+			continue
 		}
 
-		if err := h.repo.InsertTrackingData(trackingData); err == nil {
-			successCount++
+		if err := h.submit(point); err != nil {
+			refused = err
+			rejected = append(rejected, rejection{Index: i, DeviceID: item.DeviceID, Reason: err.Error()})
+			continue
 		}
+		accepted++
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Batch processing completed",
-		"total":   len(req.Data),
-		"success": successCount,
-	})
-}
\ No newline at end of file
+	body := gin.H{
+		"message":  "Batch processing completed",
+		"total":    len(req.Data),
+		"accepted": accepted,
+		"rejected": rejected,
+	}
+	if refused != nil {
+		respondIngestRefused(c, refused, body)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, body)
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+type SessionHandler struct {
+	sessions *services.SessionService
+}
+
+func NewSessionHandler(sessions *services.SessionService) *SessionHandler {
+	return &SessionHandler{sessions: sessions}
+}
+
+// Exchange a refresh token for a new access and refresh token
+func (h *SessionHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	pair, user, err := h.sessions.Refresh(req.RefreshToken, c.GetHeader("User-Agent"), c.ClientIP())
+	if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, NewLoginResponse(pair, user))
+}
+
+// End the caller's current session
+func (h *SessionHandler) Logout(c *gin.Context) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return
+	}
+
+	if err := h.sessions.Logout(claims); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// End every session of the caller, on all devices
+func (h *SessionHandler) LogoutAll(c *gin.Context) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return
+	}
+
+	n, err := h.sessions.RevokeUserSessions(claims.UserID, services.RevokeReasonLogoutAll)
+	if err == nil {
+		// Tokens issued outside a session are not covered by the sessions
+		err = h.sessions.Logout(claims)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions", "sessions_revoked": n})
+}
+
+// List a user's active sessions
+func (h *SessionHandler) GetUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	sessions, err := h.sessions.GetActiveSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// Kill every session of a user immediately
+func (h *SessionHandler) RevokeUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	n, err := h.sessions.RevokeUserSessions(userID, services.RevokeReasonAdmin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions_revoked": n})
+}
+
+// tokenClaims returns the claims AuthRequired stored for the request
+func tokenClaims(c *gin.Context) (*auth.Claims, bool) {
+	value, _ := c.Get("token_claims")
+	claims, ok := value.(*auth.Claims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return nil, false
+	}
+	return claims, true
+}
+
+// NewLoginResponse is the body returned by login, registration and refresh
+func NewLoginResponse(pair *models.TokenPair, user *models.User) models.LoginResponse {
+	return models.LoginResponse{
+		Token:            pair.AccessToken,
+		RefreshToken:     pair.RefreshToken,
+		ExpiresIn:        pair.ExpiresIn,
+		RefreshExpiresAt: &pair.RefreshExpiresAt,
+		User: models.UserResponse{
+			ID:            user.ID,
+			Username:      user.Username,
+			Email:         user.Email,
+			FullName:      user.FullName,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerifiedAt != nil,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+		},
+	}
+}
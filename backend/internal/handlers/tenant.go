@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+// TenantScope resolves which fleet owner's rows the caller may read. Staff
+// may pass ?fleet_owner_id= to look at one tenant. On failure it writes the
+// error response and returns false.
+func TenantScope(c *gin.Context, db *sql.DB) (models.TenantScope, bool) {
+	userID, ok := c.Get("user_id")
+	id, isInt := userID.(int)
+	if !ok || !isInt {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return models.TenantScope{}, false
+	}
+	role, _ := c.Get("user_role")
+	roleStr, _ := role.(string)
+
+	requested := 0
+	if v := c.Query("fleet_owner_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fleet_owner_id"})
+			return models.TenantScope{}, false
+		}
+		requested = n
+	}
+
+	scope, err := services.ResolveTenantScope(db, id, roleStr, requested)
+	if errors.Is(err, services.ErrNoFleetOwner) || errors.Is(err, services.ErrTenantAccessDenied) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return models.TenantScope{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve access"})
+		return models.TenantScope{}, false
+	}
+	return scope, true
+}
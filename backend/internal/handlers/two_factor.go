@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/middleware"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/ratelimit"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+type TwoFactorHandler struct {
+	db        *sql.DB
+	twoFactor *services.TwoFactorService
+	lockout   *ratelimit.Lockout
+}
+
+// NewTwoFactorHandler counts wrong codes against lockout, under a key of
+// their own so they do not share the password counter
+func NewTwoFactorHandler(db *sql.DB, twoFactor *services.TwoFactorService, lockout *ratelimit.Lockout) *TwoFactorHandler {
+	return &TwoFactorHandler{db: db, twoFactor: twoFactor, lockout: lockout}
+}
+
+// Show whether the caller has 2FA and how many recovery codes are left
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.twoFactor.Status(claims.UserID, claims.Role)
+	if err != nil {
+		h.respondError(c, err, "Failed to get two-factor status")
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// Start enrollment: returns the secret and the otpauth:// URI for the QR code
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return
+	}
+
+	setup, err := h.twoFactor.Setup(claims.UserID, claims.Username)
+	if err != nil {
+		h.respondError(c, err, "Failed to start two-factor setup")
+		return
+	}
+	c.JSON(http.StatusOK, setup)
+}
+
+// Confirm enrollment with a code. Users who logged in with an enrollment
+// token also get their full session here.
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	claims, req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactor.Enable(claims.UserID, req.Code)
+	if !h.checkCode(c, claims.UserID, err) {
+		return
+	}
+	if err != nil {
+		h.respondError(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	response := gin.H{"recovery_codes": codes}
+	if claims.Scope == auth.ScopeTwoFactorEnroll {
+		pair, user, err := h.twoFactor.FinishEnrollment(claims, c.GetHeader("User-Agent"), c.ClientIP())
+		if err != nil {
+			h.respondError(c, err, "Failed to start session")
+			return
+		}
+		response["session"] = NewLoginResponse(pair, user)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Second login step: exchange the two-factor token and a code for a session
+func (h *TwoFactorHandler) Verify(c *gin.Context) {
+	claims, req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	pair, user, err := h.twoFactor.CompleteLogin(claims, req.Code, c.GetHeader("User-Agent"), c.ClientIP())
+	if !h.checkCode(c, claims.UserID, err) {
+		return
+	}
+	if err != nil {
+		h.respondError(c, err, "Failed to verify code")
+		return
+	}
+	c.JSON(http.StatusOK, NewLoginResponse(pair, user))
+}
+
+// Turn 2FA off; refused for roles that require it
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	claims, req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	err := h.twoFactor.Disable(claims.UserID, claims.Role, req.Code)
+	if !h.checkCode(c, claims.UserID, err) {
+		return
+	}
+	if err != nil {
+		h.respondError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// Replace the recovery codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	claims, req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(claims.UserID, req.Code)
+	if !h.checkCode(c, claims.UserID, err) {
+		return
+	}
+	if err != nil {
+		h.respondError(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// Remove a user's 2FA so they can enroll again
+func (h *TwoFactorHandler) ResetUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.twoFactor.Reset(userID); err != nil {
+		h.respondError(c, err, "Failed to reset two-factor authentication")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+func (h *TwoFactorHandler) bindCode(c *gin.Context) (*auth.Claims, models.TwoFactorCodeRequest, bool) {
+	var req models.TwoFactorCodeRequest
+	claims, ok := tokenClaims(c)
+	if !ok {
+		return nil, req, false
+	}
+	if locked, retryAfter := h.lockout.Locked(h.lockoutKey(claims.UserID), time.Now()); locked {
+		middleware.TooManyRequests(c, retryAfter, "Too many wrong codes, try again later")
+		return nil, req, false
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return nil, req, false
+	}
+	return claims, req, true
+}
+
+// checkCode counts a wrong code towards the lockout and answers it. It
+// returns false once the response has been written.
+func (h *TwoFactorHandler) checkCode(c *gin.Context, userID int, err error) bool {
+	key := h.lockoutKey(userID)
+	if !errors.Is(err, services.ErrInvalidTwoFactorCode) {
+		if err == nil {
+			h.lockout.Succeed(key)
+		}
+		return true
+	}
+
+	if lock, lockouts := h.lockout.Fail(key, time.Now()); lock > 0 {
+		err := services.RecordAuditEvent(h.db, models.AuditEvent{
+			Action:    models.AuditTwoFactorLocked,
+			ActorID:   &userID,
+			IPAddress: c.ClientIP(),
+			Details:   map[string]interface{}{"lockouts": lockouts, "locked_seconds": int(lock.Seconds())},
+		})
+		if err != nil {
+			log.Printf("Audit error: %v", err)
+		}
+		middleware.TooManyRequests(c, lock, "Too many wrong codes, try again later")
+		return false
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": services.ErrInvalidTwoFactorCode.Error()})
+	return false
+}
+
+func (h *TwoFactorHandler) lockoutKey(userID int) string {
+	return "2fa:" + strconv.Itoa(userID)
+}
+
+func (h *TwoFactorHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrTwoFactorEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTwoFactorNotEnabled), errors.Is(err, services.ErrTwoFactorNotStarted):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTwoFactorMandatory):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Two-factor error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+type UserRoleHandler struct {
+	db       *sql.DB
+	sessions *services.SessionService
+}
+
+func NewUserRoleHandler(db *sql.DB, sessions *services.SessionService) *UserRoleHandler {
+	return &UserRoleHandler{db: db, sessions: sessions}
+}
+
+// List every role with the permissions it grants
+func (h *UserRoleHandler) GetRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"roles": services.GetRoles()})
+}
+
+// List users and their roles, optionally filtered by ?role=
+func (h *UserRoleHandler) GetUsers(c *gin.Context) {
+	users, err := services.GetUsersByRole(h.db, c.Query("role"))
+	if errors.Is(err, services.ErrInvalidRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+// Assign a role to a user
+func (h *UserRoleHandler) AssignRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.RoleAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	actorID, _ := c.Get("user_id")
+	actorIDInt, _ := actorID.(int)
+
+	user, err := services.AssignUserRole(h.db, actorIDInt, userID, req.Role)
+	switch {
+	case errors.Is(err, services.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrLastSuperAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign role"})
+	default:
+		// Tokens carry the role, so end the user's sessions to apply the new
+		// one right away
+		if _, err := h.sessions.RevokeUserSessions(userID, services.RevokeReasonRole); err != nil {
+			log.Printf("Failed to revoke sessions after role change - UserID: %d: %v", userID, err)
+		}
+		c.JSON(http.StatusOK, gin.H{"user": user})
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+type ValidationRulesHandler struct {
+	rules *services.ValidationRulesService
+}
+
+func NewValidationRulesHandler(rules *services.ValidationRulesService) *ValidationRulesHandler {
+	return &ValidationRulesHandler{rules: rules}
+}
+
+// bindValidationRules reads and checks rules from the request body
+func bindValidationRules(c *gin.Context) (*models.ValidationRules, bool) {
+	var rules models.ValidationRules
+	if err := c.ShouldBindJSON(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err := services.CheckValidationRules(&rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	rules.Version, rules.CreatedBy, rules.CreatedAt = 0, nil, nil
+	return &rules, true
+}
+
+// Get the validation rules in force
+func (h *ValidationRulesHandler) GetRules(c *gin.Context) {
+	rules, err := h.rules.Active()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get validation rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// Replace the validation rules; they are saved as a new version
+func (h *ValidationRulesHandler) UpdateRules(c *gin.Context) {
+	rules, ok := bindValidationRules(c)
+	if !ok {
+		return
+	}
+
+	if err := h.rules.Save(rules, c.GetInt("user_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save validation rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// Show how proposed rules would have judged the last ?limit submissions
+// (default 50, at most 500) compared with the rules in force
+func (h *ValidationRulesHandler) DryRun(c *gin.Context) {
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		limit = n
+	}
+	rules, ok := bindValidationRules(c)
+	if !ok {
+		return
+	}
+
+	dryRun, err := h.rules.DryRun(rules, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run validation rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dryRun)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/services"
+)
+
+type VerificationQueueHandler struct {
+	queue *services.VerificationQueueService
+}
+
+func NewVerificationQueueHandler(queue *services.VerificationQueueService) *VerificationQueueHandler {
+	return &VerificationQueueHandler{queue: queue}
+}
+
+// respondQueueError maps a queue failure to a response
+func respondQueueError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrQueueEmpty):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrQueueUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrQueueNotHeld):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update verification queue"})
+	}
+}
+
+// List the submissions waiting for a verifier, closest deadline first;
+// ?mine=true lists only those the caller holds
+func (h *VerificationQueueHandler) List(c *gin.Context) {
+	verifierID := 0
+	if c.Query("mine") == "true" {
+		verifierID = c.GetInt("user_id")
+	}
+
+	items, err := h.queue.Items(verifierID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get verification queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// Claim the unassigned submission whose SLA ends first
+func (h *VerificationQueueHandler) ClaimNext(c *gin.Context) {
+	item, err := h.queue.ClaimNext(c.GetInt("user_id"))
+	if err != nil {
+		respondQueueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"item": item})
+}
+
+// Claim a submission, or renew the caller's claim on it
+func (h *VerificationQueueHandler) Claim(c *gin.Context) {
+	vehicleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+		return
+	}
+
+	item, err := h.queue.Claim(vehicleID, c.GetInt("user_id"))
+	if err != nil {
+		respondQueueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"item": item})
+}
+
+// Release a claim. Supervisors may release anyone's.
+func (h *VerificationQueueHandler) Release(c *gin.Context) {
+	vehicleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+		return
+	}
+
+	verifierID := c.GetInt("user_id")
+	if auth.HasPermission(c.GetString("user_role"), auth.PermVerificationQueueManage) {
+		verifierID = 0
+	}
+	if err := h.queue.Release(vehicleID, verifierID); err != nil {
+		respondQueueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Submission released"})
+}
+
+// Hand every unassigned submission to the verifiers in turn now, rather
+// than at the next scheduled round
+func (h *VerificationQueueHandler) Assign(c *gin.Context) {
+	assigned, err := h.queue.AssignRoundRobin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign submissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"assigned": assigned})
+}
+
+// Show each verifier's workload and what they decided over the last
+// ?days days (default 7, at most 90)
+func (h *VerificationQueueHandler) Workload(c *gin.Context) {
+	days := 0
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a positive number"})
+			return
+		}
+		days = n
+	}
+
+	workload, err := h.queue.Workload(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get verifier workload"})
+		return
+	}
+
+	c.JSON(http.StatusOK, workload)
+}
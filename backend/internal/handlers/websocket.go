@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
 	"github.com/youruser/aplikasi-tms/backend/internal/repository"
 )
 
@@ -18,18 +20,32 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// Hub fans messages out to connected clients. Each client only receives
+// messages its tenant scope allows.
 type Hub struct {
-	clients    map[*websocket.Conn]bool
-	broadcast  chan []byte
-	register   chan *websocket.Conn
+	clients    map[*websocket.Conn]models.TenantScope
+	broadcast  chan hubMessage
+	register   chan hubClient
 	unregister chan *websocket.Conn
 	mutex      sync.RWMutex
 }
 
+type hubClient struct {
+	conn  *websocket.Conn
+	scope models.TenantScope
+}
+
+// hubMessage is a payload and the fleet owner it belongs to; nil means
+// the message is for staff only
+type hubMessage struct {
+	data         []byte
+	fleetOwnerID *int
+}
+
 var trackingHub = &Hub{
-	clients:    make(map[*websocket.Conn]bool),
-	broadcast:  make(chan []byte),
-	register:   make(chan *websocket.Conn),
+	clients:    make(map[*websocket.Conn]models.TenantScope),
+	broadcast:  make(chan hubMessage),
+	register:   make(chan hubClient),
 	unregister: make(chan *websocket.Conn),
 }
 
@@ -40,9 +56,9 @@ func init() {
 func (h *Hub) run() {
 	for {
 		select {
-		case conn := <-h.register:
+		case client := <-h.register:
 			h.mutex.Lock()
-			h.clients[conn] = true
+			h.clients[client.conn] = client.scope
 			h.mutex.Unlock()
 			log.Printf("WebSocket client connected. Total: %d", len(h.clients))
 
@@ -56,26 +72,36 @@ func (h *Hub) run() {
 			log.Printf("WebSocket client disconnected. Total: %d", len(h.clients))
 
 		case message := <-h.broadcast:
-			h.mutex.RLock()
-			for conn := range h.clients {
-				if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			h.mutex.Lock()
+			for conn, scope := range h.clients {
+				if !scope.Allows(message.fleetOwnerID) {
+					continue
+				}
+				if err := conn.WriteMessage(websocket.TextMessage, message.data); err != nil {
 					delete(h.clients, conn)
 					conn.Close()
 				}
 			}
-			h.mutex.RUnlock()
+			h.mutex.Unlock()
 		}
 	}
 }
 
-func HandleWebSocket(c *gin.Context) {
+// HandleWebSocket upgrades an authenticated request. The caller's tenant
+// scope is resolved before the upgrade so refusals are plain HTTP errors.
+func HandleWebSocket(c *gin.Context, db *sql.DB) {
+	scope, ok := TenantScope(c, db)
+	if !ok {
+		return
+	}
+
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
 
-	trackingHub.register <- conn
+	trackingHub.register <- hubClient{conn: conn, scope: scope}
 
 	// Keep connection alive
 	go func() {
@@ -92,8 +118,8 @@ func HandleWebSocket(c *gin.Context) {
 	}()
 }
 
-// Broadcast GPS updates to all connected clients
-func BroadcastGPSUpdate(deviceID string, latitude, longitude, speed float64) {
+// Broadcast GPS updates to the device's fleet owner and staff
+func BroadcastGPSUpdate(deviceID string, fleetOwnerID *int, latitude, longitude, speed float64) {
 	update := map[string]interface{}{
 		"type":      "gps_update",
 		"device_id": deviceID,
@@ -109,18 +135,19 @@ func BroadcastGPSUpdate(deviceID string, latitude, longitude, speed float64) {
 	}
 
 	select {
-	case trackingHub.broadcast <- data:
+	case trackingHub.broadcast <- hubMessage{data: data, fleetOwnerID: fleetOwnerID}:
 	default:
 		// Channel is full, skip this update
 	}
 }
 
-// Start periodic position broadcast
+// Start periodic position broadcast. The snapshot covers every fleet, so
+// it only goes to staff.
 func StartPositionBroadcast(repo *repository.GPSTrackingRepository) {
 	ticker := time.NewTicker(10 * time.Second) // Broadcast every 10 seconds
 	go func() {
 		for range ticker.C {
-			positions, err := repo.GetLatestPositions()
+			positions, err := repo.GetLatestPositions(0)
 			if err != nil {
 				continue
 			}
@@ -137,10 +164,50 @@ func StartPositionBroadcast(repo *repository.GPSTrackingRepository) {
 			}
 
 			select {
-			case trackingHub.broadcast <- data:
+			case trackingHub.broadcast <- hubMessage{data: data}:
 			default:
 				// Channel is full, skip this update
 			}
 		}
 	}()
-}
\ No newline at end of file
+}
+
+// BroadcastGeofenceEvent pushes a geofence enter/exit/dwell event to the
+// vehicle's fleet owner and staff
+func BroadcastGeofenceEvent(event models.GeofenceEvent, fleetOwnerID *int) {
+	message := map[string]interface{}{
+		"type":  "geofence_event",
+		"event": event,
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+
+	select {
+	case trackingHub.broadcast <- hubMessage{data: data, fleetOwnerID: fleetOwnerID}:
+	default:
+		// Channel is full, skip this update
+	}
+}
+
+// BroadcastDrivingAlert pushes a speeding/harsh-driving/idle alert to the
+// vehicle's fleet owner and staff
+func BroadcastDrivingAlert(alert models.DrivingAlert) {
+	message := map[string]interface{}{
+		"type":  "driving_alert",
+		"alert": alert,
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+
+	select {
+	case trackingHub.broadcast <- hubMessage{data: data, fleetOwnerID: alert.FleetOwnerID}:
+	default:
+		// Channel is full, skip this update
+	}
+}
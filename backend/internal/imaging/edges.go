@@ -0,0 +1,174 @@
+package imaging
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// maxSkew is the widest rotation searched, in degrees
+	maxSkew = 15.0
+	// maxEdgePoints bounds the work of the skew search
+	maxEdgePoints = 40000
+	// edgeLineShare is how much of the frame an unbroken run of edge pixels
+	// must span to count as a document edge
+	edgeLineShare = 0.3
+)
+
+type point struct {
+	x, y float64
+}
+
+// edgePoints returns the pixels whose gradient stands out from the image
+func (g *Gray) edgePoints() []point {
+	mag := g.sobel()
+	mean, std := meanStd(mag)
+	threshold := math.Max(mean+2*std, 60)
+
+	var points []point
+	for i, m := range mag {
+		if m >= threshold {
+			points = append(points, point{x: float64(i % g.W), y: float64(i / g.W)})
+		}
+	}
+	return points
+}
+
+// thin keeps at most maxEdgePoints of points, evenly spread
+func thin(points []point) []point {
+	if len(points) <= maxEdgePoints {
+		return points
+	}
+	thinned := make([]point, 0, maxEdgePoints)
+	step := float64(len(points)) / maxEdgePoints
+	for i := 0.0; int(i) < len(points) && len(thinned) < maxEdgePoints; i += step {
+		thinned = append(thinned, points[int(i)])
+	}
+	return thinned
+}
+
+// profile counts points by their distance across lines at angle degrees,
+// in one pixel bins. offset is the index of distance zero.
+func profile(points []point, angle float64, size int) ([]int, int) {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	offset := size / 2
+	bins := make([]int, size)
+	for _, p := range points {
+		v := int(math.Round(p.y*cos-p.x*sin)) + offset
+		if v >= 0 && v < size {
+			bins[v]++
+		}
+	}
+	return bins, offset
+}
+
+func sharpness(bins []int) float64 {
+	var s float64
+	for _, n := range bins {
+		s += float64(n) * float64(n)
+	}
+	return s
+}
+
+// estimateSkew returns the angle in degrees of the dominant lines (text
+// rows and document edges): the angle at which projecting the edge points
+// across those lines gives the sharpest profile. Positive means the lines
+// fall to the right.
+func estimateSkew(points []point, w, h int) float64 {
+	if len(points) == 0 {
+		return 0
+	}
+	points = thin(points)
+	size := 2*(w+h) + 1
+	best, bestScore := 0.0, -1.0
+	search := func(from, to, step float64) {
+		for a := from; a <= to+1e-9; a += step {
+			bins, _ := profile(points, a, size)
+			if s := sharpness(bins); s > bestScore {
+				best, bestScore = a, s
+			}
+		}
+	}
+	search(-maxSkew, maxSkew, 0.5)
+	search(best-0.4, best+0.4, 0.1)
+	return math.Round(best*10) / 10
+}
+
+// bounds are the document edges found in the frame
+type bounds struct {
+	// sides is how many of the four edges were found
+	sides int
+	// width and height are the distances between opposite edges, when
+	// both were found
+	width, height int
+}
+
+// findBounds looks for the outermost straight edges running along (top,
+// bottom) and across (left, right) the skew angle
+func findBounds(points []point, w, h int, angle float64) bounds {
+	top, bottom, rows := outerLines(points, angle, w, h, edgeLineShare*float64(w))
+	left, right, cols := outerLines(points, angle+90, w, h, edgeLineShare*float64(h))
+	b := bounds{sides: rows + cols}
+	if rows == 2 {
+		b.height = bottom - top
+	}
+	if cols == 2 {
+		b.width = right - left
+	}
+	return b
+}
+
+// outerLines returns the distances of the first and last lines at angle
+// degrees that run unbroken for at least length pixels, and how many of
+// the two were found. Text also makes long rows of edge pixels, but with
+// gaps between letters and words; a document edge has none.
+func outerLines(points []point, angle float64, w, h int, length float64) (int, int, int) {
+	size := 2*(w+h) + 1
+	bins, offset := profile(points, angle, size)
+
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	along := map[int][]float64{}
+	for _, p := range points {
+		v := int(math.Round(p.y*cos-p.x*sin)) + offset
+		if v >= 1 && v < size-1 && float64(bins[v-1]+bins[v]+bins[v+1]) >= length {
+			along[v] = append(along[v], p.x*cos+p.y*sin)
+		}
+	}
+
+	first, last := -1, -1
+	for i := 1; i < size-1; i++ {
+		var run []float64
+		for j := i - 1; j <= i+1; j++ {
+			run = append(run, along[j]...)
+		}
+		if float64(len(run)) < length || longestRun(run) < length {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+	switch {
+	case first < 0:
+		return 0, 0, 0
+	case last-first <= 2:
+		// A single edge shows up in neighbouring bins; count it once
+		return first, first, 1
+	}
+	return first, last, 2
+}
+
+// longestRun returns the longest stretch of positions with no gap wider
+// than two pixels
+func longestRun(positions []float64) float64 {
+	sort.Float64s(positions)
+	best, start := 0.0, positions[0]
+	for i := 1; i < len(positions); i++ {
+		if positions[i]-positions[i-1] > 2 {
+			start = positions[i]
+		}
+		best = math.Max(best, positions[i]-start)
+	}
+	return best
+}
@@ -0,0 +1,94 @@
+// Package imaging analyses document photos in pure Go: sharpness,
+// exposure, glare, framing and skew.
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// Gray is a luminance image with values from 0 to 255
+type Gray struct {
+	W, H int
+	Pix  []float64
+}
+
+func (g *Gray) at(x, y int) float64 {
+	return g.Pix[y*g.W+x]
+}
+
+// NewGray converts img to luminance, averaging blocks of pixels so the
+// longest side is at most maxSide. Analysis runs on this smaller copy;
+// the thresholds assume it.
+func NewGray(img image.Image, maxSide int) *Gray {
+	b := img.Bounds()
+	step := 1
+	if longest := max(b.Dx(), b.Dy()); maxSide > 0 && longest > maxSide {
+		step = int(math.Ceil(float64(longest) / float64(maxSide)))
+	}
+
+	g := &Gray{W: b.Dx() / step, H: b.Dy() / step}
+	if g.W == 0 || g.H == 0 {
+		return &Gray{}
+	}
+	g.Pix = make([]float64, g.W*g.H)
+	for y := 0; y < g.H; y++ {
+		for x := 0; x < g.W; x++ {
+			var sum float64
+			for dy := 0; dy < step; dy++ {
+				for dx := 0; dx < step; dx++ {
+					r, gr, bl, _ := img.At(b.Min.X+x*step+dx, b.Min.Y+y*step+dy).RGBA()
+					// Rec. 601 luma on 16-bit channels
+					sum += (0.299*float64(r) + 0.587*float64(gr) + 0.114*float64(bl)) / 257
+				}
+			}
+			g.Pix[y*g.W+x] = sum / float64(step*step)
+		}
+	}
+	return g
+}
+
+// meanStd returns the mean and standard deviation of values
+func meanStd(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum, sq float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)))
+}
+
+// laplacian is the 4-neighbour Laplacian of the interior pixels. Its
+// variance is low when an image is out of focus.
+func (g *Gray) laplacian() []float64 {
+	if g.W < 3 || g.H < 3 {
+		return nil
+	}
+	out := make([]float64, 0, (g.W-2)*(g.H-2))
+	for y := 1; y < g.H-1; y++ {
+		for x := 1; x < g.W-1; x++ {
+			out = append(out, g.at(x-1, y)+g.at(x+1, y)+g.at(x, y-1)+g.at(x, y+1)-4*g.at(x, y))
+		}
+	}
+	return out
+}
+
+// sobel returns the gradient magnitude of every pixel; the one pixel
+// border is left at zero
+func (g *Gray) sobel() []float64 {
+	mag := make([]float64, g.W*g.H)
+	for y := 1; y < g.H-1; y++ {
+		for x := 1; x < g.W-1; x++ {
+			gx := g.at(x+1, y-1) + 2*g.at(x+1, y) + g.at(x+1, y+1) - g.at(x-1, y-1) - 2*g.at(x-1, y) - g.at(x-1, y+1)
+			gy := g.at(x-1, y+1) + 2*g.at(x, y+1) + g.at(x+1, y+1) - g.at(x-1, y-1) - 2*g.at(x, y-1) - g.at(x+1, y-1)
+			mag[y*g.W+x] = math.Hypot(gx, gy)
+		}
+	}
+	return mag
+}
@@ -0,0 +1,252 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// document renders a w by h photo of a light card with dark text rows on a
+// darker desk, rotated by angle degrees. The card covers cardShare of each
+// side.
+func document(w, h int, angle, cardShare float64) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	sin, cos := math.Sincos(-angle * math.Pi / 180)
+	cx, cy := float64(w)/2, float64(h)/2
+	halfW, halfH := cardShare*float64(w)/2, cardShare*float64(h)/2
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// Map back into the unrotated card
+			dx, dy := float64(x)-cx, float64(y)-cy
+			u, v := dx*cos-dy*sin, dx*sin+dy*cos
+
+			level := uint8(70)
+			if math.Abs(u) < halfW && math.Abs(v) < halfH {
+				level = 225
+				// Text rows inset from the card edges
+				row := math.Mod(v+halfH, 40)
+				if math.Abs(u) < halfW*0.8 && math.Abs(v) < halfH*0.8 && row > 16 && row < 28 && math.Mod(u+halfW, 14) < 9 {
+					level = 30
+				}
+			}
+			img.SetGray(x, y, color.Gray{Y: level})
+		}
+	}
+	return img
+}
+
+// boxBlur averages every pixel with its neighbours within radius
+func boxBlur(src *image.Gray, radius int) *image.Gray {
+	b := src.Bounds()
+	dst := image.NewGray(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			sum, n := 0, 0
+			for dy := -radius; dy <= radius; dy++ {
+				for dx := -radius; dx <= radius; dx++ {
+					p := image.Pt(x+dx, y+dy)
+					if p.In(b) {
+						sum += int(src.GrayAt(p.X, p.Y).Y)
+						n++
+					}
+				}
+			}
+			dst.SetGray(x, y, color.Gray{Y: uint8(sum / n)})
+		}
+	}
+	return dst
+}
+
+// mapLevels rewrites every pixel through f
+func mapLevels(src *image.Gray, f func(x, y int, level uint8) uint8) *image.Gray {
+	b := src.Bounds()
+	dst := image.NewGray(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.SetGray(x, y, color.Gray{Y: f(x, y, src.GrayAt(x, y).Y)})
+		}
+	}
+	return dst
+}
+
+var cardMin = MinResolution{Long: 1000, Short: 600}
+
+func TestAnalyzeGoodPhoto(t *testing.T) {
+	r := Analyze(document(1200, 760, 0, 0.85), cardMin)
+
+	for name, c := range r.Checks {
+		if c.Status == StatusPoor || c.Status == StatusFair {
+			t.Errorf("%s: expected a clean photo to pass, got %+v", name, c)
+		}
+	}
+	if len(r.Checks) != 7 {
+		t.Errorf("Expected every check, got %v", r.Checks)
+	}
+	if sides := r.Checks[CheckBounds].Extra["sides_found"]; sides != 4 {
+		t.Errorf("Expected all four card edges, got %v", sides)
+	}
+	if s := r.Score(); s < 0.85 {
+		t.Errorf("Expected a high overall score, got %v", s)
+	}
+}
+
+func TestAnalyzeBlur(t *testing.T) {
+	sharp := document(1200, 760, 0, 0.85)
+	r := Analyze(boxBlur(sharp, 6), cardMin)
+
+	blur := r.Checks[CheckBlur]
+	if blur.Status != StatusPoor {
+		t.Errorf("Expected a blurred photo to fail, got %+v", blur)
+	}
+	if blur.Value >= Analyze(sharp, cardMin).Checks[CheckBlur].Value/4 {
+		t.Errorf("Expected blurring to cut the Laplacian variance, got %v", blur.Value)
+	}
+}
+
+func TestAnalyzeExposure(t *testing.T) {
+	doc := document(1200, 760, 0, 0.85)
+
+	dark := Analyze(mapLevels(doc, func(_, _ int, l uint8) uint8 { return l / 5 }), cardMin)
+	if c := dark.Checks[CheckBrightness]; c.Status != StatusPoor || c.Extra["problem"] != "too_dark" {
+		t.Errorf("Expected an underexposed photo to be too dark, got %+v", c)
+	}
+	if c := dark.Checks[CheckContrast]; c.Status != StatusPoor {
+		t.Errorf("Expected an underexposed photo to lack contrast, got %+v", c)
+	}
+
+	washed := Analyze(mapLevels(doc, func(_, _ int, l uint8) uint8 { return 200 + l/8 }), cardMin)
+	if c := washed.Checks[CheckBrightness]; c.Extra["problem"] != "too_bright" {
+		t.Errorf("Expected a washed out photo to be too bright, got %+v", c)
+	}
+}
+
+func TestAnalyzeGlare(t *testing.T) {
+	doc := document(1200, 760, 0, 0.85)
+	// A flash reflection on the card
+	spot := mapLevels(doc, func(x, y int, l uint8) uint8 {
+		if math.Hypot(float64(x-700), float64(y-350)) < 90 {
+			return 255
+		}
+		return l
+	})
+
+	c := Analyze(spot, cardMin).Checks[CheckGlare]
+	if c.Status != StatusPoor || c.Extra["hot_spots"].(int) == 0 {
+		t.Errorf("Expected the reflection to be found, got %+v", c)
+	}
+}
+
+func TestAnalyzeSkew(t *testing.T) {
+	for _, angle := range []float64{-8, 4.5, 12} {
+		r := Analyze(document(1200, 900, angle, 0.7), cardMin)
+		c := r.Checks[CheckSkew]
+		if math.Abs(c.Value-angle) > 0.5 {
+			t.Errorf("Expected skew near %v, got %v", angle, c.Value)
+		}
+		if angle == 12 && c.Status != StatusPoor {
+			t.Errorf("Expected a 12 degree tilt to fail, got %+v", c)
+		}
+		if angle == 4.5 && c.Status == StatusPoor {
+			t.Errorf("Expected a slight tilt to pass, got %+v", c)
+		}
+	}
+}
+
+func TestAnalyzeFraming(t *testing.T) {
+	far := Analyze(document(1200, 760, 0, 0.45), cardMin).Checks[CheckBounds]
+	if far.Status != StatusPoor || far.Extra["sides_found"] != 4 {
+		t.Errorf("Expected a distant card to be too small, got %+v", far)
+	}
+
+	// Card running off every edge of the frame: only text, no borders
+	cut := Analyze(document(1200, 1000, 0, 1.3), cardMin).Checks[CheckBounds]
+	if cut.Status != StatusPoor {
+		t.Errorf("Expected a cut off card to fail, got %+v", cut)
+	}
+
+	// A scan cropped to the card has no borders but a card's shape
+	cropped := Analyze(document(1284, 810, 0, 1.3), cardMin).Checks[CheckBounds]
+	if cropped.Extra["cropped"] != true || cropped.Status == StatusPoor {
+		t.Errorf("Expected a cropped scan to pass, got %+v", cropped)
+	}
+}
+
+func TestAnalyzeResolution(t *testing.T) {
+	r := Analyze(document(640, 400, 0, 0.85), cardMin)
+	c := r.Checks[CheckResolution]
+	if c.Status != StatusFair || c.Extra["width"] != 640 {
+		t.Errorf("Expected a 640x400 card photo to be fair, got %+v", c)
+	}
+}
+
+// selfie renders a w by h portrait: a lit background with sensor noise
+// and a face-sized ellipse, with level applied to every pixel
+func selfie(w, h int, level func(x, y int, v float64) float64) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	rng := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := 40 + 170*float64(x)/float64(w) + rng.NormFloat64()*6
+			dx, dy := float64(x-w/2)/(float64(w)*0.2), float64(y-h/2)/(float64(h)*0.3)
+			if dx*dx+dy*dy < 1 {
+				v = 150 + 40*dy + rng.NormFloat64()*6
+			}
+			if level != nil {
+				v = level(x, y, v)
+			}
+			img.SetGray(x, y, color.Gray{Y: uint8(math.Max(0, math.Min(255, v)))})
+		}
+	}
+	return img
+}
+
+func TestCheckLiveness(t *testing.T) {
+	face := image.Rect(240, 180, 560, 660)
+
+	live := CheckLiveness(selfie(800, 840, nil), face)
+	if !live.Passed || live.Score != 1 {
+		t.Errorf("Expected a plain selfie to pass, got %+v", live)
+	}
+
+	// Photo of a screen: the pixel grid beats with the camera's
+	screen := CheckLiveness(selfie(800, 840, func(x, _ int, v float64) float64 {
+		return v + 25*math.Sin(2*math.Pi*float64(x)/5)
+	}), face)
+	if !hasFlag(screen, FlagScreenMoire) {
+		t.Errorf("Expected moiré to be flagged, got %+v", screen)
+	}
+
+	// Printed photo: washed out
+	print := CheckLiveness(selfie(800, 840, func(_, _ int, v float64) float64 {
+		return 110 + v/4
+	}), face)
+	if !hasFlag(print, FlagLowDynamicRange) || print.Passed {
+		t.Errorf("Expected flat contrast to be flagged, got %+v", print)
+	}
+
+	// A phone held up in front of a wall
+	framed := CheckLiveness(selfie(800, 840, func(x, y int, v float64) float64 {
+		if x < 120 || x >= 680 || y < 90 || y >= 750 {
+			return 200
+		}
+		if x < 140 || x >= 660 || y < 110 || y >= 730 {
+			return 15
+		}
+		return v
+	}), face)
+	if !hasFlag(framed, FlagFrame) {
+		t.Errorf("Expected the phone's edges to be flagged, got %+v", framed)
+	}
+}
+
+func hasFlag(l *Liveness, flag string) bool {
+	for _, f := range l.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
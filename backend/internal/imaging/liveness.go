@@ -0,0 +1,153 @@
+package imaging
+
+import (
+	"image"
+	"sort"
+)
+
+// Liveness flags, each a sign that a selfie was taken of a screen or a
+// print rather than of a person
+const (
+	FlagScreenMoire     = "screen_moire"
+	FlagFrame           = "frame_around_subject"
+	FlagLowDynamicRange = "low_dynamic_range"
+	FlagGlare           = "glare"
+)
+
+const (
+	// livenessSide is the longest side the face region is analysed at;
+	// larger keeps the pixel grid of a screen visible
+	livenessSide = 768
+	// moireLevel is the autocorrelation of the fine detail at which it
+	// counts as a periodic pattern
+	moireLevel = 0.3
+	// minDynamicRange is the spread of luminance (2nd to 98th percentile)
+	// a face lit in a real room reaches
+	minDynamicRange = 90
+)
+
+// Liveness is the outcome of the liveness heuristics. They flag photos
+// for a verifier; they do not prove a person was there.
+type Liveness struct {
+	Score  float64            `json:"score"`
+	Passed bool               `json:"passed"`
+	Flags  []string           `json:"flags"`
+	Values map[string]float64 `json:"values"`
+}
+
+// cropped shows only part of an image
+type cropped struct {
+	image.Image
+	r image.Rectangle
+}
+
+func (c cropped) Bounds() image.Rectangle {
+	return c.r
+}
+
+// CheckLiveness looks for signs that a selfie is a picture of a picture.
+// face is the face in img; the region around it is checked for moiré,
+// flat contrast and glare, the whole frame for a border around it.
+func CheckLiveness(img image.Image, face image.Rectangle) *Liveness {
+	l := &Liveness{Flags: []string{}, Values: map[string]float64{}}
+
+	region := img.Bounds()
+	if !face.Empty() {
+		// The face with half its size around it
+		grown := face.Inset(-max(face.Dx(), face.Dy()) / 2).Intersect(region)
+		if !grown.Empty() {
+			region = grown
+		}
+	}
+	g := NewGray(cropped{img, region}, livenessSide)
+	if len(g.Pix) == 0 {
+		return l
+	}
+
+	moire := g.periodicity()
+	l.Values["moire"] = round(moire)
+	if moire >= moireLevel {
+		l.Flags = append(l.Flags, FlagScreenMoire)
+	}
+
+	spread := dynamicRange(g.Pix)
+	l.Values["dynamic_range"] = round(spread)
+	if spread < minDynamicRange {
+		l.Flags = append(l.Flags, FlagLowDynamicRange)
+	}
+
+	glare := glareCheck(g)
+	l.Values["glare"] = glare.Value
+	if glare.Status == StatusPoor {
+		l.Flags = append(l.Flags, FlagGlare)
+	}
+
+	// A phone or a print held up to the camera shows all four of its edges
+	whole := NewGray(img, analysisSide)
+	points := whole.edgePoints()
+	b := findBounds(points, whole.W, whole.H, estimateSkew(points, whole.W, whole.H))
+	l.Values["frame_sides"] = float64(b.sides)
+	if b.sides == 4 {
+		l.Flags = append(l.Flags, FlagFrame)
+	}
+
+	l.Score = round(clamp01(1 - 0.3*float64(len(l.Flags))))
+	l.Passed = len(l.Flags) == 0
+	return l
+}
+
+// periodicity is the strongest autocorrelation of the fine detail
+// (Laplacian) at a shift of 3 to 24 pixels, along rows or columns. In a
+// natural photo it is near 0; the pixel grid of a screen or the dots of
+// a print repeat and push it towards 1.
+func (g *Gray) periodicity() float64 {
+	lap := g.laplacian()
+	w, h := g.W-2, g.H-2
+	if len(lap) == 0 {
+		return 0
+	}
+	var energy float64
+	for _, v := range lap {
+		energy += v * v
+	}
+	if energy == 0 {
+		return 0
+	}
+
+	best := 0.0
+	for lag := 3; lag <= 24; lag++ {
+		var across, down float64
+		var nAcross, nDown int
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				v := lap[y*w+x]
+				if x+lag < w {
+					across += v * lap[y*w+x+lag]
+					nAcross++
+				}
+				if y+lag < h {
+					down += v * lap[(y+lag)*w+x]
+					nDown++
+				}
+			}
+		}
+		// Normalise by the share of pixels each sum covered
+		n := float64(len(lap))
+		if nAcross > 0 {
+			best = max(best, across/energy*n/float64(nAcross))
+		}
+		if nDown > 0 {
+			best = max(best, down/energy*n/float64(nDown))
+		}
+	}
+	return best
+}
+
+// dynamicRange is the spread between the 2nd and 98th percentile
+func dynamicRange(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	lo := sorted[len(sorted)*2/100]
+	hi := sorted[(len(sorted)-1)*98/100]
+	return hi - lo
+}
@@ -0,0 +1,247 @@
+package imaging
+
+import (
+	"image"
+	"math"
+)
+
+// Check names
+const (
+	CheckBlur       = "blur"
+	CheckBrightness = "brightness"
+	CheckContrast   = "contrast"
+	CheckGlare      = "glare"
+	CheckResolution = "resolution"
+	CheckBounds     = "document_bounds"
+	CheckSkew       = "skew"
+)
+
+// Check statuses, best first
+const (
+	StatusExcellent = "excellent"
+	StatusGood      = "good"
+	StatusFair      = "fair"
+	StatusPoor      = "poor"
+)
+
+const (
+	// analysisSide is the longest side of the copy the checks run on
+	analysisSide = 1024
+	// glareLevel is the luminance treated as blown out
+	glareLevel = 250
+	// glareGrid splits the frame into cells to find local hot spots
+	glareGrid = 12
+)
+
+// MinResolution is the smallest acceptable photo, long side by short side
+type MinResolution struct {
+	Long, Short int
+}
+
+// Check is the outcome of one measurement. Score runs from 0 to 1; Value
+// is the raw measurement in the unit the check uses.
+type Check struct {
+	Score  float64                `json:"score"`
+	Status string                 `json:"status"`
+	Value  float64                `json:"value"`
+	Extra  map[string]interface{} `json:"extra,omitempty"`
+}
+
+// Report holds every check of one photo
+type Report struct {
+	Width, Height int
+	Checks        map[string]Check
+}
+
+// checkWeights give each check's share of the overall score. Skew only
+// lowers the score through its own status.
+var checkWeights = map[string]float64{
+	CheckBlur:       0.3,
+	CheckBrightness: 0.15,
+	CheckContrast:   0.15,
+	CheckGlare:      0.15,
+	CheckResolution: 0.15,
+	CheckBounds:     0.05,
+	CheckSkew:       0.05,
+}
+
+// Score is the weighted mean of the check scores
+func (r *Report) Score() float64 {
+	var sum, weights float64
+	for name, w := range checkWeights {
+		if c, ok := r.Checks[name]; ok {
+			sum += w * c.Score
+			weights += w
+		}
+	}
+	if weights == 0 {
+		return 0
+	}
+	return round(sum / weights)
+}
+
+// Status grades the overall score
+func (r *Report) Status() string {
+	return statusOf(r.Score())
+}
+
+// Analyze measures a document photo
+func Analyze(img image.Image, minRes MinResolution) *Report {
+	b := img.Bounds()
+	r := &Report{Width: b.Dx(), Height: b.Dy(), Checks: map[string]Check{}}
+	r.Checks[CheckResolution] = resolutionCheck(r.Width, r.Height, minRes)
+
+	g := NewGray(img, analysisSide)
+	if len(g.Pix) == 0 {
+		return r
+	}
+	mean, std := meanStd(g.Pix)
+	r.Checks[CheckBrightness] = brightnessCheck(mean)
+	r.Checks[CheckContrast] = contrastCheck(std)
+	r.Checks[CheckGlare] = glareCheck(g)
+	r.Checks[CheckBlur] = blurCheck(g)
+
+	points := g.edgePoints()
+	skew := estimateSkew(points, g.W, g.H)
+	r.Checks[CheckSkew] = skewCheck(skew)
+	r.Checks[CheckBounds] = boundsCheck(findBounds(points, g.W, g.H, skew), g.W, g.H)
+	return r
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func statusOf(score float64) string {
+	switch {
+	case score >= 0.9:
+		return StatusExcellent
+	case score >= 0.7:
+		return StatusGood
+	case score >= 0.45:
+		return StatusFair
+	}
+	return StatusPoor
+}
+
+func newCheck(score, value float64, extra map[string]interface{}) Check {
+	score = round(clamp01(score))
+	return Check{Score: score, Status: statusOf(score), Value: round(value), Extra: extra}
+}
+
+// blurCheck scores the variance of the Laplacian: sharp text has strong
+// second derivatives, a blurred photo has almost none
+func blurCheck(g *Gray) Check {
+	_, std := meanStd(g.laplacian())
+	variance := std * std
+	return newCheck(variance/300, variance, nil)
+}
+
+// brightnessCheck wants the mean luminance in the middle of the range;
+// 80 to 190 scores fully
+func brightnessCheck(mean float64) Check {
+	score := 1.0
+	switch {
+	case mean < 80:
+		score = mean / 80
+	case mean > 190:
+		score = (255 - mean) / 65
+	}
+	extra := map[string]interface{}{}
+	if mean < 80 {
+		extra["problem"] = "too_dark"
+	} else if mean > 190 {
+		extra["problem"] = "too_bright"
+	}
+	return newCheck(score, mean, extra)
+}
+
+// contrastCheck scores the RMS contrast (luminance standard deviation)
+func contrastCheck(std float64) Check {
+	return newCheck(std/55, std, nil)
+}
+
+// glareCheck looks for blown-out pixels, overall and bunched in one cell,
+// where a flash or lamp reflects off a laminated card
+func glareCheck(g *Gray) Check {
+	cellW := max(1, g.W/glareGrid)
+	cellH := max(1, g.H/glareGrid)
+	cols := (g.W + cellW - 1) / cellW
+	rows := (g.H + cellH - 1) / cellH
+	cells := make([]int, cols*rows)
+	sizes := make([]int, cols*rows)
+
+	saturated := 0
+	for y := 0; y < g.H; y++ {
+		for x := 0; x < g.W; x++ {
+			cell := (y/cellH)*cols + x/cellW
+			sizes[cell]++
+			if g.at(x, y) >= glareLevel {
+				cells[cell]++
+				saturated++
+			}
+		}
+	}
+
+	hotSpots, worst := 0, 0.0
+	for i, n := range cells {
+		share := float64(n) / float64(sizes[i])
+		worst = math.Max(worst, share)
+		if share >= 0.5 {
+			hotSpots++
+		}
+	}
+	ratio := float64(saturated) / float64(len(g.Pix))
+
+	// 2% blown out overall, or one cell mostly blown out, is already poor
+	score := 1 - math.Max(ratio/0.04, worst*1.2)
+	return newCheck(score, ratio, map[string]interface{}{"hot_spots": hotSpots})
+}
+
+// resolutionCheck compares the photo with the smallest acceptable size
+func resolutionCheck(w, h int, minRes MinResolution) Check {
+	long, short := max(w, h), min(w, h)
+	score := math.Min(float64(long)/float64(minRes.Long), float64(short)/float64(minRes.Short))
+	return newCheck(score, float64(w*h)/1e6, map[string]interface{}{"width": w, "height": h})
+}
+
+// skewCheck allows a few degrees of tilt, which OCR copes with
+func skewCheck(angle float64) Check {
+	return newCheck(1-(math.Abs(angle)-3)/10, angle, nil)
+}
+
+// Aspect ratios of a card (ID-1) and an A-series page, used to recognise a
+// photo already cropped to the document
+var documentAspects = []float64{85.6 / 53.98, math.Sqrt2}
+
+// boundsCheck wants the whole document in the frame and filling most of
+// it. A frame with no edges at all is accepted when its shape matches a
+// document, as a scan or a cropped photo does.
+func boundsCheck(b bounds, w, h int) Check {
+	sides := b.sides
+	extra := map[string]interface{}{"sides_found": sides}
+
+	if sides == 0 {
+		aspect := float64(max(w, h)) / float64(min(w, h))
+		for _, a := range documentAspects {
+			if math.Abs(aspect-a)/a < 0.06 {
+				extra["cropped"] = true
+				return newCheck(0.9, 1, extra)
+			}
+		}
+		return newCheck(0.3, 0, extra)
+	}
+	if sides < 4 {
+		// Part of the document is outside the frame
+		return newCheck(0.2*float64(sides), 0, extra)
+	}
+
+	coverage := float64(b.width*b.height) / float64(w*h)
+	extra["coverage"] = round(coverage)
+	// A document filling under a quarter of the frame is too far away
+	return newCheck(coverage/0.5, coverage, extra)
+}
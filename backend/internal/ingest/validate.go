@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// maxClockSkew is how far in the future a device timestamp may be
+const maxClockSkew = 5 * time.Minute
+
+// PointRequest is one point as posted by a device
+type PointRequest struct {
+	DeviceID  string   `json:"device_id"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Speed     float64  `json:"speed"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// ParsePoint validates a posted point and converts it. A missing timestamp
+// means now; a malformed one is rejected rather than silently replaced, as
+// is one older than maxAge when maxAge is set.
+func ParsePoint(req PointRequest, now time.Time, maxAge time.Duration) (models.GPSTrackingData, error) {
+	deviceID := strings.TrimSpace(req.DeviceID)
+	if deviceID == "" {
+		return models.GPSTrackingData{}, fmt.Errorf("device_id is required")
+	}
+	if len(deviceID) > 50 {
+		return models.GPSTrackingData{}, fmt.Errorf("device_id is longer than 50 characters")
+	}
+	if req.Latitude == nil || req.Longitude == nil {
+		return models.GPSTrackingData{}, fmt.Errorf("latitude and longitude are required")
+	}
+	if *req.Latitude < -90 || *req.Latitude > 90 {
+		return models.GPSTrackingData{}, fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if *req.Longitude < -180 || *req.Longitude > 180 {
+		return models.GPSTrackingData{}, fmt.Errorf("longitude must be between -180 and 180")
+	}
+	if *req.Latitude == 0 && *req.Longitude == 0 {
+		return models.GPSTrackingData{}, fmt.Errorf("position 0,0 means the device has no fix")
+	}
+	if req.Speed < 0 || req.Speed > 300 {
+		return models.GPSTrackingData{}, fmt.Errorf("speed must be between 0 and 300 km/h")
+	}
+
+	timestamp := now
+	if req.Timestamp != "" {
+		t, err := time.Parse(time.RFC3339, req.Timestamp)
+		if err != nil {
+			return models.GPSTrackingData{}, fmt.Errorf("timestamp must be RFC3339")
+		}
+		if t.After(now.Add(maxClockSkew)) {
+			return models.GPSTrackingData{}, fmt.Errorf("timestamp is in the future")
+		}
+		if maxAge > 0 && t.Before(now.Add(-maxAge)) {
+			return models.GPSTrackingData{}, fmt.Errorf("timestamp is older than the raw GPS history kept")
+		}
+		timestamp = t
+	}
+
+	return models.GPSTrackingData{
+		DeviceID:  deviceID,
+		Latitude:  *req.Latitude,
+		Longitude: *req.Longitude,
+		Speed:     req.Speed,
+		Timestamp: timestamp,
+	}, nil
+}
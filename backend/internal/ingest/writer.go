@@ -0,0 +1,258 @@
+// Package ingest buffers incoming GPS points in memory and writes them to
+// the database in batches, so each point does not cost its own round trip.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+var (
+	// ErrBufferFull means the writer is healthy but behind; retry shortly
+	ErrBufferFull = errors.New("ingest buffer full")
+	// ErrUnavailable means the buffer is full because writes are failing
+	ErrUnavailable = errors.New("ingest storage unavailable")
+	// ErrClosed is returned after Close
+	ErrClosed = errors.New("ingest writer closed")
+	// ErrInvalidBatch is wrapped by a FlushFunc when storage refused the
+	// batch's data itself, so retrying it unchanged cannot succeed
+	ErrInvalidBatch = errors.New("ingest batch rejected by storage")
+)
+
+const (
+	minRetryDelay = 500 * time.Millisecond
+	maxRetryDelay = 30 * time.Second
+	// stored batches waiting for post-processing before flushing blocks
+	processQueueSize = 16
+)
+
+// FlushFunc writes one batch of points. Errors caused by the points
+// rather than by storage wrap ErrInvalidBatch.
+type FlushFunc func(points []models.GPSTrackingData) error
+
+// StoredFunc is called with each batch after it has been written, in order
+type StoredFunc func(points []models.GPSTrackingData)
+
+// Stats is a snapshot of the writer's counters
+type Stats struct {
+	Queued        int       `json:"queued"`
+	Capacity      int       `json:"capacity"`
+	Written       int64     `json:"written"`
+	Refused       int64     `json:"refused"`
+	Dropped       int64     `json:"dropped"`
+	FailedFlushes int64     `json:"failed_flushes"`
+	Degraded      bool      `json:"degraded"`
+	LastFlush     time.Time `json:"last_flush"`
+}
+
+// Writer accepts points without blocking and flushes them in batches when
+// BatchSize points are waiting or FlushInterval has passed. Failed flushes
+// are retried with backoff; while running, only points storage rejects as
+// invalid are dropped.
+type Writer struct {
+	cfg     config.IngestConfig
+	flush   FlushFunc
+	stored  StoredFunc
+	queue   chan models.GPSTrackingData
+	process chan []models.GPSTrackingData
+
+	mu        sync.RWMutex
+	closed    bool
+	abort     chan struct{}
+	abortOnce sync.Once
+	done      chan struct{}
+
+	degraded      atomic.Bool
+	written       atomic.Int64
+	refused       atomic.Int64
+	dropped       atomic.Int64
+	failedFlushes atomic.Int64
+	lastFlush     atomic.Int64
+}
+
+// NewWriter creates a writer and starts its background goroutines. stored
+// may be nil.
+func NewWriter(cfg config.IngestConfig, flush FlushFunc, stored StoredFunc) *Writer {
+	if cfg.BatchSize > cfg.BufferSize {
+		cfg.BatchSize = cfg.BufferSize
+	}
+
+	w := &Writer{
+		cfg:     cfg,
+		flush:   flush,
+		stored:  stored,
+		queue:   make(chan models.GPSTrackingData, cfg.BufferSize),
+		process: make(chan []models.GPSTrackingData, processQueueSize),
+		abort:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	processed := make(chan struct{})
+	go func() {
+		defer close(processed)
+		for batch := range w.process {
+			if w.stored != nil {
+				w.stored(batch)
+			}
+		}
+	}()
+	go func() {
+		defer close(w.done)
+		w.run()
+		close(w.process)
+		<-processed
+	}()
+
+	return w
+}
+
+// Submit queues a point without blocking
+func (w *Writer) Submit(point models.GPSTrackingData) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+
+	select {
+	case w.queue <- point:
+		return nil
+	default:
+		w.refused.Add(1)
+		if w.degraded.Load() {
+			return ErrUnavailable
+		}
+		return ErrBufferFull
+	}
+}
+
+// Close stops accepting points and waits for the buffer to be written. If
+// ctx expires first, unwritten points are dropped and an error returned.
+func (w *Writer) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		lost := len(w.queue)
+		w.abortOnce.Do(func() { close(w.abort) })
+		<-w.done
+		return fmt.Errorf("ingest writer closed with %d points unwritten: %v", lost, ctx.Err())
+	}
+}
+
+// MaxPointAge is how old a point may be to be accepted, 0 for any age
+func (w *Writer) MaxPointAge() time.Duration {
+	return w.cfg.MaxPointAge
+}
+
+func (w *Writer) Stats() Stats {
+	stats := Stats{
+		Queued:        len(w.queue),
+		Capacity:      cap(w.queue),
+		Written:       w.written.Load(),
+		Refused:       w.refused.Load(),
+		Dropped:       w.dropped.Load(),
+		FailedFlushes: w.failedFlushes.Load(),
+		Degraded:      w.degraded.Load(),
+	}
+	if ns := w.lastFlush.Load(); ns != 0 {
+		stats.LastFlush = time.Unix(0, ns)
+	}
+	return stats
+}
+
+func (w *Writer) run() {
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.GPSTrackingData, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case point, ok := <-w.queue:
+			if !ok {
+				w.write(batch)
+				return
+			}
+			batch = append(batch, point)
+			if len(batch) >= w.cfg.BatchSize {
+				if !w.write(batch) {
+					return
+				}
+				batch = make([]models.GPSTrackingData, 0, w.cfg.BatchSize)
+			}
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				if !w.write(batch) {
+					return
+				}
+				batch = make([]models.GPSTrackingData, 0, w.cfg.BatchSize)
+			}
+		}
+	}
+}
+
+// write flushes a batch, retrying until it succeeds or Close gives up. A
+// batch storage rejects as invalid is split in halves until the bad points
+// are found and dropped. It reports false when aborted.
+func (w *Writer) write(batch []models.GPSTrackingData) bool {
+	if len(batch) == 0 {
+		return true
+	}
+
+	delay := minRetryDelay
+	for {
+		err := w.flush(batch)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, ErrInvalidBatch) {
+			w.degraded.Store(false)
+			if len(batch) == 1 {
+				w.dropped.Add(1)
+				log.Printf("GPS ingest dropped point of device %q at %v: %v", batch[0].DeviceID, batch[0].Timestamp, err)
+				return true
+			}
+			half := len(batch) / 2
+			return w.write(batch[:half]) && w.write(batch[half:])
+		}
+
+		w.failedFlushes.Add(1)
+		w.degraded.Store(true)
+		log.Printf("GPS ingest flush of %d points failed, retrying in %v: %v", len(batch), delay, err)
+
+		select {
+		case <-w.abort:
+			return false
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+
+	w.degraded.Store(false)
+	w.written.Add(int64(len(batch)))
+	w.lastFlush.Store(time.Now().UnixNano())
+
+	select {
+	case w.process <- batch:
+	case <-w.abort:
+		return false
+	}
+	return true
+}
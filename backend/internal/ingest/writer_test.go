@@ -0,0 +1,280 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// fakeStore records flushed batches and can be made to fail or stall
+type fakeStore struct {
+	mu      sync.Mutex
+	batches [][]models.GPSTrackingData
+	fail    bool
+	block   chan struct{}
+}
+
+func (s *fakeStore) flush(points []models.GPSTrackingData) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("database unavailable")
+	}
+	s.batches = append(s.batches, append([]models.GPSTrackingData(nil), points...))
+	return nil
+}
+
+func (s *fakeStore) setFail(fail bool) {
+	s.mu.Lock()
+	s.fail = fail
+	s.mu.Unlock()
+}
+
+func (s *fakeStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, b := range s.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func testPoint(i int) models.GPSTrackingData {
+	return models.GPSTrackingData{
+		DeviceID:  "GPS-01",
+		Latitude:  -6.2,
+		Longitude: 106.8,
+		Speed:     float64(i),
+		Timestamp: time.Date(2025, 1, 10, 3, 0, i, 0, time.UTC),
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWriterFlushesFullBatches(t *testing.T) {
+	store := &fakeStore{}
+	cfg := config.IngestConfig{BufferSize: 100, BatchSize: 3, FlushInterval: time.Hour}
+	w := NewWriter(cfg, store.flush, nil)
+	defer w.Close(context.Background())
+
+	for i := 0; i < 7; i++ {
+		if err := w.Submit(testPoint(i)); err != nil {
+			t.Fatalf("Submit %d: %v", i, err)
+		}
+	}
+
+	waitFor(t, "two full batches", func() bool { return store.count() == 6 })
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.batches) != 2 || len(store.batches[0]) != 3 {
+		t.Errorf("Expected 2 batches of 3, got %d", len(store.batches))
+	}
+}
+
+func TestWriterFlushesOnInterval(t *testing.T) {
+	store := &fakeStore{}
+	cfg := config.IngestConfig{BufferSize: 100, BatchSize: 50, FlushInterval: 20 * time.Millisecond}
+	w := NewWriter(cfg, store.flush, nil)
+	defer w.Close(context.Background())
+
+	w.Submit(testPoint(0))
+	waitFor(t, "interval flush", func() bool { return store.count() == 1 })
+}
+
+func TestWriterBackPressure(t *testing.T) {
+	store := &fakeStore{block: make(chan struct{})}
+	cfg := config.IngestConfig{BufferSize: 2, BatchSize: 1, FlushInterval: time.Hour}
+	w := NewWriter(cfg, store.flush, nil)
+
+	// The first point is taken by the stalled flush, two fill the buffer
+	w.Submit(testPoint(0))
+	waitFor(t, "flush to start", func() bool { return len(w.queue) == 0 })
+	w.Submit(testPoint(1))
+	w.Submit(testPoint(2))
+
+	if err := w.Submit(testPoint(3)); !errors.Is(err, ErrBufferFull) {
+		t.Errorf("Expected ErrBufferFull, got %v", err)
+	}
+	if w.Stats().Refused != 1 {
+		t.Errorf("Expected 1 refused point, got %d", w.Stats().Refused)
+	}
+
+	close(store.block)
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if store.count() != 3 {
+		t.Errorf("Expected 3 points written, got %d", store.count())
+	}
+}
+
+func TestWriterUnavailableWhileFlushesFail(t *testing.T) {
+	store := &fakeStore{fail: true}
+	cfg := config.IngestConfig{BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour}
+	w := NewWriter(cfg, store.flush, nil)
+
+	w.Submit(testPoint(0))
+	waitFor(t, "degraded", func() bool { return w.Stats().Degraded })
+	w.Submit(testPoint(1))
+
+	if err := w.Submit(testPoint(2)); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable, got %v", err)
+	}
+
+	// Once storage recovers the retried batch and the queued point land
+	store.setFail(false)
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if store.count() != 2 || w.Stats().Degraded {
+		t.Errorf("Expected recovery with 2 points written, got %d", store.count())
+	}
+}
+
+func TestWriterDropsInvalidPoints(t *testing.T) {
+	store := &fakeStore{}
+	// Storage refuses any batch holding the point with speed 5
+	flush := func(points []models.GPSTrackingData) error {
+		for _, p := range points {
+			if p.Speed == 5 {
+				return fmt.Errorf("%w: value out of range", ErrInvalidBatch)
+			}
+		}
+		return store.flush(points)
+	}
+	var mu sync.Mutex
+	var seen []float64
+	stored := func(points []models.GPSTrackingData) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range points {
+			seen = append(seen, p.Speed)
+		}
+	}
+
+	cfg := config.IngestConfig{BufferSize: 100, BatchSize: 8, FlushInterval: time.Hour}
+	w := NewWriter(cfg, flush, stored)
+	for i := 0; i < 8; i++ {
+		w.Submit(testPoint(i))
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.count() != 7 || w.Stats().Dropped != 1 || w.Stats().FailedFlushes != 0 {
+		t.Fatalf("Expected 7 points written and 1 dropped, got %+v", w.Stats())
+	}
+	want := []float64{0, 1, 2, 3, 4, 6, 7}
+	for i, speed := range seen {
+		if speed != want[i] {
+			t.Fatalf("Expected %v processed in order, got %v", want, seen)
+		}
+	}
+}
+
+func TestWriterCloseDrainsAndRefuses(t *testing.T) {
+	store := &fakeStore{}
+	var mu sync.Mutex
+	var seen []float64
+	stored := func(points []models.GPSTrackingData) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range points {
+			seen = append(seen, p.Speed)
+		}
+	}
+
+	cfg := config.IngestConfig{BufferSize: 100, BatchSize: 4, FlushInterval: time.Hour}
+	w := NewWriter(cfg, store.flush, stored)
+	for i := 0; i < 10; i++ {
+		w.Submit(testPoint(i))
+	}
+
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Submit(testPoint(10)); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+
+	if store.count() != 10 || len(seen) != 10 {
+		t.Fatalf("Expected 10 points written and processed, got %d and %d", store.count(), len(seen))
+	}
+	for i, speed := range seen {
+		if speed != float64(i) {
+			t.Fatalf("Points processed out of order: %v", seen)
+		}
+	}
+}
+
+func TestWriterCloseTimeout(t *testing.T) {
+	store := &fakeStore{fail: true}
+	cfg := config.IngestConfig{BufferSize: 10, BatchSize: 1, FlushInterval: time.Hour}
+	w := NewWriter(cfg, store.flush, nil)
+	w.Submit(testPoint(0))
+	w.Submit(testPoint(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := w.Close(ctx); err == nil {
+		t.Error("Expected an error when points are left unwritten")
+	}
+}
+
+func TestParsePoint(t *testing.T) {
+	now := time.Date(2025, 1, 10, 3, 0, 0, 0, time.UTC)
+	lat, lng := -6.2, 106.8
+	zero := 0.0
+	far := 95.0
+
+	valid, err := ParsePoint(PointRequest{DeviceID: " GPS-01 ", Latitude: &lat, Longitude: &lng, Speed: 40}, now, 0)
+	if err != nil {
+		t.Fatalf("Expected valid point, got %v", err)
+	}
+	if valid.DeviceID != "GPS-01" || !valid.Timestamp.Equal(now) {
+		t.Errorf("Unexpected point: %+v", valid)
+	}
+
+	tests := []struct {
+		name string
+		req  PointRequest
+	}{
+		{"missing device", PointRequest{Latitude: &lat, Longitude: &lng}},
+		{"missing latitude", PointRequest{DeviceID: "GPS-01", Longitude: &lng}},
+		{"latitude out of range", PointRequest{DeviceID: "GPS-01", Latitude: &far, Longitude: &lng}},
+		{"no fix", PointRequest{DeviceID: "GPS-01", Latitude: &zero, Longitude: &zero}},
+		{"negative speed", PointRequest{DeviceID: "GPS-01", Latitude: &lat, Longitude: &lng, Speed: -1}},
+		{"bad timestamp", PointRequest{DeviceID: "GPS-01", Latitude: &lat, Longitude: &lng, Timestamp: "10/01/2025"}},
+		{"future timestamp", PointRequest{DeviceID: "GPS-01", Latitude: &lat, Longitude: &lng, Timestamp: "2025-01-10T03:10:00Z"}},
+		{"past raw history", PointRequest{DeviceID: "GPS-01", Latitude: &lat, Longitude: &lng, Timestamp: "2024-12-01T03:00:00Z"}},
+	}
+	for _, tt := range tests {
+		if _, err := ParsePoint(tt.req, now, 30*24*time.Hour); err == nil {
+			t.Errorf("%s: expected rejection", tt.name)
+		}
+	}
+
+	old := PointRequest{DeviceID: "GPS-01", Latitude: &lat, Longitude: &lng, Timestamp: "2024-12-01T03:00:00Z"}
+	if _, err := ParsePoint(old, now, 0); err != nil {
+		t.Errorf("Expected any age to be accepted without a limit, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessageStripsHeaderInjection(t *testing.T) {
+	msg := Message{To: "a@example.com\r\nBcc: evil@example.com", Subject: "Hi\nBcc: evil@example.com", Body: "line 1\nline 2"}
+	data := string(buildMessage("TMS <no-reply@tms.local>", msg, time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)))
+
+	headers, body, ok := strings.Cut(data, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("Expected headers and body, got %q", data)
+	}
+	for _, line := range strings.Split(headers, "\r\n") {
+		if strings.HasPrefix(line, "Bcc:") {
+			t.Errorf("Expected no injected header, got %q", line)
+		}
+	}
+	if body != "line 1\r\nline 2" {
+		t.Errorf("Expected CRLF line endings in body, got %q", body)
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer()
+	m.Send(Message{To: "a@example.com", Subject: "one"})
+	m.Send(Message{To: "b@example.com", Subject: "two"})
+
+	sent := m.Sent()
+	if len(sent) != 2 || sent[1].Subject != "two" {
+		t.Errorf("Expected both messages in order, got %+v", sent)
+	}
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/audit"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// auditActorHook is the context key of the callback authenticate runs
+// once the actor is known, for routes whose entity is the actor
+const auditActorHook = "audit_actor_hook"
+
+// maxAuditCapture bounds the request and response bodies read to find an
+// entity key
+const maxAuditCapture = 1 << 20
+
+// AuditRecorder reads entity snapshots and stores audit entries
+type AuditRecorder interface {
+	Snapshot(entity audit.Entity, key string) (map[string]interface{}, error)
+	Record(event *models.AuditEvent) error
+}
+
+// auditCapture keeps a copy of the response so the key of a created
+// entity can be read from it
+type auditCapture struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditCapture) Write(data []byte) (int, error) {
+	if w.body.Len()+len(data) <= maxAuditCapture {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditCapture) WriteString(s string) (int, error) {
+	if w.body.Len()+len(s) <= maxAuditCapture {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Audit records every successful state-changing request. Routes are keyed
+// "METHOD /full/path"; those in entities also record the before/after diff
+// of the row they change, and those in skip are not recorded at all.
+func Audit(recorder AuditRecorder, entities map[string]audit.Entity, skip map[string]bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		route := c.FullPath()
+		if route == "" || skip[method+" "+route] {
+			c.Next()
+			return
+		}
+
+		entity, tracked := entities[method+" "+route]
+		key := ""
+		var before map[string]interface{}
+		var capture *auditCapture
+		if tracked {
+			key = auditKeyFromRequest(c, entity)
+			if key != "" {
+				before = auditSnapshot(recorder, entity, key)
+			} else if entity.Actor {
+				// Authentication runs after this middleware, so the
+				// snapshot is taken when it identifies the actor
+				c.Set(auditActorHook, func(userID int) {
+					key = strconv.Itoa(userID)
+					before = auditSnapshot(recorder, entity, key)
+				})
+			} else if entity.Result != "" {
+				capture = &auditCapture{ResponseWriter: c.Writer}
+				c.Writer = capture
+			}
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status >= http.StatusBadRequest {
+			return
+		}
+
+		event := &models.AuditEvent{
+			Action:     "api." + strings.ToLower(method),
+			IPAddress:  c.ClientIP(),
+			Method:     method,
+			Route:      route,
+			StatusCode: status,
+			CreatedAt:  time.Now(),
+		}
+		if id, ok := c.Get("user_id"); ok {
+			if actorID, ok := id.(int); ok {
+				event.ActorID = &actorID
+			}
+		}
+		if role, ok := c.Get("user_role"); ok {
+			event.ActorRole, _ = role.(string)
+		}
+
+		if tracked {
+			if key == "" && capture != nil {
+				key = audit.Lookup(capture.body.Bytes(), entity.Result)
+			}
+			if key != "" {
+				after := auditSnapshot(recorder, entity, key)
+				event.EntityType = entity.Type
+				event.EntityID = key
+				event.Action = entity.Type + "." + auditVerb(before, after)
+				event.Changes = audit.Diff(before, after)
+			}
+		}
+
+		if err := recorder.Record(event); err != nil {
+			log.Printf("Audit write error - Route: %s %s: %v", method, route, err)
+		}
+	}
+}
+
+// auditKeyFromRequest finds the entity key before the handler runs
+func auditKeyFromRequest(c *gin.Context, entity audit.Entity) string {
+	if entity.Param != "" {
+		return c.Param(entity.Param)
+	}
+	if entity.Body == "" || c.Request.Body == nil {
+		return ""
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditCapture))
+	if err != nil {
+		return ""
+	}
+	// Hand the handler the body it would have read, including anything
+	// past the limit
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), c.Request.Body))
+	return audit.Lookup(data, entity.Body)
+}
+
+// auditActorKnown takes the before snapshot of an actor entity
+func auditActorKnown(c *gin.Context, userID int) {
+	if hook, ok := c.Get(auditActorHook); ok {
+		if snapshot, ok := hook.(func(int)); ok {
+			snapshot(userID)
+		}
+	}
+}
+
+func auditSnapshot(recorder AuditRecorder, entity audit.Entity, key string) map[string]interface{} {
+	row, err := recorder.Snapshot(entity, key)
+	if err != nil {
+		log.Printf("Audit snapshot error: %v", err)
+	}
+	return row
+}
+
+func auditVerb(before, after map[string]interface{}) string {
+	switch {
+	case before == nil && after != nil:
+		return "create"
+	case before != nil && after == nil:
+		return "delete"
+	}
+	return "update"
+}
@@ -9,8 +9,38 @@ import (
 	"github.com/youruser/aplikasi-tms/backend/internal/auth"
 )
 
-// AuthRequired middleware for protected routes
+// RevocationChecker reports whether an access token's jti was revoked
+type RevocationChecker func(jti string) (bool, error)
+
+var revocationChecker RevocationChecker
+
+// SetRevocationChecker installs the revocation check AuthRequired runs on
+// every request. Once set, tokens without a jti are refused.
+func SetRevocationChecker(check RevocationChecker) {
+	revocationChecker = check
+}
+
+// AuthRequired middleware for protected routes. Only full-access tokens
+// are accepted.
 func AuthRequired() gin.HandlerFunc {
+	return authenticate("")
+}
+
+// TwoFactorPending accepts only the token issued after the password step
+// of a two-factor login
+func TwoFactorPending() gin.HandlerFunc {
+	return authenticate(auth.ScopeTwoFactor)
+}
+
+// TwoFactorEnrollment accepts full-access tokens and the enrollment token
+// given to users who must set up two-factor authentication to log in
+func TwoFactorEnrollment() gin.HandlerFunc {
+	return authenticate("", auth.ScopeTwoFactorEnroll)
+}
+
+// authenticate validates the bearer token and requires its scope to be one
+// of scopes
+func authenticate(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 		if token == "" {
@@ -41,19 +71,78 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		if !scopeAllowed(claims.Scope, scopes) {
+			if claims.Scope != "" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication required", "code": "2fa_required"})
+			} else {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Token not valid for this endpoint"})
+			}
+			c.Abort()
+			return
+		}
+
+		if revocationChecker != nil {
+			if claims.ID == "" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token is no longer accepted, please log in again"})
+				c.Abort()
+				return
+			}
+			revoked, err := revocationChecker(claims.ID)
+			if err != nil {
+				log.Printf("Token revocation check failed: %v", err)
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token"})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				c.Abort()
+				return
+			}
+		}
+
+		if !rateLimitUser(c, claims.UserID) {
+			return
+		}
+
 		// Set user info in context
+		c.Set("token_claims", claims)
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("user_role", claims.Role)
+		auditActorKnown(c, claims.UserID)
 		c.Next()
 	}
 }
 
-// AdminRequired middleware for admin-only routes
+func scopeAllowed(scope string, scopes []string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryTokenAuth is AuthRequired for WebSocket upgrades, which browsers
+// cannot send headers with: the token may come as ?token= instead
+func QueryTokenAuth() gin.HandlerFunc {
+	authRequired := AuthRequired()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		authRequired(c)
+	}
+}
+
+// AdminRequired middleware for routes only super admins may use
 func AdminRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("user_role")
-		if !exists || role != "admin" {
+		if !exists || role != auth.RoleSuperAdmin {
 			// Log unauthorized admin access attempt
 			userID, _ := c.Get("user_id")
 			username, _ := c.Get("username")
@@ -74,4 +163,22 @@ func AdminRequired() gin.HandlerFunc {
 		}
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// RequirePermission middleware for routes that need a named permission,
+// e.g. RequirePermission("vehicles.verify"). Use after AuthRequired.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("user_role")
+		roleStr, _ := role.(string)
+		if !auth.HasPermission(roleStr, permission) {
+			userID, _ := c.Get("user_id")
+			log.Printf("Permission denied - UserID: %v, Role: %s, Permission: %s, IP: %s",
+				userID, SanitizeForLog(roleStr), permission, SanitizeForLog(c.ClientIP()))
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission required: " + permission})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
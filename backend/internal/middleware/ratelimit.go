@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/youruser/aplikasi-tms/backend/internal/ratelimit"
+)
+
+var userRateLimiter *ratelimit.Limiter
+
+// SetUserRateLimiter installs the per-user limit AuthRequired applies to
+// every authenticated request
+func SetUserRateLimiter(limiter *ratelimit.Limiter) {
+	userRateLimiter = limiter
+}
+
+// RateLimitByIP limits requests per client IP
+func RateLimitByIP(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ok, retryAfter := limiter.Allow(c.ClientIP()); !ok {
+			log.Printf("Rate limit exceeded - IP: %s, Path: %s", SanitizeForLog(c.ClientIP()), SanitizeForLog(c.Request.URL.Path))
+			TooManyRequests(c, retryAfter, "Too many requests, please slow down")
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitUser applies the per-user limit, if one is installed
+func rateLimitUser(c *gin.Context, userID int) bool {
+	if userRateLimiter == nil {
+		return true
+	}
+	ok, retryAfter := userRateLimiter.Allow(strconv.Itoa(userID))
+	if !ok {
+		log.Printf("Rate limit exceeded - UserID: %d, Path: %s", userID, SanitizeForLog(c.Request.URL.Path))
+		TooManyRequests(c, retryAfter, "Too many requests, please slow down")
+	}
+	return ok
+}
+
+// TooManyRequests aborts with 429 and a Retry-After header in whole seconds
+func TooManyRequests(c *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": message, "retry_after": seconds})
+}
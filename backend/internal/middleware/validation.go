@@ -57,14 +57,6 @@ func ValidateInput() gin.HandlerFunc {
 			return
 		}
 
-		c.Next()
-	}
-}
-
-// RateLimitByIP simple rate limiting (for production use Redis)
-func RateLimitByIP() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Simple rate limiting - in production use Redis
 		c.Next()
 	}
 }
\ No newline at end of file
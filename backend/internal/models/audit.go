@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// Audit actions
+const (
+	AuditAccountLocked   = "auth.account_locked"
+	AuditTwoFactorLocked = "auth.2fa_locked"
+)
+
+// AuditEvent is one append-only audit log entry. Events written for API
+// calls carry the request fields and, when the route maps to an entity,
+// the fields of that entity which changed.
+type AuditEvent struct {
+	ID         int64                  `json:"id"`
+	Action     string                 `json:"action"`
+	ActorID    *int                   `json:"actor_id,omitempty"`
+	ActorRole  string                 `json:"actor_role,omitempty"`
+	IPAddress  string                 `json:"ip_address"`
+	Method     string                 `json:"method,omitempty"`
+	Route      string                 `json:"route,omitempty"`
+	StatusCode int                    `json:"status_code,omitempty"`
+	EntityType string                 `json:"entity_type,omitempty"`
+	EntityID   string                 `json:"entity_id,omitempty"`
+	Changes    map[string]AuditChange `json:"changes,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	PrevHash   string                 `json:"prev_hash,omitempty"`
+	Hash       string                 `json:"hash,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditChange is the value of one field before and after a request; nil
+// means the entity did not exist on that side
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditFilter narrows an audit log query; zero fields match everything
+type AuditFilter struct {
+	ActorID    *int
+	Action     string
+	EntityType string
+	EntityID   string
+	From       time.Time
+	To         time.Time
+	Limit      int
+	Offset     int
+}
+
+// AuditChainReport is the result of re-computing the audit hash chain
+type AuditChainReport struct {
+	Valid   bool `json:"valid"`
+	Checked int  `json:"checked"`
+	Legacy  int  `json:"legacy"`
+	// Head is the hash of the newest entry; comparing it with a copy kept
+	// elsewhere shows whether entries were removed from the end
+	Head string `json:"head,omitempty"`
+	// BrokenAt is the first entry whose hash does not match
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
@@ -8,6 +8,7 @@ type Driver struct {
 	LicenseNumber  string     `json:"license_number"`
 	LicenseExpiry  time.Time  `json:"license_expiry"`
 	Status         string     `json:"status"`
+	FleetOwnerID   *int       `json:"fleet_owner_id"`
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 	// Relations
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+type DrivingAlert struct {
+	ID             int64      `json:"id"`
+	DeviceID       string     `json:"device_id"`
+	VehicleID      *int       `json:"vehicle_id"`
+	FleetOwnerID   *int       `json:"fleet_owner_id"`
+	AlertType      string     `json:"alert_type"` // speeding, harsh_acceleration, harsh_braking, idling, after_hours
+	Severity       string     `json:"severity"`   // warning, critical
+	Value          *float64   `json:"value"`
+	Threshold      *float64   `json:"threshold"`
+	GeofenceID     *int       `json:"geofence_id"`
+	Latitude       float64    `json:"latitude"`
+	Longitude      float64    `json:"longitude"`
+	Message        string     `json:"message"`
+	OccurredAt     time.Time  `json:"occurred_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	AcknowledgedBy *int       `json:"acknowledged_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// DeviceDrivingState is the last point seen from a device plus the alert
+// episodes that are still open, so each episode is reported once
+type DeviceDrivingState struct {
+	DeviceID            string
+	LastLatitude        float64
+	LastLongitude       float64
+	LastSpeed           float64
+	LastTimestamp       time.Time
+	StoppedSince        *time.Time
+	IdleAlerted         bool
+	Speeding            bool
+	AfterHoursAlertedOn *time.Time
+}
+
+// DrivingVehicle is what the rule engine needs to know about the vehicle
+// a device is fitted to
+type DrivingVehicle struct {
+	VehicleID          int
+	RegistrationNumber string
+	VehicleType        string
+	FleetOwnerID       *int
+	OwnerUserID        *int
+	SpeedLimitKmh      *float64
+}
+
+type VehicleSpeedLimit struct {
+	VehicleType string    `json:"vehicle_type"`
+	MaxSpeedKmh float64   `json:"max_speed_kmh"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type VehicleSpeedLimitRequest struct {
+	VehicleType string  `json:"vehicle_type" binding:"required"`
+	MaxSpeedKmh float64 `json:"max_speed_kmh" binding:"required,gt=0"`
+}
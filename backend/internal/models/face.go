@@ -0,0 +1,22 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FaceVerification is one comparison of a fleet owner's selfie with their
+// KTP photo. Result is the full FaceMatchResult as returned to the owner.
+type FaceVerification struct {
+	ID             int64           `json:"id"`
+	FleetOwnerID   int             `json:"fleet_owner_id"`
+	UserID         int             `json:"user_id"`
+	MatchScore     float64         `json:"match_score"`
+	Threshold      float64         `json:"threshold"`
+	IsMatch        bool            `json:"is_match"`
+	LivenessPassed bool            `json:"liveness_passed"`
+	LivenessFlags  []string        `json:"liveness_flags"`
+	Verified       bool            `json:"verified"`
+	Result         json.RawMessage `json:"result"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
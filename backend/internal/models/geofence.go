@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/geo"
+)
+
+type Geofence struct {
+	ID            int         `json:"id"`
+	Name          string      `json:"name"`
+	Category      string      `json:"category"`   // depot, customer_site, restricted
+	ShapeType     string      `json:"shape_type"` // circle, polygon
+	CenterLat     *float64    `json:"center_lat,omitempty"`
+	CenterLng     *float64    `json:"center_lng,omitempty"`
+	RadiusMeters  *float64    `json:"radius_meters,omitempty"`
+	Polygon       []geo.Point `json:"polygon,omitempty"`
+	DwellSeconds  int         `json:"dwell_seconds"`
+	SpeedLimitKmh *float64    `json:"speed_limit_kmh,omitempty"`
+	IsActive      bool        `json:"is_active"`
+	CreatedBy     *int        `json:"created_by"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+type GeofenceRequest struct {
+	Name          string      `json:"name" binding:"required"`
+	Category      string      `json:"category"`
+	ShapeType     string      `json:"shape_type" binding:"required,oneof=circle polygon"`
+	CenterLat     *float64    `json:"center_lat"`
+	CenterLng     *float64    `json:"center_lng"`
+	RadiusMeters  *float64    `json:"radius_meters"`
+	Polygon       []geo.Point `json:"polygon"`
+	DwellSeconds  *int        `json:"dwell_seconds"`
+	SpeedLimitKmh *float64    `json:"speed_limit_kmh"`
+	IsActive      *bool       `json:"is_active"`
+}
+
+type GeofenceEvent struct {
+	ID           int64     `json:"id"`
+	GeofenceID   int       `json:"geofence_id"`
+	GeofenceName string    `json:"geofence_name,omitempty"`
+	Category     string    `json:"category,omitempty"`
+	DeviceID     string    `json:"device_id"`
+	VehicleID    *int      `json:"vehicle_id"`
+	EventType    string    `json:"event_type"` // enter, exit, dwell
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	TripID       *int      `json:"trip_id"`
+	StopID       *int      `json:"stop_id"`
+	OccurredAt   time.Time `json:"occurred_at"`
+}
+
+type GeofenceDeviceState struct {
+	DeviceID      string
+	GeofenceID    int
+	EnteredAt     time.Time
+	DwellReported bool
+}
@@ -5,6 +5,7 @@ import "time"
 type GPSDevice struct {
 	ID               int       `json:"id"`
 	DeviceID         string    `json:"device_id"`
+	IMEI             *string   `json:"imei"`
 	VehicleID        *int      `json:"vehicle_id"`
 	RegistrationID   int       `json:"registration_id"`
 	Status           string    `json:"status"` // pending_installation, installed, active, inactive
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// GPSHistoryPoint is one point of device history. At minute resolution it
+// stands for every fix in that minute: the position is the last fix and
+// Speed is the average.
+type GPSHistoryPoint struct {
+	DeviceID   string    `json:"device_id"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Speed      float64   `json:"speed"`
+	MaxSpeed   float64   `json:"max_speed"`
+	PointCount int       `json:"point_count"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// GPSMaintenanceResult reports what one retention run changed
+type GPSMaintenanceResult struct {
+	PartitionsCreated []string `json:"partitions_created"`
+	PartitionsDropped []string `json:"partitions_dropped"`
+	PointsDownsampled int64    `json:"points_downsampled"`
+	SummariesDeleted  int64    `json:"summaries_deleted"`
+}
+
+// RouteStop is a place the vehicle stayed for at least the minimum stop
+// duration
+type RouteStop struct {
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	ArrivedAt    time.Time `json:"arrived_at"`
+	DepartedAt   time.Time `json:"departed_at"`
+	DwellSeconds int64     `json:"dwell_seconds"`
+}
+
+// RoutePlayback reconstructs what a device did over a time range. The
+// route is an encoded polyline; TimeOffsets and Speeds line up with its
+// points, offsets counting seconds from StartedAt.
+type RoutePlayback struct {
+	DeviceID        string      `json:"device_id"`
+	From            time.Time   `json:"from"`
+	To              time.Time   `json:"to"`
+	Resolution      string      `json:"resolution"`
+	PointCount      int         `json:"point_count"`
+	Truncated       bool        `json:"truncated"`
+	Polyline        string      `json:"polyline"`
+	TimeOffsets     []int64     `json:"time_offsets"`
+	Speeds          []float64   `json:"speeds"`
+	StartedAt       *time.Time  `json:"started_at"`
+	EndedAt         *time.Time  `json:"ended_at"`
+	DistanceMeters  float64     `json:"distance_meters"`
+	DrivingSeconds  int64       `json:"driving_seconds"`
+	StoppedSeconds  int64       `json:"stopped_seconds"`
+	NoSignalSeconds int64       `json:"no_signal_seconds"`
+	MaxSpeedKmh     float64     `json:"max_speed_kmh"`
+	AvgSpeedKmh     float64     `json:"avg_speed_kmh"`
+	Stops           []RouteStop `json:"stops"`
+}
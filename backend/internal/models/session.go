@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// RefreshToken is one link in a session's chain of refresh tokens. Only
+// the hash of the token is stored.
+type RefreshToken struct {
+	ID              int64
+	UserID          int
+	SessionID       string
+	TokenHash       string
+	AccessJTI       string
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	ReplacedBy      *int64
+	RevokedAt       *time.Time
+	UserAgent       string
+	IPAddress       string
+	CreatedAt       time.Time
+}
+
+// Session is a login that can still be refreshed
+type Session struct {
+	SessionID  string    `json:"session_id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	StartedAt  time.Time `json:"started_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// TokenPair is what login and refresh return
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresIn        int       `json:"expires_in"` // access token lifetime in seconds
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	SessionID        string    `json:"-"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -0,0 +1,40 @@
+package models
+
+// TenantScope limits reads to one fleet owner's rows. Staff get
+// AllTenants unless they pick a tenant; the zero value matches nothing, so
+// a scope that was never resolved cannot leak data.
+type TenantScope struct {
+	FleetOwnerID int
+	All          bool
+}
+
+// AllTenants is the scope of staff who have not picked a tenant
+func AllTenants() TenantScope {
+	return TenantScope{All: true}
+}
+
+// FleetOwnerScope limits reads to one fleet owner
+func FleetOwnerScope(fleetOwnerID int) TenantScope {
+	return TenantScope{FleetOwnerID: fleetOwnerID}
+}
+
+// FilterID is the argument for "($n = 0 OR fleet_owner_id = $n)" filters:
+// 0 for every tenant, the fleet owner's ID, or -1 to match nothing
+func (s TenantScope) FilterID() int {
+	if s.All {
+		return 0
+	}
+	if s.FleetOwnerID > 0 {
+		return s.FleetOwnerID
+	}
+	return -1
+}
+
+// Allows reports whether a row owned by fleetOwnerID is visible. Rows
+// without an owner are visible to staff only.
+func (s TenantScope) Allows(fleetOwnerID *int) bool {
+	if s.All {
+		return true
+	}
+	return fleetOwnerID != nil && s.FleetOwnerID > 0 && *fleetOwnerID == s.FleetOwnerID
+}
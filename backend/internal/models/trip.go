@@ -12,11 +12,14 @@ type Trip struct {
 	ArrivalTime   *time.Time `json:"arrival_time"`
 	Status        string     `json:"status"`
 	Distance      *float64   `json:"distance"`
+	CustomerContractID *int  `json:"customer_contract_id"`
+	FleetOwnerID  *int       `json:"fleet_owner_id"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
 	// Relations
 	Driver        *Driver    `json:"driver,omitempty"`
 	Vehicle       *Vehicle   `json:"vehicle,omitempty"`
+	Stops         []TripStop `json:"stops,omitempty"`
 }
 
 type TripRequest struct {
@@ -28,4 +31,113 @@ type TripRequest struct {
 	ArrivalTime   *string `json:"arrival_time" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 	Status        string  `json:"status,omitempty"`
 	Distance      *float64 `json:"distance"`
-}
\ No newline at end of file
+	CustomerContractID *int `json:"customer_contract_id"`
+	Stops         []TripStopRequest `json:"stops" binding:"omitempty,dive"`
+}
+type TripTransitionRequest struct {
+	Status    string `json:"status" binding:"required"`
+	DriverID  *int   `json:"driver_id"`
+	VehicleID *int   `json:"vehicle_id"`
+	Notes     string `json:"notes"`
+}
+
+type TripStatusHistory struct {
+	ID            int       `json:"id"`
+	TripID        int       `json:"trip_id"`
+	FromStatus    *string   `json:"from_status"`
+	ToStatus      string    `json:"to_status"`
+	ChangedBy     *int      `json:"changed_by"`
+	ChangedByName string    `json:"changed_by_name,omitempty"`
+	ChangedByRole string    `json:"changed_by_role"`
+	Notes         string    `json:"notes,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type TripStop struct {
+	ID             int        `json:"id"`
+	TripID         int        `json:"trip_id"`
+	Sequence       int        `json:"sequence"`
+	StopType       string     `json:"stop_type"`
+	Address        string     `json:"address"`
+	Latitude       *float64   `json:"latitude"`
+	Longitude      *float64   `json:"longitude"`
+	WindowStart    *time.Time `json:"window_start"`
+	WindowEnd      *time.Time `json:"window_end"`
+	ContactName    *string    `json:"contact_name"`
+	ContactPhone   *string    `json:"contact_phone"`
+	ExpectedCargo  *string    `json:"expected_cargo"`
+	ExpectedWeight *float64   `json:"expected_weight"`
+	Status         string     `json:"status"`
+	ArrivedAt      *time.Time `json:"arrived_at"`
+	DepartedAt     *time.Time `json:"departed_at"`
+	Notes          *string    `json:"notes"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type TripStopRequest struct {
+	StopType       string   `json:"stop_type"`
+	Address        string   `json:"address" binding:"required"`
+	Latitude       *float64 `json:"latitude"`
+	Longitude      *float64 `json:"longitude"`
+	WindowStart    *string  `json:"window_start"`
+	WindowEnd      *string  `json:"window_end"`
+	ContactName    *string  `json:"contact_name"`
+	ContactPhone   *string  `json:"contact_phone"`
+	ExpectedCargo  *string  `json:"expected_cargo"`
+	ExpectedWeight *float64 `json:"expected_weight"`
+	Notes          *string  `json:"notes"`
+}
+
+type TripPOD struct {
+	ID            int            `json:"id"`
+	TripID        int            `json:"trip_id"`
+	StopID        *int           `json:"stop_id"`
+	RecipientName string         `json:"recipient_name"`
+	SignaturePath string         `json:"signature_path"`
+	Latitude      float64        `json:"latitude"`
+	Longitude     float64        `json:"longitude"`
+	Accuracy      *float64       `json:"accuracy"`
+	Notes         *string        `json:"notes"`
+	CapturedBy    *int           `json:"captured_by"`
+	CapturedAt    time.Time      `json:"captured_at"`
+	Photos        []TripPODPhoto `json:"photos"`
+}
+
+type TripPODPhoto struct {
+	ID         int       `json:"id"`
+	PODID      int       `json:"pod_id"`
+	FileName   string    `json:"file_name"`
+	FilePath   string    `json:"file_path"`
+	FileSize   int       `json:"file_size"`
+	MimeType   string    `json:"mime_type"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+type TripPODRequest struct {
+	RecipientName string   `form:"recipient_name" binding:"required"`
+	Latitude      *float64 `form:"latitude" binding:"required"`
+	Longitude     *float64 `form:"longitude" binding:"required"`
+	Accuracy      *float64 `form:"accuracy"`
+	StopID        *int     `form:"stop_id"`
+	Notes         string   `form:"notes"`
+}
+
+type CustomerContract struct {
+	ID             int        `json:"id"`
+	CustomerName   string     `json:"customer_name"`
+	ContractNumber string     `json:"contract_number"`
+	RequiresPOD    bool       `json:"requires_pod"`
+	ValidFrom      *time.Time `json:"valid_from"`
+	ValidUntil     *time.Time `json:"valid_until"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type CustomerContractRequest struct {
+	CustomerName   string  `json:"customer_name" binding:"required"`
+	ContractNumber string  `json:"contract_number" binding:"required"`
+	RequiresPOD    bool    `json:"requires_pod"`
+	ValidFrom      *string `json:"valid_from" binding:"omitempty,datetime=2006-01-02"`
+	ValidUntil     *string `json:"valid_until" binding:"omitempty,datetime=2006-01-02"`
+}
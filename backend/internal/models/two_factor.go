@@ -0,0 +1,31 @@
+package models
+
+// Second login steps
+const (
+	TwoFactorStepVerify = "verify"
+	TwoFactorStepEnroll = "enroll"
+)
+
+// TwoFactorChallenge replaces the login response when the password was
+// right but a second step is needed. Token only works for that step.
+type TwoFactorChallenge struct {
+	Step      string `json:"two_factor"`
+	Token     string `json:"two_factor_token"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorCodeRequest carries a TOTP code or a recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
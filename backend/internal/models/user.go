@@ -3,25 +3,28 @@ package models
 import "time"
 
 type User struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"` // Hidden from JSON responses
-	FullName  string    `json:"full_name"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"-"` // Hidden from JSON responses
+	FullName string `json:"full_name"`
+	Role     string `json:"role"`
+	// EmailVerifiedAt is nil until the user follows the verification link
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // UserResponse for API responses without sensitive data
 type UserResponse struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	FullName  string    `json:"full_name"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            int       `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	FullName      string    `json:"full_name"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type LoginRequest struct {
@@ -38,6 +41,33 @@ type RegisterRequest struct {
 }
 
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
-}
\ No newline at end of file
+	Token            string       `json:"token"`
+	RefreshToken     string       `json:"refresh_token,omitempty"`
+	ExpiresIn        int          `json:"expires_in,omitempty"`
+	RefreshExpiresAt *time.Time   `json:"refresh_expires_at,omitempty"`
+	User             UserResponse `json:"user"`
+}
+
+// RoleInfo describes a role and the permissions it grants
+type RoleInfo struct {
+	Role            string   `json:"role"`
+	Permissions     []string `json:"permissions"`
+	SelfRegistrable bool     `json:"self_registrable"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type RoleAssignmentRequest struct {
+	Role string `json:"role" binding:"required"`
+}
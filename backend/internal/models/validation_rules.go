@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// ValidationRules decide which automatic checks a vehicle submission gets
+// and how they are scored. Every save is a new version; the latest one is
+// in force.
+type ValidationRules struct {
+	Version int `json:"version"`
+	// RequiredDocuments are the attachment types each vehicle type needs;
+	// "default" covers the types not listed
+	RequiredDocuments map[string][]string `json:"required_documents"`
+	// Checks by check type, e.g. "document_completeness"
+	Checks map[string]ValidationRule `json:"checks"`
+	// A submission without failed checks or warnings is approved from
+	// AutoApproveScore; one below AutoRejectScore is rejected (0 never
+	// rejects)
+	AutoApproveScore float64 `json:"auto_approve_score"`
+	AutoRejectScore  float64 `json:"auto_reject_score"`
+	// AutoApproval decides whether an auto-approved submission is approved
+	// without waiting for a verifier
+	AutoApproval AutoApprovalPolicy `json:"auto_approval"`
+	Note         string             `json:"note,omitempty"`
+	CreatedBy    *int               `json:"created_by,omitempty"`
+	CreatedAt    *time.Time         `json:"created_at,omitempty"`
+}
+
+type ValidationRule struct {
+	Enabled bool    `json:"enabled"`
+	Weight  float64 `json:"weight"`
+}
+
+type AutoApprovalPolicy struct {
+	Enabled bool `json:"enabled"`
+	// CrossChecks must all pass before approving: samsat, kir, insurance,
+	// duplicate
+	CrossChecks []string `json:"cross_checks"`
+	// QASampleRate is the share of auto-approvals picked at random for a
+	// verifier to review afterwards, from 0 to 1
+	QASampleRate float64 `json:"qa_sample_rate"`
+}
+
+// VerificationTrail records how the system came to a verification
+// decision by itself
+type VerificationTrail struct {
+	RuleVersion     int     `json:"rule_version"`
+	ConfidenceScore float64 `json:"confidence_score"`
+	// CrossChecks are the status of each cross-check the policy required
+	CrossChecks map[string]string `json:"cross_checks,omitempty"`
+	QASampled   bool              `json:"qa_sampled"`
+}
+
+// QAReview is an auto-approved vehicle waiting for a verifier's review
+type QAReview struct {
+	VehicleID          int       `json:"vehicle_id"`
+	RegistrationNumber string    `json:"registration_number"`
+	VehicleType        string    `json:"vehicle_type"`
+	ApprovedAt         time.Time `json:"approved_at"`
+	RuleVersion        *int      `json:"rule_version"`
+	ConfidenceScore    *float64  `json:"confidence_score"`
+}
+
+// ValidationDryRun compares the rules in force with proposed ones over
+// recent submissions
+type ValidationDryRun struct {
+	CurrentVersion int                       `json:"current_version"`
+	Submissions    int                       `json:"submissions"`
+	Changed        int                       `json:"changed"`
+	Before         map[string]int            `json:"before"`
+	After          map[string]int            `json:"after"`
+	Vehicles       []ValidationDryRunVehicle `json:"vehicles"`
+}
+
+type ValidationDryRunVehicle struct {
+	VehicleID          int       `json:"vehicle_id"`
+	RegistrationNumber string    `json:"registration_number"`
+	VehicleType        string    `json:"vehicle_type"`
+	SubmittedAt        time.Time `json:"submitted_at"`
+	CurrentStatus      string    `json:"current_status"`
+	CurrentScore       float64   `json:"current_score"`
+	ProposedStatus     string    `json:"proposed_status"`
+	ProposedScore      float64   `json:"proposed_score"`
+	Changed            bool      `json:"changed"`
+}
@@ -54,6 +54,9 @@ type AutoValidationResult struct {
 	OverallStatus   string            `json:"overall_status"`
 	Checks         []ValidationCheck `json:"checks"`
 	ConfidenceScore float64          `json:"confidence_score"`
+	// RuleVersion is the version of the validation rules applied; 0 is the
+	// built-in defaults
+	RuleVersion    int              `json:"rule_version"`
 	ProcessedAt    time.Time        `json:"processed_at"`
 }
 
@@ -91,17 +94,18 @@ type VehicleRequest struct {
 
 
 type VehicleAttachment struct {
-	ID               int       `json:"id" db:"id"`
-	VehicleID        int       `json:"vehicle_id" db:"vehicle_id"`
-	AttachmentType   string    `json:"attachment_type" db:"attachment_type"`
-	FileName         string    `json:"file_name" db:"file_name"`
-	FilePath         string    `json:"file_path" db:"file_path"`
-	FileSize         int       `json:"file_size" db:"file_size"`
-	MimeType         string    `json:"mime_type" db:"mime_type"`
-	OCRData          *string   `json:"ocr_data" db:"ocr_data"`
-	ValidationStatus string    `json:"validation_status" db:"validation_status"`
-	ValidationErrors []string  `json:"validation_errors" db:"validation_errors"`
-	UploadedAt       time.Time `json:"uploaded_at" db:"uploaded_at"`
+	ID               int        `json:"id" db:"id"`
+	VehicleID        int        `json:"vehicle_id" db:"vehicle_id"`
+	AttachmentType   string     `json:"attachment_type" db:"attachment_type"`
+	FileName         string     `json:"file_name" db:"file_name"`
+	FilePath         string     `json:"file_path" db:"file_path"`
+	FileSize         int        `json:"file_size" db:"file_size"`
+	MimeType         string     `json:"mime_type" db:"mime_type"`
+	OCRData          *string    `json:"ocr_data" db:"ocr_data"`
+	ValidationStatus string     `json:"validation_status" db:"validation_status"`
+	ValidationErrors []string   `json:"validation_errors" db:"validation_errors"`
+	ValidatedAt      *time.Time `json:"validated_at" db:"validated_at"`
+	UploadedAt       time.Time  `json:"uploaded_at" db:"uploaded_at"`
 }
 
 type VehicleResponse struct {
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// QueueItem is a vehicle submission waiting for a verifier
+type QueueItem struct {
+	VehicleID          int    `json:"vehicle_id"`
+	RegistrationNumber string `json:"registration_number"`
+	VehicleType        string `json:"vehicle_type"`
+	OwnerName          string `json:"owner_name"`
+	// Stage is pending, under_review or pending_inspection
+	Stage          string    `json:"stage"`
+	StageEnteredAt time.Time `json:"stage_entered_at"`
+	Deadline       time.Time `json:"deadline"`
+	// SLAStatus is on_track, due_soon or breached
+	SLAStatus           string     `json:"sla_status"`
+	Escalated           bool       `json:"escalated"`
+	AssignedTo          *int       `json:"assigned_to"`
+	AssignedName        string     `json:"assigned_name,omitempty"`
+	AssignedAt          *time.Time `json:"assigned_at,omitempty"`
+	AssignmentExpiresAt *time.Time `json:"assignment_expires_at,omitempty"`
+}
+
+// Verifier is a user who can be handed verification work
+type Verifier struct {
+	ID             int        `json:"id"`
+	Name           string     `json:"name"`
+	Role           string     `json:"role"`
+	LastAssignedAt *time.Time `json:"last_assigned_at"`
+}
+
+// VerifierWorkload is what a verifier holds now and what they decided
+// over the report's period
+type VerifierWorkload struct {
+	Verifier
+	Assigned int `json:"assigned"`
+	// Breached are assigned submissions past their SLA
+	Breached        int     `json:"breached"`
+	Claimed         int     `json:"claimed"`
+	Decided         int     `json:"decided"`
+	Approved        int     `json:"approved"`
+	Rejected        int     `json:"rejected"`
+	NeedsCorrection int     `json:"needs_correction"`
+	DecidedPerDay   float64 `json:"decided_per_day"`
+}
+
+// VerificationWorkload reports the queue and every verifier's share of it
+type VerificationWorkload struct {
+	Days       int                `json:"days"`
+	Queued     int                `json:"queued"`
+	Unassigned int                `json:"unassigned"`
+	Breached   int                `json:"breached"`
+	Verifiers  []VerifierWorkload `json:"verifiers"`
+}
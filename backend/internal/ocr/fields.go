@@ -0,0 +1,271 @@
+package ocr
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How much a value's format lowers the confidence in it
+const (
+	fitExact     = 1.0
+	fitCorrected = 0.85 // characters had to be swapped to fit the format
+	fitLoose     = 0.6  // readable but outside the expected format
+	fitPoor      = 0.3
+)
+
+// label is a field printed as "LABEL : value" on one line
+type label struct {
+	field string
+	re    *regexp.Regexp
+	parse func(raw string) (string, float64)
+}
+
+func newLabel(field, pattern string, parse func(string) (string, float64)) label {
+	return label{field: field, re: regexp.MustCompile(`(?i)^[^A-Z0-9]*(?:` + pattern + `)\b`), parse: parse}
+}
+
+// parseLabelled reads the first line that carries each label
+func parseLabelled(text *Text, labels []label) Fields {
+	fields := Fields{}
+	for _, line := range text.Lines {
+		for _, l := range labels {
+			if _, found := fields[l.field]; found {
+				continue
+			}
+			loc := l.re.FindStringIndex(line.Text)
+			if loc == nil {
+				continue
+			}
+			value, fit := l.parse(strings.TrimLeft(line.Text[loc[1]:], " :;.=_|"))
+			if value == "" {
+				continue
+			}
+			fields[l.field] = Field{Value: value, Confidence: math.Round(line.Confidence*fit*1000) / 1000}
+			break
+		}
+	}
+	return fields
+}
+
+// Characters OCR commonly confuses, mapped to what they stand for in a
+// field that only holds digits or only letters
+var (
+	asDigit  = map[rune]rune{'O': '0', 'Q': '0', 'D': '0', 'I': '1', 'L': '1', '|': '1', 'Z': '2', 'S': '5', 'B': '8', 'G': '6', 'T': '7'}
+	asLetter = map[rune]rune{'0': 'O', '1': 'I', '2': 'Z', '4': 'A', '5': 'S', '6': 'G', '7': 'T', '8': 'B'}
+)
+
+// swap replaces characters found in table and counts the replacements
+func swap(s string, table map[rune]rune) (string, int) {
+	swapped := 0
+	out := []rune(s)
+	for i, r := range out {
+		if to, ok := table[r]; ok {
+			out[i] = to
+			swapped++
+		}
+	}
+	return string(out), swapped
+}
+
+func fitOf(swapped int) float64 {
+	if swapped > 0 {
+		return fitCorrected
+	}
+	return fitExact
+}
+
+var nonAlnum = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// alnum upper-cases s and drops everything but letters and digits
+func alnum(s string) string {
+	return nonAlnum.ReplaceAllString(strings.ToUpper(s), "")
+}
+
+var (
+	plateParts   = regexp.MustCompile(`^([A-Z0-9]{1,2})\s+([A-Z0-9]{1,4})\s+([A-Z0-9]{1,3})$`)
+	plateCompact = regexp.MustCompile(`^([A-Z]{1,2})(\d{1,4})([A-Z]{0,3})$`)
+)
+
+// parsePlate reads a registration number such as "B 1234 ABC": a one or
+// two letter region, up to four digits and up to three letters
+func parsePlate(raw string) (string, float64) {
+	cleaned := strings.Join(strings.Fields(nonAlnum.ReplaceAllString(strings.ToUpper(raw), " ")), " ")
+	if cleaned == "" {
+		return "", 0
+	}
+
+	if m := plateParts.FindStringSubmatch(cleaned); m != nil {
+		region, a := swap(m[1], asLetter)
+		number, b := swap(m[2], asDigit)
+		suffix, c := swap(m[3], asLetter)
+		return region + " " + number + " " + suffix, fitOf(a + b + c)
+	}
+	if m := plateCompact.FindStringSubmatch(strings.ReplaceAll(cleaned, " ", "")); m != nil {
+		return strings.TrimSpace(m[1] + " " + m[2] + " " + m[3]), fitExact
+	}
+	return cleaned, fitPoor
+}
+
+// parseNIK reads a 16 digit national identity number. Its digits encode
+// the region (province 11-94) and birth date, with 40 added to the day for
+// women, which catches most misreads.
+func parseNIK(raw string) (string, float64) {
+	s := alnum(raw)
+	if s == "" {
+		return "", 0
+	}
+	s, swapped := swap(s, asDigit)
+	if len(s) != 16 || strings.Trim(s, "0123456789") != "" {
+		return s, fitPoor
+	}
+	if !validNIK(s) {
+		return s, fitLoose
+	}
+	return s, fitOf(swapped)
+}
+
+func validNIK(nik string) bool {
+	province, _ := strconv.Atoi(nik[0:2])
+	day, _ := strconv.Atoi(nik[6:8])
+	month, _ := strconv.Atoi(nik[8:10])
+	if day > 40 {
+		day -= 40
+	}
+	return province >= 11 && province <= 94 && day >= 1 && day <= 31 && month >= 1 && month <= 12
+}
+
+// parseChassis reads a chassis number, a 17 character VIN on current
+// vehicles. VINs never contain I, O or Q.
+func parseChassis(raw string) (string, float64) {
+	s := alnum(raw)
+	if s == "" {
+		return "", 0
+	}
+	s, swapped := swap(s, map[rune]rune{'O': '0', 'Q': '0', 'I': '1'})
+	switch {
+	case len(s) == 17:
+		return s, fitOf(swapped)
+	case len(s) >= 10 && len(s) <= 20:
+		return s, fitLoose
+	}
+	return s, fitPoor
+}
+
+// parseEngine reads an engine number, which has no fixed format
+func parseEngine(raw string) (string, float64) {
+	s := alnum(raw)
+	if s == "" {
+		return "", 0
+	}
+	if len(s) >= 5 && len(s) <= 15 {
+		return s, fitExact
+	}
+	return s, fitPoor
+}
+
+var datePattern = regexp.MustCompile(`(\d{1,2})\s*[-/.]\s*(\d{1,2})\s*[-/.]\s*(\d{4})`)
+
+// parseDate reads a DD-MM-YYYY date (any of - / . as separator) and
+// returns it as YYYY-MM-DD
+func parseDate(raw string) (string, float64) {
+	upper := strings.ToUpper(raw)
+	s, _ := swap(upper, asDigit)
+	loc := datePattern.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return "", 0
+	}
+	m := datePattern.FindStringSubmatch(s)
+	// Only swaps inside the date count against it
+	_, swapped := swap(upper[loc[0]:loc[1]], asDigit)
+	day, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	year, _ := strconv.Atoi(m[3])
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day || int(date.Month()) != month || year < 1900 || year > 2100 {
+		return "", 0
+	}
+	return date.Format("2006-01-02"), fitOf(swapped)
+}
+
+// parseExpiry is parseDate, but also accepts the lifetime validity of
+// newer KTPs
+func parseExpiry(raw string) (string, float64) {
+	if strings.Contains(strings.ToUpper(raw), "SEUMUR") {
+		return "SEUMUR HIDUP", fitExact
+	}
+	return parseDate(raw)
+}
+
+// parseYear reads a four digit manufacturing year
+func parseYear(raw string) (string, float64) {
+	s, swapped := swap(alnum(raw), asDigit)
+	if len(s) < 4 {
+		return "", 0
+	}
+	s = s[:4]
+	year, err := strconv.Atoi(s)
+	if err != nil {
+		return "", 0
+	}
+	if year < 1950 || year > time.Now().Year()+1 {
+		return s, fitPoor
+	}
+	return s, fitOf(swapped)
+}
+
+var (
+	textJunk = regexp.MustCompile(`[^A-Z0-9 .,'/()-]+`)
+	hasDigit = regexp.MustCompile(`\d`)
+)
+
+// parseText cleans a free text value such as an address
+func parseText(raw string) (string, float64) {
+	s := strings.Join(strings.Fields(textJunk.ReplaceAllString(strings.ToUpper(raw), " ")), " ")
+	s = strings.Trim(s, " .,-/")
+	if s == "" {
+		return "", 0
+	}
+	return s, fitExact
+}
+
+// parseName is parseText for names, which hold no digits
+func parseName(raw string) (string, float64) {
+	s, fit := parseText(raw)
+	if hasDigit.MatchString(s) {
+		return s, fitLoose
+	}
+	return s, fit
+}
+
+// parseGender reads LAKI-LAKI or PEREMPUAN; the KTP prints the blood type
+// on the same line
+func parseGender(raw string) (string, float64) {
+	s := strings.ToUpper(raw)
+	switch {
+	case strings.Contains(s, "LAKI"):
+		return "LAKI-LAKI", fitExact
+	case strings.Contains(s, "PEREMPUAN"):
+		return "PEREMPUAN", fitExact
+	case strings.Contains(s, "PEREM") || strings.Contains(s, "EMPUAN"):
+		return "PEREMPUAN", fitCorrected
+	}
+	return "", 0
+}
+
+var rtrwPattern = regexp.MustCompile(`^(\d{1,3})\s*/\s*(\d{1,3})`)
+
+// parseRTRW reads the neighbourhood numbers, e.g. 001/002
+func parseRTRW(raw string) (string, float64) {
+	s, swapped := swap(strings.ToUpper(strings.TrimSpace(raw)), asDigit)
+	m := rtrwPattern.FindStringSubmatch(s)
+	if m == nil {
+		return "", 0
+	}
+	rt, _ := strconv.Atoi(m[1])
+	rw, _ := strconv.Atoi(m[2])
+	return fmt.Sprintf("%03d/%03d", rt, rw), fitOf(swapped)
+}
@@ -0,0 +1,72 @@
+package ocr
+
+import (
+	"math"
+	"strings"
+)
+
+// KTP field names
+const (
+	KTPNIK           = "nik"
+	KTPName          = "name"
+	KTPBirthPlace    = "birth_place"
+	KTPBirthDate     = "birth_date"
+	KTPGender        = "gender"
+	KTPAddress       = "address"
+	KTPRTRW          = "rt_rw"
+	KTPVillage       = "village"
+	KTPDistrict      = "district"
+	KTPCity          = "city"
+	KTPProvince      = "province"
+	KTPReligion      = "religion"
+	KTPMaritalStatus = "marital_status"
+	KTPOccupation    = "occupation"
+	KTPNationality   = "nationality"
+	KTPExpiryDate    = "expiry_date"
+)
+
+// KTPRequired are the fields identity checks rely on
+var KTPRequired = []string{KTPNIK, KTPName, KTPBirthDate, KTPAddress}
+
+// ktpBirth holds "PLACE, DD-MM-YYYY" until it is split
+const ktpBirth = "birth"
+
+var ktpLabels = []label{
+	newLabel(KTPProvince, `PROVINSI`, parseText),
+	newLabel(KTPCity, `KOTA|KABUPATEN|KAB\.`, parseText),
+	newLabel(KTPNIK, `N[I1L]K`, parseNIK),
+	newLabel(KTPName, `NAMA`, parseName),
+	newLabel(ktpBirth, `TEMPAT\s*/?\s*TGL\.?\s*LAHIR|TEMPAT\s*/?\s*TANGGAL\s*LAHIR|TTL`, parseText),
+	newLabel(KTPGender, `JENIS\s*KELAMIN`, parseGender),
+	newLabel(KTPAddress, `ALAMAT`, parseText),
+	newLabel(KTPRTRW, `RT\s*/?\s*RW`, parseRTRW),
+	newLabel(KTPVillage, `KEL\s*/?\s*DESA|KELURAHAN|DESA`, parseText),
+	newLabel(KTPDistrict, `KECAMATAN`, parseText),
+	newLabel(KTPReligion, `AGAMA`, parseText),
+	newLabel(KTPMaritalStatus, `STATUS\s*PERKAWINAN`, parseText),
+	newLabel(KTPOccupation, `PEKERJAAN`, parseText),
+	newLabel(KTPNationality, `KEWARGANEGARAAN`, parseText),
+	newLabel(KTPExpiryDate, `BERLAKU\s*HINGGA`, parseExpiry),
+}
+
+// ParseKTP reads the fields of an identity card
+func ParseKTP(text *Text) Fields {
+	fields := parseLabelled(text, ktpLabels)
+
+	if birth, ok := fields[ktpBirth]; ok {
+		delete(fields, ktpBirth)
+		place := birth.Value
+		if i := strings.Index(place, ","); i >= 0 {
+			place = place[:i]
+		} else if loc := datePattern.FindStringIndex(place); loc != nil {
+			place = place[:loc[0]]
+		}
+		if place = strings.TrimSpace(place); place != "" {
+			fields[KTPBirthPlace] = Field{Value: place, Confidence: birth.Confidence}
+		}
+		if date, fit := parseDate(birth.Value); date != "" {
+			fields[KTPBirthDate] = Field{Value: date, Confidence: math.Round(birth.Confidence*fit*1000) / 1000}
+		}
+	}
+	return fields
+}
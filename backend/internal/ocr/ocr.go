@@ -0,0 +1,65 @@
+// Package ocr reads text from document photos and parses the fields of
+// Indonesian vehicle registration (STNK) and identity (KTP) cards.
+package ocr
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrUnavailable means the OCR engine cannot run, e.g. it is not
+	// installed
+	ErrUnavailable = errors.New("OCR engine unavailable")
+	ErrNoText      = errors.New("no text found in image")
+)
+
+// Text is what an engine read from an image, one entry per line in
+// reading order
+type Text struct {
+	Lines []Line
+}
+
+// Line is one line of recognised text. Confidence is the engine's mean
+// word confidence, from 0 to 1.
+type Line struct {
+	Text       string
+	Confidence float64
+}
+
+// String joins the lines with newlines
+func (t *Text) String() string {
+	lines := make([]string, len(t.Lines))
+	for i, l := range t.Lines {
+		lines[i] = l.Text
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Field is one parsed value. Confidence combines the engine's confidence
+// in the line with how well the value fits the field's format.
+type Field struct {
+	Value      string
+	Confidence float64
+}
+
+// Fields are parsed values by field name
+type Fields map[string]Field
+
+// Value returns the value of a field, or ""
+func (f Fields) Value(name string) string {
+	return f[name].Value
+}
+
+// Score is the mean confidence over required, counting missing fields
+// as zero
+func (f Fields) Score(required []string) float64 {
+	if len(required) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, name := range required {
+		sum += f[name].Confidence
+	}
+	return sum / float64(len(required))
+}
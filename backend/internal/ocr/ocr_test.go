@@ -0,0 +1,159 @@
+package ocr
+
+import (
+	"strings"
+	"testing"
+)
+
+// lines builds engine output with one confidence for every line
+func lines(confidence float64, text string) *Text {
+	t := &Text{}
+	for _, l := range strings.Split(strings.TrimSpace(text), "\n") {
+		t.Lines = append(t.Lines, Line{Text: strings.TrimSpace(l), Confidence: confidence})
+	}
+	return t
+}
+
+func TestParseTSV(t *testing.T) {
+	tsv := "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+		"1\t1\t0\t0\t0\t0\t0\t0\t800\t600\t-1\t\n" +
+		"4\t1\t1\t1\t1\t0\t10\t10\t300\t20\t-1\t\n" +
+		"5\t1\t1\t1\t1\t1\t10\t10\t40\t20\t90\tNO.\n" +
+		"5\t1\t1\t1\t1\t2\t60\t10\t80\t20\t80.5\tRANGKA\n" +
+		"5\t1\t1\t1\t2\t1\t10\t40\t40\t20\t95\tWARNA\n" +
+		"5\t1\t1\t1\t2\t2\t60\t40\t10\t20\t-1\t \n" +
+		"5\t1\t1\t1\t2\t3\t80\t40\t60\t20\t75\tHITAM\n"
+
+	text, err := ParseTSV([]byte(tsv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(text.Lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %+v", text.Lines)
+	}
+	if text.Lines[0].Text != "NO. RANGKA" || text.Lines[0].Confidence != 0.8525 {
+		t.Errorf("Unexpected first line %+v", text.Lines[0])
+	}
+	if text.Lines[1].Text != "WARNA HITAM" || text.Lines[1].Confidence != 0.85 {
+		t.Errorf("Unexpected second line %+v", text.Lines[1])
+	}
+}
+
+func TestParseSTNK(t *testing.T) {
+	text := lines(0.9, `
+		SURAT TANDA NOMOR KENDARAAN BERMOTOR
+		NO. REGISTRASI : B 1234 ABC
+		NAMA PEMILIK : AHMAD SURYANTO
+		ALAMAT : JL. MERDEKA NO. 123, JAKARTA
+		MERK : TOYOTA
+		TYPE : AVANZA 1.3 G
+		TAHUN PEMBUATAN : 2O20
+		NO. RANGKA/NIK : MHKA1BA1HKK123456
+		N0. MESIN : 3SZ1234567
+		WARNA : HITAM
+		BERLAKU SAMPAI : 31-12-2027`)
+
+	fields := ParseSTNK(text)
+	want := map[string]string{
+		STNKPlateNumber:   "B 1234 ABC",
+		STNKOwnerName:     "AHMAD SURYANTO",
+		STNKAddress:       "JL. MERDEKA NO. 123, JAKARTA",
+		STNKBrand:         "TOYOTA",
+		STNKModel:         "AVANZA 1.3 G",
+		STNKYear:          "2020",
+		STNKChassisNumber: "MHKA1BA1HKK123456",
+		STNKEngineNumber:  "3SZ1234567",
+		STNKColor:         "HITAM",
+		STNKExpiryDate:    "2027-12-31",
+	}
+	for field, value := range want {
+		if got := fields.Value(field); got != value {
+			t.Errorf("%s = %q, want %q", field, got, value)
+		}
+	}
+	if c := fields[STNKPlateNumber].Confidence; c != 0.9 {
+		t.Errorf("Expected a clean plate to keep the line confidence, got %v", c)
+	}
+	if c := fields[STNKYear].Confidence; c >= 0.9 {
+		t.Errorf("Expected a corrected year to lose confidence, got %v", c)
+	}
+	if score := fields.Score(STNKRequired); score < 0.85 {
+		t.Errorf("Expected a high document score, got %v", score)
+	}
+}
+
+func TestParseKTP(t *testing.T) {
+	text := lines(0.8, `
+		PROVINSI DKI JAKARTA
+		KOTA JAKARTA PUSAT
+		NIK : 3171O14505850002
+		Nama : SITI AMINAH
+		Tempat/Tgl Lahir : JAKARTA, 05-05-1985
+		Jenis Kelamin : PEREMPUAN Gol. Darah : O
+		Alamat : JL. MERDEKA NO. 123
+		RT/RW : 1/2
+		Kel/Desa : MENTENG
+		Kecamatan : MENTENG
+		Agama : ISLAM
+		Status Perkawinan : KAWIN
+		Pekerjaan : KARYAWAN SWASTA
+		Kewarganegaraan : WNI
+		Berlaku Hingga : SEUMUR HIDUP`)
+
+	fields := ParseKTP(text)
+	want := map[string]string{
+		KTPProvince:      "DKI JAKARTA",
+		KTPCity:          "JAKARTA PUSAT",
+		KTPNIK:           "3171014505850002",
+		KTPName:          "SITI AMINAH",
+		KTPBirthPlace:    "JAKARTA",
+		KTPBirthDate:     "1985-05-05",
+		KTPGender:        "PEREMPUAN",
+		KTPRTRW:          "001/002",
+		KTPVillage:       "MENTENG",
+		KTPDistrict:      "MENTENG",
+		KTPMaritalStatus: "KAWIN",
+		KTPExpiryDate:    "SEUMUR HIDUP",
+	}
+	for field, value := range want {
+		if got := fields.Value(field); got != value {
+			t.Errorf("%s = %q, want %q", field, got, value)
+		}
+	}
+	if c := fields[KTPNIK].Confidence; c != 0.68 {
+		t.Errorf("Expected the corrected NIK at 0.8 x 0.85, got %v", c)
+	}
+	if score := fields.Score(KTPRequired); score <= 0.7 {
+		t.Errorf("Expected a good document score, got %v", score)
+	}
+}
+
+func TestFieldParsers(t *testing.T) {
+	cases := []struct {
+		name  string
+		parse func(string) (string, float64)
+		raw   string
+		value string
+		fit   float64
+	}{
+		{"plate", parsePlate, "8 1234 A8C", "B 1234 ABC", fitCorrected},
+		{"compact plate", parsePlate, "AB1234CD", "AB 1234 CD", fitExact},
+		{"unreadable plate", parsePlate, "12 ABCD", "12 ABCD", fitPoor},
+		{"nik", parseNIK, "3201 2345 0101 0001", "3201234501010001", fitExact},
+		{"nik bad region", parseNIK, "0001234501010001", "0001234501010001", fitLoose},
+		{"short nik", parseNIK, "320123", "320123", fitPoor},
+		{"chassis", parseChassis, "MHKA1BA1HKKI23456", "MHKA1BA1HKK123456", fitCorrected},
+		{"short chassis", parseChassis, "MH12", "MH12", fitPoor},
+		{"date", parseDate, "31/12/2025", "2025-12-31", fitExact},
+		{"invalid date", parseDate, "31-02-2025", "", 0},
+		{"year", parseYear, "2019", "2019", fitExact},
+		{"name with digits", parseName, "AHMAD 5URYANTO", "AHMAD 5URYANTO", fitLoose},
+		{"gender", parseGender, "LAKI-LAKI Gol. Darah : A", "LAKI-LAKI", fitExact},
+	}
+	for _, tc := range cases {
+		value, fit := tc.parse(tc.raw)
+		if value != tc.value || fit != tc.fit {
+			t.Errorf("%s: got %q (%v), want %q (%v)", tc.name, value, fit, tc.value, tc.fit)
+		}
+	}
+}
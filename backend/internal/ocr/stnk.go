@@ -0,0 +1,43 @@
+package ocr
+
+// STNK field names
+const (
+	STNKPlateNumber   = "plate_number"
+	STNKOwnerName     = "owner_name"
+	STNKNIK           = "nik"
+	STNKAddress       = "address"
+	STNKBrand         = "vehicle_brand"
+	STNKModel         = "vehicle_model"
+	STNKYear          = "vehicle_year"
+	STNKChassisNumber = "chassis_number"
+	STNKEngineNumber  = "engine_number"
+	STNKColor         = "vehicle_color"
+	STNKExpiryDate    = "expiry_date"
+	STNKIssueDate     = "issue_date"
+)
+
+// STNKRequired are the fields verification relies on; a missing one
+// lowers the document score
+var STNKRequired = []string{STNKPlateNumber, STNKOwnerName, STNKChassisNumber, STNKEngineNumber, STNKExpiryDate}
+
+// stnkLabels follow the printed STNK. More specific labels come first, so
+// "NAMA PEMILIK" is not read as a bare "NAMA".
+var stnkLabels = []label{
+	newLabel(STNKPlateNumber, `N[O0]\.?\s*(?:REG(?:ISTRASI)?|POL(?:ISI)?)|NOMOR\s*(?:REGISTRASI|POLISI)|TNKB`, parsePlate),
+	newLabel(STNKOwnerName, `NAMA\s*PEMILIK|NAMA`, parseName),
+	newLabel(STNKNIK, `NIK|N[O0]\.?\s*KTP|NOMOR\s*KTP`, parseNIK),
+	newLabel(STNKAddress, `ALAMAT`, parseText),
+	newLabel(STNKBrand, `MERK|MEREK`, parseText),
+	newLabel(STNKModel, `TYPE|TIPE`, parseText),
+	newLabel(STNKYear, `TAHUN\s*(?:PEMBUATAN|PERAKITAN)|THN\.?\s*(?:PEMBUATAN|PEMB\.?)`, parseYear),
+	newLabel(STNKChassisNumber, `N[O0]\.?\s*RANGKA\s*/\s*NIK|N[O0]\.?\s*RANGKA|NOMOR\s*RANGKA|VIN`, parseChassis),
+	newLabel(STNKEngineNumber, `N[O0]\.?\s*MESIN|NOMOR\s*MESIN`, parseEngine),
+	newLabel(STNKColor, `WARNA(?:\s*TNKB)?`, parseText),
+	newLabel(STNKExpiryDate, `BERLAKU\s*(?:SAMPAI|HINGGA|S\.?\s*D\.?)|MASA\s*BERLAKU`, parseDate),
+	newLabel(STNKIssueDate, `TANGGAL\s*(?:DAFTAR|PENDAFTARAN|TERBIT|PENERBITAN)|TGL\.?\s*(?:DAFTAR|TERBIT)`, parseDate),
+}
+
+// ParseSTNK reads the fields of a vehicle registration certificate
+func ParseSTNK(text *Text) Fields {
+	return parseLabelled(text, stnkLabels)
+}
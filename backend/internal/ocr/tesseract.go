@@ -0,0 +1,126 @@
+package ocr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tesseract runs the tesseract command line tool. It reads the image from
+// stdin and parses the TSV output, which carries a confidence per word.
+type Tesseract struct {
+	Path      string
+	Languages string
+	Timeout   time.Duration
+}
+
+// NewTesseract uses the tesseract binary at path (looked up in PATH when
+// it has no slash) with languages such as "ind+eng"
+func NewTesseract(path, languages string, timeout time.Duration) *Tesseract {
+	return &Tesseract{Path: path, Languages: languages, Timeout: timeout}
+}
+
+// Available reports whether the binary can be found
+func (t *Tesseract) Available() bool {
+	_, err := exec.LookPath(t.Path)
+	return err == nil
+}
+
+// Recognize reads the text of a PNG or JPEG image
+func (t *Tesseract) Recognize(ctx context.Context, image []byte) (*Text, error) {
+	if !t.Available() {
+		return nil, ErrUnavailable
+	}
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	// psm 6 reads the card as one uniform block, which keeps each label
+	// on the same line as its value
+	cmd := exec.CommandContext(ctx, t.Path, "stdin", "stdout", "-l", t.Languages, "--psm", "6", "tsv")
+	cmd.Stdin = bytes.NewReader(image)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("tesseract timed out after %s", t.Timeout)
+		}
+		return nil, fmt.Errorf("tesseract failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	text, err := ParseTSV(stdout.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(text.Lines) == 0 {
+		return nil, ErrNoText
+	}
+	return text, nil
+}
+
+type lineKey struct {
+	page, block, par, line int
+}
+
+// ParseTSV groups the words of tesseract's TSV output into lines
+func ParseTSV(data []byte) (*Text, error) {
+	text := &Text{}
+	index := map[lineKey]int{}
+	counts := []int{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for first := true; scanner.Scan(); first = false {
+		cols := strings.Split(scanner.Text(), "\t")
+		if first || len(cols) < 12 || cols[0] != "5" {
+			// Header, or a page/block/paragraph/line row
+			continue
+		}
+		word := strings.TrimSpace(cols[11])
+		conf, err := strconv.ParseFloat(cols[10], 64)
+		if word == "" || err != nil || conf < 0 {
+			continue
+		}
+
+		var key lineKey
+		nums := []*int{&key.page, &key.block, &key.par, &key.line}
+		for i, n := range nums {
+			if *n, err = strconv.Atoi(cols[i+1]); err != nil {
+				return nil, fmt.Errorf("invalid tesseract output: %q", scanner.Text())
+			}
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(text.Lines)
+			index[key] = i
+			text.Lines = append(text.Lines, Line{})
+			counts = append(counts, 0)
+		}
+		l := &text.Lines[i]
+		if l.Text != "" {
+			l.Text += " "
+		}
+		l.Text += word
+		l.Confidence += conf / 100
+		counts[i]++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range text.Lines {
+		text.Lines[i].Confidence /= float64(counts[i])
+	}
+	return text, nil
+}
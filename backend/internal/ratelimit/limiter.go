@@ -0,0 +1,110 @@
+// Package ratelimit provides token bucket rate limiting and per-account
+// login lockout.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: Burst requests at once, refilled at Rate
+// requests per second
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute allows n requests per minute with a burst of n
+func PerMinute(n int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: n}
+}
+
+// Store holds bucket state. MemoryStore keeps it in the process; a shared
+// store (e.g. Redis) can be plugged in when running several instances.
+type Store interface {
+	// Take removes one token from key's bucket. When the bucket is empty it
+	// returns false and how long until a token is available.
+	Take(key string, limit Limit, now time.Time) (bool, time.Duration)
+}
+
+// Limiter applies one Limit to many keys
+type Limiter struct {
+	store  Store
+	limit  Limit
+	prefix string
+}
+
+// NewLimiter limits keys in store; prefix keeps limiters sharing a store apart
+func NewLimiter(store Store, prefix string, limit Limit) *Limiter {
+	return &Limiter{store: store, limit: limit, prefix: prefix}
+}
+
+// Allow reports whether key may make a request now, or how long it must wait
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	return l.store.Take(l.prefix+key, l.limit, time.Now())
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// sweepInterval is how often MemoryStore drops buckets that have refilled
+const sweepInterval = time.Minute
+
+// MemoryStore is an in-process Store
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(limit, now)
+	}
+
+	burst := float64(limit.Burst)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(burst, b.tokens+elapsed*limit.Rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if limit.Rate <= 0 {
+		return false, time.Hour
+	}
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets that would be full by now; a missing bucket is
+// treated as full, so this only frees memory
+func (s *MemoryStore) sweep(limit Limit, now time.Time) {
+	s.lastSweep = now
+	if limit.Rate <= 0 {
+		return
+	}
+	for key, b := range s.buckets {
+		refill := time.Duration((float64(limit.Burst) - b.tokens) / limit.Rate * float64(time.Second))
+		if now.Sub(b.last) >= refill {
+			delete(s.buckets, key)
+		}
+	}
+}
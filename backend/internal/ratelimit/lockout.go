@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// lockoutResetAfter clears an account's lockout history after this long
+// without a failed attempt
+const lockoutResetAfter = 24 * time.Hour
+
+type accountState struct {
+	failures    int
+	lockouts    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// Lockout locks an account after MaxAttempts failed logins in a row. The
+// first lock lasts Base; each further lock doubles, up to Max.
+type Lockout struct {
+	maxAttempts int
+	base        time.Duration
+	max         time.Duration
+
+	mu        sync.Mutex
+	accounts  map[string]*accountState
+	lastSweep time.Time
+}
+
+func NewLockout(maxAttempts int, base, max time.Duration) *Lockout {
+	return &Lockout{maxAttempts: maxAttempts, base: base, max: max, accounts: make(map[string]*accountState)}
+}
+
+// UserKey is the lockout key of a user's password logins, shared by
+// their username and email
+func UserKey(userID int) string {
+	return "login:" + strconv.Itoa(userID)
+}
+
+// Locked reports whether account is locked at now and for how much longer
+func (l *Lockout) Locked(account string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.accounts[account]
+	if !ok || !now.Before(state.lockedUntil) {
+		return false, 0
+	}
+	return true, state.lockedUntil.Sub(now)
+}
+
+// Fail records a failed login. When it locks the account it returns the
+// lock duration and the number of locks so far.
+func (l *Lockout) Fail(account string, now time.Time) (time.Duration, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+	state, ok := l.accounts[account]
+	if !ok {
+		state = &accountState{}
+		l.accounts[account] = state
+	}
+	state.failures++
+	state.lastFailure = now
+	if state.failures < l.maxAttempts {
+		return 0, state.lockouts
+	}
+
+	lock := l.base << uint(state.lockouts)
+	if lock > l.max || lock <= 0 {
+		lock = l.max
+	}
+	state.failures = 0
+	state.lockouts++
+	state.lockedUntil = now.Add(lock)
+	return lock, state.lockouts
+}
+
+// Succeed clears an account's failures after a successful login
+func (l *Lockout) Succeed(account string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.accounts, account)
+}
+
+// sweep forgets accounts that are unlocked and have not failed for a while
+func (l *Lockout) sweep(now time.Time) {
+	l.lastSweep = now
+	for key, state := range l.accounts {
+		if !now.Before(state.lockedUntil) && now.Sub(state.lastFailure) >= lockoutResetAfter {
+			delete(l.accounts, key)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	limit := PerMinute(3)
+	now := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := store.Take("ip:1", limit, now); !ok {
+			t.Fatalf("Expected request %d within the burst to pass", i+1)
+		}
+	}
+	ok, wait := store.Take("ip:1", limit, now)
+	if ok {
+		t.Fatal("Expected request over the burst to be refused")
+	}
+	if wait != 20*time.Second {
+		t.Errorf("Expected to wait 20s for the next token, got %v", wait)
+	}
+
+	if ok, _ := store.Take("ip:2", limit, now); !ok {
+		t.Error("Expected other keys to have their own bucket")
+	}
+	if ok, _ := store.Take("ip:1", limit, now.Add(20*time.Second)); !ok {
+		t.Error("Expected a token after the refill interval")
+	}
+}
+
+func TestLimiterPrefixesKeys(t *testing.T) {
+	store := NewMemoryStore()
+	byIP := NewLimiter(store, "ip:", PerMinute(1))
+	byUser := NewLimiter(store, "user:", PerMinute(1))
+
+	if ok, _ := byIP.Allow("7"); !ok {
+		t.Fatal("Expected first IP request to pass")
+	}
+	if ok, _ := byUser.Allow("7"); !ok {
+		t.Error("Expected user 7 not to share IP 7's bucket")
+	}
+	if ok, wait := byIP.Allow("7"); ok || wait <= 0 {
+		t.Errorf("Expected second IP request to be refused with a wait, got %v, %v", ok, wait)
+	}
+}
+
+func TestLockoutBackoff(t *testing.T) {
+	lockout := NewLockout(3, time.Minute, 5*time.Minute)
+	now := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	account := UserKey(7)
+
+	fail := func(times int) time.Duration {
+		var lock time.Duration
+		for i := 0; i < times; i++ {
+			lock, _ = lockout.Fail(account, now)
+		}
+		return lock
+	}
+
+	if lock := fail(2); lock != 0 {
+		t.Fatalf("Expected no lock before the third failure, got %v", lock)
+	}
+	if lock := fail(1); lock != time.Minute {
+		t.Fatalf("Expected a 1 minute lock, got %v", lock)
+	}
+	if locked, wait := lockout.Locked("login:7", now.Add(30*time.Second)); !locked || wait != 30*time.Second {
+		t.Errorf("Expected account locked for 30s more, got %v, %v", locked, wait)
+	}
+
+	now = now.Add(time.Minute)
+	if locked, _ := lockout.Locked(account, now); locked {
+		t.Error("Expected lock to expire")
+	}
+	if lock := fail(3); lock != 2*time.Minute {
+		t.Errorf("Expected second lock to double to 2 minutes, got %v", lock)
+	}
+	now = now.Add(2 * time.Minute)
+	fail(3)
+	now = now.Add(4 * time.Minute)
+	if lock := fail(3); lock != 5*time.Minute {
+		t.Errorf("Expected lock capped at 5 minutes, got %v", lock)
+	}
+
+	lockout.Succeed(account)
+	if locked, _ := lockout.Locked(account, now); locked {
+		t.Error("Expected a successful login to clear the lock")
+	}
+}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Breaker stops asking a registry that keeps failing. After failures
+// errors in a row it answers ErrUnavailable for cooldown without calling
+// the provider; then one lookup is let through, which closes the breaker
+// when it succeeds and opens it again when it fails.
+type Breaker struct {
+	provider Provider
+	failures int
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	failed    int
+	openUntil time.Time
+}
+
+func NewBreaker(provider Provider, failures int, cooldown time.Duration) *Breaker {
+	return &Breaker{provider: provider, failures: failures, cooldown: cooldown, now: time.Now}
+}
+
+func (b *Breaker) Lookup(ctx context.Context, q Query) (*Record, error) {
+	b.mu.Lock()
+	now := b.now()
+	if b.failed >= b.failures {
+		if now.Before(b.openUntil) {
+			b.mu.Unlock()
+			return nil, fmt.Errorf("%w: too many failures, retrying after %s", ErrUnavailable, b.openUntil.Format(time.RFC3339))
+		}
+		// Let this lookup through; the others wait out another cooldown
+		b.openUntil = now.Add(b.cooldown)
+	}
+	b.mu.Unlock()
+
+	record, err := b.provider.Lookup(ctx, q)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// A caller that gave up says nothing about the registry
+	if err != nil && ctx.Err() != nil {
+		return nil, err
+	}
+	if err != nil {
+		b.failed++
+		if b.failed >= b.failures {
+			b.openUntil = b.now().Add(b.cooldown)
+		}
+		return nil, err
+	}
+	b.failed = 0
+	return record, nil
+}
+
+// Open reports whether the breaker is refusing lookups
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failed >= b.failures && b.now().Before(b.openUntil)
+}
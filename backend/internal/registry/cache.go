@@ -0,0 +1,58 @@
+package registry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	record  *Record
+	expires time.Time
+}
+
+// Cached keeps a provider's answers, including "not registered", for a
+// TTL. Errors are not kept, so the next lookup asks again.
+type Cached struct {
+	provider Provider
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	entries   map[Query]cacheEntry
+	lastSweep time.Time
+}
+
+func NewCached(provider Provider, ttl time.Duration) *Cached {
+	return &Cached{provider: provider, ttl: ttl, now: time.Now, entries: make(map[Query]cacheEntry)}
+}
+
+func (c *Cached) Lookup(ctx context.Context, q Query) (*Record, error) {
+	key := q.key()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.record, nil
+	}
+
+	record, err := c.provider.Lookup(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastSweep) >= c.ttl {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.entries[key] = cacheEntry{record: record, expires: now.Add(c.ttl)}
+	return record, nil
+}
@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxResponse bounds how much of a registry response is read
+const maxResponse = 1 << 20
+
+// HTTP asks a registry's REST API:
+//
+//	GET <base URL>/vehicles/<registration number>?chassis_number=...&engine_number=...
+//
+// answered with a Record as JSON, or 404 when the vehicle is not
+// registered. An API key is sent as a bearer token.
+type HTTP struct {
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+}
+
+// NewHTTP asks the registry at baseURL, giving up on a request after
+// timeout
+func NewHTTP(baseURL, apiKey string, timeout time.Duration) *HTTP {
+	return &HTTP{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		Client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (h *HTTP) Lookup(ctx context.Context, q Query) (*Record, error) {
+	params := url.Values{}
+	if q.ChassisNumber != "" {
+		params.Set("chassis_number", q.ChassisNumber)
+	}
+	if q.EngineNumber != "" {
+		params.Set("engine_number", q.EngineNumber)
+	}
+	target := h.BaseURL + "/vehicles/" + url.PathEscape(Normalize(q.RegistrationNumber))
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if h.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.APIKey)
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return &Record{}, nil
+	default:
+		return nil, fmt.Errorf("registry answered %s", resp.Status)
+	}
+
+	var record Record
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponse)).Decode(&record); err != nil {
+		return nil, fmt.Errorf("invalid registry response: %v", err)
+	}
+	record.Found = true
+	return &record, nil
+}
@@ -0,0 +1,83 @@
+// Package registry looks vehicles up in the registries an admin checks a
+// submission against: Samsat (registration and vehicle tax), KIR
+// (periodic roadworthiness tests) and insurers.
+package registry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Registry kinds, which are also the cross-check types
+const (
+	Samsat    = "samsat"
+	KIR       = "kir"
+	Insurance = "insurance"
+)
+
+// Kinds lists every registry kind
+var Kinds = []string{Samsat, KIR, Insurance}
+
+// ErrUnavailable means a registry cannot be asked right now
+var ErrUnavailable = errors.New("registry not available")
+
+// Query identifies a vehicle. Registries look it up by registration
+// number; the chassis and engine number help those that need them.
+type Query struct {
+	RegistrationNumber string
+	ChassisNumber      string
+	EngineNumber       string
+}
+
+// key is the query with spacing and case that registries ignore removed
+func (q Query) key() Query {
+	return Query{
+		RegistrationNumber: Normalize(q.RegistrationNumber),
+		ChassisNumber:      Normalize(q.ChassisNumber),
+		EngineNumber:       Normalize(q.EngineNumber),
+	}
+}
+
+// Record is what a registry holds on a vehicle. Found is false when the
+// registry does not know it; the other fields are then empty.
+type Record struct {
+	Found              bool   `json:"found"`
+	RegistrationNumber string `json:"registration_number,omitempty"`
+	ChassisNumber      string `json:"chassis_number,omitempty"`
+	EngineNumber       string `json:"engine_number,omitempty"`
+	// ValidUntil is when the tax, test or policy runs out, as YYYY-MM-DD
+	ValidUntil string                 `json:"valid_until,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+}
+
+// Expiry parses ValidUntil
+func (r *Record) Expiry() (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, r.ValidUntil); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Provider looks a vehicle up in one registry. Records it returns are
+// shared and must not be changed.
+type Provider interface {
+	Lookup(ctx context.Context, q Query) (*Record, error)
+}
+
+// Normalize strips a registration, chassis or engine number down to its
+// letters and digits, upper case, so "b 1234 xyz" equals "B1234XYZ"
+func Normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return -1
+	}, s)
+}
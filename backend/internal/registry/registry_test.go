@@ -0,0 +1,131 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func stubServer(t *testing.T) (*Stub, *HTTP) {
+	stub := NewStub()
+	stub.APIKey = "secret"
+	stub.Set("B 1234 XYZ", Record{ChassisNumber: "MHKA1BA1HKK123456", ValidUntil: "2030-01-15",
+		Details: map[string]interface{}{"tax_status": "active"}})
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	return stub, NewHTTP(srv.URL+"/", "secret", time.Second)
+}
+
+func TestHTTPLookup(t *testing.T) {
+	stub, provider := stubServer(t)
+
+	record, err := provider.Lookup(context.Background(), Query{RegistrationNumber: "b1234xyz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !record.Found || record.ChassisNumber != "MHKA1BA1HKK123456" || record.Details["tax_status"] != "active" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if expiry, ok := record.Expiry(); !ok || expiry.Year() != 2030 {
+		t.Errorf("Expected the expiry to parse, got %v", expiry)
+	}
+
+	record, err = provider.Lookup(context.Background(), Query{RegistrationNumber: "L 1 A"})
+	if err != nil || record.Found {
+		t.Errorf("Expected an unregistered vehicle not to be found, got %+v %v", record, err)
+	}
+
+	stub.FailNext(1)
+	if _, err := provider.Lookup(context.Background(), Query{RegistrationNumber: "B 1234 XYZ"}); err == nil {
+		t.Error("Expected an error when the registry answers 503")
+	}
+
+	provider.APIKey = "wrong"
+	if _, err := provider.Lookup(context.Background(), Query{RegistrationNumber: "B 1234 XYZ"}); err == nil {
+		t.Error("Expected an error with the wrong key")
+	}
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	_, provider := stubServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := provider.Lookup(ctx, Query{RegistrationNumber: "B 1234 XYZ"}); err == nil {
+		t.Error("Expected a cancelled lookup to fail")
+	}
+}
+
+func TestCached(t *testing.T) {
+	stub, provider := stubServer(t)
+	cached := NewCached(provider, time.Hour)
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	cached.now = func() time.Time { return now }
+
+	for _, plate := range []string{"B 1234 XYZ", "b1234xyz", "L 1 A", "L1A"} {
+		if _, err := cached.Lookup(context.Background(), Query{RegistrationNumber: plate}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stub.Calls() != 2 {
+		t.Errorf("Expected one call per vehicle, found or not, got %d", stub.Calls())
+	}
+
+	now = now.Add(time.Hour)
+	stub.FailNext(1)
+	if _, err := cached.Lookup(context.Background(), Query{RegistrationNumber: "B 1234 XYZ"}); err == nil {
+		t.Error("Expected the expired entry to be looked up again")
+	}
+	if _, err := cached.Lookup(context.Background(), Query{RegistrationNumber: "B 1234 XYZ"}); err != nil {
+		t.Errorf("Expected the error not to be cached, got %v", err)
+	}
+	if stub.Calls() != 4 {
+		t.Errorf("Expected 4 calls, got %d", stub.Calls())
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	stub, provider := stubServer(t)
+	breaker := NewBreaker(provider, 2, time.Minute)
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	breaker.now = func() time.Time { return now }
+	q := Query{RegistrationNumber: "B 1234 XYZ"}
+
+	stub.FailNext(2)
+	breaker.Lookup(context.Background(), q)
+	breaker.Lookup(context.Background(), q)
+	if !breaker.Open() {
+		t.Fatal("Expected the breaker to open after 2 failures")
+	}
+	if _, err := breaker.Lookup(context.Background(), q); !errors.Is(err, ErrUnavailable) || stub.Calls() != 2 {
+		t.Errorf("Expected an open breaker not to call the registry, got %v after %d calls", err, stub.Calls())
+	}
+
+	// After the cooldown a failing trial opens it again
+	now = now.Add(time.Minute)
+	stub.FailNext(1)
+	if _, err := breaker.Lookup(context.Background(), q); err == nil || errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected the trial lookup to reach the registry, got %v", err)
+	}
+	if !breaker.Open() {
+		t.Error("Expected a failed trial to open the breaker again")
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := breaker.Lookup(context.Background(), q); err != nil {
+		t.Fatal(err)
+	}
+	if breaker.Open() || stub.Calls() != 4 {
+		t.Errorf("Expected a successful trial to close the breaker, %d calls", stub.Calls())
+	}
+
+	// Callers giving up do not count as registry failures
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	breaker.Lookup(ctx, q)
+	breaker.Lookup(ctx, q)
+	if breaker.Open() {
+		t.Error("Expected cancelled lookups not to open the breaker")
+	}
+}
@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Stub is an in-memory registry that speaks the protocol of HTTP, for
+// tests and for running the cross-checks locally:
+//
+//	srv := httptest.NewServer(stub)
+//	provider := registry.NewHTTP(srv.URL, stub.APIKey, time.Second)
+type Stub struct {
+	// APIKey, when set, is the bearer token every request must carry
+	APIKey string
+
+	mu      sync.Mutex
+	records map[string]Record
+	fail    int
+	calls   int
+}
+
+func NewStub() *Stub {
+	return &Stub{records: make(map[string]Record)}
+}
+
+// Set registers a vehicle
+func (s *Stub) Set(registrationNumber string, record Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[Normalize(registrationNumber)] = record
+}
+
+// FailNext makes the next n requests answer 503
+func (s *Stub) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = n
+}
+
+// Calls is the number of requests served
+func (s *Stub) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.calls++
+	fail := s.fail > 0
+	if fail {
+		s.fail--
+	}
+	record, found := s.records[Normalize(strings.TrimPrefix(r.URL.Path, "/vehicles/"))]
+	s.mu.Unlock()
+
+	switch {
+	case s.APIKey != "" && r.Header.Get("Authorization") != "Bearer "+s.APIKey:
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	case fail:
+		http.Error(w, "registry unavailable", http.StatusServiceUnavailable)
+	case r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, "/vehicles/"):
+		http.NotFound(w, r)
+	case !found:
+		http.Error(w, "vehicle not registered", http.StatusNotFound)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(record)
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+type AccountRepository struct {
+	db *sql.DB
+}
+
+func NewAccountRepository(db *sql.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// CreateToken stores a new email token for userID and retires the unused
+// ones of the same purpose, so only the latest link works
+func (r *AccountRepository) CreateToken(userID int, purpose, tokenHash string, expiresAt, now time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE user_tokens SET used_at = $3
+					  WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, userID, purpose, now)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+					  VALUES ($1, $2, $3, $4)`, userID, purpose, tokenHash, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ConsumeToken marks a token used. It reports false when the token is
+// unknown, expired or already used.
+func (r *AccountRepository) ConsumeToken(userID int, purpose, tokenHash string, now time.Time) (bool, error) {
+	res, err := r.db.Exec(`UPDATE user_tokens SET used_at = $4
+						   WHERE token_hash = $3 AND user_id = $1 AND purpose = $2
+						   AND used_at IS NULL AND expires_at > $4`, userID, purpose, tokenHash, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ResetPassword consumes a reset token and sets the new password hash in
+// one transaction, marking the email verified too. It reports false,
+// changing nothing, when the token is unknown, expired or already used.
+func (r *AccountRepository) ResetPassword(userID int, purpose, tokenHash, passwordHash string, now time.Time) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE user_tokens SET used_at = $4
+						 WHERE token_hash = $3 AND user_id = $1 AND purpose = $2
+						 AND used_at IS NULL AND expires_at > $4`, userID, purpose, tokenHash, now)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+
+	_, err = tx.Exec(`UPDATE users SET password_hash = $2, email_verified_at = COALESCE(email_verified_at, $3), updated_at = $3
+					  WHERE id = $1`, userID, passwordHash, now)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetUser returns the user with id, or nil
+func (r *AccountRepository) GetUser(id int) (*models.User, error) {
+	return r.getUser(`WHERE id = $1`, id)
+}
+
+// GetUserByEmail returns the user with email, or nil
+func (r *AccountRepository) GetUserByEmail(email string) (*models.User, error) {
+	return r.getUser(`WHERE LOWER(email) = LOWER($1)`, email)
+}
+
+func (r *AccountRepository) getUser(where string, arg interface{}) (*models.User, error) {
+	var u models.User
+	var verifiedAt sql.NullTime
+	err := r.db.QueryRow(`SELECT id, username, email, full_name, role, email_verified_at, created_at, updated_at
+						  FROM users `+where, arg).
+		Scan(&u.ID, &u.Username, &u.Email, &u.FullName, &u.Role, &verifiedAt, &u.CreatedAt, &u.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if verifiedAt.Valid {
+		u.EmailVerifiedAt = &verifiedAt.Time
+	}
+	return &u, nil
+}
+
+func (r *AccountRepository) MarkEmailVerified(userID int, now time.Time) error {
+	_, err := r.db.Exec(`UPDATE users SET email_verified_at = COALESCE(email_verified_at, $2), updated_at = $2
+						 WHERE id = $1`, userID, now)
+	return err
+}
+
+func (r *AccountRepository) IsEmailVerified(userID int) (bool, error) {
+	var verified bool
+	err := r.db.QueryRow(`SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1`, userID).Scan(&verified)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return verified, err
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+type AttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepository(db *sql.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+// ClaimQueued marks the oldest queued attachment as processing, counts the
+// attempt and returns it, or nil when nothing is queued. An attachment left
+// processing since before staleBefore (the worker died) is claimed again
+// while it has had fewer than maxAttempts tries.
+func (r *AttachmentRepository) ClaimQueued(staleBefore time.Time, maxAttempts int) (*models.VehicleAttachment, error) {
+	query := `UPDATE vehicle_attachments
+			  SET validation_status = 'processing', validation_started_at = CURRENT_TIMESTAMP,
+			      validation_attempts = validation_attempts + 1
+			  WHERE id = (
+			      SELECT id FROM vehicle_attachments
+			      WHERE validation_status = 'queued'
+			         OR (validation_status = 'processing' AND validation_started_at < $1
+			             AND validation_attempts < $2)
+			      ORDER BY uploaded_at, id
+			      LIMIT 1
+			      FOR UPDATE SKIP LOCKED)
+			  RETURNING id, vehicle_id, attachment_type, file_name, file_path,
+			            COALESCE(file_size, 0), COALESCE(mime_type, ''), validation_status, uploaded_at`
+
+	var a models.VehicleAttachment
+	err := r.db.QueryRow(query, staleBefore, maxAttempts).Scan(&a.ID, &a.VehicleID, &a.AttachmentType, &a.FileName, &a.FilePath,
+		&a.FileSize, &a.MimeType, &a.ValidationStatus, &a.UploadedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// FailStale gives up on attachments left processing since before
+// staleBefore after maxAttempts tries, storing status and
+// validationErrors, and returns the IDs of their vehicles
+func (r *AttachmentRepository) FailStale(staleBefore time.Time, maxAttempts int, status string, validationErrors []string) ([]int, error) {
+	errorsJSON, err := json.Marshal(validationErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE vehicle_attachments
+			  SET validation_status = $3, validation_errors = $4, validated_at = CURRENT_TIMESTAMP
+			  WHERE validation_status = 'processing' AND validation_started_at < $1
+			    AND validation_attempts >= $2
+			  RETURNING vehicle_id`
+
+	rows, err := r.db.Query(query, staleBefore, maxAttempts, status, string(errorsJSON))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vehicleIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		vehicleIDs = append(vehicleIDs, id)
+	}
+	return vehicleIDs, rows.Err()
+}
+
+// SaveValidation stores the outcome of reading an attachment
+func (r *AttachmentRepository) SaveValidation(id int, status string, ocrData []byte, validationErrors []string) error {
+	if validationErrors == nil {
+		validationErrors = []string{}
+	}
+	errorsJSON, err := json.Marshal(validationErrors)
+	if err != nil {
+		return err
+	}
+
+	var data interface{}
+	if len(ocrData) > 0 {
+		data = string(ocrData)
+	}
+
+	query := `UPDATE vehicle_attachments
+			  SET validation_status = $2, ocr_data = $3, validation_errors = $4, validated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1`
+
+	_, err = r.db.Exec(query, id, status, data, string(errorsJSON))
+	return err
+}
+
+// LatestOCRData returns the stored analysis of the vehicle's most recently
+// uploaded attachment of one of types that has been read, or "" if none
+func (r *AttachmentRepository) LatestOCRData(vehicleID int, types []string) (string, error) {
+	query := `SELECT ocr_data FROM vehicle_attachments
+			  WHERE vehicle_id = $1 AND attachment_type = ANY($2) AND ocr_data IS NOT NULL
+			  ORDER BY uploaded_at DESC, id DESC
+			  LIMIT 1`
+
+	var data string
+	err := r.db.QueryRow(query, vehicleID, pq.Array(types)).Scan(&data)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return data, err
+}
+
+// GetVehicleIdentity returns the registration, chassis and engine numbers
+// of a vehicle, or nil
+func (r *AttachmentRepository) GetVehicleIdentity(vehicleID int) (*models.Vehicle, error) {
+	query := `SELECT id, registration_number, COALESCE(chassis_number, ''), COALESCE(engine_number, '')
+			  FROM vehicles WHERE id = $1`
+
+	var v models.Vehicle
+	err := r.db.QueryRow(query, vehicleID).Scan(&v.ID, &v.RegistrationNumber, &v.ChassisNumber, &v.EngineNumber)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/audit"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// auditChainLock serialises appends so every entry links to the one
+// written just before it
+const auditChainLock = 7_210_417
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// nullJSON stores an empty value as NULL, matching what audit.Canonical
+// hashes
+func nullJSON(data json.RawMessage) interface{} {
+	if string(data) == "null" {
+		return nil
+	}
+	return string(data)
+}
+
+// Record appends event to the hash chain. CreatedAt is set by the caller
+// so the hash covers the stored timestamp.
+func (r *AuditRepository) Record(event *models.AuditEvent) error {
+	event.CreatedAt = audit.Timestamp(event.CreatedAt)
+	changes, err := audit.Canonical(event.Changes)
+	if err != nil {
+		return err
+	}
+	details, err := audit.Canonical(event.Details)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return err
+	}
+	var prev sql.NullString
+	err = tx.QueryRow(`SELECT hash FROM audit_log WHERE hash IS NOT NULL ORDER BY id DESC LIMIT 1`).Scan(&prev)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	event.PrevHash = prev.String
+	if event.Hash, err = audit.Hash(event.PrevHash, event); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO audit_log (action, actor_id, actor_role, ip_address, method, route, status_code,
+			  entity_type, entity_id, changes, details, prev_hash, hash, created_at)
+			  VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, 0),
+			  NULLIF($8, ''), NULLIF($9, ''), $10, $11, NULLIF($12, ''), $13, $14)
+			  RETURNING id`
+
+	err = tx.QueryRow(query, event.Action, event.ActorID, event.ActorRole, event.IPAddress, event.Method,
+		event.Route, event.StatusCode, event.EntityType, event.EntityID, nullJSON(changes), nullJSON(details),
+		event.PrevHash, event.Hash, event.CreatedAt).Scan(&event.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Snapshot returns the row of entity keyed by key as JSON, or nil when it
+// does not exist. Table and column come from the route table, never from
+// the request.
+func (r *AuditRepository) Snapshot(entity audit.Entity, key string) ([]byte, error) {
+	query := fmt.Sprintf(`SELECT row_to_json(t)::text FROM %s t WHERE t.%s::text = $1 LIMIT 1`,
+		entity.Table, entity.KeyColumn())
+
+	var row []byte
+	err := r.db.QueryRow(query, key).Scan(&row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return row, err
+}
+
+const auditColumns = `id, action, actor_id, COALESCE(actor_role, ''), COALESCE(ip_address, ''),
+	COALESCE(method, ''), COALESCE(route, ''), COALESCE(status_code, 0), COALESCE(entity_type, ''),
+	COALESCE(entity_id, ''), changes, details, COALESCE(prev_hash, ''), COALESCE(hash, ''), created_at`
+
+func scanAuditEvent(row interface{ Scan(...interface{}) error }) (*models.AuditEvent, error) {
+	var e models.AuditEvent
+	var actorID sql.NullInt64
+	var changes, details []byte
+	err := row.Scan(&e.ID, &e.Action, &actorID, &e.ActorRole, &e.IPAddress, &e.Method, &e.Route,
+		&e.StatusCode, &e.EntityType, &e.EntityID, &changes, &details, &e.PrevHash, &e.Hash, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if actorID.Valid {
+		id := int(actorID.Int64)
+		e.ActorID = &id
+	}
+	if changes != nil {
+		decoder := json.NewDecoder(bytes.NewReader(changes))
+		decoder.UseNumber()
+		if err := decoder.Decode(&e.Changes); err != nil {
+			return nil, err
+		}
+	}
+	if details != nil {
+		row, err := audit.DecodeRow(details)
+		if err != nil {
+			return nil, err
+		}
+		e.Details = row
+	}
+	return &e, nil
+}
+
+// List returns the entries matching filter, newest first
+func (r *AuditRepository) List(filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ActorID != nil {
+		where("actor_id = $%d", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.EntityType != "" {
+		where("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		where("entity_id = $%d", filter.EntityID)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= $%d", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		where("created_at < $%d", filter.To.UTC())
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+// Walk calls visit with every entry in chain order until it returns false
+func (r *AuditRepository) Walk(visit func(*models.AuditEvent) bool) error {
+	rows, err := r.db.Query(`SELECT ` + auditColumns + ` FROM audit_log ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if !visit(e) {
+			break
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+type DrivingAlertRepository struct {
+	db *sql.DB
+}
+
+func NewDrivingAlertRepository(db *sql.DB) *DrivingAlertRepository {
+	return &DrivingAlertRepository{db: db}
+}
+
+// GetDeviceVehicle returns the vehicle a device is fitted to together with
+// its owner and speed limit, or nil if the device is not on a vehicle
+func (r *DrivingAlertRepository) GetDeviceVehicle(deviceID string) (*models.DrivingVehicle, error) {
+	query := `SELECT v.id, v.registration_number, v.vehicle_type, v.fleet_owner_id,
+			  COALESCE(fo.user_id, v.created_by),
+			  COALESCE(sl.max_speed_kmh, dl.max_speed_kmh)
+			  FROM gps_devices d
+			  JOIN vehicles v ON d.vehicle_id = v.id
+			  LEFT JOIN fleet_owners fo ON v.fleet_owner_id = fo.id
+			  LEFT JOIN vehicle_speed_limits sl ON sl.vehicle_type = LOWER(v.vehicle_type)
+			  LEFT JOIN vehicle_speed_limits dl ON dl.vehicle_type = 'default'
+			  WHERE d.device_id = $1`
+
+	var v models.DrivingVehicle
+	err := r.db.QueryRow(query, deviceID).Scan(&v.VehicleID, &v.RegistrationNumber, &v.VehicleType,
+		&v.FleetOwnerID, &v.OwnerUserID, &v.SpeedLimitKmh)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// GetState returns the stored driving state of a device, or nil for a
+// device that has not reported before
+func (r *DrivingAlertRepository) GetState(deviceID string) (*models.DeviceDrivingState, error) {
+	query := `SELECT device_id, last_latitude, last_longitude, last_speed, last_timestamp,
+			  stopped_since, idle_alerted, speeding, after_hours_alerted_on
+			  FROM device_driving_state WHERE device_id = $1`
+
+	var s models.DeviceDrivingState
+	err := r.db.QueryRow(query, deviceID).Scan(&s.DeviceID, &s.LastLatitude, &s.LastLongitude, &s.LastSpeed,
+		&s.LastTimestamp, &s.StoppedSince, &s.IdleAlerted, &s.Speeding, &s.AfterHoursAlertedOn)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *DrivingAlertRepository) SaveState(s *models.DeviceDrivingState) error {
+	query := `INSERT INTO device_driving_state (device_id, last_latitude, last_longitude, last_speed, last_timestamp,
+			  stopped_since, idle_alerted, speeding, after_hours_alerted_on)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			  ON CONFLICT (device_id) DO UPDATE SET
+			  last_latitude = EXCLUDED.last_latitude, last_longitude = EXCLUDED.last_longitude,
+			  last_speed = EXCLUDED.last_speed, last_timestamp = EXCLUDED.last_timestamp,
+			  stopped_since = EXCLUDED.stopped_since, idle_alerted = EXCLUDED.idle_alerted,
+			  speeding = EXCLUDED.speeding, after_hours_alerted_on = EXCLUDED.after_hours_alerted_on`
+
+	_, err := r.db.Exec(query, s.DeviceID, s.LastLatitude, s.LastLongitude, s.LastSpeed, s.LastTimestamp,
+		s.StoppedSince, s.IdleAlerted, s.Speeding, s.AfterHoursAlertedOn)
+	return err
+}
+
+func (r *DrivingAlertRepository) InsertAlert(a *models.DrivingAlert) error {
+	query := `INSERT INTO driving_alerts (device_id, vehicle_id, fleet_owner_id, alert_type, severity, value,
+			  threshold, geofence_id, latitude, longitude, message, occurred_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			  RETURNING id, created_at`
+
+	return r.db.QueryRow(query, a.DeviceID, a.VehicleID, a.FleetOwnerID, a.AlertType, a.Severity, a.Value,
+		a.Threshold, a.GeofenceID, a.Latitude, a.Longitude, a.Message, a.OccurredAt).Scan(&a.ID, &a.CreatedAt)
+}
+
+// DrivingAlertFilter narrows GetAlerts. Zero values skip a filter.
+type DrivingAlertFilter struct {
+	FleetOwnerID       int
+	VehicleID          int
+	AlertType          string
+	UnacknowledgedOnly bool
+	Since              time.Time
+	Limit              int
+}
+
+func (r *DrivingAlertRepository) GetAlerts(f DrivingAlertFilter) ([]models.DrivingAlert, error) {
+	query := `SELECT id, device_id, vehicle_id, fleet_owner_id, alert_type, severity, value, threshold,
+			  geofence_id, latitude, longitude, message, occurred_at, acknowledged_at, acknowledged_by, created_at
+			  FROM driving_alerts
+			  WHERE occurred_at >= $1
+			  AND ($2 = 0 OR fleet_owner_id = $2)
+			  AND ($3 = 0 OR vehicle_id = $3)
+			  AND ($4 = '' OR alert_type = $4)
+			  AND (NOT $5 OR acknowledged_at IS NULL)
+			  ORDER BY occurred_at DESC LIMIT $6`
+
+	rows, err := r.db.Query(query, f.Since, f.FleetOwnerID, f.VehicleID, f.AlertType, f.UnacknowledgedOnly, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.DrivingAlert{}
+	for rows.Next() {
+		var a models.DrivingAlert
+		err := rows.Scan(&a.ID, &a.DeviceID, &a.VehicleID, &a.FleetOwnerID, &a.AlertType, &a.Severity, &a.Value,
+			&a.Threshold, &a.GeofenceID, &a.Latitude, &a.Longitude, &a.Message, &a.OccurredAt,
+			&a.AcknowledgedAt, &a.AcknowledgedBy, &a.CreatedAt)
+		if err != nil {
+			continue
+		}
+		alerts = append(alerts, a)
+	}
+
+	return alerts, nil
+}
+
+// AcknowledgeAlert marks an alert as seen. A non-zero fleetOwnerID restricts
+// the update to that owner's alerts.
+func (r *DrivingAlertRepository) AcknowledgeAlert(id int64, userID, fleetOwnerID int) error {
+	query := `UPDATE driving_alerts SET acknowledged_at = CURRENT_TIMESTAMP, acknowledged_by = $1
+			  WHERE id = $2 AND ($3 = 0 OR fleet_owner_id = $3)`
+
+	result, err := r.db.Exec(query, userID, id, fleetOwnerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *DrivingAlertRepository) GetSpeedLimits() ([]models.VehicleSpeedLimit, error) {
+	rows, err := r.db.Query("SELECT vehicle_type, max_speed_kmh, updated_at FROM vehicle_speed_limits ORDER BY vehicle_type")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := []models.VehicleSpeedLimit{}
+	for rows.Next() {
+		var l models.VehicleSpeedLimit
+		if err := rows.Scan(&l.VehicleType, &l.MaxSpeedKmh, &l.UpdatedAt); err != nil {
+			continue
+		}
+		limits = append(limits, l)
+	}
+
+	return limits, nil
+}
+
+func (r *DrivingAlertRepository) UpsertSpeedLimit(l *models.VehicleSpeedLimit) error {
+	query := `INSERT INTO vehicle_speed_limits (vehicle_type, max_speed_kmh)
+			  VALUES (LOWER($1), $2)
+			  ON CONFLICT (vehicle_type) DO UPDATE SET max_speed_kmh = EXCLUDED.max_speed_kmh,
+			  updated_at = CURRENT_TIMESTAMP
+			  RETURNING vehicle_type, updated_at`
+
+	return r.db.QueryRow(query, l.VehicleType, l.MaxSpeedKmh).Scan(&l.VehicleType, &l.UpdatedAt)
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+type FaceVerificationRepository struct {
+	db *sql.DB
+}
+
+func NewFaceVerificationRepository(db *sql.DB) *FaceVerificationRepository {
+	return &FaceVerificationRepository{db: db}
+}
+
+// Create stores a verification. A verified one also marks the fleet owner
+// verified, in the same transaction.
+func (r *FaceVerificationRepository) Create(v *models.FaceVerification) error {
+	flags := v.LivenessFlags
+	if flags == nil {
+		flags = []string{}
+	}
+	flagsJSON, err := json.Marshal(flags)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO face_verifications (fleet_owner_id, user_id, match_score, threshold, is_match,
+			  liveness_passed, liveness_flags, verified, result)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			  RETURNING id, created_at`
+	err = tx.QueryRow(query, v.FleetOwnerID, v.UserID, v.MatchScore, v.Threshold, v.IsMatch,
+		v.LivenessPassed, string(flagsJSON), v.Verified, string(v.Result)).Scan(&v.ID, &v.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if v.Verified {
+		_, err = tx.Exec(`UPDATE fleet_owners
+						  SET verified = true, verified_at = $2, face_verification_id = $3, updated_at = $2
+						  WHERE id = $1`, v.FleetOwnerID, v.CreatedAt, v.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// VerifiedKTPPath returns the file of the user's latest KTP document an
+// admin approved, or ""
+func (r *FaceVerificationRepository) VerifiedKTPPath(userID int) (string, error) {
+	query := `SELECT file_path FROM user_documents
+			  WHERE user_id = $1 AND document_type = 'ktp' AND verification_status = 'approved'
+			  ORDER BY updated_at DESC, id DESC
+			  LIMIT 1`
+
+	var path string
+	err := r.db.QueryRow(query, userID).Scan(&path)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return path, err
+}
+
+// ListByFleetOwner returns a fleet owner's verifications, newest first
+func (r *FaceVerificationRepository) ListByFleetOwner(fleetOwnerID, limit int) ([]models.FaceVerification, error) {
+	query := `SELECT id, fleet_owner_id, COALESCE(user_id, 0), match_score, threshold, is_match,
+			  liveness_passed, liveness_flags, verified, result, created_at
+			  FROM face_verifications
+			  WHERE fleet_owner_id = $1
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT $2`
+
+	rows, err := r.db.Query(query, fleetOwnerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	verifications := []models.FaceVerification{}
+	for rows.Next() {
+		var v models.FaceVerification
+		var flags, result []byte
+		if err := rows.Scan(&v.ID, &v.FleetOwnerID, &v.UserID, &v.MatchScore, &v.Threshold, &v.IsMatch,
+			&v.LivenessPassed, &flags, &v.Verified, &result, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(flags, &v.LivenessFlags); err != nil {
+			return nil, err
+		}
+		v.Result = result
+		verifications = append(verifications, v)
+	}
+	return verifications, rows.Err()
+}
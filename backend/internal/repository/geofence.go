@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+type GeofenceRepository struct {
+	db *sql.DB
+}
+
+func NewGeofenceRepository(db *sql.DB) *GeofenceRepository {
+	return &GeofenceRepository{db: db}
+}
+
+const geofenceSelectQuery = `SELECT id, name, category, shape_type, center_lat, center_lng, radius_meters,
+			  polygon, dwell_seconds, speed_limit_kmh, is_active, created_by, created_at, updated_at
+			  FROM geofences`
+
+func scanGeofence(row interface{ Scan(...interface{}) error }) (models.Geofence, error) {
+	var g models.Geofence
+	var polygon []byte
+	err := row.Scan(&g.ID, &g.Name, &g.Category, &g.ShapeType, &g.CenterLat, &g.CenterLng, &g.RadiusMeters,
+		&polygon, &g.DwellSeconds, &g.SpeedLimitKmh, &g.IsActive, &g.CreatedBy, &g.CreatedAt, &g.UpdatedAt)
+	if err != nil {
+		return g, err
+	}
+	if len(polygon) > 0 {
+		if err := json.Unmarshal(polygon, &g.Polygon); err != nil {
+			return g, err
+		}
+	}
+	return g, nil
+}
+
+func polygonJSON(g *models.Geofence) (interface{}, error) {
+	if len(g.Polygon) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(g.Polygon)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (r *GeofenceRepository) CreateGeofence(g *models.Geofence) error {
+	polygon, err := polygonJSON(g)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO geofences (name, category, shape_type, center_lat, center_lng, radius_meters,
+			  polygon, dwell_seconds, speed_limit_kmh, is_active, created_by)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			  RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRow(query, g.Name, g.Category, g.ShapeType, g.CenterLat, g.CenterLng, g.RadiusMeters,
+		polygon, g.DwellSeconds, g.SpeedLimitKmh, g.IsActive, g.CreatedBy).Scan(&g.ID, &g.CreatedAt, &g.UpdatedAt)
+}
+
+func (r *GeofenceRepository) UpdateGeofence(g *models.Geofence) error {
+	polygon, err := polygonJSON(g)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE geofences SET name = $1, category = $2, shape_type = $3, center_lat = $4, center_lng = $5,
+			  radius_meters = $6, polygon = $7, dwell_seconds = $8, speed_limit_kmh = $9, is_active = $10,
+			  updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $11 RETURNING created_by, created_at, updated_at`
+
+	return r.db.QueryRow(query, g.Name, g.Category, g.ShapeType, g.CenterLat, g.CenterLng, g.RadiusMeters,
+		polygon, g.DwellSeconds, g.SpeedLimitKmh, g.IsActive, g.ID).Scan(&g.CreatedBy, &g.CreatedAt, &g.UpdatedAt)
+}
+
+func (r *GeofenceRepository) DeleteGeofence(id int) error {
+	result, err := r.db.Exec("DELETE FROM geofences WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *GeofenceRepository) GetGeofenceByID(id int) (*models.Geofence, error) {
+	g, err := scanGeofence(r.db.QueryRow(geofenceSelectQuery+" WHERE id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (r *GeofenceRepository) GetGeofences(activeOnly bool) ([]models.Geofence, error) {
+	query := geofenceSelectQuery
+	if activeOnly {
+		query += " WHERE is_active = true"
+	}
+	query += " ORDER BY name"
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	geofences := []models.Geofence{}
+	for rows.Next() {
+		g, err := scanGeofence(rows)
+		if err != nil {
+			continue
+		}
+		geofences = append(geofences, g)
+	}
+
+	return geofences, nil
+}
+
+// GetDeviceStates returns the geofences a device is currently inside
+func (r *GeofenceRepository) GetDeviceStates(deviceID string) (map[int]models.GeofenceDeviceState, error) {
+	query := `SELECT device_id, geofence_id, entered_at, dwell_reported
+			  FROM geofence_device_state WHERE device_id = $1`
+
+	rows, err := r.db.Query(query, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[int]models.GeofenceDeviceState)
+	for rows.Next() {
+		var s models.GeofenceDeviceState
+		if err := rows.Scan(&s.DeviceID, &s.GeofenceID, &s.EnteredAt, &s.DwellReported); err != nil {
+			return nil, err
+		}
+		states[s.GeofenceID] = s
+	}
+
+	return states, rows.Err()
+}
+
+// AdvanceDevice records at as the device's last evaluated point. It reports
+// false, recording nothing, when that point or a later one was evaluated
+// already.
+func (r *GeofenceRepository) AdvanceDevice(deviceID string, at time.Time) (bool, error) {
+	query := `INSERT INTO geofence_device_cursor (device_id, last_point_at)
+			  VALUES ($1, $2)
+			  ON CONFLICT (device_id) DO UPDATE SET last_point_at = EXCLUDED.last_point_at
+			  WHERE geofence_device_cursor.last_point_at < EXCLUDED.last_point_at`
+	result, err := r.db.Exec(query, deviceID, at)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *GeofenceRepository) EnterGeofence(deviceID string, geofenceID int, at time.Time) error {
+	query := `INSERT INTO geofence_device_state (device_id, geofence_id, entered_at)
+			  VALUES ($1, $2, $3)
+			  ON CONFLICT (device_id, geofence_id) DO NOTHING`
+	_, err := r.db.Exec(query, deviceID, geofenceID, at)
+	return err
+}
+
+func (r *GeofenceRepository) ExitGeofence(deviceID string, geofenceID int) error {
+	_, err := r.db.Exec("DELETE FROM geofence_device_state WHERE device_id = $1 AND geofence_id = $2",
+		deviceID, geofenceID)
+	return err
+}
+
+func (r *GeofenceRepository) MarkDwellReported(deviceID string, geofenceID int) error {
+	_, err := r.db.Exec(`UPDATE geofence_device_state SET dwell_reported = true
+						 WHERE device_id = $1 AND geofence_id = $2`, deviceID, geofenceID)
+	return err
+}
+
+func (r *GeofenceRepository) InsertEvent(e *models.GeofenceEvent) error {
+	query := `INSERT INTO geofence_events (geofence_id, device_id, vehicle_id, event_type, latitude, longitude,
+			  trip_id, stop_id, occurred_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+
+	return r.db.QueryRow(query, e.GeofenceID, e.DeviceID, e.VehicleID, e.EventType, e.Latitude, e.Longitude,
+		e.TripID, e.StopID, e.OccurredAt).Scan(&e.ID)
+}
+
+// GetEvents lists geofence events, newest first. Zero values skip a filter.
+func (r *GeofenceRepository) GetEvents(geofenceID int, deviceID string, since time.Time, limit int) ([]models.GeofenceEvent, error) {
+	query := `SELECT e.id, e.geofence_id, g.name, g.category, e.device_id, e.vehicle_id, e.event_type,
+			  e.latitude, e.longitude, e.trip_id, e.stop_id, e.occurred_at
+			  FROM geofence_events e
+			  JOIN geofences g ON e.geofence_id = g.id
+			  WHERE e.occurred_at >= $1
+			  AND ($2 = 0 OR e.geofence_id = $2)
+			  AND ($3 = '' OR e.device_id = $3)
+			  ORDER BY e.occurred_at DESC LIMIT $4`
+
+	rows, err := r.db.Query(query, since, geofenceID, deviceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.GeofenceEvent{}
+	for rows.Next() {
+		var e models.GeofenceEvent
+		err := rows.Scan(&e.ID, &e.GeofenceID, &e.GeofenceName, &e.Category, &e.DeviceID, &e.VehicleID,
+			&e.EventType, &e.Latitude, &e.Longitude, &e.TripID, &e.StopID, &e.OccurredAt)
+		if err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// GetDeviceVehicleID returns the vehicle a device is fitted to, if any
+func (r *GeofenceRepository) GetDeviceVehicleID(deviceID string) (*int, error) {
+	var vehicleID sql.NullInt64
+	err := r.db.QueryRow("SELECT vehicle_id FROM gps_devices WHERE device_id = $1", deviceID).Scan(&vehicleID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !vehicleID.Valid {
+		return nil, nil
+	}
+	id := int(vehicleID.Int64)
+	return &id, nil
+}
@@ -27,7 +27,7 @@ func (r *GPSDeviceRepository) CreateDevice(registrationID int, deviceID string)
 }
 
 func (r *GPSDeviceRepository) GetAllDevices() ([]models.GPSDevice, error) {
-	query := `SELECT d.id, d.device_id, d.vehicle_id, d.registration_id, d.status, 
+	query := `SELECT d.id, d.device_id, d.imei, d.vehicle_id, d.registration_id, d.status, 
 			  d.installed_date, d.last_signal, d.created_at, d.updated_at,
 			  v.registration_number
 			  FROM gps_devices d
@@ -45,7 +45,7 @@ func (r *GPSDeviceRepository) GetAllDevices() ([]models.GPSDevice, error) {
 		var device models.GPSDevice
 		var vehicleReg sql.NullString
 		
-		err := rows.Scan(&device.ID, &device.DeviceID, &device.VehicleID, &device.RegistrationID, 
+		err := rows.Scan(&device.ID, &device.DeviceID, &device.IMEI, &device.VehicleID, &device.RegistrationID, 
 			&device.Status, &device.InstalledDate, &device.LastSignal, &device.CreatedAt, &device.UpdatedAt, &vehicleReg)
 		if err != nil {
 			continue
@@ -57,12 +57,15 @@ func (r *GPSDeviceRepository) GetAllDevices() ([]models.GPSDevice, error) {
 	return devices, nil
 }
 
-func (r *GPSDeviceRepository) AssignToVehicle(deviceID string, vehicleID int) error {
+// AssignToVehicle installs a device on a vehicle. An empty imei keeps the
+// IMEI already on record.
+func (r *GPSDeviceRepository) AssignToVehicle(deviceID string, vehicleID int, imei string) error {
 	query := `UPDATE gps_devices SET vehicle_id = $1, status = 'installed', 
+			  imei = COALESCE(NULLIF($3, ''), imei),
 			  installed_date = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP 
 			  WHERE device_id = $2`
 	
-	_, err := r.db.Exec(query, vehicleID, deviceID)
+	_, err := r.db.Exec(query, vehicleID, deviceID, imei)
 	return err
 }
 
@@ -70,4 +73,34 @@ func (r *GPSDeviceRepository) UpdateStatus(deviceID string, status string) error
 	query := `UPDATE gps_devices SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE device_id = $2`
 	_, err := r.db.Exec(query, status, deviceID)
 	return err
-}
\ No newline at end of file
+}
+
+// GetDeviceIDByIMEI maps a tracker IMEI to its device_id. Devices registered
+// with the IMEI as their device_id match too. Inactive devices are refused.
+func (r *GPSDeviceRepository) GetDeviceIDByIMEI(imei string) (string, error) {
+	query := `SELECT device_id FROM gps_devices
+			  WHERE (imei = $1 OR device_id = $1) AND status <> 'inactive'
+			  ORDER BY imei = $1 DESC NULLS LAST LIMIT 1`
+
+	var deviceID string
+	err := r.db.QueryRow(query, imei).Scan(&deviceID)
+	return deviceID, err
+}
+
+// GetDeviceFleetOwnerID returns the fleet owner of the vehicle a device is
+// installed on, or nil when the device is not on a vehicle
+func (r *GPSDeviceRepository) GetDeviceFleetOwnerID(deviceID string) (*int, error) {
+	query := `SELECT v.fleet_owner_id FROM gps_devices d
+			  LEFT JOIN vehicles v ON d.vehicle_id = v.id
+			  WHERE d.device_id = $1`
+
+	var fleetOwnerID sql.NullInt64
+	if err := r.db.QueryRow(query, deviceID).Scan(&fleetOwnerID); err != nil {
+		return nil, err
+	}
+	if !fleetOwnerID.Valid {
+		return nil, nil
+	}
+	id := int(fleetOwnerID.Int64)
+	return &id, nil
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// gpsMaintenanceLockID serialises partition changes across server instances
+const gpsMaintenanceLockID = 72410002
+
+// minuteSummarySelect folds raw points into one row per device per minute,
+// keeping the last fix as the position
+const minuteSummarySelect = `SELECT device_id, date_trunc('minute', timestamp) AS minute,
+			  (array_agg(latitude ORDER BY timestamp DESC))[1],
+			  (array_agg(longitude ORDER BY timestamp DESC))[1],
+			  AVG(COALESCE(speed, 0)), MAX(COALESCE(speed, 0)), COUNT(*)`
+
+const minuteSummaryUpsert = `ON CONFLICT (device_id, minute) DO UPDATE SET
+			  avg_speed = (gps_tracking_minutes.avg_speed * gps_tracking_minutes.point_count + EXCLUDED.avg_speed * EXCLUDED.point_count)
+			              / (gps_tracking_minutes.point_count + EXCLUDED.point_count),
+			  max_speed = GREATEST(gps_tracking_minutes.max_speed, EXCLUDED.max_speed),
+			  point_count = gps_tracking_minutes.point_count + EXCLUDED.point_count,
+			  latitude = EXCLUDED.latitude,
+			  longitude = EXCLUDED.longitude`
+
+func lockGPSMaintenance(tx *sql.Tx) error {
+	_, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", gpsMaintenanceLockID)
+	return err
+}
+
+// partitionBound formats a range bound; ATTACH PARTITION takes no parameters
+func partitionBound(t time.Time) string {
+	return pq.QuoteLiteral(t.Format("2006-01-02 15:04:05"))
+}
+
+// GetPartitions lists the partitions of gps_tracking, including the default
+func (r *GPSTrackingRepository) GetPartitions() ([]string, error) {
+	query := `SELECT c.relname FROM pg_inherits i
+			  JOIN pg_class c ON c.oid = i.inhrelid
+			  WHERE i.inhparent = 'gps_tracking'::regclass
+			  ORDER BY c.relname`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// CreatePartition adds the partition for [from, to). Rows that already
+// landed in the default partition for that range are moved into it first,
+// otherwise attaching would fail.
+func (r *GPSTrackingRepository) CreatePartition(name string, from, to time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockGPSMaintenance(tx); err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := tx.QueryRow("SELECT to_regclass($1) IS NOT NULL", name).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	table := pq.QuoteIdentifier(name)
+	if _, err := tx.Exec(`CREATE TABLE ` + table + ` (LIKE gps_tracking INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`); err != nil {
+		return err
+	}
+	query := `INSERT INTO ` + table + ` SELECT * FROM gps_tracking_default WHERE timestamp >= $1 AND timestamp < $2`
+	if _, err := tx.Exec(query, from, to); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM gps_tracking_default WHERE timestamp >= $1 AND timestamp < $2`, from, to); err != nil {
+		return err
+	}
+	query = `ALTER TABLE gps_tracking ATTACH PARTITION ` + table +
+		` FOR VALUES FROM (` + partitionBound(from) + `) TO (` + partitionBound(to) + `)`
+	if _, err := tx.Exec(query); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DownsamplePartition folds a whole partition into minute summaries and
+// drops it. It returns the number of raw points summarised.
+func (r *GPSTrackingRepository) DownsamplePartition(name string) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := lockGPSMaintenance(tx); err != nil {
+		return 0, err
+	}
+
+	table := pq.QuoteIdentifier(name)
+	var points int64
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&points); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO gps_tracking_minutes (device_id, minute, latitude, longitude, avg_speed, max_speed, point_count) ` +
+		minuteSummarySelect + ` FROM ` + table + ` GROUP BY device_id, minute ` + minuteSummaryUpsert
+	if _, err := tx.Exec(query); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`ALTER TABLE gps_tracking DETACH PARTITION ` + table); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DROP TABLE ` + table); err != nil {
+		return 0, err
+	}
+
+	return points, tx.Commit()
+}
+
+// DownsampleDefault folds default-partition points older than before into
+// minute summaries and deletes them. before should be minute-aligned.
+func (r *GPSTrackingRepository) DownsampleDefault(before time.Time) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := lockGPSMaintenance(tx); err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO gps_tracking_minutes (device_id, minute, latitude, longitude, avg_speed, max_speed, point_count) ` +
+		minuteSummarySelect + ` FROM gps_tracking_default WHERE timestamp < $1 GROUP BY device_id, minute ` + minuteSummaryUpsert
+	if _, err := tx.Exec(query, before); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`DELETE FROM gps_tracking_default WHERE timestamp < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	points, _ := result.RowsAffected()
+
+	return points, tx.Commit()
+}
+
+// DeleteSummariesBefore removes minute summaries older than before
+func (r *GPSTrackingRepository) DeleteSummariesBefore(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM gps_tracking_minutes WHERE minute < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func historyOrder(newestFirst bool) string {
+	if newestFirst {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// GetRawHistory returns raw points in [from, to)
+func (r *GPSTrackingRepository) GetRawHistory(deviceID string, from, to time.Time, limit int, newestFirst bool) ([]models.GPSHistoryPoint, error) {
+	query := `SELECT device_id, latitude, longitude, COALESCE(speed, 0), timestamp
+			  FROM gps_tracking
+			  WHERE device_id = $1 AND timestamp >= $2 AND timestamp < $3
+			  ORDER BY timestamp ` + historyOrder(newestFirst) + ` LIMIT $4`
+
+	rows, err := r.db.Query(query, deviceID, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.GPSHistoryPoint{}
+	for rows.Next() {
+		p := models.GPSHistoryPoint{PointCount: 1}
+		if err := rows.Scan(&p.DeviceID, &p.Latitude, &p.Longitude, &p.Speed, &p.Timestamp); err != nil {
+			continue
+		}
+		p.MaxSpeed = p.Speed
+		history = append(history, p)
+	}
+	return history, rows.Err()
+}
+
+// GetMinuteHistory returns one point per minute in [from, to). Stored
+// summaries cover downsampled periods; raw points that are still kept are
+// summarised on the fly. A minute with both is merged the way downsampling
+// merges it, so the boundary minute is not returned twice.
+func (r *GPSTrackingRepository) GetMinuteHistory(deviceID string, from, to time.Time, limit int, newestFirst bool) ([]models.GPSHistoryPoint, error) {
+	query := `SELECT device_id,
+			  (array_agg(latitude ORDER BY is_raw DESC))[1], (array_agg(longitude ORDER BY is_raw DESC))[1],
+			  SUM(avg_speed * point_count) / SUM(point_count), MAX(max_speed), SUM(point_count)::int, minute
+			  FROM (
+			      SELECT device_id, minute, latitude, longitude, avg_speed, max_speed, point_count, false AS is_raw
+			      FROM gps_tracking_minutes
+			      WHERE device_id = $1 AND minute >= $2 AND minute < $3
+			      UNION ALL
+			      SELECT *, true FROM (` + minuteSummarySelect + `
+			          FROM gps_tracking
+			          WHERE device_id = $1 AND timestamp >= $2 AND timestamp < $3
+			          GROUP BY device_id, minute
+			      ) AS raw (device_id, minute, latitude, longitude, avg_speed, max_speed, point_count)
+			  ) AS m
+			  GROUP BY device_id, minute
+			  ORDER BY minute ` + historyOrder(newestFirst) + ` LIMIT $4`
+
+	rows, err := r.db.Query(query, deviceID, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.GPSHistoryPoint{}
+	for rows.Next() {
+		var p models.GPSHistoryPoint
+		if err := rows.Scan(&p.DeviceID, &p.Latitude, &p.Longitude, &p.Speed, &p.MaxSpeed, &p.PointCount, &p.Timestamp); err != nil {
+			continue
+		}
+		history = append(history, p)
+	}
+	return history, rows.Err()
+}
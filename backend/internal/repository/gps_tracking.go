@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/youruser/aplikasi-tms/backend/internal/models"
 )
 
@@ -17,7 +18,8 @@ func NewGPSTrackingRepository(db *sql.DB) *GPSTrackingRepository {
 
 func (r *GPSTrackingRepository) InsertTrackingData(data *models.GPSTrackingData) error {
 	query := `INSERT INTO gps_tracking (device_id, latitude, longitude, speed, timestamp) 
-			  VALUES ($1, $2, $3, $4, $5)`
+			  VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (device_id, timestamp) DO NOTHING`
 	
 	_, err := r.db.Exec(query, data.DeviceID, data.Latitude, data.Longitude, data.Speed, data.Timestamp)
 	
@@ -29,7 +31,77 @@ func (r *GPSTrackingRepository) InsertTrackingData(data *models.GPSTrackingData)
 	return err
 }
 
-func (r *GPSTrackingRepository) GetLatestPositions() ([]map[string]interface{}, error) {
+// BulkInsertTrackingData writes a batch of points with COPY, skipping those
+// already stored, and moves each device's last_signal forward once, all in
+// one transaction
+func (r *GPSTrackingRepository) BulkInsertTrackingData(points []models.GPSTrackingData) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// COPY cannot skip duplicates, so the batch goes through a temporary
+	// table; fixes a tracker resent are already stored and are dropped
+	_, err = tx.Exec(`CREATE TEMP TABLE gps_tracking_batch (device_id VARCHAR(50), latitude DOUBLE PRECISION,
+					  longitude DOUBLE PRECISION, speed DOUBLE PRECISION, timestamp TIMESTAMP) ON COMMIT DROP`)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(pq.CopyIn("gps_tracking_batch", "device_id", "latitude", "longitude", "speed", "timestamp"))
+	if err != nil {
+		return err
+	}
+
+	latest := make(map[string]time.Time)
+	for _, p := range points {
+		if _, err := stmt.Exec(p.DeviceID, p.Latitude, p.Longitude, p.Speed, p.Timestamp); err != nil {
+			stmt.Close()
+			return err
+		}
+		if p.Timestamp.After(latest[p.DeviceID]) {
+			latest[p.DeviceID] = p.Timestamp
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO gps_tracking (device_id, latitude, longitude, speed, timestamp)
+					  SELECT device_id, latitude, longitude, speed, timestamp FROM gps_tracking_batch
+					  ON CONFLICT (device_id, timestamp) DO NOTHING`)
+	if err != nil {
+		return err
+	}
+
+	// Timestamps go over as text so they are stored exactly like single inserts
+	deviceIDs := make([]string, 0, len(latest))
+	timestamps := make([]string, 0, len(latest))
+	for deviceID, ts := range latest {
+		deviceIDs = append(deviceIDs, deviceID)
+		timestamps = append(timestamps, ts.Format("2006-01-02 15:04:05.999999999Z07:00"))
+	}
+
+	query := `UPDATE gps_devices d SET last_signal = v.ts
+			  FROM unnest($1::varchar[], $2::timestamp[]) AS v(device_id, ts)
+			  WHERE d.device_id = v.device_id AND (d.last_signal IS NULL OR d.last_signal < v.ts)`
+	if _, err := tx.Exec(query, pq.Array(deviceIDs), pq.Array(timestamps)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetLatestPositions returns the last fix of every active device. A
+// fleetOwnerID of 0 returns every fleet's devices.
+func (r *GPSTrackingRepository) GetLatestPositions(fleetOwnerID int) ([]map[string]interface{}, error) {
 	query := `SELECT DISTINCT ON (d.device_id) 
 			  d.device_id, d.vehicle_id, v.registration_number,
 			  t.latitude, t.longitude, t.speed, t.timestamp,
@@ -38,9 +110,10 @@ func (r *GPSTrackingRepository) GetLatestPositions() ([]map[string]interface{},
 			  LEFT JOIN gps_tracking t ON d.device_id = t.device_id
 			  LEFT JOIN vehicles v ON d.vehicle_id = v.id
 			  WHERE d.status = 'active' AND t.timestamp IS NOT NULL
+			  AND ($1 = 0 OR v.fleet_owner_id = $1)
 			  ORDER BY d.device_id, t.timestamp DESC`
 	
-	rows, err := r.db.Query(query)
+	rows, err := r.db.Query(query, fleetOwnerID)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+const refreshTokenColumns = `id, user_id, session_id, token_hash, access_jti, access_expires_at,
+	expires_at, replaced_by, revoked_at, COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at`
+
+func scanRefreshToken(row interface{ Scan(...interface{}) error }) (*models.RefreshToken, error) {
+	var t models.RefreshToken
+	var replacedBy sql.NullInt64
+	var revokedAt sql.NullTime
+	err := row.Scan(&t.ID, &t.UserID, &t.SessionID, &t.TokenHash, &t.AccessJTI, &t.AccessExpiresAt,
+		&t.ExpiresAt, &replacedBy, &revokedAt, &t.UserAgent, &t.IPAddress, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if replacedBy.Valid {
+		t.ReplacedBy = &replacedBy.Int64
+	}
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+	return &t, nil
+}
+
+func insertRefreshToken(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}, t *models.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (user_id, session_id, token_hash, access_jti, access_expires_at,
+			  expires_at, user_agent, ip_address)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  RETURNING id, created_at`
+
+	return q.QueryRow(query, t.UserID, t.SessionID, t.TokenHash, t.AccessJTI, t.AccessExpiresAt,
+		t.ExpiresAt, t.UserAgent, t.IPAddress).Scan(&t.ID, &t.CreatedAt)
+}
+
+// CreateRefreshToken stores the first token of a new session
+func (r *SessionRepository) CreateRefreshToken(t *models.RefreshToken) error {
+	return insertRefreshToken(r.db, t)
+}
+
+// GetRefreshToken looks a token up by its hash, or returns nil
+func (r *SessionRepository) GetRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	query := `SELECT ` + refreshTokenColumns + ` FROM refresh_tokens WHERE token_hash = $1`
+
+	t, err := scanRefreshToken(r.db.QueryRow(query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// ReplaceRefreshToken retires oldID and stores next in its place. It
+// reports false when oldID was already retired, so two refreshes racing
+// with the same token cannot both succeed.
+func (r *SessionRepository) ReplaceRefreshToken(oldID int64, next *models.RefreshToken, now time.Time) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE refresh_tokens SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`, oldID, now)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+
+	if err := insertRefreshToken(tx, next); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET replaced_by = $2 WHERE id = $1`, oldID, next.ID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// revokeWhere ends the sessions matched by filter ("session_id = $1" or
+// "user_id = $1"). Refresh tokens are retired and access tokens that have
+// not expired yet are added to the revocation list. It returns the number
+// of sessions that were still active.
+func (r *SessionRepository) revokeWhere(filter string, arg interface{}, reason string, now time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var sessions int
+	err = tx.QueryRow(`WITH revoked AS (
+						   UPDATE refresh_tokens SET revoked_at = $2
+						   WHERE `+filter+` AND revoked_at IS NULL AND expires_at > $2
+						   RETURNING session_id)
+					   SELECT COUNT(DISTINCT session_id) FROM revoked`, arg, now).Scan(&sessions)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO revoked_tokens (jti, user_id, reason, expires_at)
+					  SELECT access_jti, user_id, $3, access_expires_at FROM refresh_tokens
+					  WHERE `+filter+` AND access_expires_at > $2
+					  ON CONFLICT (jti) DO NOTHING`, arg, now, reason)
+	if err != nil {
+		return 0, err
+	}
+
+	return sessions, tx.Commit()
+}
+
+// RevokeSession ends one session
+func (r *SessionRepository) RevokeSession(sessionID, reason string, now time.Time) (int, error) {
+	return r.revokeWhere("session_id = $1", sessionID, reason, now)
+}
+
+// RevokeUserSessions ends every session of a user
+func (r *SessionRepository) RevokeUserSessions(userID int, reason string, now time.Time) (int, error) {
+	return r.revokeWhere("user_id = $1", userID, reason, now)
+}
+
+// RevokeAccessToken adds a single access token to the revocation list
+func (r *SessionRepository) RevokeAccessToken(jti string, userID int, reason string, expiresAt time.Time) error {
+	_, err := r.db.Exec(`INSERT INTO revoked_tokens (jti, user_id, reason, expires_at)
+						 VALUES ($1, $2, $3, $4) ON CONFLICT (jti) DO NOTHING`, jti, userID, reason, expiresAt)
+	return err
+}
+
+func (r *SessionRepository) IsTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	return revoked, err
+}
+
+// GetActiveSessions lists a user's sessions that can still be refreshed
+func (r *SessionRepository) GetActiveSessions(userID int, now time.Time) ([]models.Session, error) {
+	query := `SELECT rt.session_id, COALESCE(rt.user_agent, ''), COALESCE(rt.ip_address, ''),
+			  (SELECT MIN(s.created_at) FROM refresh_tokens s WHERE s.session_id = rt.session_id),
+			  rt.created_at, rt.expires_at
+			  FROM refresh_tokens rt
+			  WHERE rt.user_id = $1 AND rt.revoked_at IS NULL AND rt.expires_at > $2
+			  ORDER BY rt.created_at DESC`
+
+	rows, err := r.db.Query(query, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.SessionID, &s.UserAgent, &s.IPAddress, &s.StartedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// GetTokenUser loads the fields an access token carries
+func (r *SessionRepository) GetTokenUser(userID int) (*models.User, error) {
+	return NewAccountRepository(r.db).GetUser(userID)
+}
+
+// DeleteExpired removes refresh tokens and revocations past their expiry;
+// an expired token is rejected on its own
+func (r *SessionRepository) DeleteExpired(now time.Time) (int64, error) {
+	res, err := r.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	revoked, _ := res.RowsAffected()
+
+	res, err = r.db.Exec(`DELETE FROM refresh_tokens WHERE expires_at < $1`, now)
+	if err != nil {
+		return revoked, err
+	}
+	refresh, _ := res.RowsAffected()
+	return revoked + refresh, nil
+}
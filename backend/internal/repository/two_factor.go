@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TwoFactorState is a user's TOTP enrollment
+type TwoFactorState struct {
+	SealedSecret string
+	EnabledAt    *time.Time
+	LastStep     int64
+}
+
+type TwoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// GetState returns userID's enrollment, or nil for an unknown user
+func (r *TwoFactorRepository) GetState(userID int) (*TwoFactorState, error) {
+	var state TwoFactorState
+	var secret sql.NullString
+	var enabledAt sql.NullTime
+	err := r.db.QueryRow(`SELECT totp_secret, totp_enabled_at, totp_last_step FROM users WHERE id = $1`, userID).
+		Scan(&secret, &enabledAt, &state.LastStep)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state.SealedSecret = secret.String
+	if enabledAt.Valid {
+		state.EnabledAt = &enabledAt.Time
+	}
+	return &state, nil
+}
+
+// SetPendingSecret starts enrollment. It does nothing once 2FA is enabled.
+func (r *TwoFactorRepository) SetPendingSecret(userID int, sealedSecret string) (bool, error) {
+	res, err := r.db.Exec(`UPDATE users SET totp_secret = $2, totp_last_step = 0
+						   WHERE id = $1 AND totp_enabled_at IS NULL`, userID, sealedSecret)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Enable turns 2FA on and replaces the recovery codes
+func (r *TwoFactorRepository) Enable(userID int, step int64, codeHashes []string, now time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_enabled_at = $2, totp_last_step = $3, updated_at = $2
+					  WHERE id = $1`, userID, now, step)
+	if err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseStep records the step of an accepted code. It reports false when a
+// code at or after step was already used.
+func (r *TwoFactorRepository) UseStep(userID int, step int64) (bool, error) {
+	res, err := r.db.Exec(`UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// UseRecoveryCode marks an unused code as used, reporting whether it was
+// valid
+func (r *TwoFactorRepository) UseRecoveryCode(userID int, codeHash string, now time.Time) (bool, error) {
+	res, err := r.db.Exec(`UPDATE recovery_codes SET used_at = $3
+						   WHERE id = (SELECT id FROM recovery_codes
+									   WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+									   LIMIT 1)`, userID, codeHash, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *TwoFactorRepository) CountRecoveryCodes(userID int) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+// ReplaceRecoveryCodes drops the old codes and stores new ones
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Disable turns 2FA off and deletes the secret and recovery codes
+func (r *TwoFactorRepository) Disable(userID int, now time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = $2
+					  WHERE id = $1`, userID, now)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+type ValidationRulesRepository struct {
+	db *sql.DB
+}
+
+func NewValidationRulesRepository(db *sql.DB) *ValidationRulesRepository {
+	return &ValidationRulesRepository{db: db}
+}
+
+// Latest returns the rules in force, or nil when none were saved
+func (r *ValidationRulesRepository) Latest() (*models.ValidationRules, error) {
+	query := `SELECT version, rules, COALESCE(note, ''), created_by, created_at
+			  FROM validation_rule_sets
+			  ORDER BY version DESC
+			  LIMIT 1`
+
+	var rules models.ValidationRules
+	var data []byte
+	var note string
+	var createdBy sql.NullInt64
+	var version int
+	var createdAt time.Time
+	err := r.db.QueryRow(query).Scan(&version, &data, &note, &createdBy, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+
+	rules.Version = version
+	rules.Note = note
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		rules.CreatedBy = &id
+	}
+	rules.CreatedAt = &createdAt
+	return &rules, nil
+}
+
+// Create saves rules as a new version and sets Version and CreatedAt
+func (r *ValidationRulesRepository) Create(rules *models.ValidationRules) error {
+	stored := *rules
+	stored.Version, stored.Note, stored.CreatedBy, stored.CreatedAt = 0, "", nil, nil
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	var createdAt time.Time
+	query := `INSERT INTO validation_rule_sets (rules, note, created_by)
+			  VALUES ($1, NULLIF($2, ''), $3)
+			  RETURNING version, created_at`
+	if err := r.db.QueryRow(query, string(data), rules.Note, rules.CreatedBy).Scan(&rules.Version, &createdAt); err != nil {
+		return err
+	}
+	rules.CreatedAt = &createdAt
+	return nil
+}
@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// queueStage is the stage a submission waits in
+const queueStage = `CASE
+	WHEN v.verification_status = 'pending_inspection' OR v.verification_substatus = 'pending_inspection' THEN 'pending_inspection'
+	WHEN v.verification_status = 'under_review' OR v.verification_substatus = 'under_review' THEN 'under_review'
+	ELSE 'pending' END`
+
+// inQueue holds for submissions waiting for a verifier; those sent back
+// for correction wait for their owner
+const inQueue = `v.verification_status IN ('pending', 'submitted', 'under_review', 'pending_inspection')
+	AND COALESCE(v.verification_substatus, '') <> 'needs_correction'`
+
+// unassigned holds when nobody holds a submission, or their hold expired
+const unassigned = `(v.assigned_to IS NULL OR v.assignment_expires_at IS NULL OR v.assignment_expires_at <= $1)`
+
+// StageSLA is how long a submission may wait in each stage, in the order
+// the queue queries take them: pending, under_review, pending_inspection
+type StageSLA [3]time.Duration
+
+// deadline is the SQL deadline of a submission with the SLA at $2, $3, $4
+const deadline = `v.stage_entered_at + make_interval(secs => CASE ` + queueStage + `
+	WHEN 'under_review' THEN $3::float8 WHEN 'pending_inspection' THEN $4::float8 ELSE $2::float8 END)`
+
+type VerificationQueueRepository struct {
+	db *sql.DB
+}
+
+func NewVerificationQueueRepository(db *sql.DB) *VerificationQueueRepository {
+	return &VerificationQueueRepository{db: db}
+}
+
+// Items lists the submissions in the queue, those held by verifierID only
+// when it is not 0. Holds that expired before now are left out.
+func (r *VerificationQueueRepository) Items(now time.Time, verifierID int) ([]models.QueueItem, error) {
+	query := `SELECT v.id, v.registration_number, COALESCE(v.vehicle_type, ''),
+			  COALESCE(fo.company_name, u.full_name, ''), ` + queueStage + `, v.stage_entered_at,
+			  v.sla_escalated_at IS NOT NULL, v.assigned_to, COALESCE(a.full_name, ''),
+			  v.assigned_at, v.assignment_expires_at
+			  FROM vehicles v
+			  LEFT JOIN fleet_owners fo ON v.fleet_owner_id = fo.id
+			  LEFT JOIN users u ON COALESCE(fo.user_id, v.created_by) = u.id
+			  LEFT JOIN users a ON v.assigned_to = a.id
+			  WHERE ` + inQueue + `
+			    AND ($2 = 0 OR (v.assigned_to = $2 AND v.assignment_expires_at > $1))
+			  ORDER BY v.stage_entered_at, v.id`
+
+	rows, err := r.db.Query(query, now, verifierID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.QueueItem{}
+	for rows.Next() {
+		var item models.QueueItem
+		var assignedTo sql.NullInt64
+		var assignedAt, expiresAt sql.NullTime
+		if err := rows.Scan(&item.VehicleID, &item.RegistrationNumber, &item.VehicleType, &item.OwnerName,
+			&item.Stage, &item.StageEnteredAt, &item.Escalated, &assignedTo, &item.AssignedName,
+			&assignedAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		if assignedTo.Valid && expiresAt.Valid && expiresAt.Time.After(now) {
+			id := int(assignedTo.Int64)
+			item.AssignedTo = &id
+			item.AssignedAt = &assignedAt.Time
+			item.AssignmentExpiresAt = &expiresAt.Time
+		} else {
+			item.AssignedName = ""
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Claim gives a submission in the queue to verifierID until expiresAt,
+// unless someone else holds it. Holding it already renews the hold. It
+// reports whether the verifier holds it now.
+func (r *VerificationQueueRepository) Claim(vehicleID, verifierID int, method string, now, expiresAt time.Time) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE vehicles v
+			  SET assigned_to = $2,
+			      assigned_at = CASE WHEN v.assigned_to = $2 AND v.assignment_expires_at > $1 THEN v.assigned_at ELSE $1 END,
+			      assignment_expires_at = $3
+			  WHERE v.id = $4 AND ` + inQueue + `
+			    AND (` + unassigned + ` OR v.assigned_to = $2)
+			  RETURNING v.assigned_at = $1`
+
+	var fresh bool
+	err = tx.QueryRow(query, now, verifierID, expiresAt, vehicleID).Scan(&fresh)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if fresh {
+		_, err = tx.Exec(`INSERT INTO verification_assignments (vehicle_id, verifier_id, method, assigned_at)
+						  VALUES ($1, $2, $3, $4)`, vehicleID, verifierID, method, now)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// Next returns the unassigned submission whose SLA ends first, 0 when
+// there is none. skip leaves out submissions already tried.
+func (r *VerificationQueueRepository) Next(now time.Time, sla StageSLA, skip []int) (int, error) {
+	query := `SELECT v.id FROM vehicles v
+			  WHERE ` + inQueue + ` AND ` + unassigned + `
+			    AND NOT (v.id = ANY($5))
+			  ORDER BY ` + deadline + `, v.id
+			  LIMIT 1`
+
+	if skip == nil {
+		skip = []int{}
+	}
+	var id int
+	err := r.db.QueryRow(query, now, sla[0].Seconds(), sla[1].Seconds(), sla[2].Seconds(), pq.Array(skip)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// Release frees a submission held by verifierID, or by anyone when
+// verifierID is 0. It reports whether anything was freed.
+func (r *VerificationQueueRepository) Release(vehicleID, verifierID int, now time.Time) (bool, error) {
+	result, err := r.db.Exec(`UPDATE vehicles
+							  SET assigned_to = NULL, assigned_at = NULL, assignment_expires_at = NULL
+							  WHERE id = $1 AND assigned_to IS NOT NULL AND assignment_expires_at > $3
+							    AND ($2 = 0 OR assigned_to = $2)`, vehicleID, verifierID, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// Breached lists the submissions past their SLA whose breach was not
+// escalated yet
+func (r *VerificationQueueRepository) Breached(now time.Time, sla StageSLA) ([]models.QueueItem, error) {
+	query := `SELECT v.id, v.registration_number, ` + queueStage + `, v.stage_entered_at,
+			  CASE WHEN v.assignment_expires_at > $1 THEN a.full_name END
+			  FROM vehicles v
+			  LEFT JOIN users a ON v.assigned_to = a.id
+			  WHERE ` + inQueue + ` AND v.sla_escalated_at IS NULL AND ` + deadline + ` <= $1
+			  ORDER BY v.stage_entered_at, v.id`
+
+	rows, err := r.db.Query(query, now, sla[0].Seconds(), sla[1].Seconds(), sla[2].Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.QueueItem
+	for rows.Next() {
+		var item models.QueueItem
+		var assignedName sql.NullString
+		if err := rows.Scan(&item.VehicleID, &item.RegistrationNumber, &item.Stage, &item.StageEnteredAt, &assignedName); err != nil {
+			return nil, err
+		}
+		item.AssignedName = assignedName.String
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// MarkEscalated records that the breach of the stage a submission entered
+// at stageEnteredAt was escalated. It reports false when the submission
+// moved on or another instance escalated it first.
+func (r *VerificationQueueRepository) MarkEscalated(vehicleID int, stageEnteredAt, now time.Time) (bool, error) {
+	result, err := r.db.Exec(`UPDATE vehicles SET sla_escalated_at = $3
+							  WHERE id = $1 AND stage_entered_at = $2 AND sla_escalated_at IS NULL`,
+		vehicleID, stageEnteredAt, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// Users lists the users with one of roles, with when they were last
+// handed a submission
+func (r *VerificationQueueRepository) Users(roles []string) ([]models.Verifier, error) {
+	query := `SELECT u.id, u.full_name, u.role, MAX(va.assigned_at)
+			  FROM users u
+			  LEFT JOIN verification_assignments va ON va.verifier_id = u.id
+			  WHERE u.role = ANY($1)
+			  GROUP BY u.id, u.full_name, u.role
+			  ORDER BY u.id`
+
+	rows, err := r.db.Query(query, pq.Array(roles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.Verifier
+	for rows.Next() {
+		var u models.Verifier
+		var lastAssigned sql.NullTime
+		if err := rows.Scan(&u.ID, &u.Name, &u.Role, &lastAssigned); err != nil {
+			return nil, err
+		}
+		if lastAssigned.Valid {
+			u.LastAssignedAt = &lastAssigned.Time
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// Throughput counts, per user, the submissions they were handed and the
+// verification decisions they made since since
+func (r *VerificationQueueRepository) Throughput(since time.Time) (map[int]*models.VerifierWorkload, error) {
+	counts := make(map[int]*models.VerifierWorkload)
+	get := func(id int) *models.VerifierWorkload {
+		if counts[id] == nil {
+			counts[id] = &models.VerifierWorkload{}
+		}
+		return counts[id]
+	}
+
+	rows, err := r.db.Query(`SELECT verifier_id, COUNT(*) FROM verification_assignments
+							 WHERE assigned_at >= $1 GROUP BY verifier_id`, since)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id, n int
+		if err := rows.Scan(&id, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		get(id).Claimed = n
+	}
+	rows.Close()
+
+	rows, err = r.db.Query(`SELECT admin_id, new_status, COUNT(*) FROM verification_history
+							WHERE admin_id IS NOT NULL AND decided_by = 'admin' AND verified_at >= $1
+							GROUP BY admin_id, new_status`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, n int
+		var status string
+		if err := rows.Scan(&id, &status, &n); err != nil {
+			return nil, err
+		}
+		w := get(id)
+		w.Decided += n
+		switch status {
+		case "approved":
+			w.Approved += n
+		case "rejected":
+			w.Rejected += n
+		case "needs_correction":
+			w.NeedsCorrection += n
+		}
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/mail"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+const (
+	verifyEmailTokenTTL   = 48 * time.Hour
+	resetPasswordTokenTTL = time.Hour
+)
+
+var ErrEmailAlreadyVerified = errors.New("email already verified")
+
+// AccountService handles email verification and password reset
+type AccountService struct {
+	repo     *repository.AccountRepository
+	sessions *SessionService
+	mailer   mail.Mailer
+	cfg      *config.MailConfig
+}
+
+func NewAccountService(db *sql.DB, mailer mail.Mailer, cfg *config.MailConfig, sessionCfg *config.SessionConfig) *AccountService {
+	return &AccountService{
+		repo:     repository.NewAccountRepository(db),
+		sessions: NewSessionService(db, sessionCfg),
+		mailer:   mailer,
+		cfg:      cfg,
+	}
+}
+
+// sendToken issues a token for purpose and mails user a link to path
+// carrying it
+func (s *AccountService) sendToken(user *models.User, purpose string, ttl time.Duration, path, subject, body string) error {
+	token, issued, err := auth.SignActionToken(user.ID, purpose, ttl)
+	if err != nil {
+		return fmt.Errorf("failed to sign token: %v", err)
+	}
+	if err := s.repo.CreateToken(user.ID, purpose, auth.HashToken(issued.Nonce), issued.ExpiresAt, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to store token: %v", err)
+	}
+
+	link := fmt.Sprintf("%s%s?token=%s", s.cfg.AppBaseURL, path, url.QueryEscape(token))
+	err = s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    fmt.Sprintf("Halo %s,\n\n%s\n\n%s\n\nLink ini berlaku sampai %s UTC dan hanya dapat digunakan sekali.\n", user.FullName, body, link, issued.ExpiresAt.Format("2006-01-02 15:04")),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	return nil
+}
+
+// consumeToken checks token and marks it used
+func (s *AccountService) consumeToken(token, purpose string) (*auth.ActionToken, error) {
+	now := time.Now().UTC()
+	parsed, err := auth.ParseActionToken(token, purpose, now)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.ConsumeToken(parsed.UserID, purpose, auth.HashToken(parsed.Nonce), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume token: %v", err)
+	}
+	if !ok {
+		return nil, auth.ErrInvalidActionToken
+	}
+	return parsed, nil
+}
+
+// SendVerification mails user a link that verifies their email address
+func (s *AccountService) SendVerification(user *models.User) error {
+	return s.sendToken(user, auth.PurposeVerifyEmail, verifyEmailTokenTTL, "/verify-email",
+		"Verifikasi email akun TMS", "Silakan verifikasi alamat email Anda dengan membuka link berikut:")
+}
+
+// ResendVerification sends a fresh verification link; earlier links stop
+// working
+func (s *AccountService) ResendVerification(userID int) error {
+	user, err := s.repo.GetUser(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %v", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+	return s.SendVerification(user)
+}
+
+func (s *AccountService) VerifyEmail(token string) error {
+	parsed, err := s.consumeToken(token, auth.PurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.MarkEmailVerified(parsed.UserID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to verify email: %v", err)
+	}
+	return nil
+}
+
+// RequestPasswordReset mails a reset link to email. Unknown addresses are
+// ignored so the endpoint does not reveal which emails have accounts.
+func (s *AccountService) RequestPasswordReset(email string) error {
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %v", err)
+	}
+	if user == nil {
+		return nil
+	}
+	return s.sendToken(user, auth.PurposeResetPassword, resetPasswordTokenTTL, "/reset-password",
+		"Reset kata sandi akun TMS", "Kami menerima permintaan reset kata sandi. Buka link berikut untuk membuat kata sandi baru:")
+}
+
+// ResetPassword sets a new password and ends every session of the user.
+// The token is only used up together with the password change, so a
+// failure leaves the link working.
+func (s *AccountService) ResetPassword(token, password string) error {
+	now := time.Now().UTC()
+	parsed, err := auth.ParseActionToken(token, auth.PurposeResetPassword, now)
+	if err != nil {
+		return err
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+	// The link proves control of the mailbox, so the address is verified too
+	ok, err := s.repo.ResetPassword(parsed.UserID, auth.PurposeResetPassword, auth.HashToken(parsed.Nonce), hash, now)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+	if !ok {
+		return auth.ErrInvalidActionToken
+	}
+
+	if n, err := s.sessions.RevokeUserSessions(parsed.UserID, RevokeReasonPassword); err != nil {
+		return err
+	} else if n > 0 {
+		log.Printf("Password reset - UserID: %d, %d session(s) revoked", parsed.UserID, n)
+	}
+	return nil
+}
+
+func (s *AccountService) IsEmailVerified(userID int) (bool, error) {
+	verified, err := s.repo.IsEmailVerified(userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check email verification: %v", err)
+	}
+	return verified, nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/mail"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+func TestActionToken(t *testing.T) {
+	now := time.Now()
+	token, issued, err := auth.SignActionToken(42, auth.PurposeResetPassword, time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	parsed, err := auth.ParseActionToken(token, auth.PurposeResetPassword, now)
+	if err != nil {
+		t.Fatalf("Expected token to parse, got %v", err)
+	}
+	if parsed.UserID != 42 || parsed.Nonce != issued.Nonce {
+		t.Errorf("Expected user 42 with nonce %s, got %+v", issued.Nonce, parsed)
+	}
+
+	if _, err := auth.ParseActionToken(token, auth.PurposeVerifyEmail, now); !errors.Is(err, auth.ErrInvalidActionToken) {
+		t.Errorf("Expected a reset token to be refused for verification, got %v", err)
+	}
+	if _, err := auth.ParseActionToken(token, auth.PurposeResetPassword, now.Add(2*time.Hour)); !errors.Is(err, auth.ErrInvalidActionToken) {
+		t.Errorf("Expected an expired token to be refused, got %v", err)
+	}
+
+	// Changing the user ID breaks the signature
+	encoded, signature, _ := strings.Cut(token, ".")
+	forged := strings.Replace(mustDecode(t, encoded), "42.", "43.", 1)
+	if _, err := auth.ParseActionToken(encode(forged)+"."+signature, auth.PurposeResetPassword, now); !errors.Is(err, auth.ErrInvalidActionToken) {
+		t.Errorf("Expected a forged token to be refused, got %v", err)
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	conn := tenantTestDB(t)
+	defer conn.Close()
+
+	name := fmt.Sprintf("verify_%d", time.Now().UnixNano())
+	user := &models.User{Username: name, Email: name + "@example.com", FullName: name, Role: auth.RoleFleetOwner}
+	err := conn.QueryRow(`INSERT INTO users (username, email, password_hash, full_name, role)
+		VALUES ($1, $2, 'x', $3, $4) RETURNING id`, user.Username, user.Email, user.FullName, user.Role).Scan(&user.ID)
+	if err != nil {
+		t.Fatalf("Failed to seed user: %v", err)
+	}
+	defer conn.Exec("DELETE FROM users WHERE id = $1", user.ID)
+
+	mailer := mail.NewMemoryMailer()
+	accounts := NewAccountService(conn, mailer, &config.MailConfig{AppBaseURL: "http://tms.test"}, &config.SessionConfig{AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, CleanupInterval: time.Hour})
+
+	if verified, _ := accounts.IsEmailVerified(user.ID); verified {
+		t.Fatal("Expected a new account to be unverified")
+	}
+	if err := accounts.SendVerification(user); err != nil {
+		t.Fatal(err)
+	}
+	first := linkToken(t, mailer)
+	if err := accounts.ResendVerification(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	second := linkToken(t, mailer)
+
+	if err := accounts.VerifyEmail(first); !errors.Is(err, auth.ErrInvalidActionToken) {
+		t.Errorf("Expected a superseded link to be refused, got %v", err)
+	}
+	if err := accounts.VerifyEmail(second); err != nil {
+		t.Fatalf("Expected the latest link to verify, got %v", err)
+	}
+	if err := accounts.VerifyEmail(second); !errors.Is(err, auth.ErrInvalidActionToken) {
+		t.Errorf("Expected a used link to be refused, got %v", err)
+	}
+	if verified, _ := accounts.IsEmailVerified(user.ID); !verified {
+		t.Error("Expected the account to be verified")
+	}
+	if err := accounts.ResendVerification(user.ID); !errors.Is(err, ErrEmailAlreadyVerified) {
+		t.Errorf("Expected ErrEmailAlreadyVerified, got %v", err)
+	}
+
+	// Unknown addresses get the same answer and no mail
+	sent := len(mailer.Sent())
+	if err := accounts.RequestPasswordReset("nobody_" + user.Email); err != nil || len(mailer.Sent()) != sent {
+		t.Errorf("Expected unknown address to be ignored silently, got %v", err)
+	}
+	if err := accounts.RequestPasswordReset(strings.ToUpper(user.Email)); err != nil {
+		t.Fatal(err)
+	}
+	if err := accounts.ResetPassword(linkToken(t, mailer), "new-password-123"); err != nil {
+		t.Fatalf("Expected password reset to succeed, got %v", err)
+	}
+	if _, err := auth.LoginUser(conn, models.LoginRequest{Email: user.Email, Password: "new-password-123"}); err != nil {
+		t.Errorf("Expected login with the new password, got %v", err)
+	}
+}
+
+// linkToken pulls the token out of the link in the last mail sent
+func linkToken(t *testing.T, mailer *mail.MemoryMailer) string {
+	t.Helper()
+	sent := mailer.Sent()
+	if len(sent) == 0 {
+		t.Fatal("Expected a mail to be sent")
+	}
+	body := sent[len(sent)-1].Body
+	start := strings.Index(body, "?token=")
+	if start < 0 {
+		t.Fatalf("Expected a link in the mail, got %q", body)
+	}
+	raw := strings.Fields(body[start+len("?token="):])[0]
+	token, err := url.QueryUnescape(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func encode(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func mustDecode(t *testing.T, s string) string {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
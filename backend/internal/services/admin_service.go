@@ -1,12 +1,17 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/registry"
 )
 
 func GetPendingVehicles(db *sql.DB) ([]map[string]interface{}, error) {
@@ -133,7 +138,37 @@ func GetAllVehiclesForAdmin(db *sql.DB) ([]map[string]interface{}, error) {
 	return vehicles, nil
 }
 
-func UpdateVehicleVerificationStatus(db *sql.DB, vehicleID int, status string, adminNotes string, adminID int) error {
+// UpdateVehicleVerificationStatus records a verification decision. The
+// system decides as SystemActorID and passes the trail of how it decided;
+// a trail with QASampled queues the vehicle for a verifier's QA review.
+// A vehicle another verifier holds is refused with ErrClaimedByOther
+// unless overrideClaim.
+func UpdateVehicleVerificationStatus(db *sql.DB, vehicleID int, status string, adminNotes string, adminID int, trail *models.VerificationTrail, overrideClaim bool) error {
+	// Start transaction
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := updateVerificationStatus(tx, vehicleID, status, adminNotes, adminID, trail, nil, overrideClaim); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	notifyVerificationDecision(db, vehicleID, status, adminNotes)
+	return nil
+}
+
+// updateVerificationStatus records a verification decision in tx, with
+// the vehicle row locked. When from is given it only decides a vehicle
+// whose status is one of from, and reports whether it did.
+func updateVerificationStatus(tx *sql.Tx, vehicleID int, status string, adminNotes string, adminID int, trail *models.VerificationTrail, from []string, overrideClaim bool) (bool, error) {
 	// Validate status - now supports more statuses
 	validStatuses := []string{"approved", "rejected", "needs_correction", "under_review", "pending_inspection"}
 	validStatus := false
@@ -144,21 +179,17 @@ func UpdateVehicleVerificationStatus(db *sql.DB, vehicleID int, status string, a
 		}
 	}
 	if !validStatus {
-		return fmt.Errorf("invalid status: %s", status)
+		return false, fmt.Errorf("invalid status: %s", status)
 	}
 
-	// Start transaction
-	tx, err := db.Begin()
+	// Get current status for history; the lock keeps a concurrent decision
+	// from slipping in between
+	currentStatus, err := lockVehicleForDecision(tx, vehicleID, adminID, overrideClaim)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %v", err)
+		return false, err
 	}
-	defer tx.Rollback()
-
-	// Get current status for history
-	var currentStatus string
-	err = tx.QueryRow("SELECT verification_status FROM vehicles WHERE id = $1", vehicleID).Scan(&currentStatus)
-	if err != nil {
-		return fmt.Errorf("failed to get current status: %v", err)
+	if len(from) > 0 && !slices.Contains(from, currentStatus) {
+		return false, nil
 	}
 
 	// Update operational status and substatus based on verification
@@ -183,32 +214,68 @@ func UpdateVehicleVerificationStatus(db *sql.DB, vehicleID int, status string, a
 		substatus = "pending_inspection"
 	}
 
+	// The system is stored as no user
+	var actor interface{} = adminID
+	decidedBy := "admin"
+	if adminID == SystemActorID {
+		actor = nil
+		decidedBy = "system"
+	}
+	var ruleVersion interface{}
+	var decision interface{}
+	qaSampled := false
+	if trail != nil {
+		data, err := json.Marshal(trail)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode decision: %v", err)
+		}
+		ruleVersion, decision, qaSampled = trail.RuleVersion, string(data), trail.QASampled
+	}
+
 	// Update vehicle status with substatus
 	query := `UPDATE vehicles 
 			  SET verification_status = $1, verification_substatus = $2, operational_status = $3, 
 			      verification_notes = $4, verified_by = $5, verified_at = CURRENT_TIMESTAMP, 
+			      qa_review_status = CASE WHEN $7 THEN 'pending' ELSE qa_review_status END,
 			      updated_at = CURRENT_TIMESTAMP
 			  WHERE id = $6`
 
-	_, err = tx.Exec(query, status, substatus, operationalStatus, adminNotes, adminID, vehicleID)
+	_, err = tx.Exec(query, status, substatus, operationalStatus, adminNotes, actor, vehicleID, qaSampled)
 	if err != nil {
-		return fmt.Errorf("failed to update vehicle status: %v", err)
+		return false, fmt.Errorf("failed to update vehicle status: %v", err)
 	}
 
 	// Insert verification history
-	historyQuery := `INSERT INTO verification_history (vehicle_id, admin_id, previous_status, new_status, admin_notes)
-					 VALUES ($1, $2, $3, $4, $5)`
-	_, err = tx.Exec(historyQuery, vehicleID, adminID, currentStatus, status, adminNotes)
+	historyQuery := `INSERT INTO verification_history (vehicle_id, admin_id, previous_status, new_status, admin_notes,
+					 decided_by, rule_version, decision)
+					 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err = tx.Exec(historyQuery, vehicleID, actor, currentStatus, status, adminNotes, decidedBy, ruleVersion, decision)
 	if err != nil {
-		return fmt.Errorf("failed to insert verification history: %v", err)
+		return false, fmt.Errorf("failed to insert verification history: %v", err)
 	}
+	return true, nil
+}
 
-	// Commit transaction
-	err = tx.Commit()
+// lockVehicleForDecision locks a vehicle for a verification decision in tx
+// and returns its verification status. Unless overrideClaim, it fails with
+// ErrClaimedByOther when someone other than adminID holds the vehicle.
+func lockVehicleForDecision(tx *sql.Tx, vehicleID, adminID int, overrideClaim bool) (string, error) {
+	var status string
+	var assignedTo sql.NullInt64
+	var held bool
+	err := tx.QueryRow(`SELECT verification_status, assigned_to, COALESCE(assignment_expires_at > $2, false)
+						FROM vehicles WHERE id = $1 FOR UPDATE`, vehicleID, time.Now().UTC()).Scan(&status, &assignedTo, &held)
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+		return "", fmt.Errorf("failed to get current status: %v", err)
 	}
+	if !overrideClaim && held && assignedTo.Valid && int(assignedTo.Int64) != adminID {
+		return "", ErrClaimedByOther
+	}
+	return status, nil
+}
 
+// notifyVerificationDecision tells the vehicle owner about a decision
+func notifyVerificationDecision(db *sql.DB, vehicleID int, status, adminNotes string) {
 	// Send notification to vehicle owner
 	go func() {
 		notificationService := NewNotificationService(db)
@@ -225,8 +292,6 @@ func UpdateVehicleVerificationStatus(db *sql.DB, vehicleID int, status string, a
 			log.Printf("Failed to send verification notification: %v", err)
 		}
 	}()
-
-	return nil
 }
 
 func GetAdminDashboardStats(db *sql.DB) (map[string]interface{}, error) {
@@ -426,7 +491,7 @@ func GetVehicleDetailsForAdmin(db *sql.DB, vehicleID int) (map[string]interface{
 
 func GetVerificationHistory(db *sql.DB, vehicleID int) ([]map[string]interface{}, error) {
 	query := `SELECT vh.id, vh.previous_status, vh.new_status, vh.admin_notes, vh.verified_at,
-			  u.full_name as admin_name, u.email as admin_email
+			  u.full_name as admin_name, u.email as admin_email, vh.decided_by, vh.rule_version, vh.decision
 			  FROM verification_history vh
 			  LEFT JOIN users u ON vh.admin_id = u.id
 			  WHERE vh.vehicle_id = $1
@@ -446,8 +511,12 @@ func GetVerificationHistory(db *sql.DB, vehicleID int) ([]map[string]interface{}
 		var adminNotes sql.NullString
 		var verifiedAt string
 		var adminName, adminEmail sql.NullString
+		var decidedBy string
+		var ruleVersion sql.NullInt64
+		var decision []byte
 
-		err := rows.Scan(&id, &previousStatus, &newStatus, &adminNotes, &verifiedAt, &adminName, &adminEmail)
+		err := rows.Scan(&id, &previousStatus, &newStatus, &adminNotes, &verifiedAt, &adminName, &adminEmail,
+			&decidedBy, &ruleVersion, &decision)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan history: %v", err)
 		}
@@ -467,6 +536,13 @@ func GetVerificationHistory(db *sql.DB, vehicleID int) ([]map[string]interface{}
 		if adminEmail.Valid {
 			h["admin_email"] = adminEmail.String
 		}
+		h["decided_by"] = decidedBy
+		if ruleVersion.Valid {
+			h["rule_version"] = ruleVersion.Int64
+		}
+		if decision != nil {
+			h["decision"] = json.RawMessage(decision)
+		}
 
 		history = append(history, h)
 	}
@@ -474,7 +550,7 @@ func GetVerificationHistory(db *sql.DB, vehicleID int) ([]map[string]interface{}
 	return history, nil
 }
 
-func GetApprovedVehicles(db *sql.DB) ([]map[string]interface{}, error) {
+func GetApprovedVehicles(db *sql.DB, scope models.TenantScope) ([]map[string]interface{}, error) {
 	query := `SELECT v.id, v.registration_number, v.vehicle_type, v.brand, v.model, v.year,
 			  v.verification_status, v.operational_status, v.created_at, v.verified_at, v.admin_notes,
 			  COALESCE(fo.company_name, '') as company_name, 
@@ -483,10 +559,10 @@ func GetApprovedVehicles(db *sql.DB) ([]map[string]interface{}, error) {
 			  FROM vehicles v
 			  LEFT JOIN fleet_owners fo ON v.fleet_owner_id = fo.id
 			  LEFT JOIN users u ON fo.user_id = u.id
-			  WHERE v.verification_status = 'approved'
+			  WHERE v.verification_status = 'approved' AND ($1 = 0 OR v.fleet_owner_id = $1)
 			  ORDER BY v.created_at DESC`
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, scope.FilterID())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get approved vehicles: %s", strings.ReplaceAll(err.Error(), "\n", " "))
 	}
@@ -716,7 +792,11 @@ func GetAdminVerificationDashboard(db *sql.DB) (map[string]interface{}, error) {
 	return dashboard, nil
 }
 
-func PerformCrossCheck(db *sql.DB, vehicleID int, checkType string) (map[string]interface{}, error) {
+// PerformCrossCheck checks a vehicle against a registry (samsat, kir,
+// insurance), asking the provider of that kind in registries, or for
+// duplicates among the other vehicles. A registry lookup stops when ctx
+// is done, and nothing is stored then.
+func PerformCrossCheck(ctx context.Context, db *sql.DB, registries map[string]registry.Provider, vehicleID int, checkType string) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 	
 	// Get vehicle details for cross-checking
@@ -728,17 +808,17 @@ func PerformCrossCheck(db *sql.DB, vehicleID int, checkType string) (map[string]
 	}
 
 	switch checkType {
-	case "samsat":
-		result = performSamsatCheck(regNumber)
-	case "kir":
-		result = performKIRCheck(regNumber)
-	case "insurance":
-		result = performInsuranceCheck(regNumber)
+	case registry.Samsat, registry.KIR, registry.Insurance:
+		query := registry.Query{RegistrationNumber: regNumber, ChassisNumber: chassisNumber, EngineNumber: engineNumber}
+		result = performRegistryCheck(ctx, registries[checkType], checkType, query, time.Now())
 	case "duplicate":
 		result = performDuplicateCheck(db, regNumber, chassisNumber, engineNumber, vehicleID)
 	default:
 		return nil, fmt.Errorf("unknown check type: %s", checkType)
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Store cross-check result in database
 	insertQuery := `INSERT INTO fraud_checks (vehicle_id, check_type, result, confidence_score, details)
@@ -756,55 +836,6 @@ func PerformCrossCheck(db *sql.DB, vehicleID int, checkType string) (map[string]
 	return result, nil
 }
 
-func performSamsatCheck(regNumber string) map[string]interface{} {
-	// Simulate Samsat API check
-	result := make(map[string]interface{})
-	result["check_type"] = "samsat"
-	result["status"] = "passed"
-	result["confidence"] = 0.95
-	result["message"] = fmt.Sprintf("Nomor polisi %s terdaftar dan pajak aktif", regNumber)
-	result["details"] = map[string]interface{}{
-		"registration_valid": true,
-		"tax_status": "active",
-		"last_tax_payment": "2024-01-15",
-		"next_due_date": "2025-01-15",
-	}
-	return result
-}
-
-func performKIRCheck(regNumber string) map[string]interface{} {
-	// Simulate KIR validation
-	result := make(map[string]interface{})
-	result["check_type"] = "kir"
-	result["status"] = "passed"
-	result["confidence"] = 0.90
-	result["message"] = "Kendaraan memiliki KIR yang masih berlaku"
-	result["details"] = map[string]interface{}{
-		"kir_valid": true,
-		"issue_date": "2024-06-01",
-		"expiry_date": "2025-06-01",
-		"inspection_station": "Dishub Jakarta Pusat",
-	}
-	return result
-}
-
-func performInsuranceCheck(regNumber string) map[string]interface{} {
-	// Simulate insurance validation
-	result := make(map[string]interface{})
-	result["check_type"] = "insurance"
-	result["status"] = "passed"
-	result["confidence"] = 0.88
-	result["message"] = "Asuransi aktif dan sesuai dengan data kendaraan"
-	result["details"] = map[string]interface{}{
-		"policy_active": true,
-		"insurance_company": "Asuransi Jasa Indonesia",
-		"policy_number": "AJI-2024-001234",
-		"coverage_type": "comprehensive",
-		"expiry_date": "2025-03-15",
-	}
-	return result
-}
-
 func performDuplicateCheck(db *sql.DB, regNumber, chassisNumber, engineNumber string, excludeVehicleID int) map[string]interface{} {
 	result := make(map[string]interface{})
 	
@@ -863,13 +894,20 @@ func performDuplicateCheck(db *sql.DB, regNumber, chassisNumber, engineNumber st
 	return result
 }
 
-func UpdateVehicleWithCorrection(db *sql.DB, vehicleID int, correctionItems []string, adminNotes string, adminID int) error {
+// UpdateVehicleWithCorrection sends a vehicle back to its owner for
+// correction. A vehicle another verifier holds is refused with
+// ErrClaimedByOther unless overrideClaim.
+func UpdateVehicleWithCorrection(db *sql.DB, vehicleID int, correctionItems []string, adminNotes string, adminID int, overrideClaim bool) error {
 	tx, err := db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to start transaction: %v", err)
 	}
 	defer tx.Rollback()
 
+	if _, err := lockVehicleForDecision(tx, vehicleID, adminID, overrideClaim); err != nil {
+		return err
+	}
+
 	// Update vehicle status to needs_correction
 	query := `UPDATE vehicles 
 			  SET verification_status = 'pending', verification_substatus = 'needs_correction', 
@@ -896,13 +934,20 @@ func UpdateVehicleWithCorrection(db *sql.DB, vehicleID int, correctionItems []st
 	return tx.Commit()
 }
 
-func ScheduleInspection(db *sql.DB, vehicleID int, inspectionDate time.Time, location string, adminID int) error {
+// ScheduleInspection puts a vehicle on hold for a physical inspection. A
+// vehicle another verifier holds is refused with ErrClaimedByOther unless
+// overrideClaim.
+func ScheduleInspection(db *sql.DB, vehicleID int, inspectionDate time.Time, location string, adminID int, overrideClaim bool) error {
 	tx, err := db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to start transaction: %v", err)
 	}
 	defer tx.Rollback()
 
+	if _, err := lockVehicleForDecision(tx, vehicleID, adminID, overrideClaim); err != nil {
+		return err
+	}
+
 	// Update vehicle to pending_inspection
 	query := `UPDATE vehicles 
 			  SET verification_substatus = 'pending_inspection', 
@@ -931,7 +976,8 @@ func ScheduleInspection(db *sql.DB, vehicleID int, inspectionDate time.Time, loc
 func GetVehicleAttachments(db *sql.DB, vehicleID int) ([]map[string]interface{}, error) {
 	// First get vehicle attachments from vehicle_attachments table
 	query := `SELECT id, vehicle_id, attachment_type, file_name, file_path, 
-			  file_size, mime_type, uploaded_at
+			  file_size, mime_type, uploaded_at,
+			  COALESCE(validation_status, 'pending'), validation_errors, ocr_data
 			  FROM vehicle_attachments 
 			  WHERE vehicle_id = $1 
 			  ORDER BY uploaded_at DESC`
@@ -948,9 +994,11 @@ func GetVehicleAttachments(db *sql.DB, vehicleID int) ([]map[string]interface{},
 		var id, vehicleIDScanned int
 		var fileSize sql.NullInt64
 		var attachmentType, fileName, filePath, uploadedAt string
-		var mimeType sql.NullString
+		var mimeType, validationErrors, ocrData sql.NullString
+		var validationStatus string
 
-		err := rows.Scan(&id, &vehicleIDScanned, &attachmentType, &fileName, &filePath, &fileSize, &mimeType, &uploadedAt)
+		err := rows.Scan(&id, &vehicleIDScanned, &attachmentType, &fileName, &filePath, &fileSize, &mimeType, &uploadedAt,
+			&validationStatus, &validationErrors, &ocrData)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan attachment: %v", err)
 		}
@@ -972,6 +1020,17 @@ func GetVehicleAttachments(db *sql.DB, vehicleID int) ([]map[string]interface{},
 		}
 		attachment["uploaded_at"] = uploadedAt
 
+		// What the document validation worker read from it
+		attachment["validation_status"] = validationStatus
+		errs := []string{}
+		if validationErrors.Valid {
+			json.Unmarshal([]byte(validationErrors.String), &errs)
+		}
+		attachment["validation_errors"] = errs
+		if ocrData.Valid {
+			attachment["ocr_data"] = json.RawMessage(ocrData.String)
+		}
+
 		attachments = append(attachments, attachment)
 	}
 
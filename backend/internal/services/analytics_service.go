@@ -14,24 +14,24 @@ const (
 	DaysInMonth = 30.0
 )
 
-func GetDashboardStats(db *sql.DB) (*models.DashboardStats, error) {
+func GetDashboardStats(db *sql.DB, scope models.TenantScope) (*models.DashboardStats, error) {
 	var stats models.DashboardStats
 
-	// Single optimized query to get all stats at once
+	// Single optimized query to get all stats at once; $1 = 0 counts every tenant
 	query := `
 		SELECT 
-			(SELECT COUNT(*) FROM vehicles) as total_vehicles,
-			(SELECT COUNT(*) FROM vehicles WHERE operational_status = 'active') as active_vehicles,
-			(SELECT COUNT(*) FROM drivers) as total_drivers,
-			(SELECT COUNT(*) FROM drivers WHERE status = 'available') as active_drivers,
-			(SELECT COUNT(*) FROM trips) as total_trips,
-			(SELECT COUNT(*) FROM trips WHERE status IN ('ongoing', 'in_progress')) as ongoing_trips,
-			(SELECT COUNT(*) FROM trips WHERE status = 'completed') as completed_trips,
-			(SELECT COALESCE(SUM(distance), 0) FROM trips WHERE distance IS NOT NULL) as total_distance,
-			(SELECT COUNT(*) FROM vehicles WHERE next_maintenance_date <= CURRENT_DATE AND next_maintenance_date IS NOT NULL) as maintenance_due
+			(SELECT COUNT(*) FROM vehicles WHERE ($1 = 0 OR fleet_owner_id = $1)) as total_vehicles,
+			(SELECT COUNT(*) FROM vehicles WHERE operational_status = 'active' AND ($1 = 0 OR fleet_owner_id = $1)) as active_vehicles,
+			(SELECT COUNT(*) FROM drivers WHERE ($1 = 0 OR fleet_owner_id = $1)) as total_drivers,
+			(SELECT COUNT(*) FROM drivers WHERE status = 'available' AND ($1 = 0 OR fleet_owner_id = $1)) as active_drivers,
+			(SELECT COUNT(*) FROM trips WHERE ($1 = 0 OR fleet_owner_id = $1)) as total_trips,
+			(SELECT COUNT(*) FROM trips WHERE status IN ('en_route_pickup', 'loading', 'in_transit', 'unloading') AND ($1 = 0 OR fleet_owner_id = $1)) as ongoing_trips,
+			(SELECT COUNT(*) FROM trips WHERE status = 'delivered' AND ($1 = 0 OR fleet_owner_id = $1)) as completed_trips,
+			(SELECT COALESCE(SUM(distance), 0) FROM trips WHERE distance IS NOT NULL AND ($1 = 0 OR fleet_owner_id = $1)) as total_distance,
+			(SELECT COUNT(*) FROM vehicles WHERE next_maintenance_date <= CURRENT_DATE AND next_maintenance_date IS NOT NULL AND ($1 = 0 OR fleet_owner_id = $1)) as maintenance_due
 	`
 
-	err := db.QueryRow(query).Scan(
+	err := db.QueryRow(query, scope.FilterID()).Scan(
 		&stats.TotalVehicles,
 		&stats.ActiveVehicles,
 		&stats.TotalDrivers,
@@ -49,7 +49,7 @@ func GetDashboardStats(db *sql.DB) (*models.DashboardStats, error) {
 	return &stats, nil
 }
 
-func GetVehicleUtilization(db *sql.DB) ([]models.VehicleUtilization, error) {
+func GetVehicleUtilization(db *sql.DB, scope models.TenantScope) ([]models.VehicleUtilization, error) {
 	query := `SELECT v.id, v.registration_number, 
 			  COUNT(t.id) as total_trips,
 			  COALESCE(SUM(t.distance), 0) as total_distance,
@@ -59,10 +59,11 @@ func GetVehicleUtilization(db *sql.DB) ([]models.VehicleUtilization, error) {
 			  END as utilization_rate
 			  FROM vehicles v
 			  LEFT JOIN trips t ON v.id = t.vehicle_id
+			  WHERE ($2 = 0 OR v.fleet_owner_id = $2)
 			  GROUP BY v.id, v.registration_number
 			  ORDER BY total_trips DESC`
 
-	rows, err := db.Query(query, DaysInMonth)
+	rows, err := db.Query(query, DaysInMonth, scope.FilterID())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get vehicle utilization: %v", err)
 	}
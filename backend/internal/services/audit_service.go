@@ -0,0 +1,138 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/audit"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	// MaxAuditExport caps the rows of one CSV export
+	MaxAuditExport = 10000
+)
+
+type AuditService struct {
+	repo *repository.AuditRepository
+}
+
+func NewAuditService(db *sql.DB) *AuditService {
+	return &AuditService{repo: repository.NewAuditRepository(db)}
+}
+
+// RecordAuditEvent stores event in the audit log. The action is also
+// logged, so a failed write still leaves a trace.
+func RecordAuditEvent(db *sql.DB, event models.AuditEvent) error {
+	return NewAuditService(db).Record(&event)
+}
+
+// Record appends event to the audit log, stamping it with the current
+// time unless the caller already did
+func (s *AuditService) Record(event *models.AuditEvent) error {
+	log.Printf("Audit - Action: %s, ActorID: %v, IP: %s", event.Action, actorForLog(event.ActorID), event.IPAddress)
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if err := s.repo.Record(event); err != nil {
+		return fmt.Errorf("failed to record audit event: %v", err)
+	}
+	return nil
+}
+
+// Snapshot reads the current row of an entity for a before/after diff, or
+// returns nil when it does not exist
+func (s *AuditService) Snapshot(entity audit.Entity, key string) (map[string]interface{}, error) {
+	row, err := s.repo.Snapshot(entity, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s %s: %v", entity.Type, key, err)
+	}
+	if row == nil {
+		return nil, nil
+	}
+	return audit.DecodeRow(row)
+}
+
+// ListEvents returns audit entries matching filter, newest first. The
+// limit defaults to 100 and is capped at 1000.
+func (s *AuditService) ListEvents(filter models.AuditFilter) ([]models.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	return s.listEvents(filter)
+}
+
+// ExportEvents returns up to MaxAuditExport entries matching filter
+func (s *AuditService) ExportEvents(filter models.AuditFilter) ([]models.AuditEvent, error) {
+	filter.Limit = MaxAuditExport
+	return s.listEvents(filter)
+}
+
+func (s *AuditService) listEvents(filter models.AuditFilter) ([]models.AuditEvent, error) {
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	events, err := s.repo.List(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %v", err)
+	}
+	return events, nil
+}
+
+// VerifyChain re-computes every hash in the audit log. Entries written
+// before the chain existed have no hash and are counted as legacy; an
+// unhashed entry after the chain started is a break.
+func (s *AuditService) VerifyChain() (*models.AuditChainReport, error) {
+	report := &models.AuditChainReport{Valid: true}
+	prev := ""
+	started := false
+
+	err := s.repo.Walk(func(e *models.AuditEvent) bool {
+		reason := ""
+		switch {
+		case e.Hash == "" && !started:
+			report.Legacy++
+			return true
+		case e.Hash == "":
+			reason = "entry has no hash"
+		case e.PrevHash != prev:
+			reason = "entry does not link to the previous entry"
+		default:
+			hash, err := audit.Hash(e.PrevHash, e)
+			if err != nil || hash != e.Hash {
+				reason = "entry hash does not match its contents"
+			}
+		}
+		if reason != "" {
+			report.Valid = false
+			report.BrokenAt = &e.ID
+			report.Reason = reason
+			return false
+		}
+		started = true
+		prev = e.Hash
+		report.Checked++
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify audit chain: %v", err)
+	}
+	report.Head = prev
+	return report, nil
+}
+
+func actorForLog(actorID *int) interface{} {
+	if actorID == nil {
+		return "-"
+	}
+	return *actorID
+}
@@ -9,7 +9,7 @@ import (
 
 func TestHashPassword(t *testing.T) {
 	password := "testpassword123"
-	hash, err := HashPassword(password)
+	hash, err := auth.HashPassword(password)
 	
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -26,25 +26,25 @@ func TestHashPassword(t *testing.T) {
 
 func TestCheckPassword(t *testing.T) {
 	password := "testpassword123"
-	hash, _ := HashPassword(password)
+	hash, _ := auth.HashPassword(password)
 	
 	// Test correct password
-	if !CheckPassword(hash, password) {
+	if !auth.CheckPassword(password, hash) {
 		t.Fatal("Expected password to match hash")
 	}
 	
 	// Test incorrect password
-	if CheckPassword(hash, "wrongpassword") {
+	if auth.CheckPassword("wrongpassword", hash) {
 		t.Fatal("Expected password to not match hash")
 	}
 }
 
-func TestGenerateToken(t *testing.T) {
+func TestGenerateAccessToken(t *testing.T) {
 	userID := 1
 	username := "testuser"
 	role := "user"
 	
-	token, err := auth.GenerateToken(userID, username, role)
+	token, claims, err := auth.GenerateAccessToken(userID, username, role, "session-1", 15*time.Minute)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -52,6 +52,11 @@ func TestGenerateToken(t *testing.T) {
 	if len(token) == 0 {
 		t.Fatal("Expected token to be generated")
 	}
+	
+	// Access tokens belong to a session and carry a jti, so they can be revoked
+	if claims.SessionID != "session-1" || claims.ID == "" {
+		t.Fatalf("Expected session and jti in claims, got %+v", claims)
+	}
 }
 
 func TestValidateToken(t *testing.T) {
@@ -59,7 +64,7 @@ func TestValidateToken(t *testing.T) {
 	username := "testuser"
 	role := "user"
 	
-	token, err := auth.GenerateToken(userID, username, role)
+	token, _, err := auth.GenerateAccessToken(userID, username, role, "session-1", 15*time.Minute)
 	if err != nil {
 		t.Fatalf("Expected no error generating token, got %v", err)
 	}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/registry"
+)
+
+// SystemActorID is the admin ID of verification decisions the system
+// makes by itself; it is stored as no user
+const SystemActorID = 0
+
+// approvalCrossChecks are the cross-checks an auto-approval policy can
+// require
+var approvalCrossChecks = []string{registry.Samsat, registry.KIR, registry.Insurance, "duplicate"}
+
+var ErrNoQAReview = errors.New("vehicle has no pending QA review")
+
+func isApprovalCrossCheck(check string) bool {
+	for _, c := range approvalCrossChecks {
+		if c == check {
+			return true
+		}
+	}
+	return false
+}
+
+// undecidedStatuses are the verification statuses the system may still
+// decide; anything else a verifier or an earlier run already decided
+var undecidedStatuses = []string{"pending", "submitted"}
+
+// approvalStore is what an auto-approval reads and writes: the database,
+// or a fake in tests
+type approvalStore interface {
+	// Undecided reports whether the vehicle still waits for a decision
+	Undecided(vehicleID int) (bool, error)
+	// CrossCheck runs a cross-check and returns its status
+	CrossCheck(vehicleID int, check string) (string, error)
+	// MarkUnderReview leaves an undecided vehicle to a verifier
+	MarkUnderReview(vehicleID int) error
+	// Decide records the system's decision unless the vehicle was decided
+	// meanwhile, and reports whether it did
+	Decide(vehicleID int, status, notes string, trail *models.VerificationTrail) (bool, error)
+}
+
+// AutoApprovalService runs the auto validation of a submission and acts
+// on it: what the rules auto-approve is approved when the policy allows
+// and its cross-checks pass, what they auto-reject is rejected. Every
+// decision is recorded like a verifier's, as the system.
+type AutoApprovalService struct {
+	validator *AutoValidationService
+	store     approvalStore
+	// sample draws the number compared with the QA sample rate
+	sample func() float64
+}
+
+func NewAutoApprovalService(db *sql.DB, registries map[string]registry.Provider) *AutoApprovalService {
+	return &AutoApprovalService{
+		validator: NewAutoValidationService(db),
+		store:     &dbApprovalStore{db: db, registries: registries},
+		sample:    rand.Float64,
+	}
+}
+
+type dbApprovalStore struct {
+	db         *sql.DB
+	registries map[string]registry.Provider
+}
+
+func (d *dbApprovalStore) Undecided(vehicleID int) (bool, error) {
+	var current string
+	if err := d.db.QueryRow("SELECT verification_status FROM vehicles WHERE id = $1", vehicleID).Scan(&current); err != nil {
+		return false, fmt.Errorf("failed to get vehicle status: %v", err)
+	}
+	return slices.Contains(undecidedStatuses, current), nil
+}
+
+func (d *dbApprovalStore) CrossCheck(vehicleID int, check string) (string, error) {
+	result, err := PerformCrossCheck(context.Background(), d.db, d.registries, vehicleID, check)
+	if err != nil {
+		return "", err
+	}
+	status, _ := result["status"].(string)
+	return status, nil
+}
+
+func (d *dbApprovalStore) MarkUnderReview(vehicleID int) error {
+	_, err := d.db.Exec(`UPDATE vehicles SET verification_substatus = 'under_review', updated_at = CURRENT_TIMESTAMP
+						 WHERE id = $1 AND verification_status = ANY($2)`, vehicleID, pq.Array(undecidedStatuses))
+	if err != nil {
+		return fmt.Errorf("failed to update vehicle substatus: %v", err)
+	}
+	return nil
+}
+
+func (d *dbApprovalStore) Decide(vehicleID int, status, notes string, trail *models.VerificationTrail) (bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// A verifier holding the vehicle decides it, not the system
+	decided, err := updateVerificationStatus(tx, vehicleID, status, notes, SystemActorID, trail, undecidedStatuses, false)
+	if errors.Is(err, ErrClaimedByOther) {
+		return false, nil
+	}
+	if err != nil || !decided {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	notifyVerificationDecision(d.db, vehicleID, status, notes)
+	return true, nil
+}
+
+// Process validates a vehicle with the rules in force and makes the
+// decision they allow. It returns the result and the verification status
+// decided, or "" when the vehicle is left for a verifier.
+func (s *AutoApprovalService) Process(vehicleID int) (*models.AutoValidationResult, string, error) {
+	rules, err := s.validator.rules.Active()
+	if err != nil {
+		return nil, "", err
+	}
+	result, err := s.validator.validateWith(vehicleID, rules)
+	if err != nil {
+		return nil, "", err
+	}
+
+	status, err := s.decide(vehicleID, result, rules)
+	if err != nil {
+		return result, "", err
+	}
+	return result, status, nil
+}
+
+func (s *AutoApprovalService) decide(vehicleID int, result *models.AutoValidationResult, rules *models.ValidationRules) (string, error) {
+	if result.OverallStatus != "auto_approved" && result.OverallStatus != "auto_rejected" {
+		return "", nil
+	}
+
+	// Never overrule a verifier, nor decide twice. This only saves the
+	// cross-checks; Decide checks again with the vehicle locked.
+	undecided, err := s.store.Undecided(vehicleID)
+	if err != nil || !undecided {
+		return "", err
+	}
+
+	trail := &models.VerificationTrail{RuleVersion: rules.Version, ConfidenceScore: result.ConfidenceScore}
+
+	if result.OverallStatus == "auto_rejected" {
+		notes := fmt.Sprintf("Ditolak otomatis: skor validasi %.2f di bawah batas %.2f (aturan versi %d)",
+			result.ConfidenceScore, rules.AutoRejectScore, rules.Version)
+		if decided, err := s.store.Decide(vehicleID, "rejected", notes, trail); err != nil || !decided {
+			return "", err
+		}
+		return "rejected", nil
+	}
+
+	policy := rules.AutoApproval
+	if !policy.Enabled {
+		return "", nil
+	}
+
+	trail.CrossChecks = map[string]string{}
+	var failed []string
+	for _, check := range policy.CrossChecks {
+		status, err := s.store.CrossCheck(vehicleID, check)
+		if err != nil {
+			return "", err
+		}
+		trail.CrossChecks[check] = status
+		if status != "passed" {
+			failed = append(failed, fmt.Sprintf("%s (%s)", check, status))
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		log.Printf("Vehicle %d not auto-approved, cross-checks did not pass: %s", vehicleID, strings.Join(failed, ", "))
+		return "", s.store.MarkUnderReview(vehicleID)
+	}
+
+	trail.QASampled = s.sample() < policy.QASampleRate
+	notes := fmt.Sprintf("Disetujui otomatis: skor validasi %.2f (aturan versi %d)", result.ConfidenceScore, rules.Version)
+	if decided, err := s.store.Decide(vehicleID, "approved", notes, trail); err != nil || !decided {
+		return "", err
+	}
+	log.Printf("Vehicle %d auto-approved with rules version %d (QA sampled: %t)", vehicleID, rules.Version, trail.QASampled)
+	return "approved", nil
+}
+
+// GetQAReviews lists the auto-approvals picked for QA that no verifier has
+// reviewed yet, oldest first
+func GetQAReviews(db *sql.DB) ([]models.QAReview, error) {
+	query := `SELECT v.id, v.registration_number, v.vehicle_type, v.verified_at, vh.rule_version,
+			  (vh.decision->>'confidence_score')::float
+			  FROM vehicles v
+			  LEFT JOIN LATERAL (
+			      SELECT rule_version, decision FROM verification_history
+			      WHERE vehicle_id = v.id AND decided_by = 'system' AND new_status = 'approved'
+			      ORDER BY verified_at DESC, id DESC
+			      LIMIT 1
+			  ) vh ON true
+			  WHERE v.qa_review_status = 'pending'
+			  ORDER BY v.verified_at, v.id`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get QA reviews: %v", err)
+	}
+	defer rows.Close()
+
+	reviews := []models.QAReview{}
+	for rows.Next() {
+		var r models.QAReview
+		var ruleVersion sql.NullInt64
+		var score sql.NullFloat64
+		if err := rows.Scan(&r.VehicleID, &r.RegistrationNumber, &r.VehicleType, &r.ApprovedAt, &ruleVersion, &score); err != nil {
+			return nil, fmt.Errorf("failed to scan QA review: %v", err)
+		}
+		if ruleVersion.Valid {
+			version := int(ruleVersion.Int64)
+			r.RuleVersion = &version
+		}
+		if score.Valid {
+			r.ConfidenceScore = &score.Float64
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews, rows.Err()
+}
+
+// CompleteQAReview records a verifier's review of an auto-approval:
+// "confirmed" keeps it, "rejected" or "needs_correction" overturns it. The
+// review is locked while it is completed, so it is completed only once.
+func CompleteQAReview(db *sql.DB, vehicleID int, outcome, notes string, adminID int) error {
+	result := "confirmed"
+	switch outcome {
+	case "confirmed":
+	case "rejected", "needs_correction":
+		result = "overturned"
+	default:
+		return fmt.Errorf("invalid QA outcome: %s", outcome)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var qaStatus sql.NullString
+	err = tx.QueryRow("SELECT qa_review_status FROM vehicles WHERE id = $1 FOR UPDATE", vehicleID).Scan(&qaStatus)
+	if err == sql.ErrNoRows || (err == nil && qaStatus.String != "pending") {
+		return ErrNoQAReview
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get QA review: %v", err)
+	}
+
+	if result == "overturned" {
+		if _, err := updateVerificationStatus(tx, vehicleID, outcome, notes, adminID, nil, nil, true); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`UPDATE vehicles SET qa_review_status = $1, qa_reviewed_by = $2, qa_reviewed_at = CURRENT_TIMESTAMP
+					  WHERE id = $3`, result, adminID, vehicleID)
+	if err != nil {
+		return fmt.Errorf("failed to complete QA review: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	if result == "overturned" {
+		notifyVerificationDecision(db, vehicleID, outcome, notes)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+type fakeApprovalStore struct {
+	undecided   bool
+	crossChecks map[string]string
+	// decided is what Decide reports, false as if a verifier got there first
+	decided bool
+
+	checked     []string
+	underReview bool
+	status      string
+	trail       *models.VerificationTrail
+}
+
+func (f *fakeApprovalStore) Undecided(vehicleID int) (bool, error) { return f.undecided, nil }
+
+func (f *fakeApprovalStore) CrossCheck(vehicleID int, check string) (string, error) {
+	f.checked = append(f.checked, check)
+	return f.crossChecks[check], nil
+}
+
+func (f *fakeApprovalStore) MarkUnderReview(vehicleID int) error {
+	f.underReview = true
+	return nil
+}
+
+func (f *fakeApprovalStore) Decide(vehicleID int, status, notes string, trail *models.VerificationTrail) (bool, error) {
+	if f.decided {
+		f.status, f.trail = status, trail
+	}
+	return f.decided, nil
+}
+
+func TestAutoApprovalDecide(t *testing.T) {
+	rules := DefaultValidationRules()
+	rules.Version = 3
+	rules.AutoApproval = models.AutoApprovalPolicy{Enabled: true, CrossChecks: []string{"samsat", "duplicate"}, QASampleRate: 0.2}
+	passed := map[string]string{"samsat": "passed", "duplicate": "passed"}
+
+	tests := []struct {
+		name        string
+		overall     string
+		store       fakeApprovalStore
+		sample      float64
+		disabled    bool
+		want        string
+		checked     []string
+		underReview bool
+		qaSampled   bool
+	}{
+		{name: "approved", overall: "auto_approved", store: fakeApprovalStore{undecided: true, decided: true, crossChecks: passed},
+			sample: 0.5, want: "approved", checked: []string{"samsat", "duplicate"}},
+		{name: "approved and sampled", overall: "auto_approved", store: fakeApprovalStore{undecided: true, decided: true, crossChecks: passed},
+			sample: 0.1, want: "approved", checked: []string{"samsat", "duplicate"}, qaSampled: true},
+		{name: "cross-check failed", overall: "auto_approved",
+			store:  fakeApprovalStore{undecided: true, decided: true, crossChecks: map[string]string{"samsat": "failed", "duplicate": "passed"}},
+			sample: 0.5, checked: []string{"samsat", "duplicate"}, underReview: true},
+		{name: "cross-check unavailable", overall: "auto_approved",
+			store:  fakeApprovalStore{undecided: true, decided: true, crossChecks: map[string]string{"samsat": "passed", "duplicate": "unavailable"}},
+			sample: 0.5, checked: []string{"samsat", "duplicate"}, underReview: true},
+		{name: "policy disabled", overall: "auto_approved", store: fakeApprovalStore{undecided: true, decided: true, crossChecks: passed},
+			sample: 0.5, disabled: true},
+		{name: "rejected", overall: "auto_rejected", store: fakeApprovalStore{undecided: true, decided: true}, want: "rejected"},
+		{name: "needs review", overall: "needs_review", store: fakeApprovalStore{undecided: true, decided: true, crossChecks: passed}},
+		{name: "already decided", overall: "auto_approved", store: fakeApprovalStore{decided: true, crossChecks: passed}, sample: 0.5},
+		{name: "decided meanwhile", overall: "auto_approved", store: fakeApprovalStore{undecided: true, crossChecks: passed},
+			sample: 0.5, checked: []string{"samsat", "duplicate"}},
+	}
+	for _, tt := range tests {
+		store := tt.store
+		policy := *rules
+		if tt.disabled {
+			policy.AutoApproval.Enabled = false
+		}
+		s := &AutoApprovalService{store: &store, sample: func() float64 { return tt.sample }}
+
+		got, err := s.decide(7, &models.AutoValidationResult{OverallStatus: tt.overall, ConfidenceScore: 0.9}, &policy)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if got != tt.want || store.status != tt.want {
+			t.Errorf("%s: expected %q, got %q (stored %q)", tt.name, tt.want, got, store.status)
+		}
+		if !reflect.DeepEqual(store.checked, tt.checked) {
+			t.Errorf("%s: expected cross-checks %v, got %v", tt.name, tt.checked, store.checked)
+		}
+		if store.underReview != tt.underReview {
+			t.Errorf("%s: expected under review %t, got %t", tt.name, tt.underReview, store.underReview)
+		}
+		if tt.want == "approved" {
+			if store.trail.QASampled != tt.qaSampled || store.trail.RuleVersion != 3 || store.trail.CrossChecks["samsat"] != "passed" {
+				t.Errorf("%s: unexpected trail %+v", tt.name, store.trail)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,18 +13,46 @@ import (
 )
 
 type AutoValidationService struct {
-	db *sql.DB
+	db    *sql.DB
+	rules *ValidationRulesService
 }
 
 func NewAutoValidationService(db *sql.DB) *AutoValidationService {
-	return &AutoValidationService{db: db}
+	return &AutoValidationService{db: db, rules: NewValidationRulesService(db)}
 }
 
+// ValidateVehicle checks a vehicle with the validation rules in force and
+// saves the result on it
 func (s *AutoValidationService) ValidateVehicle(vehicleID int) (*models.AutoValidationResult, error) {
+	rules, err := s.rules.Active()
+	if err != nil {
+		return nil, err
+	}
+	return s.validateWith(vehicleID, rules)
+}
+
+func (s *AutoValidationService) validateWith(vehicleID int, rules *models.ValidationRules) (*models.AutoValidationResult, error) {
+	result, err := s.Evaluate(vehicleID, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save validation result
+	err = s.saveValidationResult(vehicleID, result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save validation result: %v", err)
+	}
+
+	return result, nil
+}
+
+// Evaluate checks a vehicle with rules, without saving the result
+func (s *AutoValidationService) Evaluate(vehicleID int, rules *models.ValidationRules) (*models.AutoValidationResult, error) {
 	result := &models.AutoValidationResult{
 		OverallStatus:   "pending",
 		Checks:         []models.ValidationCheck{},
 		ConfidenceScore: 0.0,
+		RuleVersion:     rules.Version,
 		ProcessedAt:    time.Now(),
 	}
 
@@ -39,48 +68,58 @@ func (s *AutoValidationService) ValidateVehicle(vehicleID int) (*models.AutoVali
 		return nil, fmt.Errorf("failed to get attachments: %v", err)
 	}
 
-	// Run validation checks
+	// Run the validation checks the rules enable
 	checks := []models.ValidationCheck{}
+	enabled := func(checkType string) bool {
+		return ruleFor(rules, checkType).Enabled
+	}
 
 	// 1. Document completeness check
-	docCheck := s.validateDocumentCompleteness(attachments)
-	checks = append(checks, docCheck)
+	if enabled(CheckDocumentCompleteness) {
+		vehicleType, _ := vehicle["vehicle_type"].(string)
+		docCheck := s.validateDocumentCompleteness(attachments, requiredDocuments(rules, vehicleType))
+		checks = append(checks, docCheck)
+	}
 
 	// 2. Plate number format validation
-	plateCheck := s.validatePlateNumber(vehicle["registration_number"].(string))
-	checks = append(checks, plateCheck)
+	if enabled(CheckPlateFormat) {
+		plateCheck := s.validatePlateNumber(vehicle["registration_number"].(string))
+		checks = append(checks, plateCheck)
+	}
 
 	// 3. VIN/Chassis validation
-	if chassisNum, ok := vehicle["chassis_number"].(string); ok && chassisNum != "" {
+	if chassisNum, ok := vehicle["chassis_number"].(string); ok && chassisNum != "" && enabled(CheckVINFormat) {
 		vinCheck := s.validateVIN(chassisNum)
 		checks = append(checks, vinCheck)
 	}
 
 	// 4. Duplicate check
-	dupCheck := s.checkDuplicateVehicle(vehicle)
-	checks = append(checks, dupCheck)
+	if enabled(CheckDuplicate) {
+		dupCheck := s.checkDuplicateVehicle(vehicle)
+		checks = append(checks, dupCheck)
+	}
 
 	// 5. Document expiry check
-	expiryCheck := s.validateDocumentExpiry(attachments)
-	checks = append(checks, expiryCheck)
+	if enabled(CheckDocumentExpiry) {
+		expiryCheck := s.validateDocumentExpiry(attachments)
+		checks = append(checks, expiryCheck)
+	}
+
+	// 6. Uploaded documents read by OCR
+	if ocrCheck, ok := s.validateDocumentOCR(attachments); ok && enabled(CheckDocumentOCR) {
+		checks = append(checks, ocrCheck)
+	}
 
 	result.Checks = checks
 
 	// Calculate overall confidence and status
-	result.ConfidenceScore = s.calculateConfidenceScore(checks)
-	result.OverallStatus = s.determineOverallStatus(checks, result.ConfidenceScore)
-
-	// Save validation result
-	err = s.saveValidationResult(vehicleID, result)
-	if err != nil {
-		return nil, fmt.Errorf("failed to save validation result: %v", err)
-	}
+	result.ConfidenceScore = s.calculateConfidenceScore(checks, rules)
+	result.OverallStatus = s.determineOverallStatus(checks, result.ConfidenceScore, rules)
 
 	return result, nil
 }
 
-func (s *AutoValidationService) validateDocumentCompleteness(attachments []map[string]interface{}) models.ValidationCheck {
-	requiredDocs := []string{"stnk", "bpkb", "foto_depan"}
+func (s *AutoValidationService) validateDocumentCompleteness(attachments []map[string]interface{}, requiredDocs []string) models.ValidationCheck {
 	foundDocs := make(map[string]bool)
 
 	for _, att := range attachments {
@@ -97,7 +136,7 @@ func (s *AutoValidationService) validateDocumentCompleteness(attachments []map[s
 	}
 
 	check := models.ValidationCheck{
-		Type: "document_completeness",
+		Type: CheckDocumentCompleteness,
 	}
 
 	if len(missingDocs) == 0 {
@@ -118,7 +157,7 @@ func (s *AutoValidationService) validateDocumentCompleteness(attachments []map[s
 
 func (s *AutoValidationService) validatePlateNumber(plateNumber string) models.ValidationCheck {
 	check := models.ValidationCheck{
-		Type: "plate_format",
+		Type: CheckPlateFormat,
 	}
 
 	// Indonesian plate format: 1-2 letters + 1-4 digits + 1-3 letters
@@ -144,7 +183,7 @@ func (s *AutoValidationService) validatePlateNumber(plateNumber string) models.V
 
 func (s *AutoValidationService) validateVIN(chassisNumber string) models.ValidationCheck {
 	check := models.ValidationCheck{
-		Type: "vin_format",
+		Type: CheckVINFormat,
 	}
 
 	// Basic VIN validation (17 characters, alphanumeric, no I, O, Q)
@@ -169,7 +208,7 @@ func (s *AutoValidationService) validateVIN(chassisNumber string) models.Validat
 
 func (s *AutoValidationService) checkDuplicateVehicle(vehicle map[string]interface{}) models.ValidationCheck {
 	check := models.ValidationCheck{
-		Type: "duplicate_check",
+		Type: CheckDuplicate,
 	}
 
 	plateNumber := vehicle["registration_number"].(string)
@@ -206,53 +245,154 @@ func (s *AutoValidationService) checkDuplicateVehicle(vehicle map[string]interfa
 
 func (s *AutoValidationService) validateDocumentExpiry(attachments []map[string]interface{}) models.ValidationCheck {
 	check := models.ValidationCheck{
-		Type: "document_expiry",
+		Type: CheckDocumentExpiry,
 	}
 
-	// Simulate OCR data check for STNK expiry
-	// In real implementation, this would parse OCR data
-	check.Status = "passed"
-	check.Confidence = 0.7
-	check.Message = "Dokumen masih berlaku (simulasi OCR)"
-	check.Details = map[string]interface{}{
-		"note": "Implementasi OCR diperlukan untuk validasi tanggal",
+	// Expiry date read from the latest STNK
+	expiry := ""
+	for _, att := range attachments {
+		if att["attachment_type"] != "stnk" {
+			continue
+		}
+		var analysis DocumentAnalysis
+		if data, ok := att["ocr_data"].(string); ok && json.Unmarshal([]byte(data), &analysis) == nil && analysis.STNK != nil {
+			expiry = analysis.STNK.ExpiryDate
+		}
+	}
+
+	expiryDate, err := time.Parse("2006-01-02", expiry)
+	switch {
+	case err != nil:
+		check.Status = "warning"
+		check.Confidence = 0.5
+		check.Message = "Masa berlaku STNK belum terbaca"
+	case expiryDate.Before(time.Now()):
+		check.Status = "failed"
+		check.Confidence = 0.0
+		check.Message = "STNK sudah tidak berlaku"
+		check.Details = map[string]interface{}{
+			"expiry_date": expiry,
+		}
+	default:
+		check.Status = "passed"
+		check.Confidence = 0.9
+		check.Message = "Dokumen masih berlaku"
+		check.Details = map[string]interface{}{
+			"expiry_date": expiry,
+		}
 	}
 
 	return check
 }
 
-func (s *AutoValidationService) calculateConfidenceScore(checks []models.ValidationCheck) float64 {
-	if len(checks) == 0 {
-		return 0.0
+// validateDocumentOCR summarises the latest read of each STNK, KTP, KIR and
+// insurance attachment. It reports false when there are none.
+func (s *AutoValidationService) validateDocumentOCR(attachments []map[string]interface{}) (models.ValidationCheck, bool) {
+	check := models.ValidationCheck{
+		Type: CheckDocumentOCR,
+	}
+
+	// Attachments come oldest first, so a re-upload replaces the earlier one
+	latest := map[string]map[string]interface{}{}
+	for _, att := range attachments {
+		attType, _ := att["attachment_type"].(string)
+		if _, ok := documentByAttachment[attType]; ok {
+			latest[attType] = att
+		}
+	}
+	if len(latest) == 0 {
+		return check, false
+	}
+
+	var failed, unread, waiting []string
+	errorsByType := map[string]interface{}{}
+	for attType, att := range latest {
+		switch att["validation_status"] {
+		case AttachmentFailed:
+			failed = append(failed, attType)
+			errorsByType[attType] = att["validation_errors"]
+		case AttachmentError:
+			unread = append(unread, attType)
+			errorsByType[attType] = att["validation_errors"]
+		case AttachmentQueued, AttachmentProcessing:
+			waiting = append(waiting, attType)
+		}
 	}
+	sort.Strings(failed)
+	sort.Strings(unread)
+	sort.Strings(waiting)
 
+	switch {
+	case len(failed) > 0:
+		check.Status = "failed"
+		check.Confidence = 0.0
+		check.Message = fmt.Sprintf("Dokumen tidak sesuai: %s", strings.Join(failed, ", "))
+	case len(unread) > 0:
+		check.Status = "warning"
+		check.Confidence = 0.5
+		check.Message = fmt.Sprintf("Dokumen perlu diperiksa manual: %s", strings.Join(unread, ", "))
+	case len(waiting) > 0:
+		check.Status = "warning"
+		check.Confidence = 0.5
+		check.Message = fmt.Sprintf("Dokumen sedang diproses: %s", strings.Join(waiting, ", "))
+	default:
+		check.Status = "passed"
+		check.Confidence = 1.0
+		check.Message = "Data dokumen sesuai dengan data kendaraan"
+	}
+	if len(errorsByType) > 0 {
+		check.Details = map[string]interface{}{
+			"validation_errors": errorsByType,
+		}
+	}
+
+	return check, true
+}
+
+// calculateConfidenceScore is the weighted mean confidence of the checks; a
+// warning counts for half
+func (s *AutoValidationService) calculateConfidenceScore(checks []models.ValidationCheck, rules *models.ValidationRules) float64 {
+	totalWeight := 0.0
 	totalScore := 0.0
 	for _, check := range checks {
+		weight := ruleFor(rules, check.Type).Weight
+		totalWeight += weight
 		if check.Status == "passed" {
-			totalScore += check.Confidence
+			totalScore += weight * check.Confidence
 		} else if check.Status == "warning" {
-			totalScore += check.Confidence * 0.5
+			totalScore += weight * check.Confidence * 0.5
 		}
 	}
 
-	return totalScore / float64(len(checks))
+	if totalWeight == 0 {
+		return 0.0
+	}
+	return totalScore / totalWeight
 }
 
-func (s *AutoValidationService) determineOverallStatus(checks []models.ValidationCheck, confidence float64) string {
+// determineOverallStatus rejects a complete submission below the
+// auto-reject score, asks for a correction when a check failed, and
+// approves from the auto-approve score when nothing needs a look
+func (s *AutoValidationService) determineOverallStatus(checks []models.ValidationCheck, confidence float64, rules *models.ValidationRules) string {
 	hasFailure := false
 	hasWarning := false
+	// Documents still to be uploaded are a correction, not a rejection
+	incomplete := false
 
 	for _, check := range checks {
 		if check.Status == "failed" {
 			hasFailure = true
+			incomplete = incomplete || check.Type == CheckDocumentCompleteness
 		} else if check.Status == "warning" {
 			hasWarning = true
 		}
 	}
 
-	if hasFailure {
+	if rules.AutoRejectScore > 0 && confidence < rules.AutoRejectScore && !incomplete {
+		return "auto_rejected"
+	} else if hasFailure {
 		return "needs_correction"
-	} else if hasWarning || confidence < 0.8 {
+	} else if hasWarning || confidence < rules.AutoApproveScore {
 		return "under_review"
 	} else {
 		return "auto_approved"
@@ -260,12 +400,12 @@ func (s *AutoValidationService) determineOverallStatus(checks []models.Validatio
 }
 
 func (s *AutoValidationService) getVehicleData(vehicleID int) (map[string]interface{}, error) {
-	query := `SELECT id, registration_number, chassis_number, engine_number FROM vehicles WHERE id = $1`
+	query := `SELECT id, registration_number, chassis_number, engine_number, vehicle_type FROM vehicles WHERE id = $1`
 	
 	var id int
-	var regNumber, chassisNumber, engineNumber string
+	var regNumber, chassisNumber, engineNumber, vehicleType string
 	
-	err := s.db.QueryRow(query, vehicleID).Scan(&id, &regNumber, &chassisNumber, &engineNumber)
+	err := s.db.QueryRow(query, vehicleID).Scan(&id, &regNumber, &chassisNumber, &engineNumber, &vehicleType)
 	if err != nil {
 		return nil, err
 	}
@@ -275,11 +415,14 @@ func (s *AutoValidationService) getVehicleData(vehicleID int) (map[string]interf
 		"registration_number": regNumber,
 		"chassis_number":     chassisNumber,
 		"engine_number":      engineNumber,
+		"vehicle_type":       vehicleType,
 	}, nil
 }
 
 func (s *AutoValidationService) getVehicleAttachments(vehicleID int) ([]map[string]interface{}, error) {
-	query := `SELECT attachment_type, file_name, ocr_data FROM vehicle_attachments WHERE vehicle_id = $1`
+	query := `SELECT attachment_type, file_name, ocr_data, COALESCE(validation_status, 'pending'), validation_errors
+			  FROM vehicle_attachments WHERE vehicle_id = $1
+			  ORDER BY uploaded_at, id`
 	
 	rows, err := s.db.Query(query, vehicleID)
 	if err != nil {
@@ -289,22 +432,27 @@ func (s *AutoValidationService) getVehicleAttachments(vehicleID int) ([]map[stri
 
 	var attachments []map[string]interface{}
 	for rows.Next() {
-		var attType, fileName string
-		var ocrData sql.NullString
+		var attType, fileName, validationStatus string
+		var ocrData, validationErrors sql.NullString
 		
-		err := rows.Scan(&attType, &fileName, &ocrData)
+		err := rows.Scan(&attType, &fileName, &ocrData, &validationStatus, &validationErrors)
 		if err != nil {
 			continue
 		}
 
 		att := map[string]interface{}{
-			"attachment_type": attType,
-			"file_name":      fileName,
+			"attachment_type":   attType,
+			"file_name":         fileName,
+			"validation_status": validationStatus,
 		}
 		
 		if ocrData.Valid {
 			att["ocr_data"] = ocrData.String
 		}
+		var errs []string
+		if validationErrors.Valid && json.Unmarshal([]byte(validationErrors.String), &errs) == nil {
+			att["validation_errors"] = errs
+		}
 		
 		attachments = append(attachments, att)
 	}
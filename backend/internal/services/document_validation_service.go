@@ -0,0 +1,407 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/registry"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+// Attachment validation statuses. Attachments that are not read keep the
+// column default, "pending".
+const (
+	AttachmentQueued     = "queued"
+	AttachmentProcessing = "processing"
+	AttachmentPassed     = "passed"
+	AttachmentFailed     = "failed"
+	// AttachmentError means the document could not be read automatically
+	// and needs a verifier
+	AttachmentError = "error"
+)
+
+const (
+	// documentPollInterval is how often the worker looks for queued
+	// attachments it was not woken for
+	documentPollInterval = time.Minute
+	// documentStaleAfter is when an attachment left processing is retried
+	documentStaleAfter = 10 * time.Minute
+	// documentMaxAttempts is how many times an attachment is claimed before
+	// one that keeps stopping the worker is left to a verifier
+	documentMaxAttempts = 3
+)
+
+// Documents read from an attachment, by attachment type
+const (
+	documentSTNK      = "stnk"
+	documentKTP       = "ktp"
+	documentKIR       = "kir"
+	documentInsurance = "insurance"
+)
+
+var documentByAttachment = map[string]string{
+	"stnk":      documentSTNK,
+	"ktp":       documentKTP,
+	"uji_kir":   documentKIR,
+	"asuransi":  documentInsurance,
+	"insurance": documentInsurance,
+}
+
+// attachmentTypesOf lists the attachment types holding a document
+func attachmentTypesOf(document string) []string {
+	var types []string
+	for t, d := range documentByAttachment {
+		if d == document {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// InitialValidationStatus is the validation_status a new attachment of
+// attachmentType is stored with: documents that are read are queued
+func InitialValidationStatus(attachmentType string) string {
+	if _, ok := documentByAttachment[attachmentType]; ok {
+		return AttachmentQueued
+	}
+	return "pending"
+}
+
+// DocumentAnalysis is what reading an attachment found, stored as its
+// ocr_data
+type DocumentAnalysis struct {
+	Document   string         `json:"document"`
+	Quality    *QualityResult `json:"quality,omitempty"`
+	STNK       *STNKData      `json:"stnk,omitempty"`
+	KTP        *KTPData       `json:"ktp,omitempty"`
+	Text       []string       `json:"text,omitempty"`
+	CrossCheck []FieldMatch   `json:"cross_check,omitempty"`
+	AnalyzedAt time.Time      `json:"analyzed_at"`
+}
+
+// FieldMatch compares a value read from the document with the vehicle
+// record
+type FieldMatch struct {
+	Field    string `json:"field"`
+	Document string `json:"document"`
+	Vehicle  string `json:"vehicle"`
+	Match    bool   `json:"match"`
+}
+
+// DocumentValidationService reads uploaded vehicle documents in the
+// background: image quality, OCR and a cross-check against the vehicle
+// record, after which the vehicle's auto validation, and the decision it
+// allows, runs again
+type DocumentValidationService struct {
+	db         *sql.DB
+	repo       *repository.AttachmentRepository
+	ocr        *OCRService
+	registries map[string]registry.Provider
+}
+
+func NewDocumentValidationService(db *sql.DB, ocr *OCRService, registries map[string]registry.Provider) *DocumentValidationService {
+	return &DocumentValidationService{db: db, repo: repository.NewAttachmentRepository(db), ocr: ocr, registries: registries}
+}
+
+// StartWorker validates queued attachments until stop is closed. It runs
+// whenever wake fires and every documentPollInterval, which also picks up
+// uploads made through other instances.
+func (s *DocumentValidationService) StartWorker(stop <-chan struct{}, wake <-chan struct{}) {
+	ticker := time.NewTicker(documentPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			more, err := s.ProcessNext()
+			if err != nil {
+				log.Printf("Document validation error: %v", err)
+			}
+			if err != nil || !more {
+				break
+			}
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessNext validates the oldest queued attachment. It reports false when
+// nothing was queued.
+func (s *DocumentValidationService) ProcessNext() (bool, error) {
+	staleBefore := time.Now().Add(-documentStaleAfter)
+	vehicleIDs, err := s.repo.FailStale(staleBefore, documentMaxAttempts, AttachmentError,
+		[]string{"Dokumen tidak dapat dibaca otomatis: pembacaan gagal berulang kali"})
+	if err != nil {
+		return false, fmt.Errorf("failed to give up stale attachments: %v", err)
+	}
+	for _, vehicleID := range vehicleIDs {
+		if _, _, err := NewAutoApprovalService(s.db, s.registries).Process(vehicleID); err != nil {
+			log.Printf("Auto validation failed for vehicle %d: %v", vehicleID, err)
+		}
+	}
+
+	attachment, err := s.repo.ClaimQueued(staleBefore, documentMaxAttempts)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim queued attachment: %v", err)
+	}
+	if attachment == nil {
+		return false, nil
+	}
+
+	if err := s.ValidateAttachment(attachment); err != nil {
+		return true, err
+	}
+
+	if _, _, err := NewAutoApprovalService(s.db, s.registries).Process(attachment.VehicleID); err != nil {
+		log.Printf("Auto validation failed for vehicle %d: %v", attachment.VehicleID, err)
+	}
+	return true, nil
+}
+
+// ValidateAttachment reads one attachment and stores the outcome
+func (s *DocumentValidationService) ValidateAttachment(attachment *models.VehicleAttachment) error {
+	document := documentByAttachment[attachment.AttachmentType]
+
+	vehicle, err := s.repo.GetVehicleIdentity(attachment.VehicleID)
+	if err != nil {
+		return fmt.Errorf("failed to get vehicle: %v", err)
+	}
+	if vehicle == nil {
+		vehicle = &models.Vehicle{ID: attachment.VehicleID}
+	}
+
+	// The owner's name is checked between the STNK and the KTP, whichever
+	// is read second
+	var counterpart *DocumentAnalysis
+	switch document {
+	case documentSTNK:
+		counterpart, err = s.latestAnalysis(attachment.VehicleID, documentKTP)
+	case documentKTP:
+		counterpart, err = s.latestAnalysis(attachment.VehicleID, documentSTNK)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get stored analysis: %v", err)
+	}
+
+	var analysis *DocumentAnalysis
+	var status string
+	var validationErrors []string
+	image, err := readAttachmentImage(attachment)
+	if err != nil {
+		analysis = &DocumentAnalysis{Document: document, AnalyzedAt: time.Now()}
+		status, validationErrors = AttachmentError, []string{"Dokumen tidak dapat dibaca otomatis: " + err.Error()}
+	} else {
+		analysis, status, validationErrors = s.analyze(document, image, vehicle, counterpart)
+	}
+
+	data, err := json.Marshal(analysis)
+	if err != nil {
+		return fmt.Errorf("failed to encode analysis: %v", err)
+	}
+	if err := s.repo.SaveValidation(attachment.ID, status, data, validationErrors); err != nil {
+		return fmt.Errorf("failed to save attachment validation: %v", err)
+	}
+	return nil
+}
+
+func (s *DocumentValidationService) latestAnalysis(vehicleID int, document string) (*DocumentAnalysis, error) {
+	data, err := s.repo.LatestOCRData(vehicleID, attachmentTypesOf(document))
+	if err != nil || data == "" {
+		return nil, err
+	}
+	var analysis DocumentAnalysis
+	if err := json.Unmarshal([]byte(data), &analysis); err != nil {
+		return nil, nil
+	}
+	return &analysis, nil
+}
+
+// readAttachmentImage loads the stored file. Uploads sent as JSON store the
+// data URL itself.
+func readAttachmentImage(attachment *models.VehicleAttachment) ([]byte, error) {
+	data, err := os.ReadFile(attachment.FilePath)
+	if err != nil {
+		return nil, errors.New("file tidak ditemukan")
+	}
+	if bytes.HasPrefix(data, []byte("data:image/")) {
+		data, err = decodeDocumentImage(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, errors.New("format gambar tidak valid")
+		}
+	}
+	if len(data) > maxOCRImageBytes {
+		return nil, errors.New("ukuran gambar melebihi 10 MB")
+	}
+	return data, nil
+}
+
+// analyze checks the photo, reads the document and compares it with the
+// vehicle and, for the owner's name, with counterpart
+func (s *DocumentValidationService) analyze(document string, image []byte, vehicle *models.Vehicle, counterpart *DocumentAnalysis) (*DocumentAnalysis, string, []string) {
+	analysis := &DocumentAnalysis{Document: document, AnalyzedAt: time.Now()}
+
+	quality, err := s.ocr.CheckDocumentQuality(image, document)
+	if err != nil {
+		return analysis, AttachmentError, []string{"Dokumen tidak dapat dibaca otomatis: format gambar tidak didukung"}
+	}
+	analysis.Quality = quality
+
+	var issues []string
+	if quality.RetakeRequired {
+		issues = append(issues, "Foto dokumen perlu diambil ulang: "+strings.Join(quality.Issues, "; "))
+	}
+
+	switch document {
+	case documentSTNK:
+		err = s.analyzeSTNK(analysis, image, vehicle, counterpart, &issues)
+	case documentKTP:
+		err = s.analyzeKTP(analysis, image, counterpart, &issues)
+	default:
+		err = s.analyzeText(analysis, image, vehicle, &issues)
+	}
+	if errors.Is(err, ErrOCRUnavailable) {
+		return analysis, AttachmentError, append(issues, "OCR tidak tersedia, dokumen perlu diperiksa manual")
+	}
+	if err != nil {
+		return analysis, AttachmentError, append(issues, "Dokumen tidak dapat dibaca otomatis")
+	}
+
+	for _, m := range analysis.CrossCheck {
+		if !m.Match {
+			issues = append(issues, mismatchMessage(document, m))
+		}
+	}
+	if len(issues) > 0 {
+		return analysis, AttachmentFailed, issues
+	}
+	return analysis, AttachmentPassed, nil
+}
+
+func (s *DocumentValidationService) analyzeSTNK(analysis *DocumentAnalysis, image []byte, vehicle *models.Vehicle, ktp *DocumentAnalysis, issues *[]string) error {
+	stnk, err := s.ocr.ReadSTNK(image)
+	if err != nil {
+		return err
+	}
+	analysis.STNK = stnk
+	*issues = append(*issues, s.ocr.ValidateSTNKData(stnk)...)
+
+	analysis.CrossCheck = compareFields(
+		FieldMatch{Field: "plate_number", Document: stnk.PlateNumber, Vehicle: vehicle.RegistrationNumber},
+		FieldMatch{Field: "chassis_number", Document: stnk.ChassisNumber, Vehicle: vehicle.ChassisNumber},
+		FieldMatch{Field: "engine_number", Document: stnk.EngineNumber, Vehicle: vehicle.EngineNumber},
+	)
+	if ktp != nil && ktp.KTP != nil {
+		*issues = append(*issues, s.ocr.CrossValidateData(stnk, ktp.KTP)...)
+	}
+	return nil
+}
+
+func (s *DocumentValidationService) analyzeKTP(analysis *DocumentAnalysis, image []byte, stnk *DocumentAnalysis, issues *[]string) error {
+	ktp, err := s.ocr.ReadKTP(image)
+	if err != nil {
+		return err
+	}
+	analysis.KTP = ktp
+	*issues = append(*issues, s.ocr.ValidateKTPData(ktp)...)
+
+	if stnk != nil && stnk.STNK != nil {
+		*issues = append(*issues, s.ocr.CrossValidateData(stnk.STNK, ktp)...)
+	}
+	return nil
+}
+
+// analyzeText reads a KIR or insurance document, which have no fixed
+// layout, and looks for the vehicle's plate or chassis number in it
+func (s *DocumentValidationService) analyzeText(analysis *DocumentAnalysis, image []byte, vehicle *models.Vehicle, issues *[]string) error {
+	text, err := s.ocr.ReadText(image)
+	if err != nil {
+		return err
+	}
+	for _, line := range text.Lines {
+		analysis.Text = append(analysis.Text, line.Text)
+	}
+
+	if len(analysis.Text) == 0 {
+		*issues = append(*issues, "Tidak ada teks yang terbaca pada dokumen")
+		return nil
+	}
+	content := normalizeIdentifier(strings.Join(analysis.Text, " "))
+	plate, chassis := normalizeIdentifier(vehicle.RegistrationNumber), normalizeIdentifier(vehicle.ChassisNumber)
+	found := (plate != "" && strings.Contains(content, plate)) || (chassis != "" && strings.Contains(content, chassis))
+	analysis.CrossCheck = []FieldMatch{{
+		Field:   "plate_number",
+		Vehicle: vehicle.RegistrationNumber,
+		Match:   found,
+	}}
+	if found {
+		analysis.CrossCheck[0].Document = vehicle.RegistrationNumber
+	}
+	return nil
+}
+
+// compareFields marks which values match. A value that was not read, or
+// is not on the vehicle record, cannot be compared and is left out.
+func compareFields(fields ...FieldMatch) []FieldMatch {
+	var compared []FieldMatch
+	for _, f := range fields {
+		if f.Document == "" || f.Vehicle == "" {
+			continue
+		}
+		f.Match = normalizeIdentifier(f.Document) == normalizeIdentifier(f.Vehicle)
+		compared = append(compared, f)
+	}
+	return compared
+}
+
+// normalizeIdentifier drops everything but letters and digits, so "B 1234
+// ABC" and "b1234abc" compare equal
+func normalizeIdentifier(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return -1
+	}, s)
+}
+
+var fieldLabels = map[string]string{
+	"plate_number":   "Nomor polisi",
+	"chassis_number": "Nomor rangka",
+	"engine_number":  "Nomor mesin",
+}
+
+var documentLabels = map[string]string{
+	documentSTNK:      "STNK",
+	documentKTP:       "KTP",
+	documentKIR:       "dokumen KIR",
+	documentInsurance: "polis asuransi",
+}
+
+func mismatchMessage(document string, m FieldMatch) string {
+	if m.Document == "" {
+		return fmt.Sprintf("%s kendaraan (%s) tidak ditemukan pada %s", fieldLabels[m.Field], m.Vehicle, documentLabels[document])
+	}
+	return fmt.Sprintf("%s pada %s (%s) tidak sesuai dengan data kendaraan (%s)",
+		fieldLabels[m.Field], documentLabels[document], m.Document, m.Vehicle)
+}
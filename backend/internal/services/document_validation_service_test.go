@@ -0,0 +1,96 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/ocr"
+)
+
+func TestAnalyzeSTNKAttachment(t *testing.T) {
+	svc := &DocumentValidationService{ocr: NewOCRService(&mockOCREngine{text: mockSTNKText})}
+	vehicle := &models.Vehicle{RegistrationNumber: "L1234AB", ChassisNumber: "MHKA1BA1HKK999999"}
+	ktp := &DocumentAnalysis{Document: documentKTP, KTP: &KTPData{Name: "SITI AMINAH"}}
+
+	analysis, status, issues := svc.analyze(documentSTNK, testPNG(t, 4, 4), vehicle, ktp)
+	if status != AttachmentFailed {
+		t.Fatalf("Expected failed, got %s", status)
+	}
+	if analysis.STNK == nil || analysis.Quality == nil {
+		t.Fatalf("Expected STNK data and quality to be stored, got %+v", analysis)
+	}
+
+	// Engine number is not on the record, so only plate and chassis compare
+	if len(analysis.CrossCheck) != 2 || !analysis.CrossCheck[0].Match || analysis.CrossCheck[1].Match {
+		t.Errorf("Expected plate to match and chassis not to, got %+v", analysis.CrossCheck)
+	}
+	joined := strings.Join(issues, "\n")
+	for _, want := range []string{
+		"Foto dokumen perlu diambil ulang",
+		"Nama pemilik pada STNK tidak sesuai dengan KTP",
+		"Nomor rangka pada STNK (MHKA1BA1HKK123456) tidak sesuai dengan data kendaraan (MHKA1BA1HKK999999)",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected issue %q, got %v", want, issues)
+		}
+	}
+}
+
+func TestAnalyzeTextAttachment(t *testing.T) {
+	svc := &DocumentValidationService{ocr: NewOCRService(&mockOCREngine{text: "BUKU UJI BERKALA\nNOMOR KENDARAAN L 1234 AB"})}
+
+	analysis, _, _ := svc.analyze(documentKIR, testPNG(t, 4, 4), &models.Vehicle{RegistrationNumber: "L 1234 AB"}, nil)
+	if len(analysis.CrossCheck) != 1 || !analysis.CrossCheck[0].Match || len(analysis.Text) != 2 {
+		t.Errorf("Expected the plate to be found in the KIR text, got %+v", analysis)
+	}
+
+	_, _, issues := svc.analyze(documentInsurance, testPNG(t, 4, 4), &models.Vehicle{RegistrationNumber: "B 9 XYZ"}, nil)
+	if !strings.Contains(strings.Join(issues, "\n"), "Nomor polisi kendaraan (B 9 XYZ) tidak ditemukan pada polis asuransi") {
+		t.Errorf("Expected a missing plate issue, got %v", issues)
+	}
+}
+
+func TestAnalyzeWithoutOCR(t *testing.T) {
+	svc := &DocumentValidationService{ocr: NewOCRService(&mockOCREngine{err: ocr.ErrUnavailable})}
+
+	analysis, status, issues := svc.analyze(documentKTP, testPNG(t, 4, 4), &models.Vehicle{}, nil)
+	if status != AttachmentError || analysis.Quality == nil {
+		t.Errorf("Expected an error status with the quality kept, got %s %+v", status, analysis)
+	}
+	if issues[len(issues)-1] != "OCR tidak tersedia, dokumen perlu diperiksa manual" {
+		t.Errorf("Unexpected issues %v", issues)
+	}
+
+	if _, status, _ := svc.analyze(documentSTNK, []byte("%PDF-1.4"), &models.Vehicle{}, nil); status != AttachmentError {
+		t.Errorf("Expected a PDF to need a manual check, got %s", status)
+	}
+}
+
+func TestValidateDocumentOCR(t *testing.T) {
+	svc := &AutoValidationService{}
+
+	if _, ok := svc.validateDocumentOCR([]map[string]interface{}{{"attachment_type": "foto_depan"}}); ok {
+		t.Error("Expected no check without any document that is read")
+	}
+
+	check, _ := svc.validateDocumentOCR([]map[string]interface{}{
+		{"attachment_type": "stnk", "validation_status": AttachmentFailed, "validation_errors": []string{"old"}},
+		{"attachment_type": "ktp", "validation_status": AttachmentPassed},
+		{"attachment_type": "stnk", "validation_status": AttachmentQueued},
+	})
+	if check.Status != "warning" || check.Message != "Dokumen sedang diproses: stnk" {
+		t.Errorf("Expected the re-uploaded STNK to count as processing, got %+v", check)
+	}
+
+	check, _ = svc.validateDocumentOCR([]map[string]interface{}{
+		{"attachment_type": "asuransi", "validation_status": AttachmentFailed, "validation_errors": []string{"mismatch"}},
+	})
+	if check.Status != "failed" || check.Details == nil {
+		t.Errorf("Expected a failed check with details, got %+v", check)
+	}
+
+	if InitialValidationStatus("uji_kir") != AttachmentQueued || InitialValidationStatus("foto_depan") != "pending" {
+		t.Error("Expected only OCR documents to be queued")
+	}
+}
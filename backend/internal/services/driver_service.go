@@ -4,8 +4,71 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
 )
 
+const driverSelectQuery = `SELECT d.id, d.user_id, d.license_number, d.license_expiry, d.status,
+			  d.fleet_owner_id, d.created_at, d.updated_at,
+			  u.username, u.email, u.full_name
+			  FROM drivers d
+			  JOIN users u ON d.user_id = u.id`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDriver(row rowScanner) (*models.Driver, error) {
+	var d models.Driver
+	var u models.User
+	err := row.Scan(&d.ID, &d.UserID, &d.LicenseNumber, &d.LicenseExpiry, &d.Status,
+		&d.FleetOwnerID, &d.CreatedAt, &d.UpdatedAt,
+		&u.Username, &u.Email, &u.FullName)
+	if err != nil {
+		return nil, err
+	}
+	u.ID = d.UserID
+	d.User = &u
+	return &d, nil
+}
+
+// GetDrivers lists the drivers visible in scope
+func GetDrivers(db *sql.DB, scope models.TenantScope) ([]models.Driver, error) {
+	query := driverSelectQuery + ` WHERE ($1 = 0 OR d.fleet_owner_id = $1) ORDER BY u.full_name`
+
+	rows, err := db.Query(query, scope.FilterID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drivers: %v", err)
+	}
+	defer rows.Close()
+
+	drivers := []models.Driver{}
+	for rows.Next() {
+		d, err := scanDriver(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan driver: %v", err)
+		}
+		drivers = append(drivers, *d)
+	}
+
+	return drivers, rows.Err()
+}
+
+// GetDriverByID returns a driver visible in scope; other tenants' drivers
+// are reported as not found
+func GetDriverByID(db *sql.DB, scope models.TenantScope, id int) (*models.Driver, error) {
+	query := driverSelectQuery + ` WHERE d.id = $1 AND ($2 = 0 OR d.fleet_owner_id = $2)`
+
+	d, err := scanDriver(db.QueryRow(query, id, scope.FilterID()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("driver not found")
+		}
+		return nil, fmt.Errorf("failed to get driver: %v", err)
+	}
+	return d, nil
+}
+
 func GetDriverByUserID(db *sql.DB, userID int) (map[string]interface{}, error) {
 	query := `SELECT d.id, d.user_id, d.license_number, d.status,
 			  u.full_name, u.email
@@ -91,41 +154,6 @@ func GetDriverTrips(db *sql.DB, driverID int, status string) ([]map[string]inter
 	return trips, nil
 }
 
-func UpdateTripStatus(db *sql.DB, tripID int, driverID int, status string) error {
-	validStatuses := map[string]bool{
-		"started":   true,
-		"completed": true,
-	}
-	if !validStatuses[status] {
-		return fmt.Errorf("invalid status: %s", status)
-	}
-
-	var query string
-	if status == "started" {
-		query = `UPDATE trips SET status = $1, actual_start = CURRENT_TIMESTAMP 
-				 WHERE id = $2 AND driver_id = $3 AND status = 'assigned'`
-	} else {
-		query = `UPDATE trips SET status = $1, actual_end = CURRENT_TIMESTAMP 
-				 WHERE id = $2 AND driver_id = $3 AND status = 'started'`
-	}
-
-	result, err := db.Exec(query, status, tripID, driverID)
-	if err != nil {
-		return fmt.Errorf("failed to update trip status: %v", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %v", err)
-	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("trip not found or invalid status transition")
-	}
-
-	return nil
-}
-
 func RecordTripTracking(db *sql.DB, tripID int, latitude, longitude, speed float64) error {
 	// Validate coordinates
 	if latitude < -90 || latitude > 90 {
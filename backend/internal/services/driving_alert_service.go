@@ -0,0 +1,252 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/geo"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+// Driving alert types
+const (
+	AlertSpeeding          = "speeding"
+	AlertHarshAcceleration = "harsh_acceleration"
+	AlertHarshBraking      = "harsh_braking"
+	AlertIdling            = "idling"
+	AlertAfterHours        = "after_hours"
+)
+
+const (
+	// movingSpeedKmh matches the threshold getVehicleStatus uses for "stopped"
+	movingSpeedKmh = 5
+	// Points further apart than this say nothing reliable about acceleration
+	maxHarshInterval = 10 * time.Second
+	// A longer silence while stopped means the engine was most likely off
+	maxIdleGap = 10 * time.Minute
+)
+
+type DrivingAlertService struct {
+	db        *sql.DB
+	repo      *repository.DrivingAlertRepository
+	geofences *repository.GeofenceRepository
+	cfg       *config.DrivingRulesConfig
+}
+
+func NewDrivingAlertService(db *sql.DB, cfg *config.DrivingRulesConfig) *DrivingAlertService {
+	return &DrivingAlertService{
+		db:        db,
+		repo:      repository.NewDrivingAlertRepository(db),
+		geofences: repository.NewGeofenceRepository(db),
+		cfg:       cfg,
+	}
+}
+
+type speedLimit struct {
+	kmh        float64
+	geofenceID *int
+	source     string
+}
+
+// effectiveSpeedLimit returns the lowest limit that applies at p: the
+// vehicle type's limit or that of any geofence containing the point
+func effectiveSpeedLimit(vehicleLimit *float64, geofences []models.Geofence, p geo.Point) *speedLimit {
+	var limit *speedLimit
+	if vehicleLimit != nil {
+		limit = &speedLimit{kmh: *vehicleLimit, source: "jenis kendaraan"}
+	}
+	for _, g := range geofences {
+		if g.SpeedLimitKmh == nil || (limit != nil && *g.SpeedLimitKmh >= limit.kmh) {
+			continue
+		}
+		if GeofenceContains(g, p) {
+			id := g.ID
+			limit = &speedLimit{kmh: *g.SpeedLimitKmh, geofenceID: &id, source: "area " + g.Name}
+		}
+	}
+	return limit
+}
+
+func withinWorkingHours(cfg *config.DrivingRulesConfig, hour int) bool {
+	if cfg.WorkingHoursStart < cfg.WorkingHoursEnd {
+		return hour >= cfg.WorkingHoursStart && hour < cfg.WorkingHoursEnd
+	}
+	// Window wraps past midnight, e.g. 20-6
+	return hour >= cfg.WorkingHoursStart || hour < cfg.WorkingHoursEnd
+}
+
+func alertSeverity(value, threshold float64) string {
+	if value >= threshold*1.25 {
+		return "critical"
+	}
+	return "warning"
+}
+
+func newDrivingAlert(data *models.GPSTrackingData, alertType string, value, threshold *float64, message string) models.DrivingAlert {
+	alert := models.DrivingAlert{
+		DeviceID:   data.DeviceID,
+		AlertType:  alertType,
+		Severity:   "warning",
+		Value:      value,
+		Threshold:  threshold,
+		Latitude:   data.Latitude,
+		Longitude:  data.Longitude,
+		Message:    message,
+		OccurredAt: data.Timestamp,
+	}
+	if value != nil && threshold != nil {
+		alert.Severity = alertSeverity(*value, *threshold)
+	}
+	return alert
+}
+
+// evaluateDrivingRules compares a point with the device's previous state and
+// returns the alerts it raises together with the state to store. Each
+// episode (a stretch of speeding, an idle period, a night of after-hours
+// movement) is reported once.
+func evaluateDrivingRules(cfg *config.DrivingRulesConfig, prev *models.DeviceDrivingState, data *models.GPSTrackingData, limit *speedLimit) ([]models.DrivingAlert, models.DeviceDrivingState) {
+	next := models.DeviceDrivingState{
+		DeviceID:      data.DeviceID,
+		LastLatitude:  data.Latitude,
+		LastLongitude: data.Longitude,
+		LastSpeed:     data.Speed,
+		LastTimestamp: data.Timestamp,
+	}
+	if prev != nil {
+		next.StoppedSince = prev.StoppedSince
+		next.IdleAlerted = prev.IdleAlerted
+		next.Speeding = prev.Speeding
+		next.AfterHoursAlertedOn = prev.AfterHoursAlertedOn
+	}
+
+	var alerts []models.DrivingAlert
+
+	// Over-speed
+	if limit != nil && data.Speed > limit.kmh {
+		if !next.Speeding {
+			speed, maxSpeed := data.Speed, limit.kmh
+			alert := newDrivingAlert(data, AlertSpeeding, &speed, &maxSpeed,
+				fmt.Sprintf("Kecepatan %.0f km/jam melebihi batas %.0f km/jam (%s)", speed, maxSpeed, limit.source))
+			alert.GeofenceID = limit.geofenceID
+			alerts = append(alerts, alert)
+		}
+		next.Speeding = true
+	} else {
+		next.Speeding = false
+	}
+
+	// Harsh acceleration and braking from consecutive points
+	if prev != nil {
+		dt := data.Timestamp.Sub(prev.LastTimestamp)
+		if dt > 0 && dt <= maxHarshInterval {
+			rate := (data.Speed - prev.LastSpeed) / dt.Seconds()
+			if rate >= cfg.HarshAccelKmhPerSec {
+				threshold := cfg.HarshAccelKmhPerSec
+				alerts = append(alerts, newDrivingAlert(data, AlertHarshAcceleration, &rate, &threshold,
+					fmt.Sprintf("Akselerasi mendadak %.0f → %.0f km/jam dalam %.0f detik", prev.LastSpeed, data.Speed, dt.Seconds())))
+			} else if -rate >= cfg.HarshBrakeKmhPerSec {
+				decel, threshold := -rate, cfg.HarshBrakeKmhPerSec
+				alerts = append(alerts, newDrivingAlert(data, AlertHarshBraking, &decel, &threshold,
+					fmt.Sprintf("Pengereman mendadak %.0f → %.0f km/jam dalam %.0f detik", prev.LastSpeed, data.Speed, dt.Seconds())))
+			}
+		}
+	}
+
+	// Excessive idling: stopped but still reporting
+	if data.Speed < movingSpeedKmh {
+		if next.StoppedSince == nil || prev == nil || data.Timestamp.Sub(prev.LastTimestamp) > maxIdleGap {
+			stoppedSince := data.Timestamp
+			next.StoppedSince = &stoppedSince
+			next.IdleAlerted = false
+		}
+		idle := data.Timestamp.Sub(*next.StoppedSince)
+		if !next.IdleAlerted && idle >= time.Duration(cfg.IdleAlertMinutes)*time.Minute {
+			minutes, threshold := idle.Minutes(), float64(cfg.IdleAlertMinutes)
+			alerts = append(alerts, newDrivingAlert(data, AlertIdling, &minutes, &threshold,
+				fmt.Sprintf("Kendaraan diam dengan mesin menyala selama %.0f menit", minutes)))
+			next.IdleAlerted = true
+		}
+	} else {
+		next.StoppedSince = nil
+		next.IdleAlerted = false
+	}
+
+	// After-hours movement, once per after-hours window. A window that wraps
+	// past midnight is keyed on the day it started, so driving at 23:00 and
+	// 01:00 of the same night raises a single alert
+	if data.Speed >= movingSpeedKmh {
+		local := data.Timestamp.In(cfg.Location)
+		if !withinWorkingHours(cfg, local.Hour()) {
+			day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+			if local.Hour() < cfg.WorkingHoursEnd {
+				day = day.AddDate(0, 0, -1)
+			}
+			if next.AfterHoursAlertedOn == nil || !next.AfterHoursAlertedOn.Equal(day) {
+				alerts = append(alerts, newDrivingAlert(data, AlertAfterHours, nil, nil,
+					fmt.Sprintf("Kendaraan bergerak di luar jam operasional (%02d:%02d)", local.Hour(), local.Minute())))
+				next.AfterHoursAlertedOn = &day
+			}
+		}
+	}
+
+	return alerts, next
+}
+
+// EvaluatePoint runs the driving rules for an ingested position, stores the
+// resulting alerts and notifies the vehicle's owner. Devices that are not
+// fitted to a vehicle are ignored.
+func (s *DrivingAlertService) EvaluatePoint(data *models.GPSTrackingData) ([]models.DrivingAlert, error) {
+	vehicle, err := s.repo.GetDeviceVehicle(data.DeviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device vehicle: %v", err)
+	}
+	if vehicle == nil {
+		return nil, nil
+	}
+
+	prev, err := s.repo.GetState(data.DeviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load driving state: %v", err)
+	}
+
+	// Timestamps are stored without a zone, so compare them in UTC
+	point := *data
+	point.Timestamp = data.Timestamp.UTC()
+	if prev != nil && !point.Timestamp.After(prev.LastTimestamp) {
+		// Late or duplicate point; the state has already moved past it
+		return nil, nil
+	}
+
+	geofences, err := loadActiveGeofences(s.geofences)
+	if err != nil {
+		return nil, err
+	}
+	limit := effectiveSpeedLimit(vehicle.SpeedLimitKmh, geofences, geo.Point{Lat: point.Latitude, Lng: point.Longitude})
+
+	alerts, next := evaluateDrivingRules(s.cfg, prev, &point, limit)
+	if err := s.repo.SaveState(&next); err != nil {
+		return nil, fmt.Errorf("failed to save driving state: %v", err)
+	}
+
+	for i := range alerts {
+		alert := &alerts[i]
+		alert.VehicleID = &vehicle.VehicleID
+		alert.FleetOwnerID = vehicle.FleetOwnerID
+		if err := s.repo.InsertAlert(alert); err != nil {
+			return alerts[:i], fmt.Errorf("failed to save driving alert: %v", err)
+		}
+
+		if vehicle.OwnerUserID != nil {
+			title := fmt.Sprintf("Peringatan Mengemudi: %s", vehicle.RegistrationNumber)
+			if err := CreateNotification(s.db, *vehicle.OwnerUserID, title, alert.Message, "driving_alert"); err != nil {
+				log.Printf("Driving alert notification error: %v", err)
+			}
+		}
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/geo"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+func testDrivingRules() *config.DrivingRulesConfig {
+	return &config.DrivingRulesConfig{
+		HarshAccelKmhPerSec: 12,
+		HarshBrakeKmhPerSec: 14,
+		IdleAlertMinutes:    15,
+		WorkingHoursStart:   5,
+		WorkingHoursEnd:     22,
+		Location:            time.FixedZone("WIB", 7*60*60),
+	}
+}
+
+// 10:00 WIB, inside working hours
+var drivingTestStart = time.Date(2025, 1, 10, 3, 0, 0, 0, time.UTC)
+
+func testPoint(offset time.Duration, speed float64) *models.GPSTrackingData {
+	return &models.GPSTrackingData{
+		DeviceID:  "GPS001",
+		Latitude:  -6.2,
+		Longitude: 106.8,
+		Speed:     speed,
+		Timestamp: drivingTestStart.Add(offset),
+	}
+}
+
+func alertTypes(alerts []models.DrivingAlert) []string {
+	types := make([]string, len(alerts))
+	for i, a := range alerts {
+		types[i] = a.AlertType
+	}
+	return types
+}
+
+func TestSpeedingReportedOncePerEpisode(t *testing.T) {
+	cfg := testDrivingRules()
+	limit := &speedLimit{kmh: 80, source: "jenis kendaraan"}
+
+	alerts, state := evaluateDrivingRules(cfg, nil, testPoint(0, 95), limit)
+	if len(alerts) != 1 || alerts[0].AlertType != AlertSpeeding {
+		t.Fatalf("Expected speeding alert, got %v", alertTypes(alerts))
+	}
+
+	alerts, state = evaluateDrivingRules(cfg, &state, testPoint(time.Minute, 100), limit)
+	if len(alerts) != 0 {
+		t.Errorf("Expected no repeat while still speeding, got %v", alertTypes(alerts))
+	}
+
+	_, state = evaluateDrivingRules(cfg, &state, testPoint(2*time.Minute, 70), limit)
+	alerts, _ = evaluateDrivingRules(cfg, &state, testPoint(3*time.Minute, 90), limit)
+	if len(alerts) != 1 || alerts[0].AlertType != AlertSpeeding {
+		t.Errorf("Expected a new speeding episode, got %v", alertTypes(alerts))
+	}
+}
+
+func TestHarshAccelerationAndBraking(t *testing.T) {
+	cfg := testDrivingRules()
+
+	_, state := evaluateDrivingRules(cfg, nil, testPoint(0, 20), nil)
+	alerts, state := evaluateDrivingRules(cfg, &state, testPoint(2*time.Second, 50), nil)
+	if len(alerts) != 1 || alerts[0].AlertType != AlertHarshAcceleration {
+		t.Fatalf("Expected harsh acceleration, got %v", alertTypes(alerts))
+	}
+
+	alerts, state = evaluateDrivingRules(cfg, &state, testPoint(4*time.Second, 10), nil)
+	if len(alerts) != 1 || alerts[0].AlertType != AlertHarshBraking {
+		t.Fatalf("Expected harsh braking, got %v", alertTypes(alerts))
+	}
+
+	// The same speed change over a long gap is not harsh
+	alerts, _ = evaluateDrivingRules(cfg, &state, testPoint(time.Minute, 60), nil)
+	if len(alerts) != 0 {
+		t.Errorf("Expected no alert across a long gap, got %v", alertTypes(alerts))
+	}
+}
+
+func TestIdlingAlert(t *testing.T) {
+	cfg := testDrivingRules()
+
+	_, state := evaluateDrivingRules(cfg, nil, testPoint(0, 0), nil)
+	var alerts []models.DrivingAlert
+	for m := 1; m <= 14; m++ {
+		alerts, state = evaluateDrivingRules(cfg, &state, testPoint(time.Duration(m)*time.Minute, 0), nil)
+		if len(alerts) != 0 {
+			t.Fatalf("Expected no alert after %d minutes, got %v", m, alertTypes(alerts))
+		}
+	}
+
+	alerts, state = evaluateDrivingRules(cfg, &state, testPoint(15*time.Minute, 0), nil)
+	if len(alerts) != 1 || alerts[0].AlertType != AlertIdling {
+		t.Fatalf("Expected idling alert, got %v", alertTypes(alerts))
+	}
+
+	alerts, _ = evaluateDrivingRules(cfg, &state, testPoint(16*time.Minute, 0), nil)
+	if len(alerts) != 0 {
+		t.Errorf("Expected idling to be reported once, got %v", alertTypes(alerts))
+	}
+}
+
+func TestIdlingResetsAfterReportingGap(t *testing.T) {
+	cfg := testDrivingRules()
+
+	_, state := evaluateDrivingRules(cfg, nil, testPoint(0, 0), nil)
+	// Device was silent (engine off) for 30 minutes
+	alerts, _ := evaluateDrivingRules(cfg, &state, testPoint(30*time.Minute, 0), nil)
+	if len(alerts) != 0 {
+		t.Errorf("Expected no idling alert across a reporting gap, got %v", alertTypes(alerts))
+	}
+}
+
+func TestAfterHoursMovement(t *testing.T) {
+	cfg := testDrivingRules()
+	night := 13 * time.Hour // 23:00 WIB
+
+	_, state := evaluateDrivingRules(cfg, nil, testPoint(night, 0), nil)
+	alerts, state := evaluateDrivingRules(cfg, &state, testPoint(night+time.Minute, 40), nil)
+	if len(alerts) != 1 || alerts[0].AlertType != AlertAfterHours {
+		t.Fatalf("Expected after-hours alert, got %v", alertTypes(alerts))
+	}
+
+	alerts, _ = evaluateDrivingRules(cfg, &state, testPoint(night+10*time.Minute, 40), nil)
+	if len(alerts) != 0 {
+		t.Errorf("Expected after-hours alert once per day, got %v", alertTypes(alerts))
+	}
+
+	alerts, _ = evaluateDrivingRules(cfg, nil, testPoint(time.Hour, 40), nil)
+	if len(alerts) != 0 {
+		t.Errorf("Expected no alert during working hours, got %v", alertTypes(alerts))
+	}
+}
+
+func TestAfterHoursMovementAcrossMidnight(t *testing.T) {
+	cfg := testDrivingRules()
+	cfg.WorkingHoursStart, cfg.WorkingHoursEnd = 6, 20
+
+	alerts, state := evaluateDrivingRules(cfg, nil, testPoint(13*time.Hour, 40), nil) // 23:00 WIB
+	if len(alerts) != 1 || alerts[0].AlertType != AlertAfterHours {
+		t.Fatalf("Expected after-hours alert, got %v", alertTypes(alerts))
+	}
+
+	alerts, state = evaluateDrivingRules(cfg, &state, testPoint(15*time.Hour, 40), nil) // 01:00 WIB the next day
+	if len(alerts) != 0 {
+		t.Errorf("Expected one alert for the same night, got %v", alertTypes(alerts))
+	}
+
+	alerts, _ = evaluateDrivingRules(cfg, &state, testPoint(35*time.Hour, 40), nil) // 21:00 WIB the next night
+	if len(alerts) != 1 || alerts[0].AlertType != AlertAfterHours {
+		t.Errorf("Expected a new alert the next night, got %v", alertTypes(alerts))
+	}
+}
+
+func TestWithinWorkingHoursOvernight(t *testing.T) {
+	cfg := &config.DrivingRulesConfig{WorkingHoursStart: 20, WorkingHoursEnd: 6}
+	if !withinWorkingHours(cfg, 23) || !withinWorkingHours(cfg, 3) {
+		t.Error("Expected night hours inside an overnight window")
+	}
+	if withinWorkingHours(cfg, 12) {
+		t.Error("Expected midday outside an overnight window")
+	}
+}
+
+func TestEffectiveSpeedLimitUsesLowestLimit(t *testing.T) {
+	vehicleLimit := 80.0
+	depot := testCircleGeofence(7, -6.2, 106.8, 500, 600)
+	depotLimit := 20.0
+	depot.SpeedLimitKmh = &depotLimit
+
+	limit := effectiveSpeedLimit(&vehicleLimit, []models.Geofence{depot}, geo.Point{Lat: -6.2, Lng: 106.8})
+	if limit == nil || limit.kmh != 20 || limit.geofenceID == nil || *limit.geofenceID != 7 {
+		t.Fatalf("Expected depot limit of 20, got %+v", limit)
+	}
+
+	limit = effectiveSpeedLimit(&vehicleLimit, []models.Geofence{depot}, geo.Point{Lat: -6.3, Lng: 106.8})
+	if limit == nil || limit.kmh != 80 || limit.geofenceID != nil {
+		t.Fatalf("Expected vehicle limit outside the depot, got %+v", limit)
+	}
+
+	if effectiveSpeedLimit(nil, nil, geo.Point{}) != nil {
+		t.Error("Expected no limit when none is configured")
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/face"
+	"github.com/youruser/aplikasi-tms/backend/internal/imaging"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+// FaceEngine detects faces and describes each with an embedding: the
+// built-in face.Local, or a local model runner (face.Command).
+type FaceEngine interface {
+	Detect(ctx context.Context, image []byte) ([]face.Face, error)
+}
+
+var (
+	ErrFaceMatchUnavailable = errors.New("face matching is not available")
+	ErrNoVerifiedKTP        = errors.New("no verified KTP on file")
+)
+
+type FaceMatchResult struct {
+	MatchScore float64           `json:"match_score"`
+	IsMatch    bool              `json:"is_match"`
+	Confidence string            `json:"confidence"`
+	Threshold  float64           `json:"threshold"`
+	Liveness   *imaging.Liveness `json:"liveness"`
+	// Verified means the faces match and the selfie passed liveness
+	Verified       bool                   `json:"verified"`
+	VerificationID int64                  `json:"verification_id,omitempty"`
+	Details        map[string]interface{} `json:"details"`
+}
+
+type FaceMatchService struct {
+	engine    FaceEngine
+	threshold float64
+	repo      *repository.FaceVerificationRepository
+}
+
+// NewFaceMatchService matches faces with engine; two faces are the same
+// person from threshold similarity. db may be nil when nothing is stored.
+func NewFaceMatchService(db *sql.DB, engine FaceEngine, threshold float64) *FaceMatchService {
+	return &FaceMatchService{engine: engine, threshold: threshold, repo: repository.NewFaceVerificationRepository(db)}
+}
+
+// Match compares the face in a selfie with the photo on a KTP, both data
+// URLs, and runs the liveness heuristics on the selfie
+func (s *FaceMatchService) Match(selfieBase64, ktpBase64 string) (*FaceMatchResult, error) {
+	ktp, err := decodeDocumentImage(ktpBase64)
+	if err != nil {
+		return nil, err
+	}
+	return s.matchKTP(selfieBase64, ktp)
+}
+
+// MatchStoredKTP compares a selfie with the KTP the user uploaded and an
+// admin verified. A KTP sent along with the selfie is never trusted.
+func (s *FaceMatchService) MatchStoredKTP(userID int, selfieBase64 string) (*FaceMatchResult, error) {
+	ktp, err := s.storedKTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.matchKTP(selfieBase64, ktp)
+}
+
+// storedKTP reads the user's latest verified KTP document
+func (s *FaceMatchService) storedKTP(userID int) ([]byte, error) {
+	path, err := s.repo.VerifiedKTPPath(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get KTP document: %v", err)
+	}
+	if path == "" {
+		return nil, ErrNoVerifiedKTP
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read KTP document: %v", err)
+	}
+	// Documents uploaded as JSON are stored as the data URL they came in
+	if bytes.HasPrefix(data, []byte("data:image/")) {
+		return decodeDocumentImage(strings.TrimSpace(string(data)))
+	}
+	if len(data) > maxOCRImageBytes {
+		return nil, ErrImageTooLarge
+	}
+	if err := checkImageSize(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *FaceMatchService) matchKTP(selfieBase64 string, ktp []byte) (*FaceMatchResult, error) {
+	selfie, err := decodeDocumentImage(selfieBase64)
+	if err != nil {
+		return nil, err
+	}
+	selfieImage, err := decodeImage(selfie)
+	if err != nil {
+		return nil, err
+	}
+	if s.engine == nil {
+		return nil, ErrFaceMatchUnavailable
+	}
+
+	selfieFaces, err := s.detect(selfie)
+	if err != nil {
+		return nil, err
+	}
+	ktpFaces, err := s.detect(ktp)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FaceMatchResult{
+		Confidence: "low",
+		Threshold:  s.threshold,
+		Details: map[string]interface{}{
+			"face_detected_selfie": len(selfieFaces) > 0,
+			"face_detected_ktp":    len(ktpFaces) > 0,
+			"faces_in_selfie":      len(selfieFaces),
+		},
+	}
+
+	selfieFace, ktpFace := face.Largest(selfieFaces), face.Largest(ktpFaces)
+	var faceBox image.Rectangle
+	if selfieFace != nil {
+		faceBox = selfieFace.Box
+		result.Details["detection_score_selfie"] = roundConfidence(selfieFace.Score)
+	}
+	if ktpFace != nil {
+		result.Details["detection_score_ktp"] = roundConfidence(ktpFace.Score)
+	}
+	result.Liveness = imaging.CheckLiveness(selfieImage, faceBox)
+
+	if selfieFace == nil || ktpFace == nil {
+		return result, nil
+	}
+
+	similarity := face.Similarity(selfieFace.Embedding, ktpFace.Embedding)
+	result.MatchScore = roundConfidence(math.Max(0, similarity))
+	result.IsMatch = similarity >= s.threshold
+	switch {
+	case similarity >= s.threshold+0.15:
+		result.Confidence = "high"
+	case result.IsMatch:
+		result.Confidence = "medium"
+	}
+	// Someone else in the selfie may be the one holding the KTP
+	result.Verified = result.IsMatch && result.Liveness.Passed && len(selfieFaces) == 1
+
+	return result, nil
+}
+
+func (s *FaceMatchService) detect(image []byte) ([]face.Face, error) {
+	faces, err := s.engine.Detect(context.Background(), image)
+	if errors.Is(err, face.ErrUnavailable) {
+		return nil, ErrFaceMatchUnavailable
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect faces: %v", err)
+	}
+	return faces, nil
+}
+
+// VerifyFleetOwner matches a fleet owner's selfie with their stored,
+// verified KTP and keeps the result as evidence. A verified result marks
+// the owner verified; a failed one leaves an earlier verification in place.
+func (s *FaceMatchService) VerifyFleetOwner(fleetOwnerID, userID int, selfieBase64 string) (*FaceMatchResult, error) {
+	result, err := s.MatchStoredKTP(userID, selfieBase64)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode face match: %v", err)
+	}
+	verification := &models.FaceVerification{
+		FleetOwnerID:   fleetOwnerID,
+		UserID:         userID,
+		MatchScore:     result.MatchScore,
+		Threshold:      result.Threshold,
+		IsMatch:        result.IsMatch,
+		LivenessPassed: result.Liveness.Passed,
+		LivenessFlags:  result.Liveness.Flags,
+		Verified:       result.Verified,
+		Result:         data,
+	}
+	if err := s.repo.Create(verification); err != nil {
+		return nil, fmt.Errorf("failed to save face verification: %v", err)
+	}
+	if result.Verified {
+		log.Printf("Fleet owner %d verified by face match %d (score %.3f)", fleetOwnerID, verification.ID, result.MatchScore)
+	}
+
+	result.VerificationID = verification.ID
+	return result, nil
+}
+
+// ListVerifications returns a fleet owner's face verifications, newest
+// first
+func (s *FaceMatchService) ListVerifications(fleetOwnerID int) ([]models.FaceVerification, error) {
+	verifications, err := s.repo.ListByFleetOwner(fleetOwnerID, 50)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list face verifications: %v", err)
+	}
+	return verifications, nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/face"
+)
+
+// mockFaceEngine answers each Detect call with the next set of faces
+type mockFaceEngine struct {
+	faces [][]face.Face
+	err   error
+	calls int
+}
+
+func (m *mockFaceEngine) Detect(ctx context.Context, image []byte) ([]face.Face, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	faces := m.faces[m.calls%len(m.faces)]
+	m.calls++
+	return faces, nil
+}
+
+func testFace(embedding ...float64) face.Face {
+	return face.Face{Box: image.Rect(0, 0, 4, 4), Score: 0.99, Embedding: embedding}
+}
+
+func TestFaceMatch(t *testing.T) {
+	engine := &mockFaceEngine{faces: [][]face.Face{
+		{testFace(1, 0, 0)},
+		{testFace(0.9, 0.1, 0)},
+	}}
+	svc := NewFaceMatchService(nil, engine, 0.45)
+
+	result, err := svc.Match(testImage(t), testImage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsMatch || result.Confidence != "high" || result.MatchScore < 0.99 {
+		t.Errorf("Expected a confident match, got %+v", result)
+	}
+	if result.Liveness == nil || result.Threshold != 0.45 {
+		t.Errorf("Expected liveness and the threshold in the result, got %+v", result)
+	}
+	// A flat grey test image cannot pass the liveness heuristics
+	if result.Verified {
+		t.Error("Expected a selfie failing liveness not to verify")
+	}
+
+	engine.faces = [][]face.Face{{testFace(1, 0, 0)}, {testFace(0, 1, 0)}}
+	engine.calls = 0
+	result, _ = svc.Match(testImage(t), testImage(t))
+	if result.IsMatch || result.Confidence != "low" || result.MatchScore != 0 {
+		t.Errorf("Expected different faces not to match, got %+v", result)
+	}
+}
+
+func TestFaceMatchWithoutFace(t *testing.T) {
+	engine := &mockFaceEngine{faces: [][]face.Face{{}, {testFace(1, 0)}}}
+	svc := NewFaceMatchService(nil, engine, 0.45)
+
+	result, err := svc.Match(testImage(t), testImage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IsMatch || result.Details["face_detected_selfie"] != false || result.Details["face_detected_ktp"] != true {
+		t.Errorf("Expected no match without a face in the selfie, got %+v", result)
+	}
+
+	engine.faces = [][]face.Face{{testFace(1, 0), testFace(1, 0)}, {testFace(1, 0)}}
+	engine.calls = 0
+	result, _ = svc.Match(testImage(t), testImage(t))
+	if !result.IsMatch || result.Verified || result.Details["faces_in_selfie"] != 2 {
+		t.Errorf("Expected two faces in the selfie not to verify, got %+v", result)
+	}
+}
+
+func TestFaceMatchUnavailable(t *testing.T) {
+	if _, err := NewFaceMatchService(nil, nil, 0.45).Match(testImage(t), testImage(t)); !errors.Is(err, ErrFaceMatchUnavailable) {
+		t.Errorf("Expected ErrFaceMatchUnavailable without an engine, got %v", err)
+	}
+
+	svc := NewFaceMatchService(nil, &mockFaceEngine{err: face.ErrUnavailable}, 0.45)
+	if _, err := svc.Match(testImage(t), testImage(t)); !errors.Is(err, ErrFaceMatchUnavailable) {
+		t.Errorf("Expected ErrFaceMatchUnavailable without a model, got %v", err)
+	}
+
+	if _, err := svc.Match("not an image", testImage(t)); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("Expected ErrInvalidImage, got %v", err)
+	}
+}
@@ -27,7 +27,7 @@ var allowedTypes = map[string]bool{
 }
 
 var allowedAttachmentTypes = []string{
-	"stnk", "bpkb", "uji_kir", "asuransi", 
+	"stnk", "bpkb", "uji_kir", "asuransi", "ktp",
 	"foto_depan", "foto_belakang", "foto_samping",
 }
 
@@ -38,26 +38,60 @@ func init() {
 
 func UploadVehicleAttachment(db *sql.DB, vehicleID int, attachmentType string, file multipart.File, header *multipart.FileHeader) (*models.VehicleAttachment, error) {
 	// Validate attachment type
-	validType := false
-	for _, t := range allowedAttachmentTypes {
-		if t == attachmentType {
-			validType = true
-			break
-		}
-	}
-	if !validType {
+	if !isAllowedAttachmentType(attachmentType) {
 		return nil, fmt.Errorf("invalid attachment type: %s", attachmentType)
 	}
 
+	filePath, contentType, err := saveUploadedFile(fmt.Sprintf("%d_%s", vehicleID, attachmentType), file, header)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save to database
+	// STNK, KTP, KIR and insurance documents are queued to be read
+	query := `INSERT INTO vehicle_attachments (vehicle_id, attachment_type, file_name, file_path, file_size, mime_type, validation_status)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, uploaded_at`
+
+	var attachment models.VehicleAttachment
+	attachment.ValidationStatus = InitialValidationStatus(attachmentType)
+	err = db.QueryRow(query, vehicleID, attachmentType, header.Filename, filePath, header.Size, contentType, attachment.ValidationStatus).
+		Scan(&attachment.ID, &attachment.UploadedAt)
+
+	if err != nil {
+		os.Remove(filePath) // Clean up on error
+		return nil, fmt.Errorf("failed to save attachment record: %v", err)
+	}
+
+	attachment.VehicleID = vehicleID
+	attachment.AttachmentType = attachmentType
+	attachment.FileName = header.Filename
+	attachment.FilePath = filePath
+	attachment.FileSize = int(header.Size)
+	attachment.MimeType = contentType
+
+	return &attachment, nil
+}
+
+// saveUploadedFile validates an uploaded file and stores it under UploadDir
+// with a generated name starting with prefix. It returns the stored path and
+// the file's content type.
+func saveUploadedFile(prefix string, file multipart.File, header *multipart.FileHeader) (string, string, error) {
+	contentType := header.Header.Get("Content-Type")
+	filePath, err := storeUploadedFile(prefix, file, header, contentType)
+	return filePath, contentType, err
+}
+
+// storeUploadedFile stores an uploaded file of contentType as
+// saveUploadedFile does, for callers that found the type themselves
+func storeUploadedFile(prefix string, file multipart.File, header *multipart.FileHeader, contentType string) (string, error) {
 	// Validate file size
 	if header.Size > MaxFileSize {
-		return nil, fmt.Errorf("file size exceeds limit of %d bytes", MaxFileSize)
+		return "", fmt.Errorf("file size exceeds limit of %d bytes", MaxFileSize)
 	}
 
 	// Validate file type
-	contentType := header.Header.Get("Content-Type")
 	if !allowedTypes[contentType] {
-		return nil, fmt.Errorf("file type not allowed: %s", contentType)
+		return "", fmt.Errorf("file type not allowed: %s", contentType)
 	}
 
 	// Generate secure filename without using user input directly
@@ -69,11 +103,11 @@ func UploadVehicleAttachment(db *sql.DB, vehicleID int, attachmentType string, f
 		ext = ".tmp" // Default safe extension
 	}
 	// Generate completely new filename to avoid any path traversal
-	filename := fmt.Sprintf("%d_%s_%d%s", vehicleID, attachmentType, time.Now().UnixNano(), ext)
+	filename := fmt.Sprintf("%s_%d%s", prefix, time.Now().UnixNano(), ext)
 	
 	// Double-check generated filename is safe
 	if strings.Contains(filename, "..") || strings.Contains(filename, "/") || strings.Contains(filename, "\\") {
-		return nil, fmt.Errorf("invalid generated filename")
+		return "", fmt.Errorf("invalid generated filename")
 	}
 	
 	// Use only the filename, not any path from user input
@@ -82,20 +116,20 @@ func UploadVehicleAttachment(db *sql.DB, vehicleID int, attachmentType string, f
 	// Validate final path is within upload directory
 	absUploadDir, err := filepath.Abs(UploadDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve upload directory: %v", err)
+		return "", fmt.Errorf("failed to resolve upload directory: %v", err)
 	}
 	absFilePath, err := filepath.Abs(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve file path: %v", err)
+		return "", fmt.Errorf("failed to resolve file path: %v", err)
 	}
 	if !strings.HasPrefix(absFilePath, absUploadDir+string(filepath.Separator)) && absFilePath != absUploadDir {
-		return nil, fmt.Errorf("path traversal detected")
+		return "", fmt.Errorf("path traversal detected")
 	}
 
 	// Create file
 	dst, err := os.Create(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create file: %v", err)
+		return "", fmt.Errorf("failed to create file: %v", err)
 	}
 	defer dst.Close()
 
@@ -103,34 +137,12 @@ func UploadVehicleAttachment(db *sql.DB, vehicleID int, attachmentType string, f
 	_, err = io.Copy(dst, file)
 	if err != nil {
 		os.Remove(filePath) // Clean up on error
-		return nil, fmt.Errorf("failed to save file: %v", err)
-	}
-
-	// Save to database
-	query := `INSERT INTO vehicle_attachments (vehicle_id, attachment_type, file_name, file_path, file_size, mime_type)
-			  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, uploaded_at`
-
-	var attachment models.VehicleAttachment
-	err = db.QueryRow(query, vehicleID, attachmentType, header.Filename, filePath, header.Size, contentType).
-		Scan(&attachment.ID, &attachment.UploadedAt)
-
-	if err != nil {
-		os.Remove(filePath) // Clean up on error
-		return nil, fmt.Errorf("failed to save attachment record: %v", err)
+		return "", fmt.Errorf("failed to save file: %v", err)
 	}
 
-	attachment.VehicleID = vehicleID
-	attachment.AttachmentType = attachmentType
-	attachment.FileName = header.Filename
-	attachment.FilePath = filePath
-	attachment.FileSize = int(header.Size)
-	attachment.MimeType = contentType
-
-	return &attachment, nil
+	return filePath, nil
 }
 
-
-
 func DeleteVehicleAttachment(db *sql.DB, attachmentID int, vehicleID int) error {
 	// Get file path first
 	var filePath string
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/lib/pq"
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
 	"github.com/youruser/aplikasi-tms/backend/internal/models"
 )
 
@@ -151,9 +153,9 @@ func RegisterVehicleForFleet(db *sql.DB, req models.VehicleRegistrationRequest,
 		
 		// Save owner documents
 		if ktpFile, ok := documents["ktp_file"].(string); ok && ktpFile != "" {
-			_, err = tx.Exec(`INSERT INTO vehicle_attachments (vehicle_id, attachment_type, file_path, file_name) 
-							 VALUES ($1, $2, $3, $4)`, 
-							 vehicle.ID, "ktp", ktpFile, "KTP_Pemilik.jpg")
+			_, err = tx.Exec(`INSERT INTO vehicle_attachments (vehicle_id, attachment_type, file_path, file_name, validation_status) 
+							 VALUES ($1, $2, $3, $4, $5)`, 
+							 vehicle.ID, "ktp", ktpFile, "KTP_Pemilik.jpg", InitialValidationStatus("ktp"))
 			if err != nil {
 				return nil, fmt.Errorf("failed to save KTP document: %v", err)
 			}
@@ -170,9 +172,9 @@ func RegisterVehicleForFleet(db *sql.DB, req models.VehicleRegistrationRequest,
 		
 		// Save vehicle documents
 		if stnkFile, ok := documents["stnk_file"].(string); ok && stnkFile != "" {
-			_, err = tx.Exec(`INSERT INTO vehicle_attachments (vehicle_id, attachment_type, file_path, file_name) 
-							 VALUES ($1, $2, $3, $4)`, 
-							 vehicle.ID, "stnk", stnkFile, "STNK.jpg")
+			_, err = tx.Exec(`INSERT INTO vehicle_attachments (vehicle_id, attachment_type, file_path, file_name, validation_status) 
+							 VALUES ($1, $2, $3, $4, $5)`, 
+							 vehicle.ID, "stnk", stnkFile, "STNK.jpg", InitialValidationStatus("stnk"))
 			if err != nil {
 				return nil, fmt.Errorf("failed to save STNK document: %v", err)
 			}
@@ -197,9 +199,9 @@ func RegisterVehicleForFleet(db *sql.DB, req models.VehicleRegistrationRequest,
 		}
 		
 		if insuranceFile, ok := documents["insurance_file"].(string); ok && insuranceFile != "" {
-			_, err = tx.Exec(`INSERT INTO vehicle_attachments (vehicle_id, attachment_type, file_path, file_name) 
-							 VALUES ($1, $2, $3, $4)`, 
-							 vehicle.ID, "insurance", insuranceFile, "Asuransi.jpg")
+			_, err = tx.Exec(`INSERT INTO vehicle_attachments (vehicle_id, attachment_type, file_path, file_name, validation_status) 
+							 VALUES ($1, $2, $3, $4, $5)`, 
+							 vehicle.ID, "insurance", insuranceFile, "Asuransi.jpg", InitialValidationStatus("insurance"))
 			if err != nil {
 				return nil, fmt.Errorf("failed to save insurance document: %v", err)
 			}
@@ -328,9 +330,9 @@ func GetFleetVehicles(db *sql.DB, fleetOwnerID int) ([]models.Vehicle, error) {
 }
 
 func notifyAdminsFleetRegistration(db *sql.DB, companyName string) {
-	// Get all admin users
-	query := `SELECT id FROM users WHERE role = 'admin'`
-	rows, err := db.Query(query)
+	// Get every user who can verify fleet owners
+	query := `SELECT id FROM users WHERE role = ANY($1)`
+	rows, err := db.Query(query, pq.Array(auth.RolesWithPermission(auth.PermDocumentsVerify)))
 	if err != nil {
 		return
 	}
@@ -352,9 +354,9 @@ func notifyAdminsFleetRegistration(db *sql.DB, companyName string) {
 }
 
 func notifyAdminsVehicleRegistration(db *sql.DB, registrationNumber string) {
-	// Get all admin users
-	query := `SELECT id FROM users WHERE role = 'admin'`
-	rows, err := db.Query(query)
+	// Get every user who can verify vehicles
+	query := `SELECT id FROM users WHERE role = ANY($1)`
+	rows, err := db.Query(query, pq.Array(auth.RolesWithPermission(auth.PermVehiclesVerify)))
 	if err != nil {
 		return
 	}
@@ -376,9 +378,9 @@ func notifyAdminsVehicleRegistration(db *sql.DB, registrationNumber string) {
 }
 
 func notifyAdminsCompleteVehicleRegistration(db *sql.DB, registrationNumber string, vehicleID int) {
-	// Get all admin users
-	query := `SELECT id FROM users WHERE role = 'admin'`
-	rows, err := db.Query(query)
+	// Get every user who can verify vehicles
+	query := `SELECT id FROM users WHERE role = ANY($1)`
+	rows, err := db.Query(query, pq.Array(auth.RolesWithPermission(auth.PermVehiclesVerify)))
 	if err != nil {
 		return
 	}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	_ "github.com/lib/pq"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
 )
 
 func setupTestDB(t *testing.T) *sql.DB {
@@ -23,20 +24,20 @@ func TestCreateFleetOwner(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.Close()
 	
-	fleetOwner := map[string]interface{}{
-		"user_id":          1,
-		"company_name":     "Test Company",
-		"business_license": "TEST123",
-		"address":          "Test Address",
-		"phone":            "081234567890",
+	req := models.FleetOwnerRequest{
+		CompanyName:     "Test Company",
+		BusinessLicense: "TEST123",
+		Address:         "Test Address",
+		PhoneNumber:     "081234567890",
+		Email:           "test@company.com",
 	}
 	
-	result, err := CreateFleetOwner(db, fleetOwner)
+	result, err := RegisterFleetOwner(db, req, 1)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 	
-	if result["company_name"] != "Test Company" {
+	if result.CompanyName != "Test Company" {
 		t.Fatal("Expected company name to match")
 	}
 }
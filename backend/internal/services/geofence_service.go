@@ -0,0 +1,345 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/geo"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+// Geofence event types
+const (
+	GeofenceEventEnter = "enter"
+	GeofenceEventExit  = "exit"
+	GeofenceEventDwell = "dwell"
+)
+
+// geofenceCacheTTL bounds how stale the in-memory geofence list may get on
+// instances that did not make the change themselves
+const geofenceCacheTTL = 30 * time.Second
+
+var geofenceCache struct {
+	sync.RWMutex
+	items    []models.Geofence
+	loadedAt time.Time
+}
+
+// InvalidateGeofenceCache forces the next evaluation to reload geofences
+func InvalidateGeofenceCache() {
+	geofenceCache.Lock()
+	geofenceCache.loadedAt = time.Time{}
+	geofenceCache.Unlock()
+}
+
+type GeofenceService struct {
+	db   *sql.DB
+	repo *repository.GeofenceRepository
+}
+
+func NewGeofenceService(db *sql.DB) *GeofenceService {
+	return &GeofenceService{db: db, repo: repository.NewGeofenceRepository(db)}
+}
+
+// BuildGeofence validates a request and turns it into a geofence
+func BuildGeofence(req models.GeofenceRequest) (*models.Geofence, error) {
+	g := &models.Geofence{
+		Name:         req.Name,
+		Category:     req.Category,
+		ShapeType:    req.ShapeType,
+		DwellSeconds: 600,
+		IsActive:     true,
+	}
+	if g.Category == "" {
+		g.Category = "customer_site"
+	}
+	if g.Category != "depot" && g.Category != "customer_site" && g.Category != "restricted" {
+		return nil, fmt.Errorf("invalid category: %s", req.Category)
+	}
+	if req.DwellSeconds != nil {
+		if *req.DwellSeconds < 0 {
+			return nil, fmt.Errorf("dwell_seconds must not be negative")
+		}
+		g.DwellSeconds = *req.DwellSeconds
+	}
+	if req.SpeedLimitKmh != nil {
+		if *req.SpeedLimitKmh <= 0 {
+			return nil, fmt.Errorf("speed_limit_kmh must be positive")
+		}
+		g.SpeedLimitKmh = req.SpeedLimitKmh
+	}
+	if req.IsActive != nil {
+		g.IsActive = *req.IsActive
+	}
+
+	switch req.ShapeType {
+	case "circle":
+		if req.CenterLat == nil || req.CenterLng == nil || req.RadiusMeters == nil {
+			return nil, fmt.Errorf("circle geofence requires center_lat, center_lng and radius_meters")
+		}
+		if !(geo.Point{Lat: *req.CenterLat, Lng: *req.CenterLng}).Valid() {
+			return nil, fmt.Errorf("invalid circle center")
+		}
+		if *req.RadiusMeters <= 0 || *req.RadiusMeters > 100000 {
+			return nil, fmt.Errorf("radius_meters must be between 0 and 100000")
+		}
+		g.CenterLat, g.CenterLng, g.RadiusMeters = req.CenterLat, req.CenterLng, req.RadiusMeters
+
+	case "polygon":
+		if len(req.Polygon) < 3 {
+			return nil, fmt.Errorf("polygon geofence requires at least 3 points")
+		}
+		for _, p := range req.Polygon {
+			if !p.Valid() {
+				return nil, fmt.Errorf("invalid polygon point: %v", p)
+			}
+		}
+		g.Polygon = req.Polygon
+
+	default:
+		return nil, fmt.Errorf("invalid shape type: %s", req.ShapeType)
+	}
+
+	return g, nil
+}
+
+// GeofenceContains reports whether p lies inside the geofence
+func GeofenceContains(g models.Geofence, p geo.Point) bool {
+	switch g.ShapeType {
+	case "circle":
+		if g.CenterLat == nil || g.CenterLng == nil || g.RadiusMeters == nil {
+			return false
+		}
+		return geo.InCircle(p, geo.Point{Lat: *g.CenterLat, Lng: *g.CenterLng}, *g.RadiusMeters)
+	case "polygon":
+		return geo.PolygonBounds(g.Polygon).Contains(p) && geo.InPolygon(p, g.Polygon)
+	}
+	return false
+}
+
+type geofenceTransition struct {
+	Geofence  models.Geofence
+	EventType string
+}
+
+// detectGeofenceTransitions compares a position against the geofences and
+// the device's current inside-state and returns the events it produces
+func detectGeofenceTransitions(geofences []models.Geofence, states map[int]models.GeofenceDeviceState, p geo.Point, at time.Time) []geofenceTransition {
+	var transitions []geofenceTransition
+	for _, g := range geofences {
+		inside := GeofenceContains(g, p)
+		state, wasInside := states[g.ID]
+
+		switch {
+		case inside && !wasInside:
+			transitions = append(transitions, geofenceTransition{g, GeofenceEventEnter})
+		case !inside && wasInside:
+			transitions = append(transitions, geofenceTransition{g, GeofenceEventExit})
+		case inside && wasInside && !state.DwellReported && g.DwellSeconds > 0 &&
+			at.Sub(state.EnteredAt) >= time.Duration(g.DwellSeconds)*time.Second:
+			transitions = append(transitions, geofenceTransition{g, GeofenceEventDwell})
+		}
+	}
+	return transitions
+}
+
+// loadActiveGeofences returns the active geofences from the shared cache,
+// reloading them through repo once the cache has expired
+func loadActiveGeofences(repo *repository.GeofenceRepository) ([]models.Geofence, error) {
+	geofenceCache.RLock()
+	if time.Since(geofenceCache.loadedAt) < geofenceCacheTTL {
+		items := geofenceCache.items
+		geofenceCache.RUnlock()
+		return items, nil
+	}
+	geofenceCache.RUnlock()
+
+	items, err := repo.GetGeofences(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load geofences: %v", err)
+	}
+
+	geofenceCache.Lock()
+	geofenceCache.items = items
+	geofenceCache.loadedAt = time.Now()
+	geofenceCache.Unlock()
+	return items, nil
+}
+
+// EvaluatePoint checks an ingested position against every active geofence,
+// stores the resulting enter/exit/dwell events and applies trip automation
+func (s *GeofenceService) EvaluatePoint(data *models.GPSTrackingData) ([]models.GeofenceEvent, error) {
+	geofences, err := loadActiveGeofences(s.repo)
+	if err != nil {
+		return nil, err
+	}
+	if len(geofences) == 0 {
+		return nil, nil
+	}
+
+	// Timestamps are stored without a zone, so compare them in UTC
+	utc := *data
+	utc.Timestamp = data.Timestamp.UTC()
+	data = &utc
+
+	// Points arriving out of order must not rewind the state
+	latest, err := s.repo.AdvanceDevice(data.DeviceID, data.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update geofence state: %v", err)
+	}
+	if !latest {
+		return nil, nil
+	}
+
+	states, err := s.repo.GetDeviceStates(data.DeviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load geofence state: %v", err)
+	}
+
+	point := geo.Point{Lat: data.Latitude, Lng: data.Longitude}
+	transitions := detectGeofenceTransitions(geofences, states, point, data.Timestamp)
+	if len(transitions) == 0 {
+		return nil, nil
+	}
+
+	vehicleID, err := s.repo.GetDeviceVehicleID(data.DeviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device vehicle: %v", err)
+	}
+
+	events := make([]models.GeofenceEvent, 0, len(transitions))
+	for _, t := range transitions {
+		switch t.EventType {
+		case GeofenceEventEnter:
+			err = s.repo.EnterGeofence(data.DeviceID, t.Geofence.ID, data.Timestamp)
+		case GeofenceEventExit:
+			err = s.repo.ExitGeofence(data.DeviceID, t.Geofence.ID)
+		case GeofenceEventDwell:
+			err = s.repo.MarkDwellReported(data.DeviceID, t.Geofence.ID)
+		}
+		if err != nil {
+			return events, fmt.Errorf("failed to update geofence state: %v", err)
+		}
+
+		event := models.GeofenceEvent{
+			GeofenceID:   t.Geofence.ID,
+			GeofenceName: t.Geofence.Name,
+			Category:     t.Geofence.Category,
+			DeviceID:     data.DeviceID,
+			VehicleID:    vehicleID,
+			EventType:    t.EventType,
+			Latitude:     data.Latitude,
+			Longitude:    data.Longitude,
+			OccurredAt:   data.Timestamp,
+		}
+
+		if vehicleID != nil {
+			s.applyTripAutomation(*vehicleID, t.Geofence, &event)
+		}
+
+		if err := s.repo.InsertEvent(&event); err != nil {
+			return events, fmt.Errorf("failed to save geofence event: %v", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// applyTripAutomation advances the vehicle's active trip when it arrives at or
+// leaves one of its stops. Failures are logged; they never block ingest.
+func (s *GeofenceService) applyTripAutomation(vehicleID int, g models.Geofence, event *models.GeofenceEvent) {
+	if event.EventType == GeofenceEventDwell {
+		return
+	}
+
+	var tripID int
+	var status string
+	var destLat, destLng sql.NullFloat64
+	err := s.db.QueryRow(`SELECT id, status, destination_lat, destination_lng FROM trips
+						  WHERE vehicle_id = $1 AND status IN ($2, $3, $4, $5)
+						  ORDER BY updated_at DESC LIMIT 1`,
+		vehicleID, TripStatusEnRoutePickup, TripStatusLoading, TripStatusInTransit, TripStatusUnloading).
+		Scan(&tripID, &status, &destLat, &destLng)
+	if err == sql.ErrNoRows {
+		return
+	}
+	if err != nil {
+		log.Printf("Geofence trip lookup error: %v", err)
+		return
+	}
+	event.TripID = &tripID
+
+	stops, err := GetTripStops(s.db, tripID)
+	if err != nil {
+		log.Printf("Geofence trip stops error: %v", err)
+		return
+	}
+
+	note := fmt.Sprintf("Geofence %s: %s", event.EventType, g.Name)
+
+	// Trips without stops only react to reaching the destination
+	if len(stops) == 0 {
+		if event.EventType == GeofenceEventEnter && status == TripStatusInTransit && destLat.Valid && destLng.Valid &&
+			GeofenceContains(g, geo.Point{Lat: destLat.Float64, Lng: destLng.Float64}) {
+			s.systemTransition(tripID, TripStatusUnloading, note)
+		}
+		return
+	}
+
+	for _, stop := range stops {
+		if stop.Latitude == nil || stop.Longitude == nil {
+			continue
+		}
+		if !GeofenceContains(g, geo.Point{Lat: *stop.Latitude, Lng: *stop.Longitude}) {
+			continue
+		}
+
+		if event.EventType == GeofenceEventEnter && stop.Status == StopStatusPending {
+			if _, err := AutoCheckInTripStop(s.db, tripID, stop.ID, note); err != nil {
+				// Not the next stop in sequence; leave it to the driver
+				continue
+			}
+			event.StopID = &stop.ID
+			if stop.StopType == "pickup" && status == TripStatusEnRoutePickup {
+				s.systemTransition(tripID, TripStatusLoading, note)
+			} else if stop.StopType == "dropoff" && status == TripStatusInTransit {
+				s.systemTransition(tripID, TripStatusUnloading, note)
+			}
+			return
+		}
+
+		if event.EventType == GeofenceEventExit && stop.Status == StopStatusArrived {
+			if _, err := AutoCheckOutTripStop(s.db, tripID, stop.ID, note); err != nil {
+				log.Printf("Geofence stop check-out error: %v", err)
+				return
+			}
+			event.StopID = &stop.ID
+			if (stop.StopType == "pickup" && status == TripStatusLoading) ||
+				(stop.StopType == "dropoff" && status == TripStatusUnloading && hasPendingStops(stops, stop.ID)) {
+				s.systemTransition(tripID, TripStatusInTransit, note)
+			}
+			return
+		}
+	}
+}
+
+// hasPendingStops reports whether any stop other than exceptID is still pending
+func hasPendingStops(stops []models.TripStop, exceptID int) bool {
+	for _, stop := range stops {
+		if stop.ID != exceptID && stop.Status == StopStatusPending {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *GeofenceService) systemTransition(tripID int, status, note string) {
+	if err := SystemTransitionTrip(s.db, tripID, status, note); err != nil {
+		log.Printf("Geofence trip transition error: %v", err)
+	}
+}
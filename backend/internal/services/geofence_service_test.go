@@ -0,0 +1,106 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/geo"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+func testCircleGeofence(id int, lat, lng, radius float64, dwell int) models.Geofence {
+	return models.Geofence{
+		ID:           id,
+		Name:         "Depot",
+		ShapeType:    "circle",
+		CenterLat:    &lat,
+		CenterLng:    &lng,
+		RadiusMeters: &radius,
+		DwellSeconds: dwell,
+		IsActive:     true,
+	}
+}
+
+func TestBuildGeofence(t *testing.T) {
+	lat, lng, radius := -6.2, 106.8, 250.0
+	g, err := BuildGeofence(models.GeofenceRequest{
+		Name: "Depot Cakung", Category: "depot", ShapeType: "circle",
+		CenterLat: &lat, CenterLng: &lng, RadiusMeters: &radius,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if g.DwellSeconds != 600 || !g.IsActive {
+		t.Errorf("Expected defaults dwell=600 active=true, got dwell=%d active=%v", g.DwellSeconds, g.IsActive)
+	}
+
+	polygon := []geo.Point{{Lat: 0, Lng: 0}, {Lat: 0, Lng: 1}, {Lat: 1, Lng: 1}}
+	if _, err := BuildGeofence(models.GeofenceRequest{Name: "Site", ShapeType: "polygon", Polygon: polygon}); err != nil {
+		t.Errorf("Expected valid polygon, got %v", err)
+	}
+}
+
+func TestBuildGeofenceRejectsInvalidInput(t *testing.T) {
+	lat, lng, badRadius := -6.2, 106.8, -1.0
+
+	cases := map[string]models.GeofenceRequest{
+		"missing radius":   {Name: "A", ShapeType: "circle", CenterLat: &lat, CenterLng: &lng},
+		"negative radius":  {Name: "A", ShapeType: "circle", CenterLat: &lat, CenterLng: &lng, RadiusMeters: &badRadius},
+		"short polygon":    {Name: "A", ShapeType: "polygon", Polygon: []geo.Point{{Lat: 0, Lng: 0}, {Lat: 1, Lng: 1}}},
+		"invalid category": {Name: "A", Category: "warehouse", ShapeType: "polygon"},
+	}
+
+	for name, req := range cases {
+		if _, err := BuildGeofence(req); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestDetectGeofenceTransitions(t *testing.T) {
+	depot := testCircleGeofence(1, -6.2, 106.8, 200, 600)
+	geofences := []models.Geofence{depot}
+	inside := geo.Point{Lat: -6.2005, Lng: 106.8}
+	outside := geo.Point{Lat: -6.21, Lng: 106.8}
+	now := time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC)
+
+	transitions := detectGeofenceTransitions(geofences, nil, inside, now)
+	if len(transitions) != 1 || transitions[0].EventType != GeofenceEventEnter {
+		t.Fatalf("Expected enter event, got %+v", transitions)
+	}
+
+	states := map[int]models.GeofenceDeviceState{1: {GeofenceID: 1, EnteredAt: now}}
+
+	if got := detectGeofenceTransitions(geofences, states, inside, now.Add(5*time.Minute)); len(got) != 0 {
+		t.Errorf("Expected no event before dwell threshold, got %+v", got)
+	}
+
+	transitions = detectGeofenceTransitions(geofences, states, inside, now.Add(10*time.Minute))
+	if len(transitions) != 1 || transitions[0].EventType != GeofenceEventDwell {
+		t.Fatalf("Expected dwell event, got %+v", transitions)
+	}
+
+	states[1] = models.GeofenceDeviceState{GeofenceID: 1, EnteredAt: now, DwellReported: true}
+	if got := detectGeofenceTransitions(geofences, states, inside, now.Add(20*time.Minute)); len(got) != 0 {
+		t.Errorf("Expected dwell to be reported once, got %+v", got)
+	}
+
+	transitions = detectGeofenceTransitions(geofences, states, outside, now.Add(25*time.Minute))
+	if len(transitions) != 1 || transitions[0].EventType != GeofenceEventExit {
+		t.Fatalf("Expected exit event, got %+v", transitions)
+	}
+}
+
+func TestHasPendingStops(t *testing.T) {
+	stops := []models.TripStop{
+		{ID: 1, Status: StopStatusDeparted},
+		{ID: 2, Status: StopStatusArrived},
+		{ID: 3, Status: StopStatusPending},
+	}
+	if !hasPendingStops(stops, 2) {
+		t.Error("Expected pending stop after stop 2")
+	}
+	if hasPendingStops(stops[:2], 2) {
+		t.Error("Expected no pending stops on the last drop")
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+// History resolutions
+const (
+	HistoryResolutionAuto   = "auto"
+	HistoryResolutionRaw    = "raw"
+	HistoryResolutionMinute = "minute"
+)
+
+const (
+	// maxRawHistorySpan is the longest range auto resolution serves raw
+	maxRawHistorySpan = 24 * time.Hour
+	maxHistorySpan    = 366 * 24 * time.Hour
+	defaultPartition  = "gps_tracking_default"
+)
+
+var (
+	ErrInvalidHistoryRange = errors.New("invalid history range")
+	ErrDeviceNotFound      = errors.New("device not found")
+)
+
+type GPSHistoryService struct {
+	repo    *repository.GPSTrackingRepository
+	devices *repository.GPSDeviceRepository
+	cfg     *config.GPSRetentionConfig
+}
+
+func NewGPSHistoryService(db *sql.DB, cfg *config.GPSRetentionConfig) *GPSHistoryService {
+	return &GPSHistoryService{
+		repo:    repository.NewGPSTrackingRepository(db),
+		devices: repository.NewGPSDeviceRepository(db),
+		cfg:     cfg,
+	}
+}
+
+// checkDeviceScope reports devices outside the caller's scope as not found
+func (s *GPSHistoryService) checkDeviceScope(scope models.TenantScope, deviceID string) error {
+	fleetOwnerID, err := s.devices.GetDeviceFleetOwnerID(deviceID)
+	if err == sql.ErrNoRows {
+		return ErrDeviceNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get device: %v", err)
+	}
+	if !scope.Allows(fleetOwnerID) {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// partitionName names the gps_tracking partition holding month, matching
+// the names migration 0008 creates
+func partitionName(month time.Time) string {
+	return fmt.Sprintf("gps_tracking_y%04dm%02d", month.Year(), int(month.Month()))
+}
+
+// partitionMonth is the inverse of partitionName
+func partitionMonth(name string) (time.Time, bool) {
+	var year, month int
+	if n, err := fmt.Sscanf(name, "gps_tracking_y%04dm%02d", &year, &month); err != nil || n != 2 || month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+	if partitionName(time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)) != name {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// rawCutoff is the time before which raw points are downsampled
+func rawCutoff(cfg *config.GPSRetentionConfig, now time.Time) time.Time {
+	return now.UTC().AddDate(0, 0, -cfg.RawRetentionDays).Truncate(time.Minute)
+}
+
+// rawAvailableFrom is the oldest time raw points may still exist. Whole
+// partitions are dropped, so it is the start of the cutoff's month.
+func rawAvailableFrom(cfg *config.GPSRetentionConfig, now time.Time) time.Time {
+	return monthStart(rawCutoff(cfg, now))
+}
+
+// expiredPartitions returns the monthly partitions that end on or before
+// cutoff, oldest first
+func expiredPartitions(names []string, cutoff time.Time) []string {
+	var expired []string
+	for _, name := range names {
+		month, ok := partitionMonth(name)
+		if ok && !month.AddDate(0, 1, 0).After(cutoff) {
+			expired = append(expired, name)
+		}
+	}
+	return expired
+}
+
+// chooseHistoryResolution resolves "auto": raw points for short ranges
+// that are still fully kept, minute summaries otherwise
+func chooseHistoryResolution(cfg *config.GPSRetentionConfig, requested string, from, to, now time.Time) (string, error) {
+	switch requested {
+	case HistoryResolutionRaw, HistoryResolutionMinute:
+		return requested, nil
+	case "", HistoryResolutionAuto:
+		if to.Sub(from) <= maxRawHistorySpan && !from.Before(rawAvailableFrom(cfg, now)) {
+			return HistoryResolutionRaw, nil
+		}
+		return HistoryResolutionMinute, nil
+	}
+	return "", fmt.Errorf("%w: resolution must be auto, raw or minute", ErrInvalidHistoryRange)
+}
+
+// GetHistory returns a device's history in [from, to), newest first, and
+// the resolution used
+func (s *GPSHistoryService) GetHistory(scope models.TenantScope, deviceID string, from, to time.Time, resolution string, limit int) ([]models.GPSHistoryPoint, string, error) {
+	from, to = from.UTC(), to.UTC()
+	if !from.Before(to) {
+		return nil, "", fmt.Errorf("%w: from must be before to", ErrInvalidHistoryRange)
+	}
+	if to.Sub(from) > maxHistorySpan {
+		return nil, "", fmt.Errorf("%w: range is longer than 366 days", ErrInvalidHistoryRange)
+	}
+
+	resolution, err := chooseHistoryResolution(s.cfg, resolution, from, to, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.checkDeviceScope(scope, deviceID); err != nil {
+		return nil, "", err
+	}
+
+	var history []models.GPSHistoryPoint
+	if resolution == HistoryResolutionRaw {
+		history, err = s.repo.GetRawHistory(deviceID, from, to, limit, true)
+	} else {
+		history, err = s.repo.GetMinuteHistory(deviceID, from, to, limit, true)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get tracking history: %v", err)
+	}
+	return history, resolution, nil
+}
+
+// RunMaintenance creates upcoming partitions, folds raw points past
+// retention into minute summaries and deletes expired summaries
+func (s *GPSHistoryService) RunMaintenance(now time.Time) (*models.GPSMaintenanceResult, error) {
+	result := &models.GPSMaintenanceResult{}
+	current := monthStart(now.UTC())
+
+	existing, err := s.repo.GetPartitions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %v", err)
+	}
+	have := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		have[name] = true
+	}
+
+	for i := 0; i <= s.cfg.PremakeMonths; i++ {
+		month := current.AddDate(0, i, 0)
+		name := partitionName(month)
+		if have[name] {
+			continue
+		}
+		if err := s.repo.CreatePartition(name, month, month.AddDate(0, 1, 0)); err != nil {
+			return result, fmt.Errorf("failed to create partition %s: %v", name, err)
+		}
+		result.PartitionsCreated = append(result.PartitionsCreated, name)
+	}
+
+	cutoff := rawCutoff(s.cfg, now)
+	for _, name := range expiredPartitions(existing, cutoff) {
+		points, err := s.repo.DownsamplePartition(name)
+		if err != nil {
+			return result, fmt.Errorf("failed to downsample partition %s: %v", name, err)
+		}
+		result.PartitionsDropped = append(result.PartitionsDropped, name)
+		result.PointsDownsampled += points
+	}
+
+	if have[defaultPartition] {
+		points, err := s.repo.DownsampleDefault(cutoff)
+		if err != nil {
+			return result, fmt.Errorf("failed to downsample default partition: %v", err)
+		}
+		result.PointsDownsampled += points
+	}
+
+	if s.cfg.SummaryRetentionDays > 0 {
+		deleted, err := s.repo.DeleteSummariesBefore(now.UTC().AddDate(0, 0, -s.cfg.SummaryRetentionDays))
+		if err != nil {
+			return result, fmt.Errorf("failed to delete expired summaries: %v", err)
+		}
+		result.SummariesDeleted = deleted
+	}
+
+	return result, nil
+}
+
+// StartMaintenance runs RunMaintenance now and then every
+// MaintenanceInterval until stop is closed
+func (s *GPSHistoryService) StartMaintenance(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.cfg.MaintenanceInterval)
+	defer ticker.Stop()
+
+	for {
+		result, err := s.RunMaintenance(time.Now())
+		if err != nil {
+			log.Printf("GPS history maintenance error: %v", err)
+		} else if len(result.PartitionsCreated) > 0 || len(result.PartitionsDropped) > 0 || result.SummariesDeleted > 0 {
+			log.Printf("GPS history maintenance: created %v, dropped %v, downsampled %d points, deleted %d summaries",
+				result.PartitionsCreated, result.PartitionsDropped, result.PointsDownsampled, result.SummariesDeleted)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
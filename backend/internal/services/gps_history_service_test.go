@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+)
+
+func TestPartitionName(t *testing.T) {
+	month := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	if got := partitionName(month); got != "gps_tracking_y2025m03" {
+		t.Fatalf("Expected gps_tracking_y2025m03, got %s", got)
+	}
+
+	parsed, ok := partitionMonth("gps_tracking_y2025m03")
+	if !ok || !parsed.Equal(month) {
+		t.Errorf("Expected %v, got %v (%v)", month, parsed, ok)
+	}
+
+	for _, name := range []string{"gps_tracking_default", "gps_tracking_y2025m13", "gps_tracking_y2025m3", "gps_tracking_y2025m03_old"} {
+		if _, ok := partitionMonth(name); ok {
+			t.Errorf("Expected %s not to parse as a monthly partition", name)
+		}
+	}
+}
+
+func TestExpiredPartitions(t *testing.T) {
+	names := []string{"gps_tracking_default", "gps_tracking_y2025m01", "gps_tracking_y2025m02", "gps_tracking_y2025m03"}
+
+	// March 1st exactly: February ends at the cutoff, March is still live
+	cutoff := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	want := []string{"gps_tracking_y2025m01", "gps_tracking_y2025m02"}
+	if got := expiredPartitions(names, cutoff); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if got := expiredPartitions(names, cutoff.Add(-time.Minute)); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("Expected only January before March, got %v", got)
+	}
+}
+
+func TestChooseHistoryResolution(t *testing.T) {
+	cfg := &config.GPSRetentionConfig{RawRetentionDays: 30}
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		requested string
+		from, to  time.Time
+		want      string
+	}{
+		{"recent day", "auto", now.Add(-24 * time.Hour), now, HistoryResolutionRaw},
+		{"recent week", "auto", now.Add(-7 * 24 * time.Hour), now, HistoryResolutionMinute},
+		{"old day still in partition", "", time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC), time.Date(2025, 5, 3, 0, 0, 0, 0, time.UTC), HistoryResolutionRaw},
+		{"downsampled day", "auto", time.Date(2025, 4, 20, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 21, 0, 0, 0, 0, time.UTC), HistoryResolutionMinute},
+		{"explicit raw", "raw", now.Add(-7 * 24 * time.Hour), now, HistoryResolutionRaw},
+		{"explicit minute", "minute", now.Add(-time.Hour), now, HistoryResolutionMinute},
+	}
+	for _, tt := range tests {
+		got, err := chooseHistoryResolution(cfg, tt.requested, tt.from, tt.to, now)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: expected %s, got %s (%v)", tt.name, tt.want, got, err)
+		}
+	}
+
+	if _, err := chooseHistoryResolution(cfg, "hourly", now.Add(-time.Hour), now, now); !errors.Is(err, ErrInvalidHistoryRange) {
+		t.Errorf("Expected ErrInvalidHistoryRange for an unknown resolution, got %v", err)
+	}
+}
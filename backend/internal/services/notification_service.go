@@ -59,6 +59,28 @@ func (s *NotificationService) SendVehicleNotification(vehicleID int, templateKey
 	return s.processNotification(notification, template)
 }
 
+// SendUserNotification sends a template to staff such as verification
+// supervisors rather than to a vehicle's owner
+func (s *NotificationService) SendUserNotification(userIDs []int, templateKey string, variables map[string]interface{}) error {
+	template, err := s.getNotificationTemplate(templateKey)
+	if err != nil {
+		return fmt.Errorf("failed to get template: %v", err)
+	}
+
+	for _, userID := range userIDs {
+		notification := NotificationData{
+			UserID:      userID,
+			TemplateKey: templateKey,
+			Variables:   variables,
+			Channels:    template["channels"].([]string),
+		}
+		if err := s.processNotification(notification, template); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *NotificationService) processNotification(data NotificationData, template map[string]interface{}) error {
 	// Replace variables in message
 	message := s.replaceVariables(template["message"].(string), data.Variables)
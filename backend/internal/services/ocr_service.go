@@ -1,14 +1,26 @@
 package services
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/imaging"
+	"github.com/youruser/aplikasi-tms/backend/internal/ocr"
 )
 
-type OCRService struct{}
+type OCRService struct {
+	engine OCREngine
+}
 
 type STNKData struct {
 	PlateNumber    string                 `json:"plate_number"`
@@ -28,23 +40,24 @@ type STNKData struct {
 }
 
 type KTPData struct {
-	NIK           string  `json:"nik"`
-	Name          string  `json:"name"`
-	BirthPlace    string  `json:"birth_place"`
-	BirthDate     string  `json:"birth_date"`
-	Gender        string  `json:"gender"`
-	Address       string  `json:"address"`
-	RTRW          string  `json:"rt_rw"`
-	Village       string  `json:"village"`
-	District      string  `json:"district"`
-	City          string  `json:"city"`
-	Province      string  `json:"province"`
-	Religion      string  `json:"religion"`
-	MaritalStatus string  `json:"marital_status"`
-	Occupation    string  `json:"occupation"`
-	Nationality   string  `json:"nationality"`
-	ExpiryDate    string  `json:"expiry_date"`
-	ConfidenceScore float64 `json:"confidence_score"`
+	NIK             string           `json:"nik"`
+	Name            string           `json:"name"`
+	BirthPlace      string           `json:"birth_place"`
+	BirthDate       string           `json:"birth_date"`
+	Gender          string           `json:"gender"`
+	Address         string           `json:"address"`
+	RTRW            string           `json:"rt_rw"`
+	Village         string           `json:"village"`
+	District        string           `json:"district"`
+	City            string           `json:"city"`
+	Province        string           `json:"province"`
+	Religion        string           `json:"religion"`
+	MaritalStatus   string           `json:"marital_status"`
+	Occupation      string           `json:"occupation"`
+	Nationality     string           `json:"nationality"`
+	ExpiryDate      string           `json:"expiry_date"`
+	ConfidenceScore float64          `json:"confidence_score"`
+	ExtractedFields []ExtractedField `json:"extracted_fields"`
 }
 
 type ExtractedField struct {
@@ -53,157 +66,317 @@ type ExtractedField struct {
 	Confidence float64 `json:"confidence"`
 }
 
-type FaceMatchResult struct {
-	MatchScore   float64                `json:"match_score"`
-	IsMatch      bool                   `json:"is_match"`
-	Confidence   string                 `json:"confidence"`
-	Threshold    float64                `json:"threshold"`
-	Details      map[string]interface{} `json:"details"`
+type QualityResult struct {
+	OverallQuality  string                 `json:"overall_quality"`
+	QualityScore    float64                `json:"quality_score"`
+	Issues          []string               `json:"issues"`
+	Recommendations []string               `json:"recommendations"`
+	Checks          map[string]interface{} `json:"checks"`
+	RetakeRequired  bool                   `json:"retake_required"`
 }
 
-type QualityResult struct {
-	OverallQuality string                 `json:"overall_quality"`
-	QualityScore   float64                `json:"quality_score"`
-	Issues         []string               `json:"issues"`
-	Recommendations []string              `json:"recommendations"`
-	Checks         map[string]interface{} `json:"checks"`
+// OCREngine reads the text of a document photo. The local Tesseract
+// engine (ocr.Tesseract) is the production implementation.
+type OCREngine interface {
+	Recognize(ctx context.Context, image []byte) (*ocr.Text, error)
 }
 
-func NewOCRService() *OCRService {
-	return &OCRService{}
+var (
+	ErrOCRUnavailable     = errors.New("OCR is not available")
+	ErrInvalidImage       = errors.New("invalid base64 image format")
+	ErrImageTooLarge      = errors.New("image is larger than 10 MB")
+	ErrImageTooManyPixels = errors.New("image is larger than 40 megapixels")
+)
+
+// maxOCRImageBytes bounds a decoded document photo
+const maxOCRImageBytes = 10 << 20
+
+// maxImagePixels bounds the decoded size of an uploaded image; a small
+// compressed file can otherwise expand to gigabytes of pixels
+const maxImagePixels = 40_000_000
+
+func NewOCRService(engine OCREngine) *OCRService {
+	return &OCRService{engine: engine}
 }
 
 // ExtractSTNKData extracts data from STNK image
 func (s *OCRService) ExtractSTNKData(base64Image string) (*STNKData, error) {
-	// Validate base64 image
-	if !s.isValidBase64Image(base64Image) {
-		return nil, fmt.Errorf("invalid base64 image format")
-	}
-
-	// For demo purposes, return mock data with some randomization
-	// In production, this would call actual OCR service (Google Vision, AWS Textract, etc.)
-	
-	mockData := &STNKData{
-		PlateNumber:    s.generateMockPlateNumber(),
-		OwnerName:      "AHMAD SURYANTO",
-		NIK:            "3201234567890123",
-		Address:        "JL. MERDEKA NO. 123, JAKARTA",
-		VehicleBrand:   "TOYOTA",
-		VehicleModel:   "AVANZA",
-		VehicleYear:    "2020",
-		ChassisNumber:  "MHKA1BA1HKK123456",
-		EngineNumber:   "3SZ1234567",
-		VehicleColor:   "HITAM",
-		ExpiryDate:     "2025-12-31",
-		IssueDate:      "2020-01-15",
-		ConfidenceScore: 0.92,
-		ExtractedFields: []ExtractedField{
-			{Field: "plate_number", Value: "L1234AB", Confidence: 0.95},
-			{Field: "owner_name", Value: "AHMAD SURYANTO", Confidence: 0.90},
-			{Field: "nik", Value: "3201234567890123", Confidence: 0.88},
-			{Field: "expiry_date", Value: "2025-12-31", Confidence: 0.93},
-		},
-	}
-
-	return mockData, nil
+	image, err := decodeDocumentImage(base64Image)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReadSTNK(image)
+}
+
+// ReadSTNK extracts STNK data from an encoded PNG or JPEG image
+func (s *OCRService) ReadSTNK(image []byte) (*STNKData, error) {
+	text, err := s.ReadText(image)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := ocr.ParseSTNK(text)
+	return &STNKData{
+		PlateNumber:     fields.Value(ocr.STNKPlateNumber),
+		OwnerName:       fields.Value(ocr.STNKOwnerName),
+		NIK:             fields.Value(ocr.STNKNIK),
+		Address:         fields.Value(ocr.STNKAddress),
+		VehicleBrand:    fields.Value(ocr.STNKBrand),
+		VehicleModel:    fields.Value(ocr.STNKModel),
+		VehicleYear:     fields.Value(ocr.STNKYear),
+		ChassisNumber:   fields.Value(ocr.STNKChassisNumber),
+		EngineNumber:    fields.Value(ocr.STNKEngineNumber),
+		VehicleColor:    fields.Value(ocr.STNKColor),
+		ExpiryDate:      fields.Value(ocr.STNKExpiryDate),
+		IssueDate:       fields.Value(ocr.STNKIssueDate),
+		ConfidenceScore: roundConfidence(fields.Score(ocr.STNKRequired)),
+		ExtractedFields: extractedFields(fields, stnkFieldOrder),
+	}, nil
 }
 
 // ExtractKTPData extracts data from KTP image
 func (s *OCRService) ExtractKTPData(base64Image string) (*KTPData, error) {
-	if !s.isValidBase64Image(base64Image) {
-		return nil, fmt.Errorf("invalid base64 image format")
-	}
-
-	mockData := &KTPData{
-		NIK:           "3201234567890123",
-		Name:          "AHMAD SURYANTO",
-		BirthPlace:    "JAKARTA",
-		BirthDate:     "1985-05-15",
-		Gender:        "LAKI-LAKI",
-		Address:       "JL. MERDEKA NO. 123",
-		RTRW:          "001/002",
-		Village:       "MENTENG",
-		District:      "MENTENG",
-		City:          "JAKARTA PUSAT",
-		Province:      "DKI JAKARTA",
-		Religion:      "ISLAM",
-		MaritalStatus: "KAWIN",
-		Occupation:    "KARYAWAN SWASTA",
-		Nationality:   "WNI",
-		ExpiryDate:    "2030-05-15",
-		ConfidenceScore: 0.89,
-	}
-
-	return mockData, nil
+	image, err := decodeDocumentImage(base64Image)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReadKTP(image)
+}
+
+// ReadKTP extracts KTP data from an encoded PNG or JPEG image
+func (s *OCRService) ReadKTP(image []byte) (*KTPData, error) {
+	text, err := s.ReadText(image)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := ocr.ParseKTP(text)
+	return &KTPData{
+		NIK:             fields.Value(ocr.KTPNIK),
+		Name:            fields.Value(ocr.KTPName),
+		BirthPlace:      fields.Value(ocr.KTPBirthPlace),
+		BirthDate:       fields.Value(ocr.KTPBirthDate),
+		Gender:          fields.Value(ocr.KTPGender),
+		Address:         fields.Value(ocr.KTPAddress),
+		RTRW:            fields.Value(ocr.KTPRTRW),
+		Village:         fields.Value(ocr.KTPVillage),
+		District:        fields.Value(ocr.KTPDistrict),
+		City:            fields.Value(ocr.KTPCity),
+		Province:        fields.Value(ocr.KTPProvince),
+		Religion:        fields.Value(ocr.KTPReligion),
+		MaritalStatus:   fields.Value(ocr.KTPMaritalStatus),
+		Occupation:      fields.Value(ocr.KTPOccupation),
+		Nationality:     fields.Value(ocr.KTPNationality),
+		ExpiryDate:      fields.Value(ocr.KTPExpiryDate),
+		ConfidenceScore: roundConfidence(fields.Score(ocr.KTPRequired)),
+		ExtractedFields: extractedFields(fields, ktpFieldOrder),
+	}, nil
+}
+
+var stnkFieldOrder = []string{
+	ocr.STNKPlateNumber, ocr.STNKOwnerName, ocr.STNKNIK, ocr.STNKAddress, ocr.STNKBrand, ocr.STNKModel,
+	ocr.STNKYear, ocr.STNKChassisNumber, ocr.STNKEngineNumber, ocr.STNKColor, ocr.STNKExpiryDate, ocr.STNKIssueDate,
+}
+
+var ktpFieldOrder = []string{
+	ocr.KTPNIK, ocr.KTPName, ocr.KTPBirthPlace, ocr.KTPBirthDate, ocr.KTPGender, ocr.KTPAddress, ocr.KTPRTRW,
+	ocr.KTPVillage, ocr.KTPDistrict, ocr.KTPCity, ocr.KTPProvince, ocr.KTPReligion, ocr.KTPMaritalStatus,
+	ocr.KTPOccupation, ocr.KTPNationality, ocr.KTPExpiryDate,
 }
 
-// PerformFaceMatch compares selfie with KTP photo
-func (s *OCRService) PerformFaceMatch(selfieBase64, ktpBase64 string) (*FaceMatchResult, error) {
-	if !s.isValidBase64Image(selfieBase64) || !s.isValidBase64Image(ktpBase64) {
-		return nil, fmt.Errorf("invalid base64 image format")
+// extractedFields lists the fields that were found, in document order
+func extractedFields(fields ocr.Fields, order []string) []ExtractedField {
+	extracted := []ExtractedField{}
+	for _, name := range order {
+		if f, ok := fields[name]; ok {
+			extracted = append(extracted, ExtractedField{Field: name, Value: f.Value, Confidence: f.Confidence})
+		}
 	}
+	return extracted
+}
 
-	// Mock face matching result
-	result := &FaceMatchResult{
-		MatchScore: 0.87,
-		IsMatch:    true,
-		Confidence: "high",
-		Threshold:  0.75,
-		Details: map[string]interface{}{
-			"face_detected_selfie":   true,
-			"face_detected_ktp":      true,
-			"quality_score_selfie":   0.92,
-			"quality_score_ktp":      0.85,
-		},
+func roundConfidence(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+// ReadText runs an encoded image through the engine. A photo without any
+// text gives empty text rather than an error.
+func (s *OCRService) ReadText(image []byte) (*ocr.Text, error) {
+	if s.engine == nil {
+		return nil, ErrOCRUnavailable
+	}
+	if err := checkImageSize(image); err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	text, err := s.engine.Recognize(context.Background(), image)
+	if errors.Is(err, ocr.ErrUnavailable) {
+		return nil, ErrOCRUnavailable
+	}
+	if errors.Is(err, ocr.ErrNoText) {
+		return &ocr.Text{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document text: %v", err)
+	}
+	return text, nil
 }
 
-// ValidateDocumentQuality checks image quality and readability
+// decodeDocumentImage decodes a "data:image/...;base64," URL and checks
+// that it holds a PNG or JPEG image
+func decodeDocumentImage(base64Image string) ([]byte, error) {
+	header, payload, ok := strings.Cut(base64Image, ",")
+	if !ok || !strings.HasPrefix(header, "data:image/") {
+		return nil, ErrInvalidImage
+	}
+	if base64.StdEncoding.DecodedLen(len(payload)) > maxOCRImageBytes {
+		return nil, ErrImageTooLarge
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if err := checkImageSize(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// checkImageSize reads only the header of an encoded PNG or JPEG image and
+// refuses one that would decode to more than maxImagePixels
+func checkImageSize(data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return ErrInvalidImage
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+		return ErrImageTooManyPixels
+	}
+	return nil
+}
+
+// decodeImage decodes an encoded PNG or JPEG image once its header shows
+// it is within maxImagePixels
+func decodeImage(data []byte) (image.Image, error) {
+	if err := checkImageSize(data); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	return img, nil
+}
+
+// Smallest photo OCR reads reliably, by document type. Cards need less
+// than the A4 pages (BPKB, KIR, insurance) every other type falls back to.
+var (
+	cardMinResolution = imaging.MinResolution{Long: 1000, Short: 600}
+	pageMinResolution = imaging.MinResolution{Long: 1400, Short: 1000}
+
+	documentMinResolution = map[string]imaging.MinResolution{
+		"stnk": cardMinResolution,
+		"ktp":  cardMinResolution,
+		"sim":  cardMinResolution,
+	}
+)
+
+// qualityCheckOrder fixes the order issues are reported in, worst
+// offenders for OCR first
+var qualityCheckOrder = []string{
+	imaging.CheckResolution, imaging.CheckBlur, imaging.CheckBounds, imaging.CheckGlare,
+	imaging.CheckBrightness, imaging.CheckContrast, imaging.CheckSkew,
+}
+
+// ValidateDocumentQuality analyses a document photo before it is submitted.
+// Every check below good adds an issue and a recommendation; a poor check
+// means the photo has to be retaken.
 func (s *OCRService) ValidateDocumentQuality(base64Image, documentType string) (*QualityResult, error) {
-	if !s.isValidBase64Image(base64Image) {
-		return nil, fmt.Errorf("invalid base64 image format")
+	data, err := decodeDocumentImage(base64Image)
+	if err != nil {
+		return nil, err
 	}
+	return s.CheckDocumentQuality(data, documentType)
+}
+
+// CheckDocumentQuality analyses an encoded PNG or JPEG document photo
+func (s *OCRService) CheckDocumentQuality(data []byte, documentType string) (*QualityResult, error) {
+	img, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	minRes, ok := documentMinResolution[strings.ToLower(documentType)]
+	if !ok {
+		minRes = pageMinResolution
+	}
+	report := imaging.Analyze(img, minRes)
 
 	result := &QualityResult{
-		OverallQuality: "good",
-		QualityScore:   0.88,
-		Issues:         []string{},
+		OverallQuality:  report.Status(),
+		QualityScore:    report.Score(),
+		Issues:          []string{},
 		Recommendations: []string{},
-		Checks: map[string]interface{}{
-			"brightness": map[string]interface{}{
-				"score":  0.90,
-				"status": "good",
-			},
-			"blur": map[string]interface{}{
-				"score":  0.85,
-				"status": "good",
-			},
-			"contrast": map[string]interface{}{
-				"score":  0.92,
-				"status": "excellent",
-			},
-			"text_readability": map[string]interface{}{
-				"score":  0.87,
-				"status": "good",
-			},
-			"document_bounds": map[string]interface{}{
-				"score":  0.95,
-				"status": "excellent",
-			},
-		},
-	}
-
-	// Add quality issues based on checks
-	if result.QualityScore < 0.7 {
-		result.Issues = append(result.Issues, "Low overall image quality")
-		result.Recommendations = append(result.Recommendations, "Please retake photo with better lighting")
+		Checks:          map[string]interface{}{},
+	}
+	seen := map[string]bool{}
+	for _, name := range qualityCheckOrder {
+		check, ok := report.Checks[name]
+		if !ok {
+			continue
+		}
+		result.Checks[name] = check
+		if check.Status == imaging.StatusPoor {
+			result.RetakeRequired = true
+		}
+		if check.Status != imaging.StatusPoor && check.Status != imaging.StatusFair {
+			continue
+		}
+		issue, advice := qualityFeedback(name, check)
+		result.Issues = append(result.Issues, issue)
+		if !seen[advice] {
+			seen[advice] = true
+			result.Recommendations = append(result.Recommendations, advice)
+		}
+	}
+	if result.RetakeRequired {
+		result.OverallQuality = imaging.StatusPoor
 	}
 
 	return result, nil
 }
 
+// qualityFeedback explains a failing check to the fleet owner
+func qualityFeedback(name string, check imaging.Check) (issue, advice string) {
+	switch name {
+	case imaging.CheckResolution:
+		return fmt.Sprintf("Image resolution is too low (%vx%v)", check.Extra["width"], check.Extra["height"]),
+			"Move closer or use a higher camera resolution"
+	case imaging.CheckBlur:
+		return "Image is blurry", "Hold the camera steady and tap to focus before taking the photo"
+	case imaging.CheckBounds:
+		switch sides := check.Extra["sides_found"].(int); {
+		case sides == 0:
+			return "Document edges not found", "Place the document on a plain, contrasting surface with all four edges visible"
+		case sides < 4:
+			return "Part of the document is outside the photo", "Fit the whole document inside the frame"
+		}
+		return "Document is too small in the photo", "Move closer so the document fills the frame"
+	case imaging.CheckGlare:
+		return "Glare or reflection on the document", "Turn off the flash and tilt the document away from the light"
+	case imaging.CheckBrightness:
+		if check.Extra["problem"] == "too_bright" {
+			return "Image is overexposed", "Avoid direct light on the document and retake the photo"
+		}
+		return "Image is too dark", "Please retake photo with better lighting"
+	case imaging.CheckContrast:
+		return "Low contrast between text and background", "Please retake photo with better lighting"
+	case imaging.CheckSkew:
+		return fmt.Sprintf("Document is tilted by %.1f degrees", check.Value), "Hold the camera straight above the document"
+	}
+	return "Low image quality", "Please retake the photo"
+}
+
 // ValidateSTNKData performs business rule validation on extracted STNK data
 func (s *OCRService) ValidateSTNKData(data *STNKData) []string {
 	var issues []string
@@ -221,9 +394,9 @@ func (s *OCRService) ValidateSTNKData(data *STNKData) []string {
 		issues = append(issues, "Format nomor polisi tidak valid")
 	}
 
-	// Validate NIK format (16 digits)
+	// Validate NIK format (16 digits); most STNKs do not print it
 	nikRegex := regexp.MustCompile(`^\d{16}$`)
-	if !nikRegex.MatchString(data.NIK) {
+	if data.NIK != "" && !nikRegex.MatchString(data.NIK) {
 		issues = append(issues, "Format NIK tidak valid (harus 16 digit)")
 	}
 
@@ -260,40 +433,12 @@ func (s *OCRService) ValidateKTPData(data *KTPData) []string {
 	return issues
 }
 
-// Helper functions
-func (s *OCRService) isValidBase64Image(base64Str string) bool {
-	if !strings.HasPrefix(base64Str, "data:image/") {
-		return false
-	}
-	
-	parts := strings.Split(base64Str, ",")
-	if len(parts) != 2 {
-		return false
-	}
-	
-	_, err := base64.StdEncoding.DecodeString(parts[1])
-	return err == nil
-}
-
-func (s *OCRService) generateMockPlateNumber() string {
-	// Generate random plate number for demo
-	prefixes := []string{"L", "B", "D", "F", "N", "AA", "AB"}
-	numbers := []string{"1234", "5678", "9012", "3456"}
-	suffixes := []string{"AB", "CD", "EF", "GH", "XY"}
-	
-	prefix := prefixes[time.Now().Second()%len(prefixes)]
-	number := numbers[time.Now().Minute()%len(numbers)]
-	suffix := suffixes[time.Now().Hour()%len(suffixes)]
-	
-	return fmt.Sprintf("%s%s%s", prefix, number, suffix)
-}
-
 // CrossValidateData compares STNK and KTP data for consistency
 func (s *OCRService) CrossValidateData(stnkData *STNKData, ktpData *KTPData) []string {
 	var issues []string
 
-	// Check if NIK matches
-	if stnkData.NIK != ktpData.NIK {
+	// Check if NIK matches; most STNKs do not print one
+	if stnkData.NIK != "" && ktpData.NIK != "" && stnkData.NIK != ktpData.NIK {
 		issues = append(issues, "NIK pada STNK tidak sesuai dengan KTP")
 	}
 
@@ -303,4 +448,4 @@ func (s *OCRService) CrossValidateData(stnkData *STNKData, ktpData *KTPData) []s
 	}
 
 	return issues
-}
\ No newline at end of file
+}
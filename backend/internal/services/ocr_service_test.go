@@ -0,0 +1,182 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/ocr"
+)
+
+// mockOCREngine stands in for Tesseract and returns fixed text
+type mockOCREngine struct {
+	text string
+	err  error
+}
+
+func (m *mockOCREngine) Recognize(ctx context.Context, image []byte) (*ocr.Text, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	text := &ocr.Text{}
+	for _, line := range strings.Split(strings.TrimSpace(m.text), "\n") {
+		text.Lines = append(text.Lines, ocr.Line{Text: strings.TrimSpace(line), Confidence: 0.92})
+	}
+	return text, nil
+}
+
+const mockSTNKText = `
+	NO. REGISTRASI : L 1234 AB
+	NAMA PEMILIK : AHMAD SURYANTO
+	ALAMAT : JL. MERDEKA NO. 123, JAKARTA
+	MERK : TOYOTA
+	TYPE : AVANZA
+	TAHUN PEMBUATAN : 2020
+	NO. RANGKA : MHKA1BA1HKK123456
+	NO. MESIN : 3SZ1234567
+	WARNA : HITAM
+	BERLAKU SAMPAI : 31-12-2099`
+
+const mockKTPText = `
+	NIK : 3201231505850001
+	Nama : AHMAD SURYANTO
+	Tempat/Tgl Lahir : JAKARTA, 15-05-1985
+	Jenis Kelamin : LAKI-LAKI
+	Alamat : JL. MERDEKA NO. 123
+	Berlaku Hingga : SEUMUR HIDUP`
+
+func testImage(t *testing.T) string {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestExtractSTNKData(t *testing.T) {
+	svc := NewOCRService(&mockOCREngine{text: mockSTNKText})
+
+	data, err := svc.ExtractSTNKData(testImage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.PlateNumber != "L 1234 AB" || data.OwnerName != "AHMAD SURYANTO" || data.ExpiryDate != "2099-12-31" {
+		t.Errorf("Unexpected STNK data %+v", data)
+	}
+	if data.ConfidenceScore != 0.92 {
+		t.Errorf("Expected every required field at line confidence, got %v", data.ConfidenceScore)
+	}
+	if len(data.ExtractedFields) != 10 || data.ExtractedFields[0].Field != "plate_number" {
+		t.Errorf("Expected found fields in document order, got %+v", data.ExtractedFields)
+	}
+	if issues := svc.ValidateSTNKData(data); len(issues) != 0 {
+		t.Errorf("Expected a clean STNK to pass, got %v", issues)
+	}
+}
+
+func TestExtractKTPData(t *testing.T) {
+	svc := NewOCRService(&mockOCREngine{text: mockKTPText})
+
+	data, err := svc.ExtractKTPData(testImage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.NIK != "3201231505850001" || data.BirthDate != "1985-05-15" || data.BirthPlace != "JAKARTA" {
+		t.Errorf("Unexpected KTP data %+v", data)
+	}
+
+	stnk, _ := NewOCRService(&mockOCREngine{text: mockSTNKText}).ExtractSTNKData(testImage(t))
+	stnk.NIK = data.NIK
+	if issues := svc.CrossValidateData(stnk, data); len(issues) != 0 {
+		t.Errorf("Expected matching owner and NIK, got %v", issues)
+	}
+}
+
+func TestOCRErrors(t *testing.T) {
+	img := testImage(t)
+
+	if _, err := NewOCRService(&mockOCREngine{}).ExtractSTNKData("data:image/png;base64,bm90IGFuIGltYWdl"); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("Expected ErrInvalidImage for non-image data, got %v", err)
+	}
+	if _, err := NewOCRService(nil).ExtractKTPData(img); !errors.Is(err, ErrOCRUnavailable) {
+		t.Errorf("Expected ErrOCRUnavailable without an engine, got %v", err)
+	}
+	if _, err := NewOCRService(&mockOCREngine{err: ocr.ErrUnavailable}).ExtractKTPData(img); !errors.Is(err, ErrOCRUnavailable) {
+		t.Errorf("Expected ErrOCRUnavailable when tesseract is missing, got %v", err)
+	}
+
+	data, err := NewOCRService(&mockOCREngine{err: ocr.ErrNoText}).ExtractSTNKData(img)
+	if err != nil || data.ConfidenceScore != 0 || len(data.ExtractedFields) != 0 {
+		t.Errorf("Expected a blank image to give an empty zero-confidence result, got %+v, %v", data, err)
+	}
+}
+
+// pngHeader returns just the signature and IHDR chunk of a width x height
+// grayscale PNG, enough for DecodeConfig but with no pixel data
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	ihdr[12] = 8 // bit depth; color type, compression, filter and interlace stay 0
+
+	buf := []byte("\x89PNG\r\n\x1a\n")
+	buf = binary.BigEndian.AppendUint32(buf, 13)
+	buf = append(buf, ihdr...)
+	return binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(ihdr))
+}
+
+func TestImagePixelLimit(t *testing.T) {
+	// 8000 x 6000 is 48 MP; the header alone must be enough to refuse it
+	bomb := pngHeader(8000, 6000)
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(bomb)
+	svc := NewOCRService(&mockOCREngine{text: mockSTNKText})
+
+	if _, err := svc.ExtractSTNKData(dataURL); !errors.Is(err, ErrImageTooManyPixels) {
+		t.Errorf("Expected ErrImageTooManyPixels from OCR, got %v", err)
+	}
+	if _, err := svc.ReadText(bomb); !errors.Is(err, ErrImageTooManyPixels) {
+		t.Errorf("Expected ErrImageTooManyPixels from ReadText, got %v", err)
+	}
+	if _, err := svc.CheckDocumentQuality(bomb, "stnk"); !errors.Is(err, ErrImageTooManyPixels) {
+		t.Errorf("Expected ErrImageTooManyPixels from the quality check, got %v", err)
+	}
+	if _, _, _, err := newPDFDocument().addImage(bomb); err == nil {
+		t.Error("Expected the PDF writer to refuse a 48 MP image")
+	}
+
+	// Right at the limit the header passes and decoding is attempted
+	if err := checkImageSize(pngHeader(8000, 5000)); err != nil {
+		t.Errorf("Expected a 40 MP header to pass, got %v", err)
+	}
+}
+
+func TestValidateDocumentQuality(t *testing.T) {
+	svc := NewOCRService(nil)
+
+	// A blank thumbnail fails on size, focus and framing
+	result, err := svc.ValidateDocumentQuality(testImage(t), "stnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.RetakeRequired || result.OverallQuality != "poor" {
+		t.Errorf("Expected a blank thumbnail to need a retake, got %+v", result)
+	}
+	if len(result.Issues) == 0 || result.Issues[0] != "Image resolution is too low (4x4)" {
+		t.Errorf("Expected the resolution issue first, got %v", result.Issues)
+	}
+	if _, ok := result.Checks["blur"]; !ok || len(result.Recommendations) == 0 {
+		t.Errorf("Expected checks and recommendations, got %+v", result)
+	}
+
+	if _, err := svc.ValidateDocumentQuality("data:image/png;base64,bm90IGFuIGltYWdl", "ktp"); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("Expected ErrInvalidImage, got %v", err)
+	}
+}
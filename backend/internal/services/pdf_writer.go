@@ -0,0 +1,151 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"strings"
+)
+
+// A4 page size in PDF points
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 50.0
+)
+
+// pdfDocument is a minimal PDF 1.4 writer for text and JPEG images, enough
+// for generated reports without an external dependency. Objects 1-3 are
+// reserved for the catalog, page tree and the Helvetica font.
+type pdfDocument struct {
+	objects [][]byte
+	pages   []int
+}
+
+func newPDFDocument() *pdfDocument {
+	d := &pdfDocument{objects: make([][]byte, 3)}
+	d.objects[2] = []byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	return d
+}
+
+func (d *pdfDocument) addObject(body []byte) int {
+	d.objects = append(d.objects, body)
+	return len(d.objects)
+}
+
+// addImage re-encodes any decodable image as JPEG and returns its object
+// number with the pixel size
+func (d *pdfDocument) addImage(data []byte) (int, int, int, error) {
+	img, err := decodeImage(data)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to encode image: %v", err)
+	}
+
+	// Grayscale images are encoded with a single component
+	colorSpace := "DeviceRGB"
+	if _, ok := img.(*image.Gray); ok {
+		colorSpace = "DeviceGray"
+	}
+
+	bounds := img.Bounds()
+	header := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n",
+		bounds.Dx(), bounds.Dy(), colorSpace, buf.Len())
+	body := append([]byte(header), buf.Bytes()...)
+	body = append(body, []byte("\nendstream")...)
+
+	return d.addObject(body), bounds.Dx(), bounds.Dy(), nil
+}
+
+// addPage appends a page drawing content; images maps resource names used in
+// content (e.g. "Im1") to image object numbers
+func (d *pdfDocument) addPage(content string, images map[string]int) {
+	stream := d.addObject([]byte(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content)))
+
+	var xobjects strings.Builder
+	for name, obj := range images {
+		fmt.Fprintf(&xobjects, " /%s %d 0 R", name, obj)
+	}
+	resources := "<< /Font << /F1 3 0 R >>"
+	if xobjects.Len() > 0 {
+		resources += " /XObject <<" + xobjects.String() + " >>"
+	}
+	resources += " >>"
+
+	page := d.addObject([]byte(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources %s /Contents %d 0 R >>",
+		pdfPageWidth, pdfPageHeight, resources, stream)))
+	d.pages = append(d.pages, page)
+}
+
+// Bytes serialises the document with its cross-reference table
+func (d *pdfDocument) Bytes() []byte {
+	var kids strings.Builder
+	for _, p := range d.pages {
+		fmt.Fprintf(&kids, "%d 0 R ", p)
+	}
+	d.objects[0] = []byte("<< /Type /Catalog /Pages 2 0 R >>")
+	d.objects[1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.TrimSpace(kids.String()), len(d.pages)))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(d.objects))
+	for i, obj := range d.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(obj)
+		buf.WriteString("\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(d.objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// pdfText returns content operators drawing one line of text at x, y
+func pdfText(x, y, size float64, text string) string {
+	return fmt.Sprintf("BT /F1 %.0f Tf %.2f %.2f Td (%s) Tj ET\n", size, x, y, pdfEscape(text))
+}
+
+// pdfImage returns content operators drawing image name scaled into the box
+// at x, y (bottom-left) while keeping its aspect ratio
+func pdfImage(name string, imgWidth, imgHeight int, x, y, maxWidth, maxHeight float64) (string, float64) {
+	w, h := float64(imgWidth), float64(imgHeight)
+	scale := maxWidth / w
+	if h*scale > maxHeight {
+		scale = maxHeight / h
+	}
+	w, h = w*scale, h*scale
+	return fmt.Sprintf("q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n", w, h, x, y+maxHeight-h, name), h
+}
+
+// pdfEscape escapes string delimiters and replaces characters outside the
+// Latin-1 range that the standard fonts cannot show
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteRune(' ')
+		case r < 32 || r > 255:
+			b.WriteRune('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// MaxPODPhotos caps the number of delivery photos per proof of delivery
+const MaxPODPhotos = 10
+
+var ErrPODRequired = errors.New("proof of delivery is required before completing this trip")
+
+// SubmitTripPOD stores a proof of delivery captured by the driver assigned to
+// the trip: recipient name, signature image, delivery photos and geotag
+func SubmitTripPOD(db *sql.DB, tripID, driverID, actorID int, req models.TripPODRequest, signature *multipart.FileHeader, photos []*multipart.FileHeader) (*models.TripPOD, error) {
+	recipient := strings.TrimSpace(req.RecipientName)
+	if recipient == "" {
+		return nil, fmt.Errorf("recipient name is required")
+	}
+	if req.Latitude == nil || req.Longitude == nil {
+		return nil, fmt.Errorf("delivery location is required")
+	}
+	if *req.Latitude < -90 || *req.Latitude > 90 {
+		return nil, fmt.Errorf("invalid latitude: must be between -90 and 90")
+	}
+	if *req.Longitude < -180 || *req.Longitude > 180 {
+		return nil, fmt.Errorf("invalid longitude: must be between -180 and 180")
+	}
+	if signature == nil {
+		return nil, fmt.Errorf("signature image is required")
+	}
+	if len(photos) > MaxPODPhotos {
+		return nil, fmt.Errorf("too many photos: maximum is %d", MaxPODPhotos)
+	}
+
+	trip, err := GetDriverTrip(db, tripID, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if trip.Status != TripStatusDelivered {
+		active := false
+		for _, s := range ActiveTripStatuses() {
+			if s == trip.Status {
+				active = true
+				break
+			}
+		}
+		if !active {
+			return nil, fmt.Errorf("%w: cannot record delivery for a %s trip", ErrInvalidTripTransition, trip.Status)
+		}
+	}
+	if req.StopID != nil {
+		found := false
+		for _, stop := range trip.Stops {
+			if stop.ID == *req.StopID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, ErrTripStopNotFound
+		}
+	}
+
+	// Store files first; anything written is removed again if the
+	// database insert fails
+	var stored []string
+	cleanup := func() {
+		for _, path := range stored {
+			os.Remove(path)
+		}
+	}
+
+	signaturePath, _, err := savePODImage(fmt.Sprintf("pod_%d_signature", tripID), signature)
+	if err != nil {
+		return nil, err
+	}
+	stored = append(stored, signaturePath)
+
+	pod := models.TripPOD{
+		TripID:        tripID,
+		StopID:        req.StopID,
+		RecipientName: recipient,
+		SignaturePath: signaturePath,
+		Latitude:      *req.Latitude,
+		Longitude:     *req.Longitude,
+		Accuracy:      req.Accuracy,
+		CapturedBy:    &actorID,
+		Photos:        []models.TripPODPhoto{},
+	}
+	if req.Notes != "" {
+		pod.Notes = &req.Notes
+	}
+
+	for i, header := range photos {
+		path, contentType, err := savePODImage(fmt.Sprintf("pod_%d_photo%d", tripID, i+1), header)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		stored = append(stored, path)
+		pod.Photos = append(pod.Photos, models.TripPODPhoto{
+			FileName: header.Filename,
+			FilePath: path,
+			FileSize: int(header.Size),
+			MimeType: contentType,
+		})
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`INSERT INTO trip_pods (trip_id, stop_id, recipient_name, signature_path,
+					   latitude, longitude, accuracy, notes, captured_by)
+					   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, captured_at`,
+		pod.TripID, pod.StopID, pod.RecipientName, pod.SignaturePath,
+		pod.Latitude, pod.Longitude, pod.Accuracy, pod.Notes, pod.CapturedBy).
+		Scan(&pod.ID, &pod.CapturedAt)
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to save proof of delivery: %v", err)
+	}
+
+	for i := range pod.Photos {
+		photo := &pod.Photos[i]
+		photo.PODID = pod.ID
+		err = tx.QueryRow(`INSERT INTO trip_pod_photos (pod_id, file_name, file_path, file_size, mime_type)
+						   VALUES ($1, $2, $3, $4, $5) RETURNING id, uploaded_at`,
+			pod.ID, photo.FileName, photo.FilePath, photo.FileSize, photo.MimeType).
+			Scan(&photo.ID, &photo.UploadedAt)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("failed to save delivery photo: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to commit proof of delivery: %v", err)
+	}
+
+	return &pod, nil
+}
+
+// savePODImage stores a POD image using the shared upload storage. The
+// type is sniffed from the file rather than taken from the client.
+func savePODImage(prefix string, header *multipart.FileHeader) (string, string, error) {
+	file, err := header.Open()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open uploaded file: %v", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", "", fmt.Errorf("failed to read uploaded file: %v", err)
+	}
+	contentType := http.DetectContentType(head[:n])
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", "", fmt.Errorf("file type not allowed: %s", contentType)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", "", fmt.Errorf("failed to read uploaded file: %v", err)
+	}
+
+	filePath, err := storeUploadedFile(prefix, file, header, contentType)
+	return filePath, contentType, err
+}
+
+// GetTripPODs returns every proof of delivery recorded for a trip
+func GetTripPODs(db *sql.DB, tripID int) ([]models.TripPOD, error) {
+	rows, err := db.Query(`SELECT id, trip_id, stop_id, recipient_name, signature_path, latitude, longitude,
+						   accuracy, notes, captured_by, captured_at
+						   FROM trip_pods WHERE trip_id = $1 ORDER BY captured_at, id`, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proof of delivery: %v", err)
+	}
+	defer rows.Close()
+
+	pods := []models.TripPOD{}
+	index := map[int]int{}
+	for rows.Next() {
+		var p models.TripPOD
+		err := rows.Scan(&p.ID, &p.TripID, &p.StopID, &p.RecipientName, &p.SignaturePath, &p.Latitude, &p.Longitude,
+			&p.Accuracy, &p.Notes, &p.CapturedBy, &p.CapturedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan proof of delivery: %v", err)
+		}
+		p.Photos = []models.TripPODPhoto{}
+		index[p.ID] = len(pods)
+		pods = append(pods, p)
+	}
+	if len(pods) == 0 {
+		return pods, nil
+	}
+
+	photoRows, err := db.Query(`SELECT ph.id, ph.pod_id, ph.file_name, ph.file_path, COALESCE(ph.file_size, 0),
+								COALESCE(ph.mime_type, ''), ph.uploaded_at
+								FROM trip_pod_photos ph
+								JOIN trip_pods p ON ph.pod_id = p.id
+								WHERE p.trip_id = $1 ORDER BY ph.id`, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery photos: %v", err)
+	}
+	defer photoRows.Close()
+
+	for photoRows.Next() {
+		var ph models.TripPODPhoto
+		err := photoRows.Scan(&ph.ID, &ph.PODID, &ph.FileName, &ph.FilePath, &ph.FileSize, &ph.MimeType, &ph.UploadedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery photo: %v", err)
+		}
+		if i, ok := index[ph.PODID]; ok {
+			pods[i].Photos = append(pods[i].Photos, ph)
+		}
+	}
+
+	return pods, nil
+}
+
+// checkPODRequirement refuses delivery when the trip's customer contract
+// requires a proof of delivery and none has been recorded
+func checkPODRequirement(tx *sql.Tx, tripID int) error {
+	var requiresPOD bool
+	var podCount int
+	err := tx.QueryRow(`SELECT COALESCE(cc.requires_pod, false),
+						(SELECT COUNT(*) FROM trip_pods WHERE trip_id = t.id)
+						FROM trips t
+						LEFT JOIN customer_contracts cc ON t.customer_contract_id = cc.id
+						WHERE t.id = $1`, tripID).Scan(&requiresPOD, &podCount)
+	if err != nil {
+		return fmt.Errorf("failed to check proof of delivery: %v", err)
+	}
+
+	if requiresPOD && podCount == 0 {
+		return ErrPODRequired
+	}
+	return nil
+}
+
+// CanAccessTripPOD reports whether a user may read a trip's proof of
+// delivery: staff, and the fleet owner the trip belongs to
+func CanAccessTripPOD(db *sql.DB, tripID, userID int, role string) (bool, error) {
+	if IsStaffRole(role) {
+		return true, nil
+	}
+
+	var ownerUserID sql.NullInt64
+	err := db.QueryRow(`SELECT fo.user_id FROM trips t
+						JOIN fleet_owners fo ON t.fleet_owner_id = fo.id
+						WHERE t.id = $1`, tripID).Scan(&ownerUserID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check trip ownership: %v", err)
+	}
+
+	return ownerUserID.Valid && int(ownerUserID.Int64) == userID, nil
+}
+
+// GenerateTripPODPDF renders every proof of delivery of a trip, with its
+// signature and photos, into a single PDF document
+func GenerateTripPODPDF(db *sql.DB, tripID int) ([]byte, error) {
+	trip, err := GetTripByID(db, tripID)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := GetTripPODs(db, tripID)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no proof of delivery recorded for this trip")
+	}
+
+	return renderTripPODPDF(trip, pods, os.ReadFile)
+}
+
+func renderTripPODPDF(trip *models.Trip, pods []models.TripPOD, readFile func(string) ([]byte, error)) ([]byte, error) {
+	doc := newPDFDocument()
+	stops := map[int]models.TripStop{}
+	for _, s := range trip.Stops {
+		stops[s.ID] = s
+	}
+
+	for _, pod := range pods {
+		var content strings.Builder
+		y := pdfPageHeight - pdfMargin - 20
+
+		line := func(size float64, text string) {
+			content.WriteString(pdfText(pdfMargin, y, size, text))
+			y -= size + 8
+		}
+
+		line(18, "Proof of Delivery")
+		line(11, fmt.Sprintf("Trip #%d: %s - %s", trip.ID, trip.Origin, trip.Destination))
+		if trip.Vehicle != nil {
+			line(11, "Vehicle: "+trip.Vehicle.RegistrationNumber)
+		}
+		if trip.Driver != nil && trip.Driver.User != nil {
+			line(11, "Driver: "+trip.Driver.User.FullName)
+		}
+		if pod.StopID != nil {
+			if stop, ok := stops[*pod.StopID]; ok {
+				line(11, fmt.Sprintf("Stop %d: %s", stop.Sequence, stop.Address))
+			}
+		}
+		line(11, "Recipient: "+pod.RecipientName)
+		line(11, "Received at: "+pod.CapturedAt.Format(time.RFC1123))
+		location := fmt.Sprintf("Location: %.6f, %.6f", pod.Latitude, pod.Longitude)
+		if pod.Accuracy != nil {
+			location += fmt.Sprintf(" (+/- %.0f m)", *pod.Accuracy)
+		}
+		line(11, location)
+		if pod.Notes != nil && *pod.Notes != "" {
+			line(11, "Notes: "+*pod.Notes)
+		}
+
+		images := map[string]int{}
+		y -= 10
+		line(12, "Signature")
+		if data, err := readFile(pod.SignaturePath); err == nil {
+			if obj, w, h, err := doc.addImage(data); err == nil {
+				images["Sig"] = obj
+				ops, _ := pdfImage("Sig", w, h, pdfMargin, y-150, 250, 150)
+				content.WriteString(ops)
+			}
+		}
+		doc.addPage(content.String(), images)
+
+		for i, photo := range pod.Photos {
+			data, err := readFile(photo.FilePath)
+			if err != nil {
+				continue
+			}
+			obj, w, h, err := doc.addImage(data)
+			if err != nil {
+				continue
+			}
+
+			var page strings.Builder
+			page.WriteString(pdfText(pdfMargin, pdfPageHeight-pdfMargin-20, 14,
+				fmt.Sprintf("Trip #%d - delivery photo %d of %d", trip.ID, i+1, len(pod.Photos))))
+			ops, _ := pdfImage("Photo", w, h, pdfMargin, pdfMargin,
+				pdfPageWidth-2*pdfMargin, pdfPageHeight-2*pdfMargin-50)
+			page.WriteString(ops)
+			doc.addPage(page.String(), map[string]int{"Photo": obj})
+		}
+	}
+
+	return doc.Bytes(), nil
+}
+
+// CreateCustomerContract registers a customer contract trips can reference
+func CreateCustomerContract(db *sql.DB, req models.CustomerContractRequest) (*models.CustomerContract, error) {
+	contract := models.CustomerContract{
+		CustomerName:   req.CustomerName,
+		ContractNumber: req.ContractNumber,
+		RequiresPOD:    req.RequiresPOD,
+	}
+
+	var err error
+	if contract.ValidFrom, err = parseOptionalDate(req.ValidFrom, "valid_from"); err != nil {
+		return nil, err
+	}
+	if contract.ValidUntil, err = parseOptionalDate(req.ValidUntil, "valid_until"); err != nil {
+		return nil, err
+	}
+
+	err = db.QueryRow(`INSERT INTO customer_contracts (customer_name, contract_number, requires_pod, valid_from, valid_until)
+					   VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`,
+		contract.CustomerName, contract.ContractNumber, contract.RequiresPOD, contract.ValidFrom, contract.ValidUntil).
+		Scan(&contract.ID, &contract.CreatedAt, &contract.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create customer contract: %v", err)
+	}
+
+	return &contract, nil
+}
+
+// GetCustomerContracts lists customer contracts by customer name
+func GetCustomerContracts(db *sql.DB) ([]models.CustomerContract, error) {
+	rows, err := db.Query(`SELECT id, customer_name, contract_number, requires_pod, valid_from, valid_until,
+						   created_at, updated_at FROM customer_contracts ORDER BY customer_name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer contracts: %v", err)
+	}
+	defer rows.Close()
+
+	contracts := []models.CustomerContract{}
+	for rows.Next() {
+		var c models.CustomerContract
+		err := rows.Scan(&c.ID, &c.CustomerName, &c.ContractNumber, &c.RequiresPOD, &c.ValidFrom, &c.ValidUntil,
+			&c.CreatedAt, &c.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan customer contract: %v", err)
+		}
+		contracts = append(contracts, c)
+	}
+
+	return contracts, nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, h/2, color.Black)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRenderTripPODPDF(t *testing.T) {
+	files := map[string][]byte{
+		"sig.png":    testPNG(t, 200, 80),
+		"photo1.png": testPNG(t, 640, 480),
+		"photo2.png": testPNG(t, 480, 640),
+	}
+	readFile := func(path string) ([]byte, error) {
+		if data, ok := files[path]; ok {
+			return data, nil
+		}
+		return nil, fmt.Errorf("not found: %s", path)
+	}
+
+	trip := &models.Trip{ID: 7, Origin: "Jakarta", Destination: "Bandung (Gudang 2)"}
+	pods := []models.TripPOD{{
+		ID:            1,
+		TripID:        7,
+		RecipientName: "Budi Santoso",
+		SignaturePath: "sig.png",
+		Latitude:      -6.914744,
+		Longitude:     107.609810,
+		CapturedAt:    time.Date(2025, 1, 10, 14, 30, 0, 0, time.UTC),
+		Photos: []models.TripPODPhoto{
+			{FilePath: "photo1.png"},
+			{FilePath: "photo2.png"},
+			{FilePath: "missing.png"},
+		},
+	}}
+
+	pdf, err := renderTripPODPDF(trip, pods, readFile)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	out := string(pdf)
+	if !strings.HasPrefix(out, "%PDF-1.4") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatal("Expected a complete PDF document")
+	}
+	// Summary page plus one page per readable photo
+	if !strings.Contains(out, "/Count 3") {
+		t.Error("Expected 3 pages in the PDF")
+	}
+	if !strings.Contains(out, "(Recipient: Budi Santoso)") {
+		t.Error("Expected recipient name in the PDF")
+	}
+	if !strings.Contains(out, `Bandung \(Gudang 2\)`) {
+		t.Error("Expected parentheses to be escaped")
+	}
+	if strings.Count(out, "/Subtype /Image") != 3 {
+		t.Error("Expected signature and two photos to be embedded")
+	}
+}
+
+func TestRenderTripPODPDFGraySignature(t *testing.T) {
+	// Black-and-white signatures decode as image.Gray and are embedded as
+	// one-component JPEGs
+	gray := image.NewGray(image.Rect(0, 0, 200, 80))
+	for x := 0; x < 200; x++ {
+		gray.SetGray(x, 40, color.Gray{Y: 255})
+	}
+	var sig bytes.Buffer
+	if err := png.Encode(&sig, gray); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	files := map[string][]byte{"sig.png": sig.Bytes(), "photo.png": testPNG(t, 64, 48)}
+	readFile := func(path string) ([]byte, error) { return files[path], nil }
+
+	trip := &models.Trip{ID: 7, Origin: "Jakarta", Destination: "Bandung"}
+	pods := []models.TripPOD{{ID: 1, TripID: 7, RecipientName: "Budi", SignaturePath: "sig.png",
+		Photos: []models.TripPODPhoto{{FilePath: "photo.png"}}}}
+	pdf, err := renderTripPODPDF(trip, pods, readFile)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	out := string(pdf)
+	for space, model := range map[string]color.Model{"/DeviceGray": color.GrayModel, "/DeviceRGB": color.YCbCrModel} {
+		i := strings.Index(out, "/ColorSpace "+space)
+		if i < 0 {
+			t.Fatalf("Expected an image in %s", space)
+		}
+		start := i + strings.Index(out[i:], "stream\n") + len("stream\n")
+		end := start + strings.Index(out[start:], "\nendstream")
+		cfg, err := jpeg.DecodeConfig(strings.NewReader(out[start:end]))
+		if err != nil || cfg.ColorModel != model {
+			t.Errorf("Expected the %s image to match its JPEG, got %v (%v)", space, cfg.ColorModel, err)
+		}
+	}
+}
+
+func TestSavePODImageSniffsType(t *testing.T) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreatePart(map[string][]string{
+		"Content-Disposition": {`form-data; name="signature"; filename="sig.png"`},
+		"Content-Type":        {"image/png"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("<html><script>alert(1)</script></html>"))
+	w.Close()
+
+	form, err := multipart.NewReader(&body, w.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The client's Content-Type says PNG; the content says otherwise
+	_, _, err = savePODImage("pod_test", form.File["signature"][0])
+	if err == nil || !strings.Contains(err.Error(), "text/html") {
+		t.Errorf("Expected a mislabelled upload to be refused, got %v", err)
+	}
+}
+
+func TestSubmitTripPODValidation(t *testing.T) {
+	zero := 0.0
+	tests := []struct {
+		name string
+		req  models.TripPODRequest
+		want string
+	}{
+		{"blank recipient", models.TripPODRequest{RecipientName: "   ", Latitude: &zero, Longitude: &zero}, "recipient name"},
+		{"no location", models.TripPODRequest{RecipientName: "Budi"}, "location"},
+		// The equator and prime meridian are real places; only the
+		// signature is missing here
+		{"zero coordinates", models.TripPODRequest{RecipientName: "Budi", Latitude: &zero, Longitude: &zero}, "signature"},
+	}
+	for _, tt := range tests {
+		_, err := SubmitTripPOD(nil, 1, 1, 1, tt.req, nil, nil)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error about %s, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/registry"
+)
+
+// CrossCheckUnavailable is the status of a registry check that could not
+// be made, so the vehicle has to be checked by hand
+const CrossCheckUnavailable = "unavailable"
+
+var registryNames = map[string]string{
+	registry.Samsat:    "Samsat",
+	registry.KIR:       "KIR",
+	registry.Insurance: "asuransi",
+}
+
+// performRegistryCheck asks provider, the registry of kind, about a
+// vehicle, giving up when ctx is done. A missing provider or a failed
+// lookup is "unavailable", never "passed".
+func performRegistryCheck(ctx context.Context, provider registry.Provider, kind string, q registry.Query, now time.Time) map[string]interface{} {
+	name := registryNames[kind]
+	result := map[string]interface{}{
+		"check_type": kind,
+		"confidence": 0.0,
+	}
+
+	if provider == nil {
+		result["status"] = CrossCheckUnavailable
+		result["message"] = fmt.Sprintf("Layanan %s belum dikonfigurasi, periksa manual", name)
+		result["details"] = map[string]interface{}{"reason": "not_configured"}
+		return result
+	}
+
+	record, err := provider.Lookup(ctx, q)
+	if err != nil {
+		log.Printf("Registry %s lookup for %s failed: %v", kind, q.RegistrationNumber, err)
+		reason := "error"
+		if errors.Is(err, registry.ErrUnavailable) {
+			reason = "circuit_open"
+		}
+		result["status"] = CrossCheckUnavailable
+		result["message"] = fmt.Sprintf("Layanan %s tidak dapat dihubungi, periksa manual", name)
+		result["details"] = map[string]interface{}{"reason": reason}
+		return result
+	}
+
+	details := map[string]interface{}{}
+	for k, v := range record.Details {
+		details[k] = v
+	}
+	details["registered"] = record.Found
+	result["details"] = details
+	result["confidence"] = 0.95
+
+	if !record.Found {
+		result["status"] = "failed"
+		result["message"] = fmt.Sprintf("Nomor polisi %s tidak terdaftar di %s", q.RegistrationNumber, name)
+		return result
+	}
+
+	for _, field := range []struct{ label, vehicle, registry string }{
+		{"Nomor rangka", q.ChassisNumber, record.ChassisNumber},
+		{"Nomor mesin", q.EngineNumber, record.EngineNumber},
+	} {
+		if field.vehicle == "" || field.registry == "" {
+			continue
+		}
+		if registry.Normalize(field.vehicle) != registry.Normalize(field.registry) {
+			result["status"] = "failed"
+			result["message"] = fmt.Sprintf("%s di %s (%s) tidak sesuai dengan data kendaraan (%s)",
+				field.label, name, field.registry, field.vehicle)
+			return result
+		}
+	}
+
+	expiry, ok := record.Expiry()
+	if !ok {
+		result["status"] = "warning"
+		result["confidence"] = 0.6
+		result["message"] = fmt.Sprintf("Kendaraan terdaftar di %s, tetapi masa berlaku tidak tersedia", name)
+		return result
+	}
+	details["valid_until"] = expiry.Format("2006-01-02")
+
+	if expiry.Before(now.Truncate(24 * time.Hour)) {
+		result["status"] = "failed"
+		switch kind {
+		case registry.Samsat:
+			result["message"] = fmt.Sprintf("Pajak kendaraan %s sudah jatuh tempo sejak %s", q.RegistrationNumber, expiry.Format("02-01-2006"))
+		case registry.KIR:
+			result["message"] = fmt.Sprintf("KIR kendaraan sudah habis masa berlaku sejak %s", expiry.Format("02-01-2006"))
+		default:
+			result["message"] = fmt.Sprintf("Polis asuransi sudah berakhir sejak %s", expiry.Format("02-01-2006"))
+		}
+		return result
+	}
+
+	result["status"] = "passed"
+	switch kind {
+	case registry.Samsat:
+		result["message"] = fmt.Sprintf("Nomor polisi %s terdaftar dan pajak aktif", q.RegistrationNumber)
+	case registry.KIR:
+		result["message"] = "Kendaraan memiliki KIR yang masih berlaku"
+	default:
+		result["message"] = "Asuransi aktif dan sesuai dengan data kendaraan"
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/registry"
+)
+
+func TestPerformRegistryCheck(t *testing.T) {
+	stub := registry.NewStub()
+	stub.Set("B 1234 XYZ", registry.Record{ChassisNumber: "MHKA1BA1HKK123456", ValidUntil: "2025-06-01"})
+	stub.Set("B 5678 XYZ", registry.Record{ChassisNumber: "MHKA1BA1HKK999999", ValidUntil: "2025-06-01"})
+	stub.Set("B 9 XYZ", registry.Record{})
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+	provider := registry.NewHTTP(srv.URL, "", time.Second)
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		kind    string
+		query   registry.Query
+		now     time.Time
+		status  string
+		message string
+	}{
+		{"valid", registry.Samsat, registry.Query{RegistrationNumber: "B 1234 XYZ", ChassisNumber: "mhka1ba1hkk123456"}, now,
+			"passed", "Nomor polisi B 1234 XYZ terdaftar dan pajak aktif"},
+		{"expired", registry.KIR, registry.Query{RegistrationNumber: "B 1234 XYZ"}, now.AddDate(1, 0, 0),
+			"failed", "KIR kendaraan sudah habis masa berlaku sejak 01-06-2025"},
+		{"chassis mismatch", registry.Insurance, registry.Query{RegistrationNumber: "B 5678 XYZ", ChassisNumber: "MHKA1BA1HKK123456"}, now,
+			"failed", "Nomor rangka di asuransi (MHKA1BA1HKK999999) tidak sesuai dengan data kendaraan (MHKA1BA1HKK123456)"},
+		{"not registered", registry.Samsat, registry.Query{RegistrationNumber: "L 1 A"}, now,
+			"failed", "Nomor polisi L 1 A tidak terdaftar di Samsat"},
+		{"no expiry", registry.KIR, registry.Query{RegistrationNumber: "B 9 XYZ"}, now,
+			"warning", "Kendaraan terdaftar di KIR, tetapi masa berlaku tidak tersedia"},
+	}
+	for _, tt := range tests {
+		result := performRegistryCheck(context.Background(), provider, tt.kind, tt.query, tt.now)
+		if result["status"] != tt.status || result["message"] != tt.message {
+			t.Errorf("%s: got %v %q", tt.name, result["status"], result["message"])
+		}
+	}
+}
+
+func TestPerformRegistryCheckUnavailable(t *testing.T) {
+	q := registry.Query{RegistrationNumber: "B 1234 XYZ"}
+
+	result := performRegistryCheck(context.Background(), nil, registry.Samsat, q, time.Now())
+	if result["status"] != CrossCheckUnavailable || result["confidence"] != 0.0 {
+		t.Errorf("Expected an unconfigured registry to be unavailable, got %+v", result)
+	}
+
+	stub := registry.NewStub()
+	stub.FailNext(1)
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+	result = performRegistryCheck(context.Background(), registry.NewHTTP(srv.URL, "", time.Second), registry.Insurance, q, time.Now())
+	if result["status"] != CrossCheckUnavailable || result["message"] != "Layanan asuransi tidak dapat dihubungi, periksa manual" {
+		t.Errorf("Expected a failing registry to be unavailable, got %+v", result)
+	}
+
+	// A request that was cancelled does not wait for the registry
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = performRegistryCheck(ctx, registry.NewHTTP(srv.URL, "", time.Minute), registry.Insurance, q, time.Now())
+	if result["status"] != CrossCheckUnavailable || stub.Calls() != 1 {
+		t.Errorf("Expected a cancelled lookup not to reach the registry, got %+v after %d calls", result, stub.Calls())
+	}
+}
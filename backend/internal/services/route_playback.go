@@ -0,0 +1,169 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/geo"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+const (
+	// stopRadiusMeters is how far GPS drift may wander while parked
+	stopRadiusMeters = 100
+	// jitterMeters is movement too small to count when speed reads zero
+	jitterMeters = 25
+	// A longer silence is a signal gap unless the vehicle stayed put
+	maxPlaybackGap = 10 * time.Minute
+
+	DefaultMinStopDuration = 5 * time.Minute
+	maxPlaybackSpan        = 31 * 24 * time.Hour
+	maxPlaybackPoints      = 100000
+)
+
+func historyPoint(p models.GPSHistoryPoint) geo.Point {
+	return geo.Point{Lat: p.Latitude, Lng: p.Longitude}
+}
+
+// segmentMoving decides whether the vehicle was driving between a and b.
+// Reported speed is used when present; trackers that always send zero are
+// judged by the distance covered.
+func segmentMoving(a, b models.GPSHistoryPoint, meters, seconds float64) bool {
+	if (a.Speed+b.Speed)/2 >= movingSpeedKmh {
+		return true
+	}
+	return meters >= jitterMeters && meters/seconds*3.6 >= movingSpeedKmh
+}
+
+// detectStops groups consecutive slow points that stay within
+// stopRadiusMeters of where they began and keeps the groups lasting at
+// least minStop. A silent gap inside a group, such as an engine-off night,
+// still counts as dwell.
+func detectStops(points []models.GPSHistoryPoint, minStop time.Duration) []models.RouteStop {
+	stops := []models.RouteStop{}
+	for i := 0; i < len(points); {
+		if points[i].Speed >= movingSpeedKmh {
+			i++
+			continue
+		}
+
+		anchor := historyPoint(points[i])
+		j := i + 1
+		for j < len(points) && points[j].Speed < movingSpeedKmh && geo.Distance(anchor, historyPoint(points[j])) <= stopRadiusMeters {
+			j++
+		}
+
+		arrived, departed := points[i].Timestamp, points[j-1].Timestamp
+		if dwell := departed.Sub(arrived); dwell >= minStop {
+			var lat, lng float64
+			for _, p := range points[i:j] {
+				lat += p.Latitude
+				lng += p.Longitude
+			}
+			n := float64(j - i)
+			stops = append(stops, models.RouteStop{
+				Latitude:     lat / n,
+				Longitude:    lng / n,
+				ArrivedAt:    arrived,
+				DepartedAt:   departed,
+				DwellSeconds: int64(dwell.Seconds()),
+			})
+		}
+		i = j
+	}
+	return stops
+}
+
+// analyzeRoute fills in the route, distance, durations, speeds and stops
+// of a playback from points in time order
+func analyzeRoute(playback *models.RoutePlayback, points []models.GPSHistoryPoint, minStop time.Duration) {
+	playback.PointCount = len(points)
+	playback.TimeOffsets = make([]int64, len(points))
+	playback.Speeds = make([]float64, len(points))
+	playback.Stops = []models.RouteStop{}
+	if len(points) == 0 {
+		return
+	}
+
+	started, ended := points[0].Timestamp, points[len(points)-1].Timestamp
+	playback.StartedAt, playback.EndedAt = &started, &ended
+
+	route := make([]geo.Point, len(points))
+	for i, p := range points {
+		route[i] = historyPoint(p)
+		playback.TimeOffsets[i] = int64(p.Timestamp.Sub(started).Seconds())
+		playback.Speeds[i] = p.Speed
+		playback.MaxSpeedKmh = math.Max(playback.MaxSpeedKmh, math.Max(p.Speed, p.MaxSpeed))
+	}
+	playback.Polyline = geo.EncodePolyline(route)
+
+	var driving, stopped, noSignal time.Duration
+	for i := 1; i < len(points); i++ {
+		dt := points[i].Timestamp.Sub(points[i-1].Timestamp)
+		if dt <= 0 {
+			continue
+		}
+		meters := geo.Distance(route[i-1], route[i])
+
+		switch {
+		case dt > maxPlaybackGap && meters > stopRadiusMeters:
+			// Moved while silent: the distance happened, the time is unknown
+			noSignal += dt
+			playback.DistanceMeters += meters
+		case dt > maxPlaybackGap || !segmentMoving(points[i-1], points[i], meters, dt.Seconds()):
+			stopped += dt
+		default:
+			driving += dt
+			playback.DistanceMeters += meters
+		}
+	}
+
+	playback.DrivingSeconds = int64(driving.Seconds())
+	playback.StoppedSeconds = int64(stopped.Seconds())
+	playback.NoSignalSeconds = int64(noSignal.Seconds())
+	if driving > 0 {
+		playback.AvgSpeedKmh = math.Round(playback.DistanceMeters/driving.Seconds()*3.6*10) / 10
+	}
+	playback.DistanceMeters = math.Round(playback.DistanceMeters)
+	playback.Stops = detectStops(points, minStop)
+}
+
+// GetRoutePlayback loads a device's points for [from, to) in time order
+// and analyses them. Ranges that auto resolution would not serve raw are
+// played back from minute summaries.
+func (s *GPSHistoryService) GetRoutePlayback(scope models.TenantScope, deviceID string, from, to time.Time, resolution string, minStop time.Duration) (*models.RoutePlayback, error) {
+	from, to = from.UTC(), to.UTC()
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidHistoryRange)
+	}
+	if to.Sub(from) > maxPlaybackSpan {
+		return nil, fmt.Errorf("%w: playback range is longer than 31 days", ErrInvalidHistoryRange)
+	}
+
+	resolution, err := chooseHistoryResolution(s.cfg, resolution, from, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDeviceScope(scope, deviceID); err != nil {
+		return nil, err
+	}
+
+	var points []models.GPSHistoryPoint
+	if resolution == HistoryResolutionRaw {
+		points, err = s.repo.GetRawHistory(deviceID, from, to, maxPlaybackPoints+1, false)
+	} else {
+		points, err = s.repo.GetMinuteHistory(deviceID, from, to, maxPlaybackPoints+1, false)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracking history: %v", err)
+	}
+
+	playback := &models.RoutePlayback{DeviceID: deviceID, From: from, To: to, Resolution: resolution}
+	if len(points) > maxPlaybackPoints {
+		points = points[:maxPlaybackPoints]
+		playback.Truncated = true
+	}
+	analyzeRoute(playback, points, minStop)
+	return playback, nil
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/geo"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// routeBuilder appends points 30 seconds apart, moving east along -6.2
+type routeBuilder struct {
+	points []models.GPSHistoryPoint
+	t      time.Time
+	lng    float64
+}
+
+// metersPerDegreeLng is the length of one degree of longitude at -6.2
+var metersPerDegreeLng = geo.Distance(geo.Point{Lat: -6.2, Lng: 106.8}, geo.Point{Lat: -6.2, Lng: 106.81}) * 100
+
+func (b *routeBuilder) add(speed float64) {
+	b.points = append(b.points, models.GPSHistoryPoint{
+		DeviceID: "GPS-01", Latitude: -6.2, Longitude: b.lng, Speed: speed, MaxSpeed: speed, PointCount: 1, Timestamp: b.t,
+	})
+}
+
+// park reports n stationary points
+func (b *routeBuilder) park(n int) {
+	for i := 0; i < n; i++ {
+		b.add(0)
+		b.t = b.t.Add(30 * time.Second)
+	}
+}
+
+// drive reports n points at speed km/h
+func (b *routeBuilder) drive(n int, speed float64) {
+	for i := 0; i < n; i++ {
+		b.add(speed)
+		b.t = b.t.Add(30 * time.Second)
+		b.lng += speed / 3.6 * 30 / metersPerDegreeLng
+	}
+}
+
+func TestAnalyzeRoute(t *testing.T) {
+	b := &routeBuilder{t: time.Date(2025, 1, 10, 1, 0, 0, 0, time.UTC), lng: 106.8}
+	b.park(21)      // 10 minutes at the depot
+	b.drive(20, 60) // 10 minutes at 60 km/h: 10 km
+	b.park(13)      // 6 minutes at a customer
+	b.drive(2, 30)  // pulls out and stops for 30 seconds: too short to be a stop
+	b.park(2)
+
+	var playback models.RoutePlayback
+	analyzeRoute(&playback, b.points, DefaultMinStopDuration)
+
+	if playback.PointCount != len(b.points) || len(playback.TimeOffsets) != len(b.points) {
+		t.Fatalf("Expected %d points, got %d", len(b.points), playback.PointCount)
+	}
+	if math.Abs(playback.DistanceMeters-10500) > 100 {
+		t.Errorf("Expected about 10.5 km, got %.0f m", playback.DistanceMeters)
+	}
+	if playback.MaxSpeedKmh != 60 {
+		t.Errorf("Expected max speed 60, got %.1f", playback.MaxSpeedKmh)
+	}
+	if playback.DrivingSeconds < 600 || playback.DrivingSeconds > 720 {
+		t.Errorf("Expected about 11 minutes driving, got %d s", playback.DrivingSeconds)
+	}
+	if playback.NoSignalSeconds != 0 {
+		t.Errorf("Expected no signal gaps, got %d s", playback.NoSignalSeconds)
+	}
+	if playback.AvgSpeedKmh < 45 || playback.AvgSpeedKmh > 60 {
+		t.Errorf("Expected moving average between 45 and 60 km/h, got %.1f", playback.AvgSpeedKmh)
+	}
+
+	if len(playback.Stops) != 2 {
+		t.Fatalf("Expected 2 stops, got %d: %+v", len(playback.Stops), playback.Stops)
+	}
+	if playback.Stops[0].DwellSeconds != 600 || playback.Stops[1].DwellSeconds != 360 {
+		t.Errorf("Unexpected dwell times: %d and %d", playback.Stops[0].DwellSeconds, playback.Stops[1].DwellSeconds)
+	}
+
+	route, err := geo.DecodePolyline(playback.Polyline)
+	if err != nil || len(route) != len(b.points) {
+		t.Fatalf("Expected polyline with %d points, got %d (%v)", len(b.points), len(route), err)
+	}
+}
+
+func TestAnalyzeRouteSignalGaps(t *testing.T) {
+	b := &routeBuilder{t: time.Date(2025, 1, 10, 1, 0, 0, 0, time.UTC), lng: 106.8}
+	b.park(2)
+	// Engine off overnight in the same place: a stop, not a signal gap
+	b.t = b.t.Add(8 * time.Hour)
+	b.park(2)
+	b.drive(2, 50)
+	// Tracker silent while the truck covers 20 km
+	b.t = b.t.Add(30 * time.Minute)
+	b.lng += 20000 / metersPerDegreeLng
+	b.drive(2, 50)
+
+	var playback models.RoutePlayback
+	analyzeRoute(&playback, b.points, DefaultMinStopDuration)
+
+	if playback.NoSignalSeconds < 30*60 {
+		t.Errorf("Expected the 30 minute gap as no signal, got %d s", playback.NoSignalSeconds)
+	}
+	if playback.StoppedSeconds < 8*3600 {
+		t.Errorf("Expected the overnight stop as stopped time, got %d s", playback.StoppedSeconds)
+	}
+	if len(playback.Stops) != 1 || playback.Stops[0].DwellSeconds < 8*3600 {
+		t.Errorf("Expected one overnight stop, got %+v", playback.Stops)
+	}
+	if playback.DistanceMeters < 20000 {
+		t.Errorf("Expected the gap distance to count, got %.0f m", playback.DistanceMeters)
+	}
+}
+
+func TestAnalyzeRouteWithoutReportedSpeed(t *testing.T) {
+	// Trackers that always send speed 0 are judged by distance covered
+	b := &routeBuilder{t: time.Date(2025, 1, 10, 1, 0, 0, 0, time.UTC), lng: 106.8}
+	for i := 0; i < 10; i++ {
+		b.add(0)
+		b.t = b.t.Add(30 * time.Second)
+		b.lng += 400 / metersPerDegreeLng
+	}
+
+	var playback models.RoutePlayback
+	analyzeRoute(&playback, b.points, DefaultMinStopDuration)
+
+	if playback.DrivingSeconds != 270 || math.Abs(playback.DistanceMeters-3600) > 50 {
+		t.Errorf("Expected 270 s driving over 3.6 km, got %d s and %.0f m", playback.DrivingSeconds, playback.DistanceMeters)
+	}
+	if len(playback.Stops) != 0 {
+		t.Errorf("Expected no stops, got %+v", playback.Stops)
+	}
+}
+
+func TestAnalyzeRouteEmpty(t *testing.T) {
+	var playback models.RoutePlayback
+	analyzeRoute(&playback, nil, DefaultMinStopDuration)
+
+	if playback.PointCount != 0 || playback.StartedAt != nil || playback.Stops == nil {
+		t.Errorf("Unexpected empty playback: %+v", playback)
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+// Reasons recorded with revoked tokens
+const (
+	RevokeReasonLogout    = "logout"
+	RevokeReasonLogoutAll = "logout_all"
+	RevokeReasonAdmin     = "admin"
+	RevokeReasonReuse     = "refresh_reuse"
+	RevokeReasonRole      = "role_change"
+	RevokeReasonPassword  = "password_reset"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused means a retired refresh token was presented
+	// again, so it was probably stolen; the whole session is revoked
+	ErrRefreshTokenReused = errors.New("refresh token reused; session revoked")
+)
+
+type SessionService struct {
+	repo *repository.SessionRepository
+	cfg  *config.SessionConfig
+}
+
+func NewSessionService(db *sql.DB, cfg *config.SessionConfig) *SessionService {
+	return &SessionService{repo: repository.NewSessionRepository(db), cfg: cfg}
+}
+
+// issue creates an access token and the refresh token that renews it
+func (s *SessionService) issue(user *models.User, sessionID, userAgent, ip string, now time.Time) (*models.TokenPair, *models.RefreshToken, error) {
+	access, claims, err := auth.GenerateAccessToken(user.ID, user.Username, user.Role, sessionID, s.cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate access token: %v", err)
+	}
+	refresh, err := auth.RandomToken(32)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate refresh token: %v", err)
+	}
+
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	token := &models.RefreshToken{
+		UserID:          user.ID,
+		SessionID:       sessionID,
+		TokenHash:       auth.HashToken(refresh),
+		AccessJTI:       claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time.UTC(),
+		ExpiresAt:       now.Add(s.cfg.RefreshTokenTTL),
+		UserAgent:       userAgent,
+		IPAddress:       ip,
+	}
+	pair := &models.TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		ExpiresIn:        int(s.cfg.AccessTokenTTL.Seconds()),
+		RefreshExpiresAt: token.ExpiresAt,
+		SessionID:        sessionID,
+	}
+	return pair, token, nil
+}
+
+// StartSession issues the first token pair of a new login
+func (s *SessionService) StartSession(user *models.User, userAgent, ip string) (*models.TokenPair, error) {
+	sessionID, err := auth.RandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %v", err)
+	}
+
+	pair, token, err := s.issue(user, sessionID, userAgent, ip, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateRefreshToken(token); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %v", err)
+	}
+	return pair, nil
+}
+
+// Refresh exchanges a refresh token for a new pair. The presented token is
+// retired; presenting it again revokes the session. The new access token
+// carries the user's current role.
+func (s *SessionService) Refresh(refreshToken, userAgent, ip string) (*models.TokenPair, *models.User, error) {
+	now := time.Now().UTC()
+	old, err := s.repo.GetRefreshToken(auth.HashToken(refreshToken))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get refresh token: %v", err)
+	}
+	if old == nil || !old.ExpiresAt.After(now) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if old.RevokedAt != nil {
+		if old.ReplacedBy == nil {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		if _, err := s.repo.RevokeSession(old.SessionID, RevokeReasonReuse, now); err != nil {
+			return nil, nil, fmt.Errorf("failed to revoke session: %v", err)
+		}
+		log.Printf("Refresh token reuse - UserID: %d, Session: %s; session revoked", old.UserID, old.SessionID)
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.repo.GetTokenUser(old.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %v", err)
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	pair, next, err := s.issue(user, old.SessionID, userAgent, ip, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The session keeps its original end; refreshing does not extend it
+	if old.ExpiresAt.Before(next.ExpiresAt) {
+		next.ExpiresAt = old.ExpiresAt
+		pair.RefreshExpiresAt = old.ExpiresAt
+	}
+
+	replaced, err := s.repo.ReplaceRefreshToken(old.ID, next, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to rotate refresh token: %v", err)
+	}
+	if !replaced {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	return pair, user, nil
+}
+
+// Logout ends the session of the presented access token and revokes the
+// token itself, which also covers tokens issued outside a session
+func (s *SessionService) Logout(claims *auth.Claims) error {
+	now := time.Now().UTC()
+	if claims.SessionID != "" {
+		if _, err := s.repo.RevokeSession(claims.SessionID, RevokeReasonLogout, now); err != nil {
+			return fmt.Errorf("failed to revoke session: %v", err)
+		}
+	}
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := s.repo.RevokeAccessToken(claims.ID, claims.UserID, RevokeReasonLogout, claims.ExpiresAt.Time.UTC()); err != nil {
+			return fmt.Errorf("failed to revoke token: %v", err)
+		}
+	}
+	return nil
+}
+
+// RevokeUserSessions ends every session of a user immediately and returns
+// how many were active
+func (s *SessionService) RevokeUserSessions(userID int, reason string) (int, error) {
+	n, err := s.repo.RevokeUserSessions(userID, reason, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %v", err)
+	}
+	return n, nil
+}
+
+func (s *SessionService) GetActiveSessions(userID int) ([]models.Session, error) {
+	sessions, err := s.repo.GetActiveSessions(userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %v", err)
+	}
+	return sessions, nil
+}
+
+// IsTokenRevoked reports whether an access token's jti was revoked
+func (s *SessionService) IsTokenRevoked(jti string) (bool, error) {
+	return s.repo.IsTokenRevoked(jti)
+}
+
+// StartCleanup purges expired tokens every CleanupInterval until stop is
+// closed
+func (s *SessionService) StartCleanup(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.cfg.CleanupInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				n, err := s.repo.DeleteExpired(time.Now().UTC())
+				if err != nil {
+					log.Printf("Session cleanup error: %v", err)
+				} else if n > 0 {
+					log.Printf("Session cleanup removed %d expired token(s)", n)
+				}
+			}
+		}
+	}()
+}
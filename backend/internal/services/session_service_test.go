@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+func TestAccessTokenClaims(t *testing.T) {
+	token, claims, err := auth.GenerateAccessToken(1, "testuser", auth.RoleFleetOwner, "session-1", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	parsed, err := auth.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Expected token to validate, got %v", err)
+	}
+	if parsed.ID == "" || parsed.ID != claims.ID {
+		t.Errorf("Expected token to carry its jti, got %q", parsed.ID)
+	}
+	if parsed.SessionID != "session-1" {
+		t.Errorf("Expected session ID session-1, got %q", parsed.SessionID)
+	}
+	if ttl := time.Until(parsed.ExpiresAt.Time); ttl > 5*time.Minute || ttl < 4*time.Minute {
+		t.Errorf("Expected token to expire in about 5 minutes, got %v", ttl)
+	}
+
+	other, otherClaims, _ := auth.GenerateAccessToken(1, "testuser", auth.RoleFleetOwner, "session-1", 5*time.Minute)
+	if other == token || otherClaims.ID == claims.ID {
+		t.Error("Expected every access token to get its own jti")
+	}
+}
+
+func TestRefreshTokenHashing(t *testing.T) {
+	a, err := auth.RandomToken(32)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	b, _ := auth.RandomToken(32)
+	if len(a) != 64 || a == b {
+		t.Errorf("Expected distinct 64 character tokens, got %q and %q", a, b)
+	}
+
+	if auth.HashToken(a) != auth.HashToken(a) {
+		t.Error("Expected hashing to be deterministic")
+	}
+	if auth.HashToken(a) == auth.HashToken(b) || auth.HashToken(a) == a {
+		t.Error("Expected distinct hashes that differ from the token")
+	}
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	conn := tenantTestDB(t)
+	defer conn.Close()
+
+	name := fmt.Sprintf("session_%d", time.Now().UnixNano())
+	user := &models.User{Username: name, Email: name + "@example.com", FullName: name, Role: auth.RoleFleetOwner}
+	err := conn.QueryRow(`INSERT INTO users (username, email, password_hash, full_name, role)
+		VALUES ($1, $2, 'x', $3, $4) RETURNING id`, user.Username, user.Email, user.FullName, user.Role).Scan(&user.ID)
+	if err != nil {
+		t.Fatalf("Failed to seed user: %v", err)
+	}
+	defer conn.Exec("DELETE FROM users WHERE id = $1", user.ID)
+
+	sessions := NewSessionService(conn, &config.SessionConfig{AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, CleanupInterval: time.Hour})
+	first, err := sessions.StartSession(user, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, _, err := sessions.Refresh(first.RefreshToken, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Expected refresh to succeed, got %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.SessionID != first.SessionID {
+		t.Error("Expected a new refresh token in the same session")
+	}
+
+	// Presenting the retired token again revokes the whole session
+	if _, _, err := sessions.Refresh(first.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Expected ErrRefreshTokenReused, got %v", err)
+	}
+	if _, _, err := sessions.Refresh(second.RefreshToken, "test", "127.0.0.1"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Expected the session's current token to be revoked, got %v", err)
+	}
+
+	claims, err := auth.ValidateToken(second.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := sessions.IsTokenRevoked(claims.ID); err != nil || !revoked {
+		t.Errorf("Expected the session's access token to be revoked, got %v, %v", revoked, err)
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+var (
+	ErrTenantAccessDenied = errors.New("access to this fleet owner is denied")
+	ErrNoFleetOwner       = errors.New("fleet owner profile required")
+)
+
+// IsStaffRole reports whether a role works across every fleet owner
+func IsStaffRole(role string) bool {
+	return auth.HasPermission(role, auth.PermTenantsAll)
+}
+
+// ResolveTenantScope returns the rows a user may read. Staff see every
+// fleet owner, or only requestedFleetOwnerID when they pick one. Everyone
+// else is limited to their own fleet owner profile.
+func ResolveTenantScope(db *sql.DB, userID int, role string, requestedFleetOwnerID int) (models.TenantScope, error) {
+	return resolveTenantScope(role, requestedFleetOwnerID, func() (int, error) {
+		fleetOwner, err := GetFleetOwnerByUserID(db, userID)
+		if err != nil {
+			return 0, err
+		}
+		return fleetOwner.ID, nil
+	})
+}
+
+func resolveTenantScope(role string, requestedFleetOwnerID int, ownFleetOwner func() (int, error)) (models.TenantScope, error) {
+	if IsStaffRole(role) {
+		if requestedFleetOwnerID > 0 {
+			return models.FleetOwnerScope(requestedFleetOwnerID), nil
+		}
+		return models.AllTenants(), nil
+	}
+
+	fleetOwnerID, err := ownFleetOwner()
+	if err != nil || fleetOwnerID <= 0 {
+		return models.TenantScope{}, ErrNoFleetOwner
+	}
+	if requestedFleetOwnerID > 0 && requestedFleetOwnerID != fleetOwnerID {
+		return models.TenantScope{}, fmt.Errorf("%w: %d", ErrTenantAccessDenied, requestedFleetOwnerID)
+	}
+	return models.FleetOwnerScope(fleetOwnerID), nil
+}
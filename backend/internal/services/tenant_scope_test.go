@@ -0,0 +1,211 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/db"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+func TestResolveTenantScope(t *testing.T) {
+	own := func() (int, error) { return 7, nil }
+	none := func() (int, error) { return 0, sql.ErrNoRows }
+
+	scope, err := resolveTenantScope(auth.RoleSuperAdmin, 0, none)
+	if err != nil || !scope.All {
+		t.Errorf("Expected super admin to see every tenant, got %+v, %v", scope, err)
+	}
+
+	scope, err = resolveTenantScope(auth.RoleDispatcher, 3, none)
+	if err != nil || scope.All || scope.FleetOwnerID != 3 {
+		t.Errorf("Expected dispatcher narrowed to tenant 3, got %+v, %v", scope, err)
+	}
+
+	scope, err = resolveTenantScope("fleet_owner", 0, own)
+	if err != nil || scope.All || scope.FleetOwnerID != 7 {
+		t.Errorf("Expected fleet owner limited to tenant 7, got %+v, %v", scope, err)
+	}
+
+	if _, err := resolveTenantScope("fleet_owner", 7, own); err != nil {
+		t.Errorf("Expected fleet owner to name its own tenant, got %v", err)
+	}
+
+	if _, err := resolveTenantScope("fleet_owner", 8, own); !errors.Is(err, ErrTenantAccessDenied) {
+		t.Errorf("Expected ErrTenantAccessDenied for another tenant, got %v", err)
+	}
+
+	if _, err := resolveTenantScope(auth.RoleDriver, 0, none); !errors.Is(err, ErrNoFleetOwner) {
+		t.Errorf("Expected ErrNoFleetOwner without a profile, got %v", err)
+	}
+}
+
+func TestTenantScopeFailsClosed(t *testing.T) {
+	var unresolved models.TenantScope
+	owner := 7
+	other := 8
+
+	if unresolved.FilterID() != -1 {
+		t.Errorf("Expected zero scope to match nothing, got filter %d", unresolved.FilterID())
+	}
+	if unresolved.Allows(&owner) || unresolved.Allows(nil) {
+		t.Error("Expected zero scope to allow nothing")
+	}
+
+	scope := models.FleetOwnerScope(owner)
+	if scope.FilterID() != owner || !scope.Allows(&owner) {
+		t.Errorf("Expected tenant scope to allow its own rows, got %+v", scope)
+	}
+	if scope.Allows(&other) || scope.Allows(nil) {
+		t.Error("Expected tenant scope to hide other and unowned rows")
+	}
+
+	all := models.AllTenants()
+	if all.FilterID() != 0 || !all.Allows(nil) || !all.Allows(&other) {
+		t.Errorf("Expected staff scope to allow every row, got %+v", all)
+	}
+}
+
+// tenantTestDB connects to TEST_DATABASE_URL and applies migrations, or
+// skips the test when it is not set
+func tenantTestDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("Skipping database test - TEST_DATABASE_URL not set")
+	}
+
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if _, err := db.MigrateUp(conn); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return conn
+}
+
+type seededTenant struct {
+	fleetOwnerID int
+	vehicleID    int
+	driverID     int
+	tripID       int
+}
+
+func seedTenant(t *testing.T, conn *sql.DB, name string) seededTenant {
+	t.Helper()
+	var s seededTenant
+	var userID int
+
+	seed := func(what, query string, dest *int, args ...interface{}) {
+		if err := conn.QueryRow(query, args...).Scan(dest); err != nil {
+			t.Fatalf("Failed to seed %s for %s: %v", what, name, err)
+		}
+	}
+	seed("user", `INSERT INTO users (username, email, password_hash, full_name, user_type)
+		VALUES ($1, $1 || '@example.com', 'x', $1, 'fleet_owner') RETURNING id`, &userID, name)
+	seed("fleet owner", `INSERT INTO fleet_owners (user_id, company_name) VALUES ($1, $2) RETURNING id`,
+		&s.fleetOwnerID, userID, name)
+	seed("vehicle", `INSERT INTO vehicles (registration_number, vehicle_type, brand, model, year, chassis_number,
+		engine_number, color, ownership_status, fleet_owner_id)
+		VALUES ($1, 'truck', 'Hino', 'Dutro', 2022, $1, $1, 'white', 'owned', $2) RETURNING id`,
+		&s.vehicleID, name, s.fleetOwnerID)
+	seed("driver", `INSERT INTO drivers (user_id, license_number, license_expiry, fleet_owner_id)
+		VALUES ($1, $2, CURRENT_DATE + 365, $3) RETURNING id`, &s.driverID, userID, name, s.fleetOwnerID)
+	seed("trip", `INSERT INTO trips (vehicle_id, driver_id, origin, destination, fleet_owner_id)
+		VALUES ($1, $2, 'Jakarta', 'Bandung', $3) RETURNING id`, &s.tripID, s.vehicleID, s.driverID, s.fleetOwnerID)
+
+	t.Cleanup(func() {
+		conn.Exec("DELETE FROM trips WHERE id = $1", s.tripID)
+		conn.Exec("DELETE FROM drivers WHERE id = $1", s.driverID)
+		conn.Exec("DELETE FROM vehicles WHERE id = $1", s.vehicleID)
+		conn.Exec("DELETE FROM users WHERE id = $1", userID)
+	})
+	return s
+}
+
+func TestTenantIsolation(t *testing.T) {
+	conn := tenantTestDB(t)
+	defer conn.Close()
+
+	suffix := time.Now().UnixNano()
+	a := seedTenant(t, conn, fmt.Sprintf("tenant_a_%d", suffix))
+	b := seedTenant(t, conn, fmt.Sprintf("tenant_b_%d", suffix))
+	scope := models.FleetOwnerScope(a.fleetOwnerID)
+
+	vehicles, err := GetVehicles(conn, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vehicles) != 1 || vehicles[0].ID != a.vehicleID {
+		t.Errorf("Expected only tenant A's vehicle, got %d vehicles", len(vehicles))
+	}
+	if _, err := GetVehicleByID(conn, scope, b.vehicleID); err == nil {
+		t.Error("Expected tenant B's vehicle to be hidden")
+	}
+
+	drivers, err := GetDrivers(conn, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drivers) != 1 || drivers[0].ID != a.driverID {
+		t.Errorf("Expected only tenant A's driver, got %d drivers", len(drivers))
+	}
+	if _, err := GetDriverByID(conn, scope, b.driverID); err == nil {
+		t.Error("Expected tenant B's driver to be hidden")
+	}
+
+	trips, err := GetTrips(conn, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trips) != 1 || trips[0].ID != a.tripID {
+		t.Errorf("Expected only tenant A's trip, got %d trips", len(trips))
+	}
+	if err := CheckTripInScope(conn, scope, b.tripID); !errors.Is(err, ErrTripNotFound) {
+		t.Errorf("Expected tenant B's trip to be not found, got %v", err)
+	}
+
+	stats, err := GetDashboardStats(conn, scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalVehicles != 1 || stats.TotalDrivers != 1 || stats.TotalTrips != 1 {
+		t.Errorf("Expected dashboard to count tenant A only, got %+v", stats)
+	}
+
+	_, err = CreateTrip(conn, scope, models.TripRequest{VehicleID: &b.vehicleID, Origin: "Jakarta", Destination: "Surabaya"}, 0, "admin")
+	if !errors.Is(err, ErrTenantAccessDenied) {
+		t.Errorf("Expected trip on tenant B's vehicle to be denied, got %v", err)
+	}
+
+	// A trip is only assigned its own tenant's driver and vehicle
+	assign := func(driverID, vehicleID int) error {
+		_, err := TransitionTrip(conn, a.tripID, models.TripTransitionRequest{
+			Status: TripStatusAssigned, DriverID: &driverID, VehicleID: &vehicleID}, 0, "dispatcher")
+		return err
+	}
+	if err := assign(a.driverID, b.vehicleID); !errors.Is(err, ErrTenantAccessDenied) {
+		t.Errorf("Expected tenant B's vehicle on tenant A's trip to be denied, got %v", err)
+	}
+	if err := assign(b.driverID, a.vehicleID); !errors.Is(err, ErrTenantAccessDenied) {
+		t.Errorf("Expected tenant B's driver on tenant A's trip to be denied, got %v", err)
+	}
+	if err := assign(a.driverID, a.vehicleID); err != nil {
+		t.Errorf("Expected tenant A's driver and vehicle to be assigned, got %v", err)
+	}
+
+	// An unresolved scope sees nothing at all
+	vehicles, err = GetVehicles(conn, models.TenantScope{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vehicles) != 0 {
+		t.Errorf("Expected zero scope to return no vehicles, got %d", len(vehicles))
+	}
+}
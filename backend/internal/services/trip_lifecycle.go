@@ -0,0 +1,267 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// Trip lifecycle statuses
+const (
+	TripStatusPlanned       = "planned"
+	TripStatusAssigned      = "assigned"
+	TripStatusEnRoutePickup = "en_route_pickup"
+	TripStatusLoading       = "loading"
+	TripStatusInTransit     = "in_transit"
+	TripStatusUnloading     = "unloading"
+	TripStatusDelivered     = "delivered"
+	TripStatusCancelled     = "cancelled"
+	TripStatusFailed        = "failed"
+)
+
+var (
+	ErrTripNotFound          = errors.New("trip not found")
+	ErrInvalidTripStatus     = errors.New("invalid trip status")
+	ErrInvalidTripTransition = errors.New("invalid status transition")
+)
+
+// tripTransitions lists the statuses reachable from each status. Multi-drop
+// trips go back from unloading to in_transit between drop-offs.
+var tripTransitions = map[string][]string{
+	TripStatusPlanned:       {TripStatusAssigned, TripStatusCancelled},
+	TripStatusAssigned:      {TripStatusEnRoutePickup, TripStatusPlanned, TripStatusCancelled},
+	TripStatusEnRoutePickup: {TripStatusLoading, TripStatusCancelled, TripStatusFailed},
+	TripStatusLoading:       {TripStatusInTransit, TripStatusCancelled, TripStatusFailed},
+	TripStatusInTransit:     {TripStatusUnloading, TripStatusFailed},
+	TripStatusUnloading:     {TripStatusDelivered, TripStatusInTransit, TripStatusFailed},
+	TripStatusDelivered:     {},
+	TripStatusCancelled:     {},
+	TripStatusFailed:        {},
+}
+
+// driverTripStatuses are the statuses a driver may move their own trip into;
+// assignment and cancellation stay with dispatch
+var driverTripStatuses = map[string]bool{
+	TripStatusEnRoutePickup: true,
+	TripStatusLoading:       true,
+	TripStatusInTransit:     true,
+	TripStatusUnloading:     true,
+	TripStatusDelivered:     true,
+	TripStatusFailed:        true,
+}
+
+// legacyTripStatuses maps statuses sent by older driver app builds
+var legacyTripStatuses = map[string]string{
+	"started":   TripStatusEnRoutePickup,
+	"completed": TripStatusDelivered,
+}
+
+// IsValidTripStatus reports whether status is part of the trip lifecycle
+func IsValidTripStatus(status string) bool {
+	_, ok := tripTransitions[status]
+	return ok
+}
+
+// IsTerminalTripStatus reports whether no further transitions are possible
+func IsTerminalTripStatus(status string) bool {
+	next, ok := tripTransitions[status]
+	return ok && len(next) == 0
+}
+
+// ActiveTripStatuses are the statuses of a trip that is under way
+func ActiveTripStatuses() []string {
+	return []string{TripStatusEnRoutePickup, TripStatusLoading, TripStatusInTransit, TripStatusUnloading}
+}
+
+// ValidateTripTransition checks a move between two statuses against the state
+// machine. byDriver restricts the target to driver-owned statuses.
+func ValidateTripTransition(from, to string, byDriver bool) error {
+	if !IsValidTripStatus(to) {
+		return fmt.Errorf("%w: %s", ErrInvalidTripStatus, to)
+	}
+	if byDriver && !driverTripStatuses[to] {
+		return fmt.Errorf("%w: drivers cannot set status %s", ErrInvalidTripTransition, to)
+	}
+
+	for _, next := range tripTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidTripTransition, from, to)
+}
+
+// TransitionTrip moves a trip to a new status on behalf of a dispatcher. When
+// assigning, the driver and vehicle may be supplied with the request.
+func TransitionTrip(db *sql.DB, tripID int, req models.TripTransitionRequest, actorID int, actorRole string) (*models.Trip, error) {
+	err := transitionTrip(db, tripID, req, nil, actorID, actorRole)
+	if err != nil {
+		return nil, err
+	}
+	return GetTripByID(db, tripID)
+}
+
+// UpdateTripStatus moves a trip assigned to driverID to a new status on behalf
+// of the driver
+func UpdateTripStatus(db *sql.DB, tripID int, driverID int, actorID int, status string, notes string) error {
+	if mapped, ok := legacyTripStatuses[status]; ok {
+		status = mapped
+	}
+
+	req := models.TripTransitionRequest{Status: status, Notes: notes}
+	return transitionTrip(db, tripID, req, &driverID, actorID, "driver")
+}
+
+// SystemTransitionTrip moves a trip on behalf of the platform itself, e.g.
+// from geofence events; the history records no user
+func SystemTransitionTrip(db *sql.DB, tripID int, status string, notes string) error {
+	req := models.TripTransitionRequest{Status: status, Notes: notes}
+	return transitionTrip(db, tripID, req, nil, 0, "system")
+}
+
+func transitionTrip(db *sql.DB, tripID int, req models.TripTransitionRequest, driverID *int, actorID int, actorRole string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	var currentDriver, currentVehicle, fleetOwner sql.NullInt64
+	err = tx.QueryRow(`SELECT status, driver_id, vehicle_id, fleet_owner_id FROM trips WHERE id = $1 FOR UPDATE`, tripID).
+		Scan(&current, &currentDriver, &currentVehicle, &fleetOwner)
+	if err == sql.ErrNoRows {
+		return ErrTripNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get trip: %v", err)
+	}
+
+	// Drivers only see their own trips
+	if driverID != nil && (!currentDriver.Valid || int(currentDriver.Int64) != *driverID) {
+		return ErrTripNotFound
+	}
+
+	if err := ValidateTripTransition(current, req.Status, driverID != nil); err != nil {
+		return err
+	}
+
+	switch req.Status {
+	case TripStatusAssigned:
+		newDriver, newVehicle := req.DriverID, req.VehicleID
+		if newDriver == nil && currentDriver.Valid {
+			id := int(currentDriver.Int64)
+			newDriver = &id
+		}
+		if newVehicle == nil && currentVehicle.Valid {
+			id := int(currentVehicle.Int64)
+			newVehicle = &id
+		}
+		if newDriver == nil || newVehicle == nil {
+			return fmt.Errorf("%w: driver and vehicle are required to assign a trip", ErrInvalidTripTransition)
+		}
+		if req.DriverID != nil {
+			if err := checkAssignmentTenant(tx, "drivers", "driver", *req.DriverID, fleetOwner); err != nil {
+				return err
+			}
+		}
+		if req.VehicleID != nil {
+			if err := checkAssignmentTenant(tx, "vehicles", "vehicle", *req.VehicleID, fleetOwner); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(`UPDATE trips SET status = $1, driver_id = $2, vehicle_id = $3, updated_at = CURRENT_TIMESTAMP
+						  WHERE id = $4`, req.Status, *newDriver, *newVehicle, tripID)
+
+	case TripStatusPlanned:
+		// Sending a trip back to planning releases its driver and vehicle
+		_, err = tx.Exec(`UPDATE trips SET status = $1, driver_id = NULL, vehicle_id = NULL, updated_at = CURRENT_TIMESTAMP
+						  WHERE id = $2`, req.Status, tripID)
+
+	case TripStatusEnRoutePickup:
+		_, err = tx.Exec(`UPDATE trips SET status = $1, actual_start = COALESCE(actual_start, CURRENT_TIMESTAMP),
+						  updated_at = CURRENT_TIMESTAMP WHERE id = $2`, req.Status, tripID)
+
+	case TripStatusInTransit:
+		_, err = tx.Exec(`UPDATE trips SET status = $1, departure_time = COALESCE(departure_time, CURRENT_TIMESTAMP),
+						  updated_at = CURRENT_TIMESTAMP WHERE id = $2`, req.Status, tripID)
+
+	case TripStatusDelivered:
+		if err := checkPODRequirement(tx, tripID); err != nil {
+			return err
+		}
+		// arrival_time stays the planned arrival; actual_end is when it happened
+		_, err = tx.Exec(`UPDATE trips SET status = $1, actual_end = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+						  WHERE id = $2`, req.Status, tripID)
+
+	case TripStatusCancelled, TripStatusFailed:
+		_, err = tx.Exec(`UPDATE trips SET status = $1, actual_end = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+						  WHERE id = $2`, req.Status, tripID)
+
+	default:
+		_, err = tx.Exec(`UPDATE trips SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, req.Status, tripID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update trip status: %v", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO trip_status_history (trip_id, from_status, to_status, changed_by, changed_by_role, notes)
+					  VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6)`,
+		tripID, current, req.Status, actorID, actorRole, req.Notes)
+	if err != nil {
+		return fmt.Errorf("failed to record trip status history: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit trip transition: %v", err)
+	}
+	return nil
+}
+
+// checkAssignmentTenant checks that a driver or vehicle put on a trip
+// belongs to the trip's fleet owner, as CreateTrip does
+func checkAssignmentTenant(tx *sql.Tx, table, kind string, id int, tripOwner sql.NullInt64) error {
+	var owner sql.NullInt64
+	err := tx.QueryRow("SELECT fleet_owner_id FROM "+table+" WHERE id = $1", id).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s not found", ErrInvalidTripTransition, kind)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %v", kind, err)
+	}
+	if tripOwner.Valid && owner != tripOwner {
+		return fmt.Errorf("%w: %s belongs to another fleet owner", ErrTenantAccessDenied, kind)
+	}
+	return nil
+}
+
+// GetTripStatusHistory returns the recorded transitions of a trip, oldest first
+func GetTripStatusHistory(db *sql.DB, tripID int) ([]models.TripStatusHistory, error) {
+	query := `SELECT h.id, h.trip_id, h.from_status, h.to_status, h.changed_by,
+			  COALESCE(u.full_name, ''), COALESCE(h.changed_by_role, ''), COALESCE(h.notes, ''), h.created_at
+			  FROM trip_status_history h
+			  LEFT JOIN users u ON h.changed_by = u.id
+			  WHERE h.trip_id = $1
+			  ORDER BY h.created_at, h.id`
+
+	rows, err := db.Query(query, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip status history: %v", err)
+	}
+	defer rows.Close()
+
+	history := []models.TripStatusHistory{}
+	for rows.Next() {
+		var h models.TripStatusHistory
+		err := rows.Scan(&h.ID, &h.TripID, &h.FromStatus, &h.ToStatus, &h.ChangedBy,
+			&h.ChangedByName, &h.ChangedByRole, &h.Notes, &h.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trip status history: %v", err)
+		}
+		history = append(history, h)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateTripTransitionHappyPath(t *testing.T) {
+	path := []string{
+		TripStatusPlanned,
+		TripStatusAssigned,
+		TripStatusEnRoutePickup,
+		TripStatusLoading,
+		TripStatusInTransit,
+		TripStatusUnloading,
+		TripStatusDelivered,
+	}
+
+	for i := 1; i < len(path); i++ {
+		if err := ValidateTripTransition(path[i-1], path[i], false); err != nil {
+			t.Fatalf("Expected %s -> %s to be allowed, got %v", path[i-1], path[i], err)
+		}
+	}
+}
+
+func TestValidateTripTransitionMultiDrop(t *testing.T) {
+	// Leaving an intermediate drop puts the trip back in transit
+	if err := ValidateTripTransition(TripStatusUnloading, TripStatusInTransit, false); err != nil {
+		t.Fatalf("Expected unloading -> in_transit to be allowed, got %v", err)
+	}
+}
+
+func TestValidateTripTransitionRejectsSkips(t *testing.T) {
+	cases := [][2]string{
+		{TripStatusPlanned, TripStatusInTransit},
+		{TripStatusAssigned, TripStatusDelivered},
+		{TripStatusInTransit, TripStatusCancelled},
+		{TripStatusDelivered, TripStatusFailed},
+		{TripStatusCancelled, TripStatusAssigned},
+	}
+
+	for _, tc := range cases {
+		err := ValidateTripTransition(tc[0], tc[1], false)
+		if !errors.Is(err, ErrInvalidTripTransition) {
+			t.Errorf("Expected %s -> %s to be rejected, got %v", tc[0], tc[1], err)
+		}
+	}
+}
+
+func TestValidateTripTransitionDriverRestrictions(t *testing.T) {
+	if err := ValidateTripTransition(TripStatusAssigned, TripStatusEnRoutePickup, true); err != nil {
+		t.Fatalf("Expected driver to start pickup, got %v", err)
+	}
+
+	if err := ValidateTripTransition(TripStatusLoading, TripStatusCancelled, true); !errors.Is(err, ErrInvalidTripTransition) {
+		t.Fatalf("Expected driver cancellation to be rejected, got %v", err)
+	}
+
+	if err := ValidateTripTransition(TripStatusPlanned, TripStatusAssigned, true); !errors.Is(err, ErrInvalidTripTransition) {
+		t.Fatalf("Expected driver assignment to be rejected, got %v", err)
+	}
+}
+
+func TestValidateTripTransitionUnknownStatus(t *testing.T) {
+	err := ValidateTripTransition(TripStatusPlanned, "started", false)
+	if !errors.Is(err, ErrInvalidTripStatus) {
+		t.Fatalf("Expected unknown status error, got %v", err)
+	}
+}
+
+func TestTerminalTripStatuses(t *testing.T) {
+	for _, status := range []string{TripStatusDelivered, TripStatusCancelled, TripStatusFailed} {
+		if !IsTerminalTripStatus(status) {
+			t.Errorf("Expected %s to be terminal", status)
+		}
+	}
+	if IsTerminalTripStatus(TripStatusInTransit) {
+		t.Error("Expected in_transit not to be terminal")
+	}
+}
@@ -8,7 +8,63 @@ import (
 	"github.com/youruser/aplikasi-tms/backend/internal/models"
 )
 
-func CreateTrip(db *sql.DB, req models.TripRequest) (*models.Trip, error) {
+// tripFleetOwner decides which fleet owner a new trip belongs to: the
+// owner of its vehicle, or the caller's own fleet. A tenant may only use
+// its own vehicle and drivers.
+func tripFleetOwner(db *sql.DB, scope models.TenantScope, req models.TripRequest) (*int, error) {
+	if scope.FilterID() < 0 {
+		return nil, ErrTenantAccessDenied
+	}
+
+	var owner *int
+	if req.VehicleID != nil {
+		var vehicleOwner sql.NullInt64
+		err := db.QueryRow("SELECT fleet_owner_id FROM vehicles WHERE id = $1", *req.VehicleID).Scan(&vehicleOwner)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("vehicle not found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check vehicle: %v", err)
+		}
+		if vehicleOwner.Valid {
+			id := int(vehicleOwner.Int64)
+			owner = &id
+		}
+		if !scope.Allows(owner) {
+			return nil, fmt.Errorf("%w: vehicle belongs to another fleet owner", ErrTenantAccessDenied)
+		}
+	}
+
+	if owner == nil && !scope.All {
+		id := scope.FleetOwnerID
+		owner = &id
+	}
+
+	if req.DriverID != nil {
+		var driverOwner sql.NullInt64
+		err := db.QueryRow("SELECT fleet_owner_id FROM drivers WHERE id = $1", *req.DriverID).Scan(&driverOwner)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("driver not found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check driver: %v", err)
+		}
+		if driverOwner.Valid {
+			id := int(driverOwner.Int64)
+			if owner == nil {
+				owner = &id
+			} else if *owner != id {
+				return nil, fmt.Errorf("%w: driver belongs to another fleet owner", ErrTenantAccessDenied)
+			}
+		}
+	}
+
+	return owner, nil
+}
+
+// CreateTrip creates a trip and records its first status in the history
+// as set by actorID
+func CreateTrip(db *sql.DB, scope models.TenantScope, req models.TripRequest, actorID int, actorRole string) (*models.Trip, error) {
 	// Parse dates if provided
 	var departureTime, arrivalTime *time.Time
 	if req.DepartureTime != nil && *req.DepartureTime != "" {
@@ -26,26 +82,66 @@ func CreateTrip(db *sql.DB, req models.TripRequest) (*models.Trip, error) {
 		}
 	}
 
-	// Set default status
+	// New trips start planned, or assigned when a driver and vehicle are given
 	status := req.Status
 	if status == "" {
-		status = "planned"
+		status = TripStatusPlanned
+	}
+	if status != TripStatusPlanned && status != TripStatusAssigned {
+		return nil, fmt.Errorf("%w: trips must be created as planned or assigned", ErrInvalidTripStatus)
+	}
+	if status == TripStatusAssigned && (req.DriverID == nil || req.VehicleID == nil) {
+		return nil, fmt.Errorf("%w: driver and vehicle are required to assign a trip", ErrInvalidTripTransition)
 	}
 
+	stops, err := validateTripStops(req.Stops)
+	if err != nil {
+		return nil, err
+	}
+
+	fleetOwnerID, err := tripFleetOwner(db, scope, req)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
 	query := `INSERT INTO trips (driver_id, vehicle_id, origin, destination, 
-			  departure_time, arrival_time, status, distance) 
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+			  departure_time, arrival_time, status, distance, customer_contract_id, fleet_owner_id) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) 
 			  RETURNING id, created_at, updated_at`
 
 	var trip models.Trip
-	err := db.QueryRow(query, req.DriverID, req.VehicleID, req.Origin, req.Destination,
-		departureTime, arrivalTime, status, req.Distance).
+	err = tx.QueryRow(query, req.DriverID, req.VehicleID, req.Origin, req.Destination,
+		departureTime, arrivalTime, status, req.Distance, req.CustomerContractID, fleetOwnerID).
 		Scan(&trip.ID, &trip.CreatedAt, &trip.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create trip: %v", err)
 	}
 
+	_, err = tx.Exec(`INSERT INTO trip_status_history (trip_id, from_status, to_status, changed_by, changed_by_role, notes)
+					  VALUES ($1, NULL, $2, NULLIF($3, 0), $4, 'Trip created')`,
+		trip.ID, status, actorID, actorRole)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record trip status history: %v", err)
+	}
+
+	if len(stops) > 0 {
+		trip.Stops, err = insertTripStops(tx, trip.ID, stops)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit trip: %v", err)
+	}
+
 	trip.DriverID = req.DriverID
 	trip.VehicleID = req.VehicleID
 	trip.Origin = req.Origin
@@ -54,23 +150,25 @@ func CreateTrip(db *sql.DB, req models.TripRequest) (*models.Trip, error) {
 	trip.ArrivalTime = arrivalTime
 	trip.Status = status
 	trip.Distance = req.Distance
+	trip.CustomerContractID = req.CustomerContractID
+	trip.FleetOwnerID = fleetOwnerID
 
 	return &trip, nil
 }
 
 const tripSelectQuery = `SELECT t.id, t.driver_id, t.vehicle_id, t.origin, t.destination,
-			  t.departure_time, t.arrival_time, t.status, t.distance,
-			  t.created_at, t.updated_at,
+			  t.departure_time, t.arrival_time, t.status, t.distance, t.customer_contract_id,
+			  t.fleet_owner_id, t.created_at, t.updated_at,
 			  u.full_name as driver_name, v.registration_number
 			  FROM trips t
 			  LEFT JOIN drivers d ON t.driver_id = d.id
 			  LEFT JOIN users u ON d.user_id = u.id
 			  LEFT JOIN vehicles v ON t.vehicle_id = v.id`
 
-func GetTrips(db *sql.DB) ([]models.Trip, error) {
-	query := tripSelectQuery + ` ORDER BY t.created_at DESC`
+func GetTrips(db *sql.DB, scope models.TenantScope) ([]models.Trip, error) {
+	query := tripSelectQuery + ` WHERE ($1 = 0 OR t.fleet_owner_id = $1) ORDER BY t.created_at DESC`
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, scope.FilterID())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get trips: %v", err)
 	}
@@ -82,8 +180,8 @@ func GetTrips(db *sql.DB) ([]models.Trip, error) {
 		var driverName, vehicleReg sql.NullString
 		err := rows.Scan(
 			&t.ID, &t.DriverID, &t.VehicleID, &t.Origin, &t.Destination,
-			&t.DepartureTime, &t.ArrivalTime, &t.Status, &t.Distance,
-			&t.CreatedAt, &t.UpdatedAt, &driverName, &vehicleReg,
+			&t.DepartureTime, &t.ArrivalTime, &t.Status, &t.Distance, &t.CustomerContractID,
+			&t.FleetOwnerID, &t.CreatedAt, &t.UpdatedAt, &driverName, &vehicleReg,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan trip: %v", err)
@@ -116,13 +214,13 @@ func GetTripByID(db *sql.DB, id int) (*models.Trip, error) {
 	var driverName, vehicleReg sql.NullString
 	err := db.QueryRow(query, id).Scan(
 		&t.ID, &t.DriverID, &t.VehicleID, &t.Origin, &t.Destination,
-		&t.DepartureTime, &t.ArrivalTime, &t.Status, &t.Distance,
-		&t.CreatedAt, &t.UpdatedAt, &driverName, &vehicleReg,
+		&t.DepartureTime, &t.ArrivalTime, &t.Status, &t.Distance, &t.CustomerContractID,
+		&t.FleetOwnerID, &t.CreatedAt, &t.UpdatedAt, &driverName, &vehicleReg,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("trip not found")
+			return nil, ErrTripNotFound
 		}
 		return nil, fmt.Errorf("failed to get trip: %v", err)
 	}
@@ -141,5 +239,23 @@ func GetTripByID(db *sql.DB, id int) (*models.Trip, error) {
 		}
 	}
 
+	t.Stops, err = GetTripStops(db, t.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	return &t, nil
-}
\ No newline at end of file
+}
+// CheckTripInScope returns ErrTripNotFound unless the trip is visible in
+// scope, so other tenants cannot tell whether it exists
+func CheckTripInScope(db *sql.DB, scope models.TenantScope, tripID int) error {
+	var visible bool
+	query := `SELECT EXISTS(SELECT 1 FROM trips WHERE id = $1 AND ($2 = 0 OR fleet_owner_id = $2))`
+	if err := db.QueryRow(query, tripID, scope.FilterID()).Scan(&visible); err != nil {
+		return fmt.Errorf("failed to check trip: %v", err)
+	}
+	if !visible {
+		return ErrTripNotFound
+	}
+	return nil
+}
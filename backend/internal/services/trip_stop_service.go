@@ -0,0 +1,279 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// Trip stop statuses
+const (
+	StopStatusPending  = "pending"
+	StopStatusArrived  = "arrived"
+	StopStatusDeparted = "departed"
+)
+
+var (
+	ErrTripStopNotFound   = errors.New("trip stop not found")
+	ErrTripStopOutOfOrder = errors.New("stop is not next in sequence")
+)
+
+// tripStopTimeFormat matches the format used for trip departure/arrival times
+const tripStopTimeFormat = "2006-01-02T15:04:05Z07:00"
+
+type parsedTripStop struct {
+	req         models.TripStopRequest
+	stopType    string
+	windowStart *time.Time
+	windowEnd   *time.Time
+}
+
+// validateTripStops checks stop requests and parses their time windows
+func validateTripStops(stops []models.TripStopRequest) ([]parsedTripStop, error) {
+	parsed := make([]parsedTripStop, 0, len(stops))
+	for i, stop := range stops {
+		stop.Address = strings.TrimSpace(stop.Address)
+		if stop.Address == "" {
+			return nil, fmt.Errorf("stop %d: address is required", i+1)
+		}
+
+		p := parsedTripStop{req: stop, stopType: stop.StopType}
+		if p.stopType == "" {
+			p.stopType = "dropoff"
+		}
+		if p.stopType != "pickup" && p.stopType != "dropoff" {
+			return nil, fmt.Errorf("stop %d: invalid stop type %s", i+1, stop.StopType)
+		}
+
+		if stop.Latitude != nil && (*stop.Latitude < -90 || *stop.Latitude > 90) {
+			return nil, fmt.Errorf("stop %d: invalid latitude: must be between -90 and 90", i+1)
+		}
+		if stop.Longitude != nil && (*stop.Longitude < -180 || *stop.Longitude > 180) {
+			return nil, fmt.Errorf("stop %d: invalid longitude: must be between -180 and 180", i+1)
+		}
+
+		if stop.WindowStart != nil && *stop.WindowStart != "" {
+			t, err := time.Parse(tripStopTimeFormat, *stop.WindowStart)
+			if err != nil {
+				return nil, fmt.Errorf("stop %d: invalid window start format: %v", i+1, err)
+			}
+			p.windowStart = &t
+		}
+		if stop.WindowEnd != nil && *stop.WindowEnd != "" {
+			t, err := time.Parse(tripStopTimeFormat, *stop.WindowEnd)
+			if err != nil {
+				return nil, fmt.Errorf("stop %d: invalid window end format: %v", i+1, err)
+			}
+			p.windowEnd = &t
+		}
+		if p.windowStart != nil && p.windowEnd != nil && p.windowEnd.Before(*p.windowStart) {
+			return nil, fmt.Errorf("stop %d: window end must be after window start", i+1)
+		}
+
+		parsed = append(parsed, p)
+	}
+	return parsed, nil
+}
+
+// insertTripStops stores stops in request order, numbering them from 1
+func insertTripStops(tx *sql.Tx, tripID int, stops []parsedTripStop) ([]models.TripStop, error) {
+	query := `INSERT INTO trip_stops (trip_id, sequence, stop_type, address, latitude, longitude,
+			  window_start, window_end, contact_name, contact_phone, expected_cargo, expected_weight, notes)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			  RETURNING id, status, created_at, updated_at`
+
+	result := make([]models.TripStop, 0, len(stops))
+	for i, p := range stops {
+		s := models.TripStop{
+			TripID:         tripID,
+			Sequence:       i + 1,
+			StopType:       p.stopType,
+			Address:        p.req.Address,
+			Latitude:       p.req.Latitude,
+			Longitude:      p.req.Longitude,
+			WindowStart:    p.windowStart,
+			WindowEnd:      p.windowEnd,
+			ContactName:    p.req.ContactName,
+			ContactPhone:   p.req.ContactPhone,
+			ExpectedCargo:  p.req.ExpectedCargo,
+			ExpectedWeight: p.req.ExpectedWeight,
+			Notes:          p.req.Notes,
+		}
+
+		err := tx.QueryRow(query, tripID, s.Sequence, s.StopType, s.Address, s.Latitude, s.Longitude,
+			s.WindowStart, s.WindowEnd, s.ContactName, s.ContactPhone, s.ExpectedCargo, s.ExpectedWeight, s.Notes).
+			Scan(&s.ID, &s.Status, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create trip stop: %v", err)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+const tripStopSelectQuery = `SELECT id, trip_id, sequence, stop_type, address, latitude, longitude,
+			  window_start, window_end, contact_name, contact_phone, expected_cargo, expected_weight,
+			  status, arrived_at, departed_at, notes, created_at, updated_at
+			  FROM trip_stops`
+
+func scanTripStop(row interface{ Scan(...interface{}) error }) (models.TripStop, error) {
+	var s models.TripStop
+	err := row.Scan(&s.ID, &s.TripID, &s.Sequence, &s.StopType, &s.Address, &s.Latitude, &s.Longitude,
+		&s.WindowStart, &s.WindowEnd, &s.ContactName, &s.ContactPhone, &s.ExpectedCargo, &s.ExpectedWeight,
+		&s.Status, &s.ArrivedAt, &s.DepartedAt, &s.Notes, &s.CreatedAt, &s.UpdatedAt)
+	return s, err
+}
+
+// GetTripStops returns the stops of a trip in visiting order
+func GetTripStops(db *sql.DB, tripID int) ([]models.TripStop, error) {
+	rows, err := db.Query(tripStopSelectQuery+` WHERE trip_id = $1 ORDER BY sequence`, tripID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip stops: %v", err)
+	}
+	defer rows.Close()
+
+	stops := []models.TripStop{}
+	for rows.Next() {
+		s, err := scanTripStop(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trip stop: %v", err)
+		}
+		stops = append(stops, s)
+	}
+	return stops, nil
+}
+
+// GetDriverTrip returns a trip with its stops if it is assigned to driverID
+func GetDriverTrip(db *sql.DB, tripID int, driverID int) (*models.Trip, error) {
+	trip, err := GetTripByID(db, tripID)
+	if err != nil {
+		return nil, err
+	}
+	if trip.DriverID == nil || *trip.DriverID != driverID {
+		return nil, ErrTripNotFound
+	}
+	return trip, nil
+}
+
+// CheckInTripStop records the driver's arrival at a stop. Stops must be
+// visited in sequence and only while the trip is under way.
+func CheckInTripStop(db *sql.DB, tripID, stopID, driverID int, notes string) (*models.TripStop, error) {
+	return checkInTripStop(db, tripID, stopID, &driverID, notes)
+}
+
+// AutoCheckInTripStop records arrival at a stop detected by the system, e.g.
+// from a geofence, without a driver ownership check
+func AutoCheckInTripStop(db *sql.DB, tripID, stopID int, notes string) (*models.TripStop, error) {
+	return checkInTripStop(db, tripID, stopID, nil, notes)
+}
+
+func checkInTripStop(db *sql.DB, tripID, stopID int, driverID *int, notes string) (*models.TripStop, error) {
+	return updateTripStop(db, tripID, stopID, driverID, func(tx *sql.Tx, stop models.TripStop) error {
+		if stop.Status != StopStatusPending {
+			return fmt.Errorf("%w: stop already checked in", ErrTripStopOutOfOrder)
+		}
+
+		// Every earlier stop must already be departed
+		var open int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM trip_stops
+							WHERE trip_id = $1 AND sequence < $2 AND status <> $3`,
+			tripID, stop.Sequence, StopStatusDeparted).Scan(&open)
+		if err != nil {
+			return fmt.Errorf("failed to check previous stops: %v", err)
+		}
+		if open > 0 {
+			return ErrTripStopOutOfOrder
+		}
+
+		_, err = tx.Exec(`UPDATE trip_stops SET status = $1, arrived_at = CURRENT_TIMESTAMP,
+						  notes = COALESCE(NULLIF($2, ''), notes), updated_at = CURRENT_TIMESTAMP
+						  WHERE id = $3`, StopStatusArrived, notes, stop.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check in stop: %v", err)
+		}
+		return nil
+	})
+}
+
+// CheckOutTripStop records the driver leaving a stop they checked into
+func CheckOutTripStop(db *sql.DB, tripID, stopID, driverID int, notes string) (*models.TripStop, error) {
+	return checkOutTripStop(db, tripID, stopID, &driverID, notes)
+}
+
+// AutoCheckOutTripStop records departure from a stop detected by the system
+func AutoCheckOutTripStop(db *sql.DB, tripID, stopID int, notes string) (*models.TripStop, error) {
+	return checkOutTripStop(db, tripID, stopID, nil, notes)
+}
+
+func checkOutTripStop(db *sql.DB, tripID, stopID int, driverID *int, notes string) (*models.TripStop, error) {
+	return updateTripStop(db, tripID, stopID, driverID, func(tx *sql.Tx, stop models.TripStop) error {
+		if stop.Status != StopStatusArrived {
+			return fmt.Errorf("%w: stop must be checked in before checking out", ErrTripStopOutOfOrder)
+		}
+
+		_, err := tx.Exec(`UPDATE trip_stops SET status = $1, departed_at = CURRENT_TIMESTAMP,
+						   notes = COALESCE(NULLIF($2, ''), notes), updated_at = CURRENT_TIMESTAMP
+						   WHERE id = $3`, StopStatusDeparted, notes, stop.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check out stop: %v", err)
+		}
+		return nil
+	})
+}
+
+// updateTripStop locks the trip and stop and applies a status change. A nil
+// driverID skips the ownership check for system-initiated updates.
+func updateTripStop(db *sql.DB, tripID, stopID int, driverID *int, apply func(tx *sql.Tx, stop models.TripStop) error) (*models.TripStop, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	var tripDriver sql.NullInt64
+	err = tx.QueryRow(`SELECT status, driver_id FROM trips WHERE id = $1 FOR UPDATE`, tripID).Scan(&status, &tripDriver)
+	if err == sql.ErrNoRows || (err == nil && driverID != nil && (!tripDriver.Valid || int(tripDriver.Int64) != *driverID)) {
+		return nil, ErrTripNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip: %v", err)
+	}
+
+	active := false
+	for _, s := range ActiveTripStatuses() {
+		if s == status {
+			active = true
+			break
+		}
+	}
+	if !active {
+		return nil, fmt.Errorf("%w: trip is %s", ErrInvalidTripTransition, status)
+	}
+
+	stop, err := scanTripStop(tx.QueryRow(tripStopSelectQuery+` WHERE id = $1 AND trip_id = $2 FOR UPDATE`, stopID, tripID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTripStopNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip stop: %v", err)
+	}
+
+	if err := apply(tx, stop); err != nil {
+		return nil, err
+	}
+
+	updated, err := scanTripStop(tx.QueryRow(tripStopSelectQuery+` WHERE id = $1`, stopID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip stop: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit trip stop update: %v", err)
+	}
+	return &updated, nil
+}
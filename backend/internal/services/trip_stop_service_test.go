@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+func TestValidateTripStops(t *testing.T) {
+	start := "2025-01-10T08:00:00+07:00"
+	end := "2025-01-10T10:00:00+07:00"
+
+	stops, err := validateTripStops([]models.TripStopRequest{
+		{StopType: "pickup", Address: "Gudang Cikarang", WindowStart: &start, WindowEnd: &end},
+		{Address: " Toko Bandung "},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if stops[0].stopType != "pickup" || stops[1].stopType != "dropoff" {
+		t.Errorf("Unexpected stop types: %s, %s", stops[0].stopType, stops[1].stopType)
+	}
+	if stops[1].req.Address != "Toko Bandung" {
+		t.Errorf("Expected trimmed address, got %q", stops[1].req.Address)
+	}
+	if stops[0].windowStart == nil || stops[0].windowEnd == nil {
+		t.Fatal("Expected time window to be parsed")
+	}
+}
+
+func TestValidateTripStopsRejectsInvalidInput(t *testing.T) {
+	start := "2025-01-10T10:00:00+07:00"
+	end := "2025-01-10T08:00:00+07:00"
+	lat := 120.0
+
+	cases := map[string]models.TripStopRequest{
+		"reversed window": {Address: "A", WindowStart: &start, WindowEnd: &end},
+		"bad latitude":    {Address: "A", Latitude: &lat},
+		"bad stop type":   {Address: "A", StopType: "transit"},
+		"blank address":   {Address: "   "},
+	}
+
+	for name, stop := range cases {
+		if _, err := validateTripStops([]models.TripStopRequest{stop}); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
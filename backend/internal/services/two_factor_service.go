@@ -0,0 +1,279 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+const (
+	// twoFactorTokenTTL bounds the time between the password and code steps
+	twoFactorTokenTTL = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotStarted  = errors.New("two-factor setup has not been started")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorMandatory is returned when disabling 2FA for a role that
+	// must have it
+	ErrTwoFactorMandatory = errors.New("two-factor authentication is mandatory for this role")
+)
+
+type TwoFactorService struct {
+	repo     *repository.TwoFactorRepository
+	accounts *repository.AccountRepository
+	sessions *SessionService
+}
+
+func NewTwoFactorService(db *sql.DB, sessionCfg *config.SessionConfig) *TwoFactorService {
+	return &TwoFactorService{
+		repo:     repository.NewTwoFactorRepository(db),
+		accounts: repository.NewAccountRepository(db),
+		sessions: NewSessionService(db, sessionCfg),
+	}
+}
+
+// Challenge decides the second login step for user once the password was
+// accepted. It returns nil when the user can log in right away.
+func (s *TwoFactorService) Challenge(user *models.User) (*models.TwoFactorChallenge, error) {
+	state, err := s.repo.GetState(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor state: %v", err)
+	}
+
+	var step, scope string
+	switch {
+	case state != nil && state.EnabledAt != nil:
+		step, scope = models.TwoFactorStepVerify, auth.ScopeTwoFactor
+	case auth.RequiresTwoFactor(user.Role):
+		step, scope = models.TwoFactorStepEnroll, auth.ScopeTwoFactorEnroll
+	default:
+		return nil, nil
+	}
+
+	token, _, err := auth.GenerateScopedToken(user.ID, user.Username, user.Role, scope, twoFactorTokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %v", err)
+	}
+	return &models.TwoFactorChallenge{Step: step, Token: token, ExpiresIn: int(twoFactorTokenTTL.Seconds())}, nil
+}
+
+func (s *TwoFactorService) Status(userID int, role string) (*models.TwoFactorStatus, error) {
+	state, err := s.repo.GetState(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor state: %v", err)
+	}
+	if state == nil {
+		return nil, ErrUserNotFound
+	}
+
+	status := &models.TwoFactorStatus{Enabled: state.EnabledAt != nil, Required: auth.RequiresTwoFactor(role)}
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = s.repo.CountRecoveryCodes(userID); err != nil {
+			return nil, fmt.Errorf("failed to count recovery codes: %v", err)
+		}
+	}
+	return status, nil
+}
+
+// Setup starts enrollment with a new secret. Calling it again before
+// Enable replaces the secret.
+func (s *TwoFactorService) Setup(userID int, account string) (*models.TwoFactorSetup, error) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %v", err)
+	}
+	sealed, err := auth.SealTOTPSecret(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal secret: %v", err)
+	}
+
+	ok, err := s.repo.SetPendingSecret(userID, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store secret: %v", err)
+	}
+	if !ok {
+		return nil, ErrTwoFactorEnabled
+	}
+	return &models.TwoFactorSetup{Secret: secret, ProvisioningURI: auth.TOTPProvisioningURI(account, secret)}, nil
+}
+
+// Enable confirms enrollment with a code from the authenticator app and
+// returns the recovery codes, which are shown only this once
+func (s *TwoFactorService) Enable(userID int, code string) ([]string, error) {
+	state, err := s.repo.GetState(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor state: %v", err)
+	}
+	if state == nil {
+		return nil, ErrUserNotFound
+	}
+	if state.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	if state.SealedSecret == "" {
+		return nil, ErrTwoFactorNotStarted
+	}
+
+	secret, err := auth.OpenTOTPSecret(state.SealedSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open secret: %v", err)
+	}
+	step, ok := auth.ValidateTOTP(secret, code, time.Now(), state.LastStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Enable(userID, step, hashes, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %v", err)
+	}
+	return codes, nil
+}
+
+// Verify checks a TOTP code or an unused recovery code. Each is accepted
+// only once.
+func (s *TwoFactorService) Verify(userID int, code string) error {
+	state, err := s.repo.GetState(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get two-factor state: %v", err)
+	}
+	if state == nil || state.EnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if recovery := auth.NormalizeRecoveryCode(code); strings.Contains(recovery, "-") {
+		ok, err := s.repo.UseRecoveryCode(userID, auth.HashToken(recovery), time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("failed to use recovery code: %v", err)
+		}
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	secret, err := auth.OpenTOTPSecret(state.SealedSecret)
+	if err != nil {
+		return fmt.Errorf("failed to open secret: %v", err)
+	}
+	step, ok := auth.ValidateTOTP(secret, code, time.Now(), state.LastStep)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	// Two requests racing with the same code: only one moves the step on
+	used, err := s.repo.UseStep(userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to record code: %v", err)
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// CompleteLogin finishes a two-factor login: claims are those of the token
+// from Challenge, which is revoked once a full session is started
+func (s *TwoFactorService) CompleteLogin(claims *auth.Claims, code, userAgent, ip string) (*models.TokenPair, *models.User, error) {
+	if err := s.Verify(claims.UserID, code); err != nil {
+		return nil, nil, err
+	}
+	return s.startSession(claims, userAgent, ip)
+}
+
+// FinishEnrollment starts a full session for a user who logged in with an
+// enrollment token and has just enabled 2FA
+func (s *TwoFactorService) FinishEnrollment(claims *auth.Claims, userAgent, ip string) (*models.TokenPair, *models.User, error) {
+	return s.startSession(claims, userAgent, ip)
+}
+
+func (s *TwoFactorService) startSession(claims *auth.Claims, userAgent, ip string) (*models.TokenPair, *models.User, error) {
+	user, err := s.accounts.GetUser(claims.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %v", err)
+	}
+	if user == nil {
+		return nil, nil, ErrUserNotFound
+	}
+	if err := s.sessions.Logout(claims); err != nil {
+		return nil, nil, err
+	}
+	pair, err := s.sessions.StartSession(user, userAgent, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, user, nil
+}
+
+// Disable turns 2FA off after checking a current code
+func (s *TwoFactorService) Disable(userID int, role, code string) error {
+	if auth.RequiresTwoFactor(role) {
+		return ErrTwoFactorMandatory
+	}
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+	if err := s.repo.Disable(userID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %v", err)
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking a
+// current code
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID int, code string) ([]string, error) {
+	if err := s.Verify(userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %v", err)
+	}
+	return codes, nil
+}
+
+// Reset removes a user's 2FA for an administrator, e.g. after a lost phone,
+// and ends the user's sessions. Roles that require 2FA enroll again at
+// their next login.
+func (s *TwoFactorService) Reset(userID int) error {
+	state, err := s.repo.GetState(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get two-factor state: %v", err)
+	}
+	if state == nil {
+		return ErrUserNotFound
+	}
+	if err := s.repo.Disable(userID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to reset two-factor authentication: %v", err)
+	}
+	_, err = s.sessions.RevokeUserSessions(userID, RevokeReasonAdmin)
+	return err
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate recovery codes: %v", err)
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashToken(code)
+	}
+	return codes, hashes, nil
+}
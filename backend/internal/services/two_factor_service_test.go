@@ -0,0 +1,144 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+// RFC 6238 appendix B secret ("12345678901234567890"), base32 encoded
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range vectors {
+		got, err := auth.TOTPCode(rfcTOTPSecret, auth.TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("At %d expected %s, got %s", unix, want, got)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := auth.TOTPStep(now)
+	previous, _ := auth.TOTPCode(rfcTOTPSecret, step-1)
+	stale, _ := auth.TOTPCode(rfcTOTPSecret, step-3)
+
+	if got, ok := auth.ValidateTOTP(rfcTOTPSecret, previous, now, 0); !ok || got != step-1 {
+		t.Errorf("Expected previous step's code within the skew, got %d, %v", got, ok)
+	}
+	if _, ok := auth.ValidateTOTP(rfcTOTPSecret, stale, now, 0); ok {
+		t.Error("Expected a code three steps old to be refused")
+	}
+	if _, ok := auth.ValidateTOTP(rfcTOTPSecret, previous, now, step-1); ok {
+		t.Error("Expected an already used step to be refused")
+	}
+	if _, ok := auth.ValidateTOTP(rfcTOTPSecret, "12345", now, 0); ok {
+		t.Error("Expected a short code to be refused")
+	}
+
+	uri := auth.TOTPProvisioningURI("admin", rfcTOTPSecret)
+	if !strings.HasPrefix(uri, "otpauth://totp/TMS:admin?") || !strings.Contains(uri, "secret="+rfcTOTPSecret) {
+		t.Errorf("Unexpected provisioning URI %s", uri)
+	}
+}
+
+func TestTOTPSecretSealing(t *testing.T) {
+	sealed, err := auth.SealTOTPSecret(rfcTOTPSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, rfcTOTPSecret) {
+		t.Error("Expected the sealed secret not to contain the secret")
+	}
+	opened, err := auth.OpenTOTPSecret(sealed)
+	if err != nil || opened != rfcTOTPSecret {
+		t.Errorf("Expected to open the sealed secret, got %q, %v", opened, err)
+	}
+	if _, err := auth.OpenTOTPSecret(sealed[:len(sealed)-4] + "AAAA"); err == nil {
+		t.Error("Expected a tampered secret to fail")
+	}
+}
+
+func TestTwoFactorRequiredRoles(t *testing.T) {
+	if !auth.RequiresTwoFactor(auth.RoleSuperAdmin) || !auth.RequiresTwoFactor(auth.RoleVerifier) {
+		t.Error("Expected admins and verifiers to require two-factor authentication")
+	}
+	if auth.RequiresTwoFactor(auth.RoleFleetOwner) || auth.RequiresTwoFactor(auth.RoleDriver) {
+		t.Error("Expected two-factor authentication to be optional for fleet owners and drivers")
+	}
+	if auth.NormalizeRecoveryCode(" ABCDE fghij ") != "abcde-fghij" {
+		t.Errorf("Expected recovery codes to normalize, got %q", auth.NormalizeRecoveryCode(" ABCDE fghij "))
+	}
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	conn := tenantTestDB(t)
+	defer conn.Close()
+
+	name := fmt.Sprintf("totp_%d", time.Now().UnixNano())
+	user := &models.User{Username: name, Email: name + "@example.com", FullName: name, Role: auth.RoleVerifier}
+	err := conn.QueryRow(`INSERT INTO users (username, email, password_hash, full_name, role)
+		VALUES ($1, $2, 'x', $3, $4) RETURNING id`, user.Username, user.Email, user.FullName, user.Role).Scan(&user.ID)
+	if err != nil {
+		t.Fatalf("Failed to seed user: %v", err)
+	}
+	defer conn.Exec("DELETE FROM users WHERE id = $1", user.ID)
+
+	twoFactor := NewTwoFactorService(conn, &config.SessionConfig{AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, CleanupInterval: time.Hour})
+
+	challenge, err := twoFactor.Challenge(user)
+	if err != nil || challenge == nil || challenge.Step != models.TwoFactorStepEnroll {
+		t.Fatalf("Expected a verifier without 2FA to be sent to enrollment, got %+v, %v", challenge, err)
+	}
+	claims, err := auth.ValidateToken(challenge.Token)
+	if err != nil || claims.Scope != auth.ScopeTwoFactorEnroll {
+		t.Fatalf("Expected an enrollment-scoped token, got %+v, %v", claims, err)
+	}
+
+	setup, err := twoFactor.Setup(user.ID, user.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := auth.TOTPCode(setup.Secret, auth.TOTPStep(time.Now()))
+	recovery, err := twoFactor.Enable(user.ID, code)
+	if err != nil {
+		t.Fatalf("Expected enrollment to succeed, got %v", err)
+	}
+	if len(recovery) != recoveryCodeCount {
+		t.Errorf("Expected %d recovery codes, got %d", recoveryCodeCount, len(recovery))
+	}
+
+	if err := twoFactor.Verify(user.ID, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected the enrollment code not to work twice, got %v", err)
+	}
+	if err := twoFactor.Verify(user.ID, strings.ToUpper(recovery[0])); err != nil {
+		t.Errorf("Expected a recovery code to work, got %v", err)
+	}
+	if err := twoFactor.Verify(user.ID, recovery[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected a used recovery code to be refused, got %v", err)
+	}
+	if err := twoFactor.Disable(user.ID, user.Role, recovery[1]); !errors.Is(err, ErrTwoFactorMandatory) {
+		t.Errorf("Expected verifiers not to be able to disable 2FA, got %v", err)
+	}
+
+	challenge, err = twoFactor.Challenge(user)
+	if err != nil || challenge == nil || challenge.Step != models.TwoFactorStepVerify {
+		t.Errorf("Expected an enrolled user to get the verify step, got %+v, %v", challenge, err)
+	}
+}
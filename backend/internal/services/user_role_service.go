@@ -0,0 +1,127 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+var (
+	ErrInvalidRole    = errors.New("invalid role")
+	ErrUserNotFound   = errors.New("user not found")
+	ErrLastSuperAdmin = errors.New("cannot remove the last super admin")
+)
+
+// GetRoles lists every role with its permissions
+func GetRoles() []models.RoleInfo {
+	roles := []models.RoleInfo{}
+	for _, role := range auth.Roles() {
+		roles = append(roles, models.RoleInfo{
+			Role:            role,
+			Permissions:     auth.RolePermissions(role),
+			SelfRegistrable: auth.IsSelfRegistrableRole(role),
+		})
+	}
+	return roles
+}
+
+// GetUsersByRole lists users with their role. An empty role lists
+// everyone.
+func GetUsersByRole(db *sql.DB, role string) ([]models.UserResponse, error) {
+	if role != "" && !auth.IsValidRole(role) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRole, role)
+	}
+
+	query := `SELECT id, username, email, full_name, role, created_at, updated_at
+			  FROM users
+			  WHERE ($1 = '' OR role = $1)
+			  ORDER BY username`
+
+	rows, err := db.Query(query, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %v", err)
+	}
+	defer rows.Close()
+
+	users := []models.UserResponse{}
+	for rows.Next() {
+		var u models.UserResponse
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.FullName, &u.Role, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// AssignUserRole gives a user a new role. The last super admin cannot be
+// demoted, so the system always keeps someone who can manage roles.
+func AssignUserRole(db *sql.DB, actorID, userID int, role string) (*models.UserResponse, error) {
+	if !auth.IsValidRole(role) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRole, role)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRow("SELECT role FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+
+	if current == auth.RoleSuperAdmin && role != auth.RoleSuperAdmin {
+		// Lock every super admin row so two demotions cannot race
+		rows, err := tx.Query("SELECT id FROM users WHERE role = $1 FOR UPDATE", auth.RoleSuperAdmin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count super admins: %v", err)
+		}
+		superAdmins := 0
+		for rows.Next() {
+			superAdmins++
+		}
+		rows.Close()
+		if superAdmins <= 1 {
+			return nil, ErrLastSuperAdmin
+		}
+	}
+
+	var u models.UserResponse
+	err = tx.QueryRow(`UPDATE users SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2
+					   RETURNING id, username, email, full_name, role, created_at, updated_at`, role, userID).
+		Scan(&u.ID, &u.Username, &u.Email, &u.FullName, &u.Role, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update role: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit role change: %v", err)
+	}
+
+	log.Printf("Role changed - UserID: %d, From: %s, To: %s, By: %d", userID, current, role, actorID)
+	return &u, nil
+}
+
+// GetUserIDByLogin finds a user by username or email
+func GetUserIDByLogin(db *sql.DB, login string) (int, error) {
+	var id int
+	err := db.QueryRow("SELECT id FROM users WHERE username = $1 OR email = $1", login).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user: %v", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+func TestSelfRegistrationCannotGrantPrivilegedRoles(t *testing.T) {
+	privileged := []string{auth.RoleSuperAdmin, auth.RoleVerifier, auth.RoleDispatcher, auth.RoleFinance, auth.RoleViewer, "admin", "root"}
+	for _, role := range privileged {
+		req := models.RegisterRequest{Username: "mallory", Email: "mallory@example.com", Password: "password123", FullName: "Mallory", Role: role}
+		// Rejected before the database is touched
+		if _, err := auth.CreateUser(nil, req); !errors.Is(err, auth.ErrRoleNotAllowed) {
+			t.Errorf("Expected role %q to be refused at registration, got %v", role, err)
+		}
+	}
+
+	if !auth.IsSelfRegistrableRole(auth.DefaultRole) {
+		t.Error("Expected the default role to be self-registrable")
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	if !auth.HasPermission(auth.RoleVerifier, auth.PermVehiclesVerify) {
+		t.Error("Expected verifiers to verify vehicles")
+	}
+	if auth.HasPermission(auth.RoleDispatcher, auth.PermVehiclesVerify) {
+		t.Error("Expected dispatchers not to verify vehicles")
+	}
+	if auth.HasPermission(auth.RoleFleetOwner, auth.PermTenantsAll) || auth.HasPermission(auth.RoleDriver, auth.PermTenantsAll) {
+		t.Error("Expected fleet owners and drivers to be limited to their own tenant")
+	}
+	if auth.HasPermission("admin", auth.PermVehiclesRead) || auth.HasPermission("", auth.PermVehiclesRead) {
+		t.Error("Expected unknown roles to grant nothing")
+	}
+
+	for _, role := range GetRoles() {
+		if role.Role != auth.RoleSuperAdmin && auth.HasPermission(role.Role, auth.PermUsersManage) {
+			t.Errorf("Expected only super admins to manage roles, %s can", role.Role)
+		}
+	}
+	superAdmin := auth.RolePermissions(auth.RoleSuperAdmin)
+	if len(superAdmin) == 0 || !auth.HasPermission(auth.RoleSuperAdmin, auth.PermUsersManage) {
+		t.Errorf("Expected super admins to hold every permission, got %v", superAdmin)
+	}
+}
+
+// sqlRole matches a role compared with literals in SQL: role = 'x',
+// role IN ('x', 'y')
+var sqlRole = regexp.MustCompile(`(?i)\brole\s*(?:=|<>|!=)\s*'[^']*'|\brole\s+(?:NOT\s+)?IN\s*\([^)]*\)`)
+
+var sqlLiteral = regexp.MustCompile(`'([^']*)'`)
+
+// goRole matches a role compared with a literal in Go: role == "x"
+var goRole = regexp.MustCompile(`(?i)role"?\)?\s*[!=]=\s*"([^"]*)"`)
+
+func TestNoRemovedRoles(t *testing.T) {
+	// A role compared with one that does not exist, such as the old
+	// "admin", matches nobody
+	dirs := []string{".", "../repository", "../handlers", "../middleware", "../auth", "../../cmd/server"}
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			if strings.HasSuffix(file, "_test.go") {
+				continue
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var roles []string
+			for _, match := range sqlRole.FindAllString(string(data), -1) {
+				for _, literal := range sqlLiteral.FindAllStringSubmatch(match, -1) {
+					roles = append(roles, literal[1])
+				}
+			}
+			for _, match := range goRole.FindAllStringSubmatch(string(data), -1) {
+				roles = append(roles, match[1])
+			}
+			for _, role := range roles {
+				if role != "" && !auth.IsValidRole(role) {
+					t.Errorf("%s compares a role with %q, which is not a role", file, role)
+				}
+			}
+		}
+	}
+}
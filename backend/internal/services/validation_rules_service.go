@@ -0,0 +1,241 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+// Automatic checks of a vehicle submission, in the order they run
+const (
+	CheckDocumentCompleteness = "document_completeness"
+	CheckPlateFormat          = "plate_format"
+	CheckVINFormat            = "vin_format"
+	CheckDuplicate            = "duplicate_check"
+	CheckDocumentExpiry       = "document_expiry"
+	CheckDocumentOCR          = "document_ocr"
+)
+
+var validationCheckTypes = []string{
+	CheckDocumentCompleteness, CheckPlateFormat, CheckVINFormat,
+	CheckDuplicate, CheckDocumentExpiry, CheckDocumentOCR,
+}
+
+const (
+	defaultDryRunLimit = 50
+	maxDryRunLimit     = 500
+)
+
+// DefaultValidationRules are in force until an admin saves rules: every
+// check enabled with the same weight, STNK, BPKB and a front photo
+// required of every vehicle, approval from 0.8 without duplicates with one
+// in ten approvals sampled for QA, and no automatic rejection
+func DefaultValidationRules() *models.ValidationRules {
+	checks := make(map[string]models.ValidationRule, len(validationCheckTypes))
+	for _, checkType := range validationCheckTypes {
+		checks[checkType] = models.ValidationRule{Enabled: true, Weight: 1}
+	}
+	return &models.ValidationRules{
+		RequiredDocuments: map[string][]string{"default": {"stnk", "bpkb", "foto_depan"}},
+		Checks:            checks,
+		AutoApproveScore:  0.8,
+		AutoApproval: models.AutoApprovalPolicy{
+			Enabled:      true,
+			CrossChecks:  []string{"duplicate"},
+			QASampleRate: 0.1,
+		},
+	}
+}
+
+// CheckValidationRules reports what is wrong with rules an admin wants to
+// save
+func CheckValidationRules(rules *models.ValidationRules) error {
+	if len(rules.RequiredDocuments["default"]) == 0 {
+		return fmt.Errorf("required_documents needs a \"default\" entry")
+	}
+	for vehicleType, docs := range rules.RequiredDocuments {
+		for _, doc := range docs {
+			if !isAllowedAttachmentType(doc) {
+				return fmt.Errorf("unknown document type %q for %s; use one of %s",
+					doc, vehicleType, strings.Join(allowedAttachmentTypes, ", "))
+			}
+		}
+	}
+
+	for checkType, rule := range rules.Checks {
+		if !isValidationCheckType(checkType) {
+			return fmt.Errorf("unknown check %q; use one of %s", checkType, strings.Join(validationCheckTypes, ", "))
+		}
+		if rule.Weight < 0 || math.IsNaN(rule.Weight) || math.IsInf(rule.Weight, 0) {
+			return fmt.Errorf("weight of %s must be zero or more", checkType)
+		}
+	}
+	// The score is a weighted mean of the enabled checks, so it needs some
+	// weight to be anything
+	var totalWeight float64
+	for _, checkType := range validationCheckTypes {
+		if rule := ruleFor(rules, checkType); rule.Enabled {
+			totalWeight += rule.Weight
+		}
+	}
+	if totalWeight == 0 {
+		return fmt.Errorf("enabled checks need a total weight above 0")
+	}
+
+	if rules.AutoApproveScore <= 0 || rules.AutoApproveScore > 1 {
+		return fmt.Errorf("auto_approve_score must be above 0 and at most 1")
+	}
+	if rules.AutoRejectScore < 0 || rules.AutoRejectScore >= rules.AutoApproveScore {
+		return fmt.Errorf("auto_reject_score must be at least 0 and below auto_approve_score")
+	}
+
+	for _, check := range rules.AutoApproval.CrossChecks {
+		if !isApprovalCrossCheck(check) {
+			return fmt.Errorf("unknown cross-check %q; use one of %s", check, strings.Join(approvalCrossChecks, ", "))
+		}
+	}
+	if rules.AutoApproval.QASampleRate < 0 || rules.AutoApproval.QASampleRate > 1 {
+		return fmt.Errorf("qa_sample_rate must be between 0 and 1")
+	}
+	return nil
+}
+
+func isValidationCheckType(checkType string) bool {
+	for _, t := range validationCheckTypes {
+		if t == checkType {
+			return true
+		}
+	}
+	return false
+}
+
+func isAllowedAttachmentType(attachmentType string) bool {
+	for _, t := range allowedAttachmentTypes {
+		if t == attachmentType {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleFor is the rule of a check; a check the rules do not mention runs
+// with weight 1
+func ruleFor(rules *models.ValidationRules, checkType string) models.ValidationRule {
+	if rule, ok := rules.Checks[checkType]; ok {
+		return rule
+	}
+	return models.ValidationRule{Enabled: true, Weight: 1}
+}
+
+// requiredDocuments are the attachment types a vehicle type needs
+func requiredDocuments(rules *models.ValidationRules, vehicleType string) []string {
+	if docs, ok := rules.RequiredDocuments[vehicleType]; ok {
+		return docs
+	}
+	return rules.RequiredDocuments["default"]
+}
+
+type ValidationRulesService struct {
+	db   *sql.DB
+	repo *repository.ValidationRulesRepository
+}
+
+func NewValidationRulesService(db *sql.DB) *ValidationRulesService {
+	return &ValidationRulesService{db: db, repo: repository.NewValidationRulesRepository(db)}
+}
+
+// Active returns the rules in force, the defaults (version 0) when none
+// were saved
+func (s *ValidationRulesService) Active() (*models.ValidationRules, error) {
+	rules, err := s.repo.Latest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load validation rules: %v", err)
+	}
+	if rules == nil {
+		return DefaultValidationRules(), nil
+	}
+	return rules, nil
+}
+
+// Save puts rules in force as a new version. Check them with
+// CheckValidationRules first.
+func (s *ValidationRulesService) Save(rules *models.ValidationRules, adminID int) error {
+	rules.CreatedBy = &adminID
+	if err := s.repo.Create(rules); err != nil {
+		return fmt.Errorf("failed to save validation rules: %v", err)
+	}
+	return nil
+}
+
+// DryRun runs the last limit submissions through the rules in force and
+// through proposed, without saving anything
+func (s *ValidationRulesService) DryRun(proposed *models.ValidationRules, limit int) (*models.ValidationDryRun, error) {
+	if limit <= 0 {
+		limit = defaultDryRunLimit
+	}
+	if limit > maxDryRunLimit {
+		limit = maxDryRunLimit
+	}
+	current, err := s.Active()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT id, registration_number, vehicle_type, created_at
+							 FROM vehicles
+							 ORDER BY created_at DESC, id DESC
+							 LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get submissions: %v", err)
+	}
+	var vehicles []models.ValidationDryRunVehicle
+	for rows.Next() {
+		var v models.ValidationDryRunVehicle
+		if err := rows.Scan(&v.VehicleID, &v.RegistrationNumber, &v.VehicleType, &v.SubmittedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to get submissions: %v", err)
+		}
+		vehicles = append(vehicles, v)
+	}
+	rows.Close()
+
+	validator := NewAutoValidationService(s.db)
+	dryRun := &models.ValidationDryRun{
+		CurrentVersion: current.Version,
+		Before:         map[string]int{},
+		After:          map[string]int{},
+		Vehicles:       []models.ValidationDryRunVehicle{},
+	}
+	for _, v := range vehicles {
+		before, err := validator.Evaluate(v.VehicleID, current)
+		if err != nil {
+			return nil, err
+		}
+		after, err := validator.Evaluate(v.VehicleID, proposed)
+		if err != nil {
+			return nil, err
+		}
+
+		v.CurrentStatus, v.CurrentScore = before.OverallStatus, before.ConfidenceScore
+		v.ProposedStatus, v.ProposedScore = after.OverallStatus, after.ConfidenceScore
+		v.Changed = v.CurrentStatus != v.ProposedStatus
+		dryRun.Before[v.CurrentStatus]++
+		dryRun.After[v.ProposedStatus]++
+		if v.Changed {
+			dryRun.Changed++
+		}
+		dryRun.Vehicles = append(dryRun.Vehicles, v)
+	}
+	dryRun.Submissions = len(dryRun.Vehicles)
+
+	// Changed submissions first, then newest first as listed
+	sort.SliceStable(dryRun.Vehicles, func(i, j int) bool {
+		return dryRun.Vehicles[i].Changed && !dryRun.Vehicles[j].Changed
+	})
+	return dryRun, nil
+}
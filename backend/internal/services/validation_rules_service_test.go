@@ -0,0 +1,119 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+)
+
+func TestCheckValidationRules(t *testing.T) {
+	if err := CheckValidationRules(DefaultValidationRules()); err != nil {
+		t.Fatalf("Expected the defaults to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(r *models.ValidationRules)
+		want   string
+	}{
+		{"no default documents", func(r *models.ValidationRules) { delete(r.RequiredDocuments, "default") }, `"default"`},
+		{"unknown document", func(r *models.ValidationRules) { r.RequiredDocuments["truk"] = []string{"sim"} }, `"sim"`},
+		{"unknown check", func(r *models.ValidationRules) { r.Checks["face_match"] = models.ValidationRule{Enabled: true} }, `"face_match"`},
+		{"negative weight", func(r *models.ValidationRules) {
+			r.Checks[CheckPlateFormat] = models.ValidationRule{Enabled: true, Weight: -1}
+		}, "weight of plate_format"},
+		{"no weight", func(r *models.ValidationRules) {
+			for _, checkType := range validationCheckTypes {
+				r.Checks[checkType] = models.ValidationRule{Enabled: checkType == CheckPlateFormat}
+			}
+		}, "total weight"},
+		{"approve above 1", func(r *models.ValidationRules) { r.AutoApproveScore = 1.5 }, "auto_approve_score"},
+		{"reject above approve", func(r *models.ValidationRules) { r.AutoRejectScore = 0.9 }, "auto_reject_score"},
+		{"unknown cross-check", func(r *models.ValidationRules) {
+			r.AutoApproval.CrossChecks = []string{"samsat", "bpkb"}
+		}, `"bpkb"`},
+		{"sample rate above 1", func(r *models.ValidationRules) { r.AutoApproval.QASampleRate = 1.1 }, "qa_sample_rate"},
+	}
+	for _, tt := range tests {
+		rules := DefaultValidationRules()
+		tt.change(rules)
+		err := CheckValidationRules(rules)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error mentioning %s, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestValidationScoring(t *testing.T) {
+	svc := &AutoValidationService{}
+	checks := []models.ValidationCheck{
+		{Type: CheckDocumentCompleteness, Status: "passed", Confidence: 1.0},
+		{Type: CheckPlateFormat, Status: "passed", Confidence: 0.9},
+		{Type: CheckDocumentExpiry, Status: "warning", Confidence: 0.5},
+	}
+
+	// Equal weights: (1 + 0.9 + 0.25) / 3
+	rules := DefaultValidationRules()
+	score := svc.calculateConfidenceScore(checks, rules)
+	if math.Abs(score-2.15/3) > 1e-9 {
+		t.Errorf("Expected the mean score, got %v", score)
+	}
+	if status := svc.determineOverallStatus(checks, score, rules); status != "under_review" {
+		t.Errorf("Expected a warning to need review, got %s", status)
+	}
+
+	// A weight of 0 leaves the expiry out of the score
+	rules.Checks[CheckDocumentExpiry] = models.ValidationRule{Enabled: true, Weight: 0}
+	rules.Checks[CheckDocumentCompleteness] = models.ValidationRule{Enabled: true, Weight: 3}
+	score = svc.calculateConfidenceScore(checks, rules)
+	if math.Abs(score-3.9/4) > 1e-9 {
+		t.Errorf("Expected the weighted score, got %v", score)
+	}
+	if status := svc.determineOverallStatus(checks[:2], score, rules); status != "auto_approved" {
+		t.Errorf("Expected approval above the threshold, got %s", status)
+	}
+	rules.AutoApproveScore = 0.99
+	if status := svc.determineOverallStatus(checks[:2], score, rules); status != "under_review" {
+		t.Errorf("Expected review below the threshold, got %s", status)
+	}
+
+	failed := []models.ValidationCheck{{Type: CheckDuplicate, Status: "failed"}, {Type: CheckPlateFormat, Status: "passed", Confidence: 0.9}}
+	score = svc.calculateConfidenceScore(failed, rules)
+	if status := svc.determineOverallStatus(failed, score, rules); status != "needs_correction" {
+		t.Errorf("Expected a failed check to need correction, got %s", status)
+	}
+	rules.AutoRejectScore = 0.5
+	if status := svc.determineOverallStatus(failed, score, rules); status != "auto_rejected" {
+		t.Errorf("Expected rejection below %v, got %s at %v", rules.AutoRejectScore, status, score)
+	}
+
+	// Missing documents are corrected, never rejected
+	incomplete := append(failed, models.ValidationCheck{Type: CheckDocumentCompleteness, Status: "failed"})
+	score = svc.calculateConfidenceScore(incomplete, rules)
+	if status := svc.determineOverallStatus(incomplete, score, rules); status != "needs_correction" {
+		t.Errorf("Expected an incomplete submission to need correction, got %s at %v", status, score)
+	}
+
+	if score := svc.calculateConfidenceScore(nil, rules); score != 0 {
+		t.Errorf("Expected 0 without checks, got %v", score)
+	}
+}
+
+func TestRequiredDocumentsByVehicleType(t *testing.T) {
+	svc := &AutoValidationService{}
+	rules := DefaultValidationRules()
+	rules.RequiredDocuments["truk"] = []string{"stnk", "bpkb", "uji_kir"}
+	attachments := []map[string]interface{}{
+		{"attachment_type": "stnk"}, {"attachment_type": "bpkb"}, {"attachment_type": "foto_depan"},
+	}
+
+	if check := svc.validateDocumentCompleteness(attachments, requiredDocuments(rules, "pickup")); check.Status != "passed" {
+		t.Errorf("Expected the default documents to be complete, got %+v", check)
+	}
+	check := svc.validateDocumentCompleteness(attachments, requiredDocuments(rules, "truk"))
+	if check.Status != "failed" || check.Message != "Dokumen yang hilang: uji_kir" {
+		t.Errorf("Expected a truck to need its KIR, got %+v", check)
+	}
+}
@@ -9,9 +9,10 @@ import (
 
 	"github.com/youruser/aplikasi-tms/backend/internal/middleware"
 	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/registry"
 )
 
-func CreateVehicle(db *sql.DB, req models.VehicleRequest, userID int) (*models.Vehicle, error) {
+func CreateVehicle(db *sql.DB, registries map[string]registry.Provider, req models.VehicleRequest, userID int) (*models.Vehicle, error) {
 	// Check if registration number already exists
 	var exists bool
 	checkQuery := "SELECT EXISTS(SELECT 1 FROM vehicles WHERE registration_number = $1 OR chassis_number = $2)"
@@ -94,9 +95,9 @@ func CreateVehicle(db *sql.DB, req models.VehicleRequest, userID int) (*models.V
 			log.Printf("Failed to send submission notification: %v", err)
 		}
 
-		// Run auto validation
-		autoValidator := NewAutoValidationService(db)
-		result, err := autoValidator.ValidateVehicle(vehicle.ID)
+		// Run auto validation; approvals and rejections it makes notify the
+		// owner themselves
+		result, decided, err := NewAutoApprovalService(db, registries).Process(vehicle.ID)
 		if err != nil {
 			log.Printf("Auto validation failed for vehicle %d: %v", vehicle.ID, err)
 			return
@@ -106,8 +107,9 @@ func CreateVehicle(db *sql.DB, req models.VehicleRequest, userID int) (*models.V
 		var templateKey string
 		var extraVars map[string]interface{}
 
-		switch result.OverallStatus {
-		case "needs_correction":
+		switch {
+		case decided != "":
+		case result.OverallStatus == "needs_correction":
 			templateKey = "needs_correction"
 			correctionItems := []string{}
 			for _, check := range result.Checks {
@@ -118,14 +120,8 @@ func CreateVehicle(db *sql.DB, req models.VehicleRequest, userID int) (*models.V
 			extraVars = map[string]interface{}{
 				"correction_items": strings.Join(correctionItems, ", "),
 			}
-		case "under_review":
+		default:
 			templateKey = "under_review"
-		case "auto_approved":
-			// Update status to approved
-			_, err = db.Exec("UPDATE vehicles SET verification_status = 'approved', operational_status = 'active' WHERE id = $1", vehicle.ID)
-			if err == nil {
-				templateKey = "approved"
-			}
 		}
 
 		if templateKey != "" {
@@ -139,15 +135,17 @@ func CreateVehicle(db *sql.DB, req models.VehicleRequest, userID int) (*models.V
 	return &vehicle, nil
 }
 
-func GetVehicles(db *sql.DB) ([]models.Vehicle, error) {
+func GetVehicles(db *sql.DB, scope models.TenantScope) ([]models.Vehicle, error) {
 	query := `SELECT id, registration_number, vehicle_type, brand, model, year,
 		chassis_number, engine_number, color, capacity_weight, capacity_volume,
 		ownership_status, operational_status, insurance_company, insurance_policy_number,
 		insurance_expiry_date, last_maintenance_date, next_maintenance_date,
-		maintenance_notes, created_by, created_at, updated_at
-		FROM vehicles ORDER BY created_at DESC`
+		maintenance_notes, created_by, fleet_owner_id, created_at, updated_at
+		FROM vehicles
+		WHERE ($1 = 0 OR fleet_owner_id = $1)
+		ORDER BY created_at DESC`
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, scope.FilterID())
 	if err != nil {
 		log.Printf("Database error: failed to get vehicles: %v", middleware.SanitizeForLog(err.Error()))
 		return nil, fmt.Errorf("failed to get vehicles: %v", err)
@@ -162,7 +160,7 @@ func GetVehicles(db *sql.DB) ([]models.Vehicle, error) {
 			&v.ChassisNumber, &v.EngineNumber, &v.Color, &v.CapacityWeight, &v.CapacityVolume,
 			&v.OwnershipStatus, &v.OperationalStatus, &v.InsuranceCompany, &v.InsurancePolicyNumber,
 			&v.InsuranceExpiryDate, &v.LastMaintenanceDate, &v.NextMaintenanceDate,
-			&v.MaintenanceNotes, &v.CreatedBy, &v.CreatedAt, &v.UpdatedAt,
+			&v.MaintenanceNotes, &v.CreatedBy, &v.FleetOwnerID, &v.CreatedAt, &v.UpdatedAt,
 		)
 		if err != nil {
 			log.Printf("Database error: failed to scan vehicle row: %v", middleware.SanitizeForLog(err.Error()))
@@ -178,21 +176,23 @@ func GetVehicles(db *sql.DB) ([]models.Vehicle, error) {
 	return vehicles, nil
 }
 
-func GetVehicleByID(db *sql.DB, id int) (*models.Vehicle, error) {
+// GetVehicleByID returns a vehicle visible in scope; other tenants'
+// vehicles are reported as not found
+func GetVehicleByID(db *sql.DB, scope models.TenantScope, id int) (*models.Vehicle, error) {
 	query := `SELECT id, registration_number, vehicle_type, brand, model, year,
 		chassis_number, engine_number, color, capacity_weight, capacity_volume,
 		ownership_status, operational_status, insurance_company, insurance_policy_number,
 		insurance_expiry_date, last_maintenance_date, next_maintenance_date,
-		maintenance_notes, created_by, created_at, updated_at
-		FROM vehicles WHERE id = $1`
+		maintenance_notes, created_by, fleet_owner_id, created_at, updated_at
+		FROM vehicles WHERE id = $1 AND ($2 = 0 OR fleet_owner_id = $2)`
 
 	var v models.Vehicle
-	err := db.QueryRow(query, id).Scan(
+	err := db.QueryRow(query, id, scope.FilterID()).Scan(
 		&v.ID, &v.RegistrationNumber, &v.VehicleType, &v.Brand, &v.Model, &v.Year,
 		&v.ChassisNumber, &v.EngineNumber, &v.Color, &v.CapacityWeight, &v.CapacityVolume,
 		&v.OwnershipStatus, &v.OperationalStatus, &v.InsuranceCompany, &v.InsurancePolicyNumber,
 		&v.InsuranceExpiryDate, &v.LastMaintenanceDate, &v.NextMaintenanceDate,
-		&v.MaintenanceNotes, &v.CreatedBy, &v.CreatedAt, &v.UpdatedAt,
+		&v.MaintenanceNotes, &v.CreatedBy, &v.FleetOwnerID, &v.CreatedAt, &v.UpdatedAt,
 	)
 
 	if err != nil {
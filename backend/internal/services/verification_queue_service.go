@@ -0,0 +1,370 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/auth"
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+var (
+	ErrQueueEmpty       = errors.New("no submission is waiting")
+	ErrQueueUnavailable = errors.New("submission is not waiting or is held by another verifier")
+	ErrQueueNotHeld     = errors.New("submission is not held")
+	// ErrClaimedByOther refuses a decision on a submission another verifier
+	// holds
+	ErrClaimedByOther = errors.New("submission is held by another verifier")
+)
+
+// SLA states of a queued submission
+const (
+	SLAOnTrack  = "on_track"
+	SLADueSoon  = "due_soon"
+	SLABreached = "breached"
+)
+
+const (
+	defaultWorkloadDays = 7
+	maxWorkloadDays     = 90
+	// maxAssignPerRun bounds one round of round-robin assignment
+	maxAssignPerRun = 500
+)
+
+// stageLabels name the queue stages in escalation messages
+var stageLabels = map[string]string{
+	"pending":            "menunggu verifikasi",
+	"under_review":       "peninjauan",
+	"pending_inspection": "menunggu inspeksi",
+}
+
+// queueRepository keeps the queue: the database, or a fake in tests
+type queueRepository interface {
+	Items(now time.Time, verifierID int) ([]models.QueueItem, error)
+	Claim(vehicleID, verifierID int, method string, now, expiresAt time.Time) (bool, error)
+	Next(now time.Time, sla repository.StageSLA, skip []int) (int, error)
+	Release(vehicleID, verifierID int, now time.Time) (bool, error)
+	Breached(now time.Time, sla repository.StageSLA) ([]models.QueueItem, error)
+	MarkEscalated(vehicleID int, stageEnteredAt, now time.Time) (bool, error)
+	Users(roles []string) ([]models.Verifier, error)
+	Throughput(since time.Time) (map[int]*models.VerifierWorkload, error)
+}
+
+// VerificationQueueService hands vehicle submissions to verifiers, by
+// claim or in turn, and escalates those that wait past their stage's SLA
+type VerificationQueueService struct {
+	db            *sql.DB
+	repo          queueRepository
+	cfg           *config.VerificationQueueConfig
+	notifications *NotificationService
+	now           func() time.Time
+}
+
+func NewVerificationQueueService(db *sql.DB, cfg *config.VerificationQueueConfig) *VerificationQueueService {
+	return &VerificationQueueService{
+		db:            db,
+		repo:          repository.NewVerificationQueueRepository(db),
+		cfg:           cfg,
+		notifications: NewNotificationService(db),
+		now:           func() time.Time { return time.Now().UTC() },
+	}
+}
+
+func (s *VerificationQueueService) stageSLA() repository.StageSLA {
+	return repository.StageSLA{s.cfg.SLA["pending"], s.cfg.SLA["under_review"], s.cfg.SLA["pending_inspection"]}
+}
+
+// slaStatus is the deadline of a stage entered at entered and how it
+// stands at now: due soon in the last quarter of the SLA
+func slaStatus(entered time.Time, sla time.Duration, now time.Time) (time.Time, string) {
+	deadline := entered.Add(sla)
+	switch {
+	case !now.Before(deadline):
+		return deadline, SLABreached
+	case deadline.Sub(now) <= sla/4:
+		return deadline, SLADueSoon
+	default:
+		return deadline, SLAOnTrack
+	}
+}
+
+// verifierTurns orders verifiers for round-robin assignment: whoever was
+// handed work longest ago, or never, goes first
+func verifierTurns(verifiers []models.Verifier) []models.Verifier {
+	turns := append([]models.Verifier(nil), verifiers...)
+	sort.SliceStable(turns, func(i, j int) bool {
+		a, b := turns[i].LastAssignedAt, turns[j].LastAssignedAt
+		switch {
+		case a == nil && b == nil:
+			return turns[i].ID < turns[j].ID
+		case a == nil || b == nil:
+			return a == nil
+		case !a.Equal(*b):
+			return a.Before(*b)
+		default:
+			return turns[i].ID < turns[j].ID
+		}
+	})
+	return turns
+}
+
+// Items lists the queue, or what verifierID holds when it is not 0, the
+// closest deadline first
+func (s *VerificationQueueService) Items(verifierID int) ([]models.QueueItem, error) {
+	now := s.now()
+	items, err := s.repo.Items(now, verifierID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verification queue: %v", err)
+	}
+	for i := range items {
+		items[i].Deadline, items[i].SLAStatus = slaStatus(items[i].StageEnteredAt, s.cfg.SLA[items[i].Stage], now)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Deadline.Before(items[j].Deadline) })
+	return items, nil
+}
+
+// Claim gives a submission to verifierID for ClaimTTL; claiming it again
+// renews the claim
+func (s *VerificationQueueService) Claim(vehicleID, verifierID int) (*models.QueueItem, error) {
+	now := s.now()
+	claimed, err := s.repo.Claim(vehicleID, verifierID, config.AssignmentClaim, now, now.Add(s.cfg.ClaimTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim submission: %v", err)
+	}
+	if !claimed {
+		return nil, ErrQueueUnavailable
+	}
+	return s.held(vehicleID, verifierID)
+}
+
+// ClaimNext gives verifierID the unassigned submission whose SLA ends
+// first
+func (s *VerificationQueueService) ClaimNext(verifierID int) (*models.QueueItem, error) {
+	var tried []int
+	for {
+		now := s.now()
+		vehicleID, err := s.repo.Next(now, s.stageSLA(), tried)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next submission: %v", err)
+		}
+		if vehicleID == 0 {
+			return nil, ErrQueueEmpty
+		}
+
+		// Another verifier may take it first; try the next one
+		item, err := s.Claim(vehicleID, verifierID)
+		if !errors.Is(err, ErrQueueUnavailable) {
+			return item, err
+		}
+		tried = append(tried, vehicleID)
+	}
+}
+
+func (s *VerificationQueueService) held(vehicleID, verifierID int) (*models.QueueItem, error) {
+	items, err := s.Items(verifierID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if items[i].VehicleID == vehicleID {
+			return &items[i], nil
+		}
+	}
+	return nil, ErrQueueUnavailable
+}
+
+// Release puts a submission verifierID holds back in the queue; 0 releases
+// it whoever holds it
+func (s *VerificationQueueService) Release(vehicleID, verifierID int) error {
+	released, err := s.repo.Release(vehicleID, verifierID, s.now())
+	if err != nil {
+		return fmt.Errorf("failed to release submission: %v", err)
+	}
+	if !released {
+		return ErrQueueNotHeld
+	}
+	return nil
+}
+
+// AssignRoundRobin hands every unassigned submission, closest deadline
+// first, to the verifiers in turn for AssignTTL. It returns how many it
+// assigned.
+func (s *VerificationQueueService) AssignRoundRobin() (int, error) {
+	verifiers, err := s.repo.Users([]string{auth.RoleVerifier})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get verifiers: %v", err)
+	}
+	if len(verifiers) == 0 {
+		return 0, nil
+	}
+	turns := verifierTurns(verifiers)
+
+	assigned := 0
+	var tried []int
+	for len(tried) < maxAssignPerRun {
+		now := s.now()
+		vehicleID, err := s.repo.Next(now, s.stageSLA(), tried)
+		if err != nil {
+			return assigned, fmt.Errorf("failed to get next submission: %v", err)
+		}
+		if vehicleID == 0 {
+			break
+		}
+		tried = append(tried, vehicleID)
+
+		verifier := turns[assigned%len(turns)]
+		ok, err := s.repo.Claim(vehicleID, verifier.ID, config.AssignmentRoundRobin, now, now.Add(s.cfg.AssignTTL))
+		if err != nil {
+			return assigned, fmt.Errorf("failed to assign submission: %v", err)
+		}
+		if ok {
+			assigned++
+		}
+	}
+	return assigned, nil
+}
+
+// EscalateBreaches notifies the supervisors, once per stage, of every
+// submission waiting past its SLA. It returns how many it escalated.
+func (s *VerificationQueueService) EscalateBreaches() (int, error) {
+	now := s.now()
+	breached, err := s.repo.Breached(now, s.stageSLA())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get SLA breaches: %v", err)
+	}
+	if len(breached) == 0 {
+		return 0, nil
+	}
+
+	supervisors, err := s.repo.Users(auth.RolesWithPermission(auth.PermVerificationQueueManage))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get supervisors: %v", err)
+	}
+	supervisorIDs := make([]int, 0, len(supervisors))
+	for _, supervisor := range supervisors {
+		supervisorIDs = append(supervisorIDs, supervisor.ID)
+	}
+
+	escalated := 0
+	for _, item := range breached {
+		marked, err := s.repo.MarkEscalated(item.VehicleID, item.StageEnteredAt, now)
+		if err != nil {
+			return escalated, fmt.Errorf("failed to mark SLA breach: %v", err)
+		}
+		if !marked {
+			continue
+		}
+		escalated++
+
+		verifier := item.AssignedName
+		if verifier == "" {
+			verifier = "belum ada"
+		}
+		err = s.notifications.SendUserNotification(supervisorIDs, "verification_sla_breached", map[string]interface{}{
+			"plate":          item.RegistrationNumber,
+			"application_id": fmt.Sprintf("V-%d", item.VehicleID),
+			"stage":          stageLabels[item.Stage],
+			"hours_waiting":  int(now.Sub(item.StageEnteredAt).Hours()),
+			"sla_hours":      int(s.cfg.SLA[item.Stage].Hours()),
+			"verifier":       verifier,
+		})
+		if err != nil {
+			log.Printf("Failed to send SLA escalation for vehicle %d: %v", item.VehicleID, err)
+		}
+	}
+	return escalated, nil
+}
+
+// Workload reports what each verifier holds now and what they did over
+// the last days days
+func (s *VerificationQueueService) Workload(days int) (*models.VerificationWorkload, error) {
+	if days <= 0 {
+		days = defaultWorkloadDays
+	}
+	if days > maxWorkloadDays {
+		days = maxWorkloadDays
+	}
+
+	items, err := s.Items(0)
+	if err != nil {
+		return nil, err
+	}
+	verifiers, err := s.repo.Users(auth.RolesWithPermission(auth.PermVehiclesVerify))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verifiers: %v", err)
+	}
+	throughput, err := s.repo.Throughput(s.now().AddDate(0, 0, -days))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verifier throughput: %v", err)
+	}
+
+	workload := &models.VerificationWorkload{Days: days, Queued: len(items), Verifiers: []models.VerifierWorkload{}}
+	held := make(map[int]*models.VerifierWorkload)
+	for _, verifier := range verifiers {
+		w := models.VerifierWorkload{Verifier: verifier}
+		if counts := throughput[verifier.ID]; counts != nil {
+			w.Claimed, w.Decided = counts.Claimed, counts.Decided
+			w.Approved, w.Rejected, w.NeedsCorrection = counts.Approved, counts.Rejected, counts.NeedsCorrection
+		}
+		w.DecidedPerDay = math.Round(float64(w.Decided)/float64(days)*100) / 100
+		workload.Verifiers = append(workload.Verifiers, w)
+	}
+	for i := range workload.Verifiers {
+		held[workload.Verifiers[i].ID] = &workload.Verifiers[i]
+	}
+
+	for _, item := range items {
+		breached := item.SLAStatus == SLABreached
+		if breached {
+			workload.Breached++
+		}
+		if item.AssignedTo == nil {
+			workload.Unassigned++
+			continue
+		}
+		if w := held[*item.AssignedTo]; w != nil {
+			w.Assigned++
+			if breached {
+				w.Breached++
+			}
+		}
+	}
+	return workload, nil
+}
+
+// StartWorker assigns work when assignment is round-robin and escalates
+// SLA breaches every CheckInterval until stop is closed
+func (s *VerificationQueueService) StartWorker(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.cfg.CheckInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if s.cfg.Assignment == config.AssignmentRoundRobin {
+					n, err := s.AssignRoundRobin()
+					if err != nil {
+						log.Printf("Verification assignment error: %v", err)
+					} else if n > 0 {
+						log.Printf("Verification queue assigned %d submission(s)", n)
+					}
+				}
+				n, err := s.EscalateBreaches()
+				if err != nil {
+					log.Printf("Verification SLA check error: %v", err)
+				} else if n > 0 {
+					log.Printf("Verification queue escalated %d SLA breach(es)", n)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/youruser/aplikasi-tms/backend/internal/config"
+	"github.com/youruser/aplikasi-tms/backend/internal/models"
+	"github.com/youruser/aplikasi-tms/backend/internal/repository"
+)
+
+func TestSLAStatus(t *testing.T) {
+	entered := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	sla := 24 * time.Hour
+
+	tests := []struct {
+		after time.Duration
+		want  string
+	}{
+		{time.Hour, SLAOnTrack},
+		{18 * time.Hour, SLADueSoon},
+		{24 * time.Hour, SLABreached},
+		{30 * time.Hour, SLABreached},
+	}
+	for _, tt := range tests {
+		deadline, status := slaStatus(entered, sla, entered.Add(tt.after))
+		if status != tt.want || !deadline.Equal(entered.Add(sla)) {
+			t.Errorf("After %v: expected %s by %v, got %s by %v", tt.after, tt.want, entered.Add(sla), status, deadline)
+		}
+	}
+}
+
+func TestVerifierTurns(t *testing.T) {
+	at := func(hour int) *time.Time {
+		t := time.Date(2025, 3, 1, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+	verifiers := []models.Verifier{
+		{ID: 1, LastAssignedAt: at(10)},
+		{ID: 2, LastAssignedAt: at(9)},
+		{ID: 3},
+		{ID: 4, LastAssignedAt: at(9)},
+		{ID: 5},
+	}
+
+	turns := verifierTurns(verifiers)
+	want := []int{3, 5, 2, 4, 1}
+	for i, v := range turns {
+		if v.ID != want[i] {
+			t.Fatalf("Expected turns %v, got verifier %d at %d", want, v.ID, i)
+		}
+	}
+	if verifiers[0].ID != 1 {
+		t.Error("Expected the verifiers not to be reordered in place")
+	}
+}
+
+type fakeQueueVehicle struct {
+	id         int
+	assignedTo int
+	expiresAt  time.Time
+	// raced is handed out by Next while another verifier holds it, as if
+	// they claimed it in between
+	raced bool
+}
+
+type fakeQueueAssignment struct {
+	vehicleID, verifierID int
+	method                string
+}
+
+// fakeQueueRepository keeps the queue in deadline order
+type fakeQueueRepository struct {
+	vehicles    []*fakeQueueVehicle
+	verifiers   []models.Verifier
+	assignments []fakeQueueAssignment
+}
+
+func (f *fakeQueueRepository) vehicle(id int) *fakeQueueVehicle {
+	for _, v := range f.vehicles {
+		if v.id == id {
+			return v
+		}
+	}
+	return nil
+}
+
+func (f *fakeQueueRepository) Items(now time.Time, verifierID int) ([]models.QueueItem, error) {
+	var items []models.QueueItem
+	for _, v := range f.vehicles {
+		held := v.assignedTo != 0 && v.expiresAt.After(now)
+		if verifierID != 0 && (!held || v.assignedTo != verifierID) {
+			continue
+		}
+		item := models.QueueItem{VehicleID: v.id, Stage: "pending", StageEnteredAt: now.Add(-time.Hour)}
+		if held {
+			assignedTo, expiresAt := v.assignedTo, v.expiresAt
+			item.AssignedTo, item.AssignmentExpiresAt = &assignedTo, &expiresAt
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (f *fakeQueueRepository) Claim(vehicleID, verifierID int, method string, now, expiresAt time.Time) (bool, error) {
+	v := f.vehicle(vehicleID)
+	if v == nil {
+		return false, nil
+	}
+	held := v.assignedTo != 0 && v.expiresAt.After(now)
+	if held && v.assignedTo != verifierID {
+		return false, nil
+	}
+	if !held {
+		f.assignments = append(f.assignments, fakeQueueAssignment{vehicleID, verifierID, method})
+	}
+	v.assignedTo, v.expiresAt = verifierID, expiresAt
+	return true, nil
+}
+
+func (f *fakeQueueRepository) Next(now time.Time, sla repository.StageSLA, skip []int) (int, error) {
+	for _, v := range f.vehicles {
+		free := v.raced || v.assignedTo == 0 || !v.expiresAt.After(now)
+		skipped := false
+		for _, id := range skip {
+			skipped = skipped || id == v.id
+		}
+		if free && !skipped {
+			return v.id, nil
+		}
+	}
+	return 0, nil
+}
+
+func (f *fakeQueueRepository) Release(vehicleID, verifierID int, now time.Time) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeQueueRepository) Breached(now time.Time, sla repository.StageSLA) ([]models.QueueItem, error) {
+	return nil, nil
+}
+
+func (f *fakeQueueRepository) MarkEscalated(vehicleID int, stageEnteredAt, now time.Time) (bool, error) {
+	return false, nil
+}
+
+func (f *fakeQueueRepository) Users(roles []string) ([]models.Verifier, error) {
+	return f.verifiers, nil
+}
+
+func (f *fakeQueueRepository) Throughput(since time.Time) (map[int]*models.VerifierWorkload, error) {
+	return nil, nil
+}
+
+func newFakeQueueService(repo *fakeQueueRepository, now *time.Time) *VerificationQueueService {
+	cfg := &config.VerificationQueueConfig{
+		ClaimTTL:  30 * time.Minute,
+		AssignTTL: 8 * time.Hour,
+		SLA:       map[string]time.Duration{"pending": 24 * time.Hour},
+	}
+	return &VerificationQueueService{repo: repo, cfg: cfg, now: func() time.Time { return *now }}
+}
+
+func TestQueueClaim(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	repo := &fakeQueueRepository{vehicles: []*fakeQueueVehicle{{id: 1}}}
+	s := newFakeQueueService(repo, &now)
+
+	item, err := s.Claim(1, 10)
+	if err != nil {
+		t.Fatalf("Expected the claim to succeed, got %v", err)
+	}
+	if *item.AssignedTo != 10 || !item.AssignmentExpiresAt.Equal(now.Add(30*time.Minute)) {
+		t.Errorf("Expected verifier 10 to hold it for 30 minutes, got %d until %v", *item.AssignedTo, item.AssignmentExpiresAt)
+	}
+
+	// Claiming it again renews the claim without counting a new one
+	now = now.Add(20 * time.Minute)
+	item, err = s.Claim(1, 10)
+	if err != nil || !item.AssignmentExpiresAt.Equal(now.Add(30*time.Minute)) {
+		t.Errorf("Expected the claim to be renewed, got %v (%v)", item, err)
+	}
+	if len(repo.assignments) != 1 || repo.assignments[0].method != config.AssignmentClaim {
+		t.Errorf("Expected one claim recorded, got %v", repo.assignments)
+	}
+
+	if _, err := s.Claim(1, 11); !errors.Is(err, ErrQueueUnavailable) {
+		t.Errorf("Expected a held submission to be unavailable, got %v", err)
+	}
+	if _, err := s.Claim(2, 11); !errors.Is(err, ErrQueueUnavailable) {
+		t.Errorf("Expected a submission not in the queue to be unavailable, got %v", err)
+	}
+
+	// A lapsed claim is free for others
+	now = now.Add(31 * time.Minute)
+	if item, err := s.Claim(1, 11); err != nil || *item.AssignedTo != 11 {
+		t.Errorf("Expected verifier 11 to take the lapsed claim, got %v (%v)", item, err)
+	}
+	if len(repo.assignments) != 2 {
+		t.Errorf("Expected two claims recorded, got %v", repo.assignments)
+	}
+}
+
+func TestQueueClaimNext(t *testing.T) {
+	now := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	held := now.Add(time.Hour)
+	repo := &fakeQueueRepository{vehicles: []*fakeQueueVehicle{
+		{id: 1, assignedTo: 11, expiresAt: held, raced: true},
+		{id: 2},
+		{id: 3, assignedTo: 12, expiresAt: held},
+	}}
+	s := newFakeQueueService(repo, &now)
+
+	// The first one is taken by another verifier in between; the next is
+	// claimed instead
+	item, err := s.ClaimNext(10)
+	if err != nil || item.VehicleID != 2 || *item.AssignedTo != 10 {
+		t.Fatalf("Expected verifier 10 to claim vehicle 2, got %v (%v)", item, err)
+	}
+	if _, err := s.ClaimNext(10); !errors.Is(err, ErrQueueEmpty) {
+		t.Errorf("Expected the queue to be empty, got %v", err)
+	}
+}
+
+func TestAssignRoundRobin(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(hour int) *time.Time {
+		t := time.Date(2025, 3, 1, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+	repo := &fakeQueueRepository{vehicles: []*fakeQueueVehicle{
+		{id: 101}, {id: 102}, {id: 103}, {id: 104, assignedTo: 9, expiresAt: now.Add(time.Hour)}, {id: 105},
+	}}
+	s := newFakeQueueService(repo, &now)
+
+	if n, err := s.AssignRoundRobin(); err != nil || n != 0 || len(repo.assignments) != 0 {
+		t.Fatalf("Expected nothing assigned without verifiers, got %d (%v)", n, err)
+	}
+
+	repo.verifiers = []models.Verifier{{ID: 1, LastAssignedAt: at(10)}, {ID: 2}, {ID: 3, LastAssignedAt: at(9)}}
+	n, err := s.AssignRoundRobin()
+	if err != nil || n != 4 {
+		t.Fatalf("Expected 4 submissions assigned, got %d (%v)", n, err)
+	}
+	want := []fakeQueueAssignment{
+		{101, 2, config.AssignmentRoundRobin},
+		{102, 3, config.AssignmentRoundRobin},
+		{103, 1, config.AssignmentRoundRobin},
+		{105, 2, config.AssignmentRoundRobin},
+	}
+	for i, a := range repo.assignments {
+		if a != want[i] {
+			t.Errorf("Expected assignment %d to be %v, got %v", i, want[i], a)
+		}
+	}
+	if v := repo.vehicle(104); v.assignedTo != 9 {
+		t.Errorf("Expected the held submission to stay with verifier 9, got %d", v.assignedTo)
+	}
+	if v := repo.vehicle(101); !v.expiresAt.Equal(now.Add(8 * time.Hour)) {
+		t.Errorf("Expected an assignment to last 8 hours, got until %v", v.expiresAt)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/youruser/aplikasi-tms/backend/internal/auth"
@@ -43,7 +44,7 @@ func testLoginHandler(c *gin.Context) {
 	}
 	
 	if req.Email == "test@tms.com" && req.Password == "password" {
-		token, _ := auth.GenerateToken(1, "testuser", "user")
+		token, _, _ := auth.GenerateAccessToken(1, "testuser", "user", "test-session", 15*time.Minute)
 		c.JSON(http.StatusOK, gin.H{
 			"token": token,
 			"user": gin.H{
@@ -76,13 +76,17 @@ class RegisterRequest {
 
 class LoginResponse {
   final String token;
+  final String? refreshToken;
+  final int? expiresIn;
   final User user;
   
-  LoginResponse({required this.token, required this.user});
+  LoginResponse({required this.token, this.refreshToken, this.expiresIn, required this.user});
   
   factory LoginResponse.fromJson(Map<String, dynamic> json) {
     return LoginResponse(
       token: json['token'],
+      refreshToken: json['refresh_token'],
+      expiresIn: json['expires_in'],
       user: User.fromJson(json['user']),
     );
   }
@@ -1,4 +1,5 @@
 import 'package:flutter/material.dart';
+import '../models/models.dart';
 import '../services/auth_service.dart';
 
 class LoginScreen extends StatefulWidget {
@@ -166,16 +167,27 @@ class _LoginScreenState extends State<LoginScreen> {
       });
 
       try {
-        final response = await AuthService.login(
-          _emailController.text,
-          _passwordController.text,
-        );
+        LoginResponse response;
+        try {
+          response = await AuthService.login(
+            _emailController.text,
+            _passwordController.text,
+          );
+        } on TwoFactorRequired catch (challenge) {
+          final completed = challenge.step == 'enroll'
+              ? await _enrollTwoFactor(challenge.token)
+              : await _verifyTwoFactor(challenge.token);
+          if (completed == null) {
+            return;
+          }
+          response = completed;
+        }
 
         if (mounted) {
           print('Login response - User role: ${response.user.role}');
           print('Login response - User email: ${response.user.email}');
           
-          if (response.user.role == 'admin') {
+          if (['super_admin', 'verifier'].contains(response.user.role)) {
             print('Navigating to admin dashboard');
             Navigator.pushReplacementNamed(context, '/admin-dashboard');
           } else {
@@ -202,6 +214,99 @@ class _LoginScreenState extends State<LoginScreen> {
     }
   }
 
+  Future<String?> _askCode(String title, String message) async {
+    final controller = TextEditingController();
+    final code = await showDialog<String>(
+      context: context,
+      barrierDismissible: false,
+      builder: (context) => AlertDialog(
+        title: Text(title),
+        content: Column(
+          mainAxisSize: MainAxisSize.min,
+          crossAxisAlignment: CrossAxisAlignment.start,
+          children: [
+            SelectableText(message),
+            const SizedBox(height: 16),
+            TextField(
+              controller: controller,
+              autofocus: true,
+              decoration: const InputDecoration(
+                labelText: 'Kode',
+                border: OutlineInputBorder(),
+              ),
+            ),
+          ],
+        ),
+        actions: [
+          TextButton(
+            onPressed: () => Navigator.pop(context),
+            child: const Text('Batal'),
+          ),
+          ElevatedButton(
+            onPressed: () => Navigator.pop(context, controller.text.trim()),
+            child: const Text('Lanjut'),
+          ),
+        ],
+      ),
+    );
+    controller.dispose();
+    return code == null || code.isEmpty ? null : code;
+  }
+
+  Future<LoginResponse?> _verifyTwoFactor(String token) async {
+    final code = await _askCode(
+      'Verifikasi 2 Langkah',
+      'Masukkan kode 6 digit dari aplikasi authenticator, atau salah satu kode pemulihan.',
+    );
+    if (code == null) {
+      return null;
+    }
+    return await AuthService.verifyTwoFactor(token, code);
+  }
+
+  Future<LoginResponse?> _enrollTwoFactor(String token) async {
+    final setup = await AuthService.setupTwoFactor(token);
+    if (!mounted) {
+      return null;
+    }
+    final code = await _askCode(
+      'Aktifkan Verifikasi 2 Langkah',
+      'Akun Anda wajib memakai verifikasi 2 langkah. Tambahkan kunci berikut ke aplikasi authenticator, '
+          'lalu masukkan kode yang muncul.\n\nKunci: ${setup['secret']}\n\n${setup['provisioning_uri']}',
+    );
+    if (code == null) {
+      return null;
+    }
+
+    final recoveryCodes = await AuthService.enableTwoFactor(token, code);
+    if (mounted) {
+      await showDialog(
+        context: context,
+        barrierDismissible: false,
+        builder: (context) => AlertDialog(
+          title: const Text('Kode Pemulihan'),
+          content: SelectableText(
+            'Simpan kode berikut di tempat aman. Setiap kode hanya bisa dipakai sekali '
+            'jika Anda kehilangan akses ke aplikasi authenticator.\n\n${recoveryCodes.join('\n')}',
+          ),
+          actions: [
+            ElevatedButton(
+              onPressed: () => Navigator.pop(context),
+              child: const Text('Sudah Disimpan'),
+            ),
+          ],
+        ),
+      );
+    }
+
+    final user = await AuthService.getCurrentUser();
+    final accessToken = await AuthService.getToken();
+    if (user == null || accessToken == null) {
+      return null;
+    }
+    return LoginResponse(token: accessToken, user: user);
+  }
+
   @override
   void dispose() {
     _emailController.dispose();
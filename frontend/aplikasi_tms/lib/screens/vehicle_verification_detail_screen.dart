@@ -1044,9 +1044,24 @@ class _VehicleVerificationDetailScreenState extends State<VehicleVerificationDet
     );
   }
 
+  // A registry that could not be asked is not a failure of the vehicle
+  Color _checkStatusColor(String? status) {
+    switch (status) {
+      case 'passed':
+        return Colors.green;
+      case 'warning':
+      case 'unavailable':
+        return Colors.orange;
+      default:
+        return Colors.red;
+    }
+  }
+
   Widget _buildCheckResult(Map<String, dynamic> result) {
-    Color resultColor = result['status'] == 'passed' ? Colors.green : Colors.red;
-    IconData resultIcon = result['status'] == 'passed' ? Icons.check_circle : Icons.error;
+    Color resultColor = _checkStatusColor(result['status']);
+    IconData resultIcon = result['status'] == 'passed'
+        ? Icons.check_circle
+        : result['status'] == 'unavailable' ? Icons.cloud_off : Icons.error;
     
     return Container(
       margin: EdgeInsets.only(bottom: 8),
@@ -1340,7 +1355,7 @@ class _VehicleVerificationDetailScreenState extends State<VehicleVerificationDet
       ScaffoldMessenger.of(context).showSnackBar(
         SnackBar(
           content: Text(message),
-          backgroundColor: status == 'passed' ? Colors.green : Colors.red,
+          backgroundColor: _checkStatusColor(status),
         ),
       );
     } catch (e) {
@@ -2,6 +2,7 @@ import 'dart:convert';
 import 'package:http/http.dart' as http;
 import '../config/api_config.dart';
 import '../models/models.dart';
+import 'auth_service.dart';
 
 class VehicleUtilization {
   final int vehicleId;
@@ -32,9 +33,13 @@ class VehicleUtilization {
 class AnalyticsService {
   static Future<DashboardStats> getDashboardStats() async {
     try {
+      final token = await AuthService.getToken();
       final response = await http.get(
         Uri.parse('${ApiConfig.baseUrl}/dashboard/stats'),
-        headers: {'Content-Type': 'application/json'},
+        headers: {
+          'Content-Type': 'application/json',
+          if (token != null) 'Authorization': 'Bearer $token',
+        },
       );
 
       if (response.statusCode == 200) {
@@ -50,9 +55,13 @@ class AnalyticsService {
 
   static Future<List<VehicleUtilization>> getVehicleUtilization() async {
     try {
+      final token = await AuthService.getToken();
       final response = await http.get(
         Uri.parse('${ApiConfig.baseUrl}/dashboard/vehicle-utilization'),
-        headers: {'Content-Type': 'application/json'},
+        headers: {
+          'Content-Type': 'application/json',
+          if (token != null) 'Authorization': 'Bearer $token',
+        },
       );
 
       if (response.statusCode == 200) {
@@ -3,6 +3,15 @@ import 'package:http/http.dart' as http;
 import 'package:shared_preferences/shared_preferences.dart';
 import '../models/models.dart';
 
+// Thrown by login when the password was right but a second step is needed:
+// step is 'verify' (enter a code) or 'enroll' (set up 2FA first)
+class TwoFactorRequired implements Exception {
+  final String step;
+  final String token;
+
+  TwoFactorRequired(this.step, this.token);
+}
+
 class AuthService {
   static const String baseUrl = '/api/v1';
 
@@ -18,15 +27,14 @@ class AuthService {
 
     if (response.statusCode == 200) {
       final data = jsonDecode(response.body);
-      print('Login API response: $data');
+      if (data['two_factor'] != null) {
+        throw TwoFactorRequired(data['two_factor'], data['two_factor_token']);
+      }
       
       final loginResponse = LoginResponse.fromJson(data);
       print('Parsed user role: ${loginResponse.user.role}');
       
-      // Save token
-      final prefs = await SharedPreferences.getInstance();
-      await prefs.setString('token', loginResponse.token);
-      await prefs.setString('user', jsonEncode(loginResponse.user.toJson()));
+      await _saveSession(loginResponse);
       
       return loginResponse;
     } else {
@@ -35,6 +43,46 @@ class AuthService {
     }
   }
 
+  // Second login step with a code from the authenticator app or a recovery code
+  static Future<LoginResponse> verifyTwoFactor(String challengeToken, String code) async {
+    final data = await _postTwoFactor('verify', challengeToken, {'code': code});
+    final loginResponse = LoginResponse.fromJson(data);
+    await _saveSession(loginResponse);
+    return loginResponse;
+  }
+
+  // Returns the secret and otpauth:// URI to add to an authenticator app
+  static Future<Map<String, dynamic>> setupTwoFactor(String token) async {
+    return await _postTwoFactor('setup', token, {});
+  }
+
+  // Confirms enrollment and returns the recovery codes. When enrolling during
+  // login the new session is saved as well.
+  static Future<List<String>> enableTwoFactor(String token, String code) async {
+    final data = await _postTwoFactor('enable', token, {'code': code});
+    if (data['session'] != null) {
+      await _saveSession(LoginResponse.fromJson(data['session']));
+    }
+    return List<String>.from(data['recovery_codes']);
+  }
+
+  static Future<Map<String, dynamic>> _postTwoFactor(String path, String token, Map<String, dynamic> body) async {
+    final response = await http.post(
+      Uri.parse('$baseUrl/auth/2fa/$path'),
+      headers: {
+        'Content-Type': 'application/json',
+        'Authorization': 'Bearer $token',
+      },
+      body: jsonEncode(body),
+    );
+
+    final data = jsonDecode(response.body);
+    if (response.statusCode != 200) {
+      throw Exception(data['error'] ?? 'Two-factor request failed');
+    }
+    return data;
+  }
+
   static Future<LoginResponse> register(String username, String email, String password, String fullName) async {
     final response = await http.post(
       Uri.parse('$baseUrl/register'),
@@ -56,9 +104,63 @@ class AuthService {
     }
   }
 
+  // Returns the access token, refreshing it first when it is about to expire
   static Future<String?> getToken() async {
     final prefs = await SharedPreferences.getInstance();
-    return prefs.getString('token');
+    final token = prefs.getString('token');
+    final expiresAt = prefs.getInt('token_expires_at');
+    if (token == null || expiresAt == null) {
+      return token;
+    }
+
+    final refreshAt = DateTime.fromMillisecondsSinceEpoch(expiresAt).subtract(const Duration(seconds: 30));
+    if (DateTime.now().isBefore(refreshAt)) {
+      return token;
+    }
+    return await _refresh() ?? token;
+  }
+
+  static Future<String?> _refresh() async {
+    final prefs = await SharedPreferences.getInstance();
+    final refreshToken = prefs.getString('refresh_token');
+    if (refreshToken == null) {
+      return null;
+    }
+
+    final response = await http.post(
+      Uri.parse('$baseUrl/auth/refresh'),
+      headers: {'Content-Type': 'application/json'},
+      body: jsonEncode({'refresh_token': refreshToken}),
+    );
+
+    if (response.statusCode == 200) {
+      final loginResponse = LoginResponse.fromJson(jsonDecode(response.body));
+      await _saveSession(loginResponse);
+      return loginResponse.token;
+    }
+    if (response.statusCode == 401) {
+      // Session expired or was revoked; the user has to log in again
+      await _clearSession(prefs);
+    }
+    return null;
+  }
+
+  static Future<void> _saveSession(LoginResponse loginResponse) async {
+    final prefs = await SharedPreferences.getInstance();
+    await prefs.setString('token', loginResponse.token);
+    await prefs.setString('user', jsonEncode(loginResponse.user.toJson()));
+    if (loginResponse.refreshToken != null && loginResponse.expiresIn != null) {
+      final expiresAt = DateTime.now().add(Duration(seconds: loginResponse.expiresIn!));
+      await prefs.setString('refresh_token', loginResponse.refreshToken!);
+      await prefs.setInt('token_expires_at', expiresAt.millisecondsSinceEpoch);
+    }
+  }
+
+  static Future<void> _clearSession(SharedPreferences prefs) async {
+    await prefs.remove('token');
+    await prefs.remove('refresh_token');
+    await prefs.remove('token_expires_at');
+    await prefs.remove('user');
   }
 
   static Future<User?> getCurrentUser() async {
@@ -72,8 +174,51 @@ class AuthService {
 
   static Future<void> logout() async {
     final prefs = await SharedPreferences.getInstance();
-    await prefs.remove('token');
-    await prefs.remove('user');
+    final token = prefs.getString('token');
+    if (token != null) {
+      try {
+        await http.post(
+          Uri.parse('$baseUrl/auth/logout'),
+          headers: {'Authorization': 'Bearer $token'},
+        );
+      } catch (e) {
+        print('Logout request failed: $e');
+      }
+    }
+    await _clearSession(prefs);
+  }
+
+  static Future<void> verifyEmail(String token) async {
+    await _postAction('auth/verify-email', {'token': token});
+  }
+
+  static Future<void> resendVerification() async {
+    final token = await getToken();
+    await _postAction('auth/resend-verification', {}, token: token);
+  }
+
+  static Future<void> forgotPassword(String email) async {
+    await _postAction('auth/forgot-password', {'email': email});
+  }
+
+  static Future<void> resetPassword(String token, String password) async {
+    await _postAction('auth/reset-password', {'token': token, 'password': password});
+  }
+
+  static Future<void> _postAction(String path, Map<String, dynamic> body, {String? token}) async {
+    final response = await http.post(
+      Uri.parse('$baseUrl/$path'),
+      headers: {
+        'Content-Type': 'application/json',
+        if (token != null) 'Authorization': 'Bearer $token',
+      },
+      body: jsonEncode(body),
+    );
+
+    if (response.statusCode != 200) {
+      final error = jsonDecode(response.body);
+      throw Exception(error['error'] ?? 'Request failed');
+    }
   }
 
   static Future<void> clearCache() async {